@@ -0,0 +1,297 @@
+package main
+
+import (
+	"github.com/Ravierin/BudgetTracker/backend/internal/model"
+	"github.com/Ravierin/BudgetTracker/backend/internal/repository"
+	"github.com/Ravierin/BudgetTracker/backend/internal/service"
+	"github.com/Ravierin/BudgetTracker/backend/pkg/database"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+)
+
+// runCommand executes a one-off maintenance subcommand instead of the server
+func runCommand(ctx context.Context, stores *repository.Stores, args []string) error {
+	// Mappers used by commands (reprocess, import) need the applied sizes
+	if err := service.NewContractService(stores.Contracts).Load(ctx); err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "rebuild-rollup":
+		if stores.Rollup == nil {
+			return fmt.Errorf("this storage backend has no rollup")
+		}
+		rows, err := stores.Rollup.Rebuild(ctx)
+		if err != nil {
+			return err
+		}
+		log.Printf("Rebuilt daily rollup: %d rows", rows)
+		return nil
+	case "import":
+		return runImport(ctx, stores, args[1:])
+	case "backup":
+		return runBackup(ctx, stores, args[1:])
+	case "restore":
+		return runRestore(ctx, stores, args[1:])
+	case "reprocess":
+		return runReprocess(ctx, stores, args[1:])
+	case "backfill-volumes":
+		return runBackfillVolumes(ctx, stores, args[1:])
+	default:
+		return fmt.Errorf("unknown command: %s", args[0])
+	}
+}
+
+// runMigrate manages the schema version:
+//
+//	budget-tracker migrate up|version
+//	budget-tracker migrate down [n]
+//	budget-tracker migrate force <version>
+func runMigrate(ctx context.Context, migrator *database.Migrator, args []string) error {
+	usage := fmt.Errorf("usage: migrate up | down [n] | version | force <version>")
+	if len(args) == 0 {
+		return usage
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		log.Printf("Applied %d migrations", applied)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n <= 0 {
+				return usage
+			}
+			steps = n
+		}
+		if err := migrator.Down(ctx, steps); err != nil {
+			return err
+		}
+	case "force":
+		if len(args) != 2 {
+			return usage
+		}
+		version, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil || version < 0 {
+			return usage
+		}
+		if err := migrator.Force(ctx, version); err != nil {
+			return err
+		}
+	case "version":
+	default:
+		return usage
+	}
+
+	version, dirty, err := migrator.Version(ctx)
+	if err != nil {
+		return err
+	}
+	log.Printf("Schema version %d of %d (dirty: %v)", version, migrator.Latest(), dirty)
+	return nil
+}
+
+// runImport imports an exchange CSV statement, or any CSV through a saved
+// column-mapping profile:
+//
+//	budget-tracker import [-dry-run] bybit|mexc statement.csv
+//	budget-tracker import [-dry-run] -profile <name> trades.csv
+func runImport(ctx context.Context, stores *repository.Stores, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "preview without writing")
+	profile := fs.String("profile", "", "column-mapping profile for generic CSVs")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	format, path := *profile, fs.Arg(0)
+	if *profile == "" {
+		if fs.NArg() != 2 {
+			return fmt.Errorf("usage: import [-dry-run] bybit|mexc <file.csv> | import [-dry-run] -profile <name> <file.csv>")
+		}
+		format, path = fs.Arg(0), fs.Arg(1)
+	} else if fs.NArg() != 1 {
+		return fmt.Errorf("usage: import [-dry-run] -profile <name> <file.csv>")
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	importService := service.NewImportService(stores.Positions, stores.ImportProfiles)
+
+	var result *model.ImportResult
+	if *profile != "" {
+		result, err = importService.ImportWithProfile(ctx, *profile, file, *dryRun)
+	} else {
+		result, err = importService.ImportPositions(ctx, format, file, *dryRun)
+	}
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Import %s: %d new, %d updated, %d unchanged, %d conflicts, %d errors (applied: %v)",
+		format, len(result.New), len(result.Updated), result.Unchanged, len(result.Conflicts), len(result.Errors), result.Applied)
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(result)
+}
+
+func newBackupService(stores *repository.Stores) *service.BackupService {
+	return service.NewBackupService(
+		stores.Positions,
+		stores.Withdrawals,
+		stores.MonthlyIncomes,
+		stores.Settings,
+		stores.ImportProfiles,
+		stores.APIKeys,
+		stores.Backup,
+	)
+}
+
+// runBackup writes a full backup to a file, or stdout with "-o -". API keys
+// are encrypted with the BACKUP_PASSPHRASE environment variable.
+//
+//	budget-tracker backup [-format json|ndjson] [-include-keys] -o backup.json
+func runBackup(ctx context.Context, stores *repository.Stores, args []string) error {
+	fs := flag.NewFlagSet("backup", flag.ContinueOnError)
+	format := fs.String("format", "json", "archive format: json or ndjson (gzipped)")
+	includeKeys := fs.Bool("include-keys", false, "include API keys, encrypted with BACKUP_PASSPHRASE")
+	output := fs.String("o", "", "output file, - for stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *output == "" {
+		return fmt.Errorf("usage: backup [-format json|ndjson] [-include-keys] -o <file>")
+	}
+
+	out := os.Stdout
+	if *output != "-" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+
+	err := newBackupService(stores).Backup(ctx, out, service.BackupOptions{
+		Format:      *format,
+		IncludeKeys: *includeKeys,
+		Passphrase:  os.Getenv("BACKUP_PASSPHRASE"),
+	})
+	if err != nil {
+		return err
+	}
+
+	if *output != "-" {
+		log.Printf("Backup written to %s", *output)
+		return out.Close()
+	}
+	return nil
+}
+
+// runRestore applies a backup file in one transaction:
+//
+//	budget-tracker restore [-mode merge|replace] backup.json
+func runRestore(ctx context.Context, stores *repository.Stores, args []string) error {
+	fs := flag.NewFlagSet("restore", flag.ContinueOnError)
+	mode := fs.String("mode", "merge", "merge into existing data or replace it")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: restore [-mode merge|replace] <file>")
+	}
+
+	file, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	result, err := newBackupService(stores).Restore(ctx, file, service.RestoreOptions{
+		Mode:       *mode,
+		Passphrase: os.Getenv("BACKUP_PASSPHRASE"),
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Restore (%s): %d positions, %d withdrawals, %d monthly incomes, %d settings, %d import profiles, %d API keys",
+		result.Mode, result.Positions, result.Withdrawals, result.MonthlyIncomes, result.Settings, result.ImportProfiles, result.APIKeys)
+	return nil
+}
+
+// runReprocess re-maps the stored exchange payloads with the current mappers
+// and updates the positions that come out differently:
+//
+//	budget-tracker reprocess [-dry-run] [bybit|mexc]
+func runReprocess(ctx context.Context, stores *repository.Stores, args []string) error {
+	fs := flag.NewFlagSet("reprocess", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "report changes without writing")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 1 {
+		return fmt.Errorf("usage: reprocess [-dry-run] [bybit|mexc]")
+	}
+
+	rawRecordService := service.NewRawRecordService(stores.RawRecords, stores.Positions)
+	result, err := rawRecordService.Reprocess(ctx, fs.Arg(0), *dryRun)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Reprocess: %d payloads, %d changed, %d unchanged, %d missing, %d errors (applied: %v)",
+		result.Records, len(result.Changed), result.Unchanged, result.Missing, len(result.Errors), result.Applied)
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(result)
+}
+
+// runBackfillVolumes fetches the MEXC contract listing and rescales stored
+// position volumes to it; with -dry-run it only reports, from the stored
+// listing:
+//
+//	budget-tracker backfill-volumes [-dry-run]
+func runBackfillVolumes(ctx context.Context, stores *repository.Stores, args []string) error {
+	fs := flag.NewFlagSet("backfill-volumes", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "report changes without writing")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	contractService := service.NewContractService(stores.Contracts)
+
+	if !*dryRun {
+		if _, err := contractService.Refresh(ctx); err != nil {
+			return err
+		}
+	}
+	result, err := contractService.BackfillVolumes(ctx, *dryRun)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Volume backfill: %d positions across %d symbols (applied: %v)",
+		result.Positions, len(result.Symbols), result.Applied)
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(result)
+}
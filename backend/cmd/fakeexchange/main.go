@@ -0,0 +1,30 @@
+// Command fakeexchange serves Bybit V5 and MEXC contract V1 compatible
+// endpoints from a scenario file, for running sync end to end offline:
+//
+//	go run ./cmd/fakeexchange -scenario default
+//	BYBIT_BASE_URL=http://localhost:9090 MEXC_BASE_URL=http://localhost:9090 go run ./cmd
+package main
+
+import (
+	"github.com/Ravierin/BudgetTracker/backend/internal/fakeexchange"
+	"flag"
+	"log"
+	"net/http"
+)
+
+func main() {
+	addr := flag.String("addr", ":9090", "listen address")
+	scenarioPath := flag.String("scenario", "default", "scenario file, or the name of a built-in scenario")
+	flag.Parse()
+
+	scenario, err := fakeexchange.LoadScenario(*scenarioPath)
+	if err != nil {
+		log.Fatalf("Failed to load scenario: %v", err)
+	}
+
+	log.Printf("Fake exchange on %s (api key %q, %d Bybit closed PnL, %d MEXC positions)",
+		*addr, scenario.APIKey, len(scenario.Bybit.ClosedPnl), len(scenario.MEXC.HistoryPositions))
+	if err := http.ListenAndServe(*addr, fakeexchange.NewServer(scenario)); err != nil {
+		log.Fatalf("Fake exchange stopped: %v", err)
+	}
+}
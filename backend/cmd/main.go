@@ -4,53 +4,222 @@ import (
 	"github.com/Ravierin/BudgetTracker/backend/internal/api"
 	"github.com/Ravierin/BudgetTracker/backend/internal/repository"
 	"github.com/Ravierin/BudgetTracker/backend/internal/service"
+	"github.com/Ravierin/BudgetTracker/backend/migrations"
+	"github.com/Ravierin/BudgetTracker/backend/pkg/cluster"
 	"github.com/Ravierin/BudgetTracker/backend/pkg/config"
 	"github.com/Ravierin/BudgetTracker/backend/pkg/database"
+	"github.com/Ravierin/BudgetTracker/backend/pkg/scheduler"
 	"github.com/Ravierin/BudgetTracker/backend/pkg/server"
 	"context"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 )
 
+// shutdownTimeout bounds the whole shutdown: syncs get canceled right away,
+// but the batch they are writing may take a while to commit
+const shutdownTimeout = 45 * time.Second
+
 func main() {
 	cfg, err := config.LoadConfig()
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
-	db, err := database.NewDatabase(cfg.GetDSN())
+	if cfg.BybitBaseURL != "" {
+		api.BybitBaseURL = cfg.BybitBaseURL
+	}
+	if cfg.MEXCBaseURL != "" {
+		api.MEXCBaseURL = cfg.MEXCBaseURL
+	}
+	if cfg.BybitStreamURL != "" {
+		api.BybitStreamURL = cfg.BybitStreamURL
+	}
+	if cfg.MEXCStreamURL != "" {
+		api.MEXCStreamURL = cfg.MEXCStreamURL
+	}
+	switch {
+	case cfg.ReplayDir != "":
+		replayer, err := api.NewReplayer(cfg.ReplayDir)
+		if err != nil {
+			log.Fatalf("Failed to load exchange recordings: %v", err)
+		}
+		api.Transport = replayer
+		log.Printf("Replaying exchange responses from %s", cfg.ReplayDir)
+	case cfg.RecordDir != "":
+		recorder, err := api.NewRecorder(cfg.RecordDir)
+		if err != nil {
+			log.Fatalf("Failed to start exchange recorder: %v", err)
+		}
+		api.Transport = recorder
+		log.Printf("Recording exchange responses to %s", cfg.RecordDir)
+	}
+
+	stores, migrator, pg, closeDB, err := openStorage(cfg)
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
-	}
-	defer db.Close()
-
-	positionRepo := repository.NewPositionRepository(db)
-	positionService := service.NewPositionService(positionRepo)
-	withdrawalRepo := repository.NewWithdrawalRepository(db)
-	withdrawalService := service.NewWithdrawalService(withdrawalRepo)
-	incomeRepo := repository.NewMonthlyIncomeRepository(db)
-	incomeService := service.NewMonthlyIncomeService(incomeRepo)
-	apiKeyRepo := repository.NewAPIKeyRepository(db)
-	apiKeyService := service.NewAPIKeyService(apiKeyRepo)
+		log.Fatalf("Failed to open database: %v", err)
+	}
+	defer closeDB()
+
+	// "migrate" runs before the schema check so a dirty schema can be repaired
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(context.Background(), migrator, os.Args[2:]); err != nil {
+			log.Fatalf("Command migrate failed: %v", err)
+		}
+		return
+	}
+
+	if cfg.AutoMigrate {
+		if _, err := migrator.Up(context.Background()); err != nil {
+			log.Fatalf("Failed to migrate database: %v", err)
+		}
+	} else if err := migrator.Check(context.Background()); err != nil {
+		log.Fatalf("Database schema check failed: %v", err)
+	}
+
+	// Positions saved before instruments were tracked get theirs here
+	if n, err := stores.Positions.NormalizeInstruments(context.Background()); err != nil {
+		log.Printf("Failed to normalize position instruments: %v", err)
+	} else if n > 0 {
+		log.Printf("Normalized the instrument of %d positions", n)
+	}
+
+	// Subcommands (e.g. "rebuild-rollup") run once and exit
+	if len(os.Args) > 1 {
+		if err := runCommand(context.Background(), stores, os.Args[1:]); err != nil {
+			log.Fatalf("Command %s failed: %v", os.Args[1], err)
+		}
+		return
+	}
+
+	// The root context ends on SIGINT/SIGTERM, stopping every background job
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	positionRepo := stores.Positions
+	positionService := service.NewPositionService(positionRepo, stores.Rollup)
+	withdrawalService := service.NewWithdrawalService(stores.Withdrawals)
+	incomeService := service.NewMonthlyIncomeService(stores.MonthlyIncomes)
+	apiKeyService := service.NewAPIKeyService(stores.APIKeys)
 	balanceService := service.NewBalanceService(apiKeyService)
+	importService := service.NewImportService(positionRepo, stores.ImportProfiles)
+	settingsService := service.NewSettingsService(stores.Settings)
+	backupService := newBackupService(stores)
+	rawRecordService := service.NewRawRecordService(stores.RawRecords, positionRepo)
+	contractService := service.NewContractService(stores.Contracts)
+	openPositionService := service.NewOpenPositionService(apiKeyService)
+	changeService := service.NewChangeService(stores.Changes)
+	syncRunService := service.NewSyncRunService(stores.SyncRuns)
+	if err := contractService.Load(ctx); err != nil {
+		log.Printf("Failed to load contract metadata: %v", err)
+	}
+
+	// Background work runs as scheduled jobs, listed under /api/v1/admin/jobs
+	jobs := scheduler.New(ctx)
+	mustAddJob(jobs, scheduler.Job{
+		Name:     "contracts-refresh",
+		Schedule: cfg.ContractRefreshSchedule,
+		Timeout:  2 * time.Minute,
+		Run: func(ctx context.Context) error {
+			_, err := contractService.Refresh(ctx)
+			return err
+		},
+	})
+	mustAddJob(jobs, scheduler.Job{
+		Name:     "sync-runs-prune",
+		Schedule: cfg.SyncRunPruneSchedule,
+		Timeout:  time.Minute,
+		Run:      syncRunService.Prune,
+	})
 
 	// Create clients with empty keys - will be populated dynamically from DB
-	bybitClient := api.NewBybitClient("", "")
-	mexcClient := api.NewMEXClient("", "")
+	bybitClient := api.NewBybitClient(ctx, "", "")
+	mexcClient := api.NewMEXClient(ctx, "", "")
+
+	// Instances sharing a PostgreSQL database elect a syncing leader per
+	// exchange and relay websocket events to each other
+	var leader cluster.Leader = cluster.Single{}
+	if pg != nil {
+		pgLeader := cluster.NewPostgresLeader(pg)
+		defer pgLeader.Close()
+		leader = pgLeader
+	}
 
-	srv := server.NewServer(positionService, withdrawalService, incomeService, apiKeyService, balanceService, positionRepo, bybitClient, mexcClient)
+	srv := server.NewServer(ctx, positionService, withdrawalService, incomeService, apiKeyService, balanceService, importService, backupService, settingsService, rawRecordService, contractService, openPositionService, changeService, syncRunService, positionRepo, bybitClient, mexcClient, leader, jobs)
+	mustAddJob(jobs, scheduler.Job{
+		Name:     "initial-sync",
+		Schedule: scheduler.Once(cfg.InitialSyncDelay),
+		Timeout:  cfg.InitialSyncTimeout,
+		Run:      srv.InitialSync,
+	})
 
-	// Create sync services for all exchanges
+	// The relay outlives the root context, so events of draining syncs still
+	// reach the other instances
+	relayCtx, stopRelay := context.WithCancel(context.Background())
+	var relayDone sync.WaitGroup
+	if pg != nil {
+		instance := cluster.NewInstanceID()
+		relay := cluster.NewPostgresRelay(pg, srv.GetWSHub(), instance)
+		srv.GetWSHub().SetRelay(relay)
+		relayDone.Add(1)
+		go func() {
+			defer relayDone.Done()
+			relay.Start(relayCtx)
+		}()
+		log.Printf("Relaying websocket events as instance %s", instance)
+	}
+
+	streamed := make(map[string]bool)
+	for _, exchangeName := range cfg.Streams {
+		streamed[exchangeName] = true
+	}
+
+	// Create sync services for all exchanges; streamed ones poll only to fill
+	// gaps, unless given a schedule of their own
 	exchanges := []string{"bybit", "mexc"}
+	var streams sync.WaitGroup
 	for _, exchangeName := range exchanges {
-		syncService := server.NewSyncService(positionService, apiKeyService, srv.GetWSHub(), 30*time.Second, exchangeName)
-		go syncService.Start()
+		schedule, ok := cfg.SyncSchedules[exchangeName]
+		if !ok {
+			schedule = cfg.SyncSchedule
+			if streamed[exchangeName] {
+				schedule = scheduler.Every(cfg.StreamPollInterval)
+			}
+		}
+		jitter, ok := cfg.SyncJitters[exchangeName]
+		if !ok {
+			jitter = cfg.SyncJitter
+		}
+
+		syncService := server.NewSyncService(ctx, positionService, rawRecordService, openPositionService, apiKeyService, syncRunService, srv.GetWSHub(), leader, jobs, exchangeName)
+		if err := syncService.Register(schedule, jitter, cfg.SyncTimeout); err != nil {
+			log.Fatalf("Failed to schedule %s sync: %v", exchangeName, err)
+		}
+
+		if streamed[exchangeName] {
+			streamService := server.NewStreamService(positionService, openPositionService, apiKeyService, syncService, srv.GetWSHub(), leader, exchangeName)
+			streams.Add(1)
+			go func() {
+				defer streams.Done()
+				streamService.Start(ctx)
+			}()
+			log.Printf("[%s] Streaming enabled, REST sync %v", exchangeName, schedule)
+		}
 	}
 
+	// A contract listing older than a day is refreshed right away
+	if stale, err := contractService.Stale(ctx, 24*time.Hour); err != nil {
+		log.Printf("Failed to check contract metadata age: %v", err)
+	} else if stale {
+		jobs.Trigger("contracts-refresh")
+	}
+	jobs.Start()
+
 	httpServer := &http.Server{
 		Addr:         ":8080",
 		Handler:      srv.GetHandler(),
@@ -60,22 +229,93 @@ func main() {
 	}
 
 	go func() {
-		sigChan := make(chan os.Signal, 1)
-		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-		<-sigChan
-		log.Println("Shutting down...")
-
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer cancel()
-		if err := httpServer.Shutdown(ctx); err != nil {
-			log.Printf("HTTP server shutdown error: %v", err)
+		log.Println("Starting server on port 8080")
+		if err := httpServer.ListenAndServe(); err != http.ErrServerClosed {
+			log.Fatalf("Failed to start server: %v", err)
 		}
 	}()
 
-	log.Println("Starting server on port 8080")
-	if err := httpServer.ListenAndServe(); err != http.ErrServerClosed {
-		log.Fatalf("Failed to start server: %v", err)
+	<-ctx.Done()
+	// A second signal kills the process right away
+	stop()
+	log.Println("Shutting down...")
+
+	// Requests, whose contexts ended with the root one, finish first, then
+	// in-flight syncs and stream saves, so their events still reach websocket
+	// clients before those are closed. The
+	// deferred leader and database closes run last.
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		log.Printf("HTTP server shutdown error: %v", err)
+	}
+	if err := jobs.Stop(shutdownCtx); err != nil {
+		log.Printf("Jobs did not stop in time: %v", err)
+	}
+	if err := waitGroup(shutdownCtx, &streams); err != nil {
+		log.Printf("Streams did not stop in time: %v", err)
+	}
+	if err := srv.GetWSHub().Shutdown(shutdownCtx); err != nil {
+		log.Printf("WebSocket shutdown error: %v", err)
+	}
+	stopRelay()
+	if err := waitGroup(shutdownCtx, &relayDone); err != nil {
+		log.Printf("Event relay did not stop in time: %v", err)
 	}
 
 	log.Println("Server stopped")
 }
+
+// waitGroup waits for wg, or for ctx to end
+func waitGroup(ctx context.Context, wg *sync.WaitGroup) error {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func mustAddJob(jobs *scheduler.Scheduler, job scheduler.Job) {
+	if err := jobs.Add(job); err != nil {
+		log.Fatalf("Failed to schedule %s: %v", job.Name, err)
+	}
+}
+
+// openStorage opens the configured backend with its repositories and
+// migrator, and the PostgreSQL database when that is the backend.
+// DB_DRIVER=sqlite needs no server: everything lives in DB_PATH.
+func openStorage(cfg *config.Config) (*repository.Stores, *database.Migrator, *database.Database, func(), error) {
+	switch cfg.Driver {
+	case "postgres":
+		db, err := database.NewDatabase(cfg.GetDSN())
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
+		migrator, err := database.NewMigrator(db, migrations.FS)
+		if err != nil {
+			db.Close()
+			return nil, nil, nil, nil, err
+		}
+		return repository.NewPostgresStores(db), migrator, db, db.Close, nil
+	case "sqlite":
+		db, err := database.NewSQLiteDatabase(cfg.Path)
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
+		migrator, err := database.NewSQLiteMigrator(db, migrations.SQLite)
+		if err != nil {
+			db.Close()
+			return nil, nil, nil, nil, err
+		}
+		log.Printf("Using SQLite database %s", cfg.Path)
+		return repository.NewSQLiteStores(db), migrator, nil, db.Close, nil
+	default:
+		return nil, nil, nil, nil, fmt.Errorf("unknown DB_DRIVER: %s", cfg.Driver)
+	}
+}
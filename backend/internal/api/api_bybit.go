@@ -22,37 +22,52 @@ type BybitClient struct {
 	bybit     *bybit.Client
 	apiKey    string
 	apiSecret string
+	baseURL   string
+	// ctx ends every request of the client, e.g. on shutdown
+	ctx context.Context
 }
 
-func NewBybitClient(apiKey, apiSecretKey string) *BybitClient {
-	bybit := bybit.NewBybitHttpClient(apiKey, apiSecretKey)
+func NewBybitClient(ctx context.Context, apiKey, apiSecretKey string) *BybitClient {
+	bybit := bybit.NewBybitHttpClient(apiKey, apiSecretKey, bybit.WithBaseURL(BybitBaseURL))
+	bybit.HTTPClient = newHTTPClient(ctx)
 	return &BybitClient{
 		bybit:     bybit,
 		apiKey:    apiKey,
 		apiSecret: apiSecretKey,
+		baseURL:   BybitBaseURL,
+		ctx:       ctx,
 	}
 }
 
-func (b *BybitClient) GetPositions() ([]model.Position, error) {
-	return b.GetPositionsWithContext(context.Background())
+func (b *BybitClient) GetPositionsWithContext(ctx context.Context) ([]model.Position, error) {
+	positions, _, err := b.FetchPositions(ctx)
+	return positions, err
 }
 
-func (b *BybitClient) GetPositionsWithContext(ctx context.Context) ([]model.Position, error) {
+// FetchPositions returns closed positions along with the raw list items
+// they were mapped from
+func (b *BybitClient) FetchPositions(ctx context.Context) ([]model.Position, []model.RawExchangeRecord, error) {
 	// Try direct API call to /v5/position/get-closed-positions first
-	positions, err := b.getDirectClosedPositions(ctx)
-	if err == nil && len(positions) > 0 {
-		return positions, nil
+	records, err := b.getDirectClosedPositions(ctx)
+	if err != nil || len(records) == 0 {
+		// Fallback to GetClosePnl for UTA accounts
+		records, err = b.getClosePnl(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
 	}
-	
-	// Fallback to GetClosePnl for UTA accounts
-	return b.getClosePnl(ctx)
+
+	positions, err := mapRawRecords(records)
+	if err != nil {
+		return nil, nil, err
+	}
+	return positions, records, nil
 }
 
 // getDirectClosedPositions calls /v5/position/get-closed-positions directly
-func (b *BybitClient) getDirectClosedPositions(ctx context.Context) ([]model.Position, error) {
+func (b *BybitClient) getDirectClosedPositions(ctx context.Context) ([]model.RawExchangeRecord, error) {
 	log.Printf("[bybit] Trying /v5/position/get-closed-positions...")
 	
-	baseURL := "https://api.bybit.com"
 	endpoint := "/v5/position/get-closed-positions"
 	
 	params := url.Values{}
@@ -62,7 +77,7 @@ func (b *BybitClient) getDirectClosedPositions(ctx context.Context) ([]model.Pos
 	timestamp := strconv.FormatInt(time.Now().UnixMilli(), 10)
 	signature := b.signV5("GET", endpoint, queryString, timestamp)
 	
-	reqURL := baseURL + endpoint + "?" + queryString
+	reqURL := b.baseURL + endpoint + "?" + queryString
 	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
 	if err != nil {
 		return nil, err
@@ -73,7 +88,8 @@ func (b *BybitClient) getDirectClosedPositions(ctx context.Context) ([]model.Pos
 	req.Header.Set("X-BAPI-TIMESTAMP", timestamp)
 	req.Header.Set("X-BAPI-RECV-WINDOW", "30000")
 	
-	client := &http.Client{Timeout: 30 * time.Second}
+	client := newHTTPClient(b.ctx)
+	countPage(ctx)
 	resp, err := client.Do(req)
 	if err != nil {
 		log.Printf("[bybit] HTTP request error: %v", err)
@@ -110,43 +126,12 @@ func (b *BybitClient) getDirectClosedPositions(ctx context.Context) ([]model.Pos
 	
 	log.Printf("[bybit] GetClosedPositions retrieved %d positions", len(items))
 	
-	var positions []model.Position
-	for _, item := range items {
-		posMap, ok := item.(map[string]interface{})
-		if !ok {
-			continue
-		}
-		
-		orderID, _ := posMap["orderId"].(string)
-		symbol, _ := posMap["symbol"].(string)
-		side, _ := posMap["side"].(string)
-		
-		// Volume = cumEntryValue (position value at entry in USDT)
-		volume, _ := strconv.ParseFloat(posMap["cumEntryValue"].(string), 64)
-		leverage, _ := strconv.Atoi(posMap["leverage"].(string))
-		closedPnl, _ := strconv.ParseFloat(posMap["closedPnl"].(string), 64)
-		
-		updatedTime, _ := strconv.ParseFloat(posMap["updatedTime"].(string), 64)
-		date := time.UnixMilli(int64(updatedTime))
-		
-		positions = append(positions, model.Position{
-			OrderID:      orderID,
-			Exchange:     "bybit",
-			Symbol:       symbol,
-			Volume:       volume,
-			Leverage:     leverage,
-			ClosedPnl:    closedPnl,
-			Side:         side,
-			UpdatedAt:    date,
-		})
-	}
-	
-	return positions, nil
+	return bybitRawRecords(RawBybitClosedPnl, items), nil
 }
 
 // getClosePnl uses the GetClosePnl endpoint (for UTA accounts)
-func (b *BybitClient) getClosePnl(ctx context.Context) ([]model.Position, error) {
-	var allPositions []model.Position
+func (b *BybitClient) getClosePnl(ctx context.Context) ([]model.RawExchangeRecord, error) {
+	var allRecords []model.RawExchangeRecord
 
 	// Bybit limits time range to 7 days per request
 	// Paginate through history in 7-day chunks going back up to 2 years (max retention)
@@ -172,7 +157,7 @@ func (b *BybitClient) getClosePnl(ctx context.Context) ([]model.Position, error)
 			time.UnixMilli(startTime).Format("2006-01-02"),
 			time.UnixMilli(endTime).Format("2006-01-02"))
 
-		positions, _, err := b.fetchClosePnlChunk(ctx, startTime, endTime)
+		records, err := b.fetchClosePnlChunk(ctx, startTime, endTime)
 		if err != nil {
 			// If it's a "2 years" error, just stop pagination
 			if strings.Contains(err.Error(), "earlier than 2 years") {
@@ -182,28 +167,30 @@ func (b *BybitClient) getClosePnl(ctx context.Context) ([]model.Position, error)
 			return nil, err
 		}
 
-		log.Printf("[bybit] Retrieved %d positions from chunk", len(positions))
-		allPositions = append(allPositions, positions...)
+		log.Printf("[bybit] Retrieved %d positions from chunk", len(records))
+		allRecords = append(allRecords, records...)
 
 		// Move to next time chunk
 		endTime = startTime
-		time.Sleep(50 * time.Millisecond) // Rate limiting
+		if err := pause(ctx, 50*time.Millisecond); err != nil { // Rate limiting
+			return nil, err
+		}
 	}
 
-	log.Printf("[bybit] Total positions retrieved: %d", len(allPositions))
+	log.Printf("[bybit] Total positions retrieved: %d", len(allRecords))
 
-	if len(allPositions) == 0 {
+	if len(allRecords) == 0 {
 		// Try execution history as last resort
 		log.Printf("[bybit] No positions from getClosePnl, trying execution history...")
 		return b.getExecutionHistory(ctx)
 	}
 
-	return allPositions, nil
+	return allRecords, nil
 }
 
 // fetchClosePnlChunk fetches one chunk of closed PnL data with pagination
-func (b *BybitClient) fetchClosePnlChunk(ctx context.Context, startTime, endTime int64) ([]model.Position, string, error) {
-	var allPositions []model.Position
+func (b *BybitClient) fetchClosePnlChunk(ctx context.Context, startTime, endTime int64) ([]model.RawExchangeRecord, error) {
+	var allRecords []model.RawExchangeRecord
 	var cursor string
 
 	for {
@@ -218,84 +205,102 @@ func (b *BybitClient) fetchClosePnlChunk(ctx context.Context, startTime, endTime
 			params["cursor"] = cursor
 		}
 
+		countPage(ctx)
 		result, err := b.bybit.NewClassicalBybitServiceWithParams(params).GetClosePnl(ctx)
 		if err != nil {
-			return nil, "", err
+			return nil, err
 		}
 
 		if result.RetCode != 0 {
 			log.Printf("[bybit] GetClosePnl error: RetCode=%d, RetMsg=%s", result.RetCode, result.RetMsg)
-			return nil, "", fmt.Errorf("API error: %s", result.RetMsg)
+			return nil, fmt.Errorf("API error: %s", result.RetMsg)
 		}
 
 		list, ok := result.Result.(map[string]interface{})
 		if !ok {
-			return nil, "", nil
+			return nil, nil
 		}
 
 		items, ok := list["list"].([]interface{})
 		if !ok {
-			return nil, "", nil
+			return nil, nil
 		}
 
-		if len(items) > 0 {
-			positions := b.parseClosePnlItems(items)
-			allPositions = append(allPositions, positions...)
-		}
+		allRecords = append(allRecords, bybitRawRecords(RawBybitClosedPnl, items)...)
 
 		// Get next page cursor
 		nextPageCursor, _ := list["nextPageCursor"].(string)
 		if nextPageCursor == "" || len(items) < 100 {
-			return allPositions, "", nil
+			return allRecords, nil
 		}
 
 		cursor = nextPageCursor
-		time.Sleep(50 * time.Millisecond) // Rate limiting
+		if err := pause(ctx, 50*time.Millisecond); err != nil { // Rate limiting
+			return nil, err
+		}
 	}
 }
 
-// parseClosePnlItems parses items from GetClosePnl response
-func (b *BybitClient) parseClosePnlItems(items []interface{}) []model.Position {
-	var positions []model.Position
+// bybitRawRecords keeps list items as fetched, keyed by order ID
+func bybitRawRecords(kind string, items []interface{}) []model.RawExchangeRecord {
+	fetchedAt := time.Now().UTC()
 
+	var records []model.RawExchangeRecord
 	for _, item := range items {
 		posMap, ok := item.(map[string]interface{})
 		if !ok {
 			continue
 		}
+		payload, err := json.Marshal(posMap)
+		if err != nil {
+			continue
+		}
 
 		orderID, _ := posMap["orderId"].(string)
-		symbol, _ := posMap["symbol"].(string)
-		side, _ := posMap["side"].(string)
-
-		volume, _ := strconv.ParseFloat(posMap["cumEntryValue"].(string), 64)
-		leverage, _ := strconv.Atoi(posMap["leverage"].(string))
-		closedPnl, _ := strconv.ParseFloat(posMap["closedPnl"].(string), 64)
-
-		updatedTime, _ := strconv.ParseFloat(posMap["updatedTime"].(string), 64)
-		date := time.UnixMilli(int64(updatedTime))
-
-		positions = append(positions, model.Position{
-			OrderID:      orderID,
-			Exchange:     "bybit",
-			Symbol:       symbol,
-			Volume:       volume,
-			Leverage:     leverage,
-			ClosedPnl:    closedPnl,
-			Side:         side,
-			UpdatedAt:    date,
+		records = append(records, model.RawExchangeRecord{
+			Exchange:   "bybit",
+			ExternalID: orderID,
+			Kind:       kind,
+			Payload:    payload,
+			FetchedAt:  fetchedAt,
 		})
 	}
 
-	return positions
+	return records
+}
+
+// parseClosePnlItem maps one closed-pnl (or get-closed-positions) list item
+func parseClosePnlItem(posMap map[string]interface{}) model.Position {
+	field := func(name string) string {
+		v, _ := posMap[name].(string)
+		return v
+	}
+
+	// Volume = cumEntryValue (position value at entry in USDT)
+	volume, _ := strconv.ParseFloat(field("cumEntryValue"), 64)
+	leverage, _ := strconv.Atoi(field("leverage"))
+	closedPnl, _ := strconv.ParseFloat(field("closedPnl"), 64)
+
+	updatedTime, _ := strconv.ParseFloat(field("updatedTime"), 64)
+	date := time.UnixMilli(int64(updatedTime))
+
+	return model.Position{
+		OrderID:      field("orderId"),
+		Exchange:     "bybit",
+		Symbol:       field("symbol"),
+		Volume:       volume,
+		Leverage:     leverage,
+		ClosedPnl:    closedPnl,
+		Side:         field("side"),
+		UpdatedAt:    date,
+	}
 }
 
 // getExecutionHistory fetches closed positions from execution history via direct HTTP API
-func (b *BybitClient) getExecutionHistory(ctx context.Context) ([]model.Position, error) {
+func (b *BybitClient) getExecutionHistory(ctx context.Context) ([]model.RawExchangeRecord, error) {
 	log.Printf("[bybit] Trying direct API call to execution history...")
 
 	// Bybit V5 API: /v5/execution/list
-	baseURL := "https://api.bybit.com"
 	endpoint := "/v5/execution/list"
 	
 	var allExecutions []map[string]interface{}
@@ -319,7 +324,7 @@ func (b *BybitClient) getExecutionHistory(ctx context.Context) ([]model.Position
 		// Generate signature
 		signature := b.signV5("GET", endpoint, queryString, timestamp)
 		
-		reqURL := baseURL + endpoint + "?" + queryString
+		reqURL := b.baseURL + endpoint + "?" + queryString
 		req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
 		if err != nil {
 			return nil, err
@@ -330,7 +335,8 @@ func (b *BybitClient) getExecutionHistory(ctx context.Context) ([]model.Position
 		req.Header.Set("X-BAPI-TIMESTAMP", timestamp)
 		req.Header.Set("X-BAPI-RECV-WINDOW", "30000")
 		
-		client := &http.Client{Timeout: 30 * time.Second}
+		client := newHTTPClient(b.ctx)
+		countPage(ctx)
 		resp, err := client.Do(req)
 		if err != nil {
 			log.Printf("[bybit] HTTP request error: %v", err)
@@ -389,7 +395,9 @@ func (b *BybitClient) getExecutionHistory(ctx context.Context) ([]model.Position
 		cursor = nextCursor
 		
 		// Rate limiting - wait between requests
-		time.Sleep(200 * time.Millisecond)
+		if err := pause(ctx, 200*time.Millisecond); err != nil {
+			return nil, err
+		}
 	}
 	
 	log.Printf("[bybit] Total executions retrieved: %d", len(allExecutions))
@@ -414,41 +422,46 @@ func (b *BybitClient) getExecutionHistory(ctx context.Context) ([]model.Position
 	
 	log.Printf("[bybit] Found %d closed positions", len(orderMap))
 	
-	var positions []model.Position
+	closing := make([]interface{}, 0, len(orderMap))
 	for _, execMap := range orderMap {
-		orderID, _ := execMap["orderId"].(string)
-		symbol, _ := execMap["symbol"].(string)
-		side, _ := execMap["side"].(string)
-		
-		closedSize, _ := strconv.ParseFloat(execMap["closedSize"].(string), 64)
-		execPrice, _ := strconv.ParseFloat(execMap["execPrice"].(string), 64)
-		volume := closedSize * execPrice
-		
-		leverageStr, _ := execMap["leverage"].(string)
-		leverage, _ := strconv.Atoi(leverageStr)
-		if leverage == 0 {
-			leverage = 1
-		}
-		
-		// closedPnl might be in execFee or closedPnl field
-		closedPnl, _ := strconv.ParseFloat(execMap["closedPnl"].(string), 64)
-		
-		execTime, _ := strconv.ParseFloat(execMap["execTime"].(string), 64)
-		date := time.UnixMilli(int64(execTime))
-		
-		positions = append(positions, model.Position{
-			OrderID:      orderID,
-			Exchange:     "bybit",
-			Symbol:       symbol,
-			Volume:       volume,
-			Leverage:     leverage,
-			ClosedPnl:    closedPnl,
-			Side:         side,
-			UpdatedAt:    date,
-		})
+		closing = append(closing, execMap)
 	}
 	
-	return positions, nil
+	return bybitRawRecords(RawBybitExecution, closing), nil
+}
+
+// parseExecutionItem maps the closing execution of an order
+func parseExecutionItem(execMap map[string]interface{}) model.Position {
+	field := func(name string) string {
+		v, _ := execMap[name].(string)
+		return v
+	}
+
+	closedSize, _ := strconv.ParseFloat(field("closedSize"), 64)
+	execPrice, _ := strconv.ParseFloat(field("execPrice"), 64)
+	volume := closedSize * execPrice
+
+	leverage, _ := strconv.Atoi(field("leverage"))
+	if leverage == 0 {
+		leverage = 1
+	}
+
+	// closedPnl might be in execFee or closedPnl field
+	closedPnl, _ := strconv.ParseFloat(field("closedPnl"), 64)
+
+	execTime, _ := strconv.ParseFloat(field("execTime"), 64)
+	date := time.UnixMilli(int64(execTime))
+
+	return model.Position{
+		OrderID:      field("orderId"),
+		Exchange:     "bybit",
+		Symbol:       field("symbol"),
+		Volume:       volume,
+		Leverage:     leverage,
+		ClosedPnl:    closedPnl,
+		Side:         field("side"),
+		UpdatedAt:    date,
+	}
 }
 
 // signV5 generates signature for Bybit V5 API
@@ -470,7 +483,6 @@ func (b *BybitClient) signV5(method, path, queryString, timestamp string) string
 
 // GetBalance returns total wallet balance in USDT
 func (b *BybitClient) GetBalance(ctx context.Context) (float64, error) {
-	baseURL := "https://api.bybit.com"
 	endpoint := "/v5/account/wallet-balance"
 	
 	params := url.Values{}
@@ -480,7 +492,7 @@ func (b *BybitClient) GetBalance(ctx context.Context) (float64, error) {
 	timestamp := strconv.FormatInt(time.Now().UnixMilli(), 10)
 	signature := b.signV5("GET", endpoint, queryString, timestamp)
 	
-	reqURL := baseURL + endpoint + "?" + queryString
+	reqURL := b.baseURL + endpoint + "?" + queryString
 	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
 	if err != nil {
 		return 0, err
@@ -491,7 +503,7 @@ func (b *BybitClient) GetBalance(ctx context.Context) (float64, error) {
 	req.Header.Set("X-BAPI-TIMESTAMP", timestamp)
 	req.Header.Set("X-BAPI-RECV-WINDOW", "30000")
 	
-	client := &http.Client{Timeout: 30 * time.Second}
+	client := newHTTPClient(b.ctx)
 	resp, err := client.Do(req)
 	if err != nil {
 		return 0, err
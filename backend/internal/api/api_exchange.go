@@ -3,10 +3,101 @@ package api
 import (
 	"github.com/Ravierin/BudgetTracker/backend/internal/model"
 	"context"
+	"io"
+	"net/http"
+	"time"
 )
 
 type ExchangeClient interface {
-	GetPositions() ([]model.Position, error)
 	GetPositionsWithContext(ctx context.Context) ([]model.Position, error)
+	FetchPositions(ctx context.Context) ([]model.Position, []model.RawExchangeRecord, error)
 	GetBalance(ctx context.Context) (float64, error)
+	GetOpenPositions(ctx context.Context) ([]model.OpenPosition, error)
+	StreamSession(ctx context.Context, fn func(StreamEvent)) error
+}
+
+// Base URLs for new clients. They default to the live exchanges and are
+// overridden from config, e.g. to point at a fakeexchange server.
+var (
+	BybitBaseURL = "https://api.bybit.com"
+	MEXCBaseURL  = "https://api.mexc.com"
+)
+
+// Transport carries every exchange request; nil means http.DefaultTransport.
+// Set to a Recorder or Replayer to capture or reproduce exchange traffic.
+var Transport http.RoundTripper
+
+// newHTTPClient returns a client whose requests also end with root, the
+// context the exchange client was created with
+func newHTTPClient(root context.Context) *http.Client {
+	return &http.Client{Timeout: 30 * time.Second, Transport: &boundTransport{root: root, next: Transport}}
+}
+
+// boundTransport cancels a request when root ends, whatever context it was
+// made with
+type boundTransport struct {
+	root context.Context
+	next http.RoundTripper
+}
+
+func (t *boundTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.root.Err(); err != nil {
+		return nil, err
+	}
+	next := t.next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	ctx, cancel := context.WithCancel(req.Context())
+	stop := context.AfterFunc(t.root, cancel)
+	release := func() {
+		stop()
+		cancel()
+	}
+	resp, err := next.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		release()
+		return nil, err
+	}
+	// The body is read under ctx, so it is released only once closed
+	resp.Body = &boundBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+type boundBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *boundBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
+
+type pageCounterKey struct{}
+
+// WithPageCounter returns a context under which FetchPositions adds the
+// history pages it requests to pages
+func WithPageCounter(ctx context.Context, pages *int) context.Context {
+	return context.WithValue(ctx, pageCounterKey{}, pages)
+}
+
+// pause waits between paged requests, or returns early when ctx is done
+func pause(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func countPage(ctx context.Context) {
+	if pages, ok := ctx.Value(pageCounterKey{}).(*int); ok {
+		*pages++
+	}
 }
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClientRequestsEndWithRootContext(t *testing.T) {
+	// The exchange never answers until the request is abandoned
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	prev := MEXCBaseURL
+	MEXCBaseURL = srv.URL
+	t.Cleanup(func() { MEXCBaseURL = prev })
+
+	root, cancel := context.WithCancel(context.Background())
+	client := NewMEXClient(root, "key", "secret")
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, _, err := client.FetchPositions(context.Background())
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("FetchPositions: %v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("FetchPositions returned after %v", elapsed)
+	}
+
+	if _, _, err := client.FetchPositions(context.Background()); !errors.Is(err, context.Canceled) {
+		t.Fatalf("FetchPositions after shutdown: %v, want context.Canceled", err)
+	}
+}
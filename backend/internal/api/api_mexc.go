@@ -23,14 +23,14 @@ type MEXClient struct {
 	client    *http.Client
 }
 
-func NewMEXClient(apiKey, apiSecret string) *MEXClient {
+// NewMEXClient returns a client whose requests all end with ctx, e.g. on
+// shutdown
+func NewMEXClient(ctx context.Context, apiKey, apiSecret string) *MEXClient {
 	return &MEXClient{
 		apiKey:    apiKey,
 		apiSecret: apiSecret,
-		baseURL:   "https://api.mexc.com",
-		client: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		baseURL:   MEXCBaseURL,
+		client:    newHTTPClient(ctx),
 	}
 }
 
@@ -97,12 +97,15 @@ func (m *MEXClient) doRequestV1(ctx context.Context, endpoint string, params map
 	return body, nil
 }
 
-func (m *MEXClient) GetPositions() ([]model.Position, error) {
-	return m.GetPositionsWithContext(context.Background())
+func (m *MEXClient) GetPositionsWithContext(ctx context.Context) ([]model.Position, error) {
+	positions, _, err := m.FetchPositions(ctx)
+	return positions, err
 }
 
-func (m *MEXClient) GetPositionsWithContext(ctx context.Context) ([]model.Position, error) {
-	var allPositions []model.Position
+// FetchPositions returns closed positions along with the raw history
+// items they were mapped from
+func (m *MEXClient) FetchPositions(ctx context.Context) ([]model.Position, []model.RawExchangeRecord, error) {
+	var allRecords []model.RawExchangeRecord
 	page := 1
 
 	for {
@@ -112,9 +115,10 @@ func (m *MEXClient) GetPositionsWithContext(ctx context.Context) ([]model.Positi
 			"page_size": "100",
 		}
 
+		countPage(ctx)
 		body, err := m.doRequestV1(ctx, "/api/v1/private/position/list/history_positions", params)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
 		// Response format for v1 API - data is an array directly
@@ -125,93 +129,123 @@ func (m *MEXClient) GetPositionsWithContext(ctx context.Context) ([]model.Positi
 		}
 
 		if err := json.Unmarshal(body, &resp); err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
 		if !resp.Success || resp.Code != 0 {
-			return nil, fmt.Errorf("MEXC API v1 error: success=%v, code=%d", resp.Success, resp.Code)
+			return nil, nil, fmt.Errorf("MEXC API v1 error: success=%v, code=%d", resp.Success, resp.Code)
 		}
 
-		// Try to unmarshal data as array first (MEXC v1 returns array directly)
-		var positionsList []struct {
-			PositionID            int64   `json:"positionId"`
-			Symbol                string  `json:"symbol"`
-			PositionType          int     `json:"positionType"` // 1=Buy, 2=Sell
-			CloseVol              float64 `json:"closeVol"`
-			CloseAvgPrice         float64 `json:"closeAvgPrice"`
-			OpenAvgPrice          float64 `json:"openAvgPrice"`
-			HoldAvgPriceFullyScale string `json:"holdAvgPriceFullyScale"`
-			Leverage              int     `json:"leverage"`
-			CloseProfitLoss       float64 `json:"closeProfitLoss"`
-			Realised              float64 `json:"realised"`
-			HoldFee               float64 `json:"holdFee"`
-			Oim                   float64 `json:"oim"`
-			Im                    float64 `json:"im"`
-			CreateTime            int64   `json:"createTime"`
-			UpdateTime            int64   `json:"updateTime"`
-		}
-
-		// Try as array first
-		if err := json.Unmarshal(resp.Data, &positionsList); err != nil {
-			// Try as object with list field
-			var dataObj struct {
-				List []struct {
-					OrderID      string `json:"orderId"`
-					Symbol       string `json:"symbol"`
-					Side         int    `json:"side"`
-					DealAvgPrice string `json:"dealAvgPrice"`
-					DealQty      string `json:"dealQty"`
-					Leverage     int    `json:"leverage"`
-					Profit       string `json:"profit"`
-					CreateTime   int64  `json:"createTime"`
-				} `json:"list"`
-			}
-			if err := json.Unmarshal(resp.Data, &dataObj); err != nil {
-				return nil, fmt.Errorf("failed to parse MEXC response: %w", err)
-			}
-			positionsList = nil // Not used in this case
+		records, err := mexcRawRecords(resp.Data)
+		if err != nil {
+			return nil, nil, err
 		}
 
-		log.Printf("[mexc] Page %d: %d positions", page, len(positionsList))
+		log.Printf("[mexc] Page %d: %d positions", page, len(records))
 
-		if len(positionsList) == 0 {
+		if len(records) == 0 {
 			break // No more positions
 		}
 
-		for _, pos := range positionsList {
-			side := "Buy"
-			if pos.PositionType == 2 {
-				side = "Sell"
-			}
-
-			// Volume = CloseVol × OpenAvgPrice × ContractSize
-			// From MEXC official formula: vol = (usdtAmount × leverage) / (price × contractSize)
-			// Therefore: usdtAmount × leverage = vol × price × contractSize = Volume
-			contractSize := GetContractSize(pos.Symbol)
-			volume := pos.CloseVol * pos.OpenAvgPrice * contractSize
-
-			allPositions = append(allPositions, model.Position{
-				OrderID:   fmt.Sprintf("%d", pos.PositionID),
-				Exchange:  "mexc",
-				Symbol:    pos.Symbol,
-				Volume:    volume,
-				Leverage:  pos.Leverage,
-				ClosedPnl: pos.CloseProfitLoss,
-				Side:      side,
-				UpdatedAt: time.UnixMilli(pos.UpdateTime),
-			})
-		}
+		allRecords = append(allRecords, records...)
 
 		// If we got less than page_size, we've reached the end
-		if len(positionsList) < 100 {
+		if len(records) < 100 {
 			break
 		}
 
 		page++
-		time.Sleep(50 * time.Millisecond) // Rate limiting
+		if err := pause(ctx, 50*time.Millisecond); err != nil { // Rate limiting
+			return nil, nil, err
+		}
 	}
 
-	return allPositions, nil
+	positions, err := mapRawRecords(allRecords)
+	if err != nil {
+		return nil, nil, err
+	}
+	return positions, allRecords, nil
+}
+
+// mexcRawRecords splits one history_positions "data" payload into its
+// items, keyed by position ID
+func mexcRawRecords(data json.RawMessage) ([]model.RawExchangeRecord, error) {
+	// Try to unmarshal data as array first (MEXC v1 returns array directly)
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		// An object (e.g. an empty {"list": ...} wrapper) carries no history
+		var dataObj map[string]json.RawMessage
+		if err := json.Unmarshal(data, &dataObj); err != nil {
+			return nil, fmt.Errorf("failed to parse MEXC response: %w", err)
+		}
+		return nil, nil
+	}
+
+	fetchedAt := time.Now().UTC()
+	records := make([]model.RawExchangeRecord, 0, len(items))
+	for _, item := range items {
+		var key struct {
+			PositionID int64 `json:"positionId"`
+		}
+		if err := json.Unmarshal(item, &key); err != nil {
+			return nil, fmt.Errorf("failed to parse MEXC response: %w", err)
+		}
+		records = append(records, model.RawExchangeRecord{
+			Exchange:   "mexc",
+			ExternalID: strconv.FormatInt(key.PositionID, 10),
+			Kind:       RawMEXCHistoryPosition,
+			Payload:    item,
+			FetchedAt:  fetchedAt,
+		})
+	}
+
+	return records, nil
+}
+
+// parseMEXCHistoryPosition maps one history_positions item
+func parseMEXCHistoryPosition(item json.RawMessage) (model.Position, error) {
+	var pos struct {
+		PositionID             int64   `json:"positionId"`
+		Symbol                 string  `json:"symbol"`
+		PositionType           int     `json:"positionType"` // 1=Buy, 2=Sell
+		CloseVol               float64 `json:"closeVol"`
+		CloseAvgPrice          float64 `json:"closeAvgPrice"`
+		OpenAvgPrice           float64 `json:"openAvgPrice"`
+		HoldAvgPriceFullyScale string  `json:"holdAvgPriceFullyScale"`
+		Leverage               int     `json:"leverage"`
+		CloseProfitLoss        float64 `json:"closeProfitLoss"`
+		Realised               float64 `json:"realised"`
+		HoldFee                float64 `json:"holdFee"`
+		Oim                    float64 `json:"oim"`
+		Im                     float64 `json:"im"`
+		CreateTime             int64   `json:"createTime"`
+		UpdateTime             int64   `json:"updateTime"`
+	}
+	if err := json.Unmarshal(item, &pos); err != nil {
+		return model.Position{}, fmt.Errorf("failed to parse MEXC position: %w", err)
+	}
+
+	side := "Buy"
+	if pos.PositionType == 2 {
+		side = "Sell"
+	}
+
+	// Volume = CloseVol × OpenAvgPrice × ContractSize
+	// From MEXC official formula: vol = (usdtAmount × leverage) / (price × contractSize)
+	// Therefore: usdtAmount × leverage = vol × price × contractSize = Volume
+	contractSize := GetContractSize(pos.Symbol)
+	volume := pos.CloseVol * pos.OpenAvgPrice * contractSize
+
+	return model.Position{
+		OrderID:   fmt.Sprintf("%d", pos.PositionID),
+		Exchange:  "mexc",
+		Symbol:    pos.Symbol,
+		Volume:    volume,
+		Leverage:  pos.Leverage,
+		ClosedPnl: pos.CloseProfitLoss,
+		Side:      side,
+		UpdatedAt: time.UnixMilli(pos.UpdateTime),
+	}, nil
 }
 
 // GetBalance returns total futures account balance in USDT
@@ -329,22 +363,4 @@ func min(a, b int) int {
 	return b
 }
 
-// GetContractSize returns the contract size for a given symbol
-// Contract size varies by symbol (from getFuturesContracts API)
-// Common values: BTC=0.001, ETH=0.01, small-cap=1 or 10
-func GetContractSize(symbol string) float64 {
-	// Major coins - standard MEXC contract sizes
-	if symbol == "BTCUSDT" {
-		return 0.001  // 1 contract = 0.001 BTC
-	}
-	if symbol == "ETHUSDT" {
-		return 0.01   // 1 contract = 0.01 ETH
-	}
-	// Default for small-cap altcoins (MYX, PIPPIN, etc.)
-	// Based on your data: CloseVol=2, OpenAvgPrice=0.393, Volume=7.90
-	// 7.90 = 2 × 0.393 × contractSize
-	// contractSize = 7.90 / (2 × 0.393) ≈ 10
-	return 10.0
-}
-
 var _ ExchangeClient = (*MEXClient)(nil)
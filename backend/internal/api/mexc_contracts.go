@@ -0,0 +1,109 @@
+package api
+
+import (
+	"github.com/Ravierin/BudgetTracker/backend/internal/model"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// mexcContractSizes holds the applied contract size of every MEXC symbol
+// whose stored volumes follow contract metadata; the contract service
+// fills it from the contract_metadata table
+var mexcContractSizes = struct {
+	sync.RWMutex
+	sizes map[string]float64
+}{}
+
+// SetMEXCContractSizes replaces the applied contract sizes
+func SetMEXCContractSizes(sizes map[string]float64) {
+	mexcContractSizes.Lock()
+	defer mexcContractSizes.Unlock()
+	mexcContractSizes.sizes = sizes
+}
+
+// GetContractSize returns the contract size for a given MEXC symbol: the
+// base asset amount of one contract, e.g. 0.0001 BTC for BTC_USDT. Symbols
+// not backfilled from metadata yet fall back to LegacyContractSize.
+func GetContractSize(symbol string) float64 {
+	mexcContractSizes.RLock()
+	size, ok := mexcContractSizes.sizes[symbol]
+	mexcContractSizes.RUnlock()
+	if ok && size > 0 {
+		return size
+	}
+	return LegacyContractSize(symbol)
+}
+
+// LegacyContractSize is the fixed guess volumes were computed with before
+// contract metadata was fetched. MEXC symbols are BTC_USDT style, so in
+// practice every symbol got 10; the volume backfill rescales from it.
+func LegacyContractSize(symbol string) float64 {
+	if symbol == "BTCUSDT" {
+		return 0.001
+	}
+	if symbol == "ETHUSDT" {
+		return 0.01
+	}
+	return 10.0
+}
+
+// GetContracts fetches the public contract listing (/api/v1/contract/detail)
+func (m *MEXClient) GetContracts(ctx context.Context) ([]model.ContractMeta, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", m.baseURL+"/api/v1/contract/detail", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("MEXC contract detail error: status=%d, body=%s", resp.StatusCode, string(body))
+	}
+
+	var apiResp struct {
+		Success bool `json:"success"`
+		Code    int  `json:"code"`
+		Data    []struct {
+			Symbol       string  `json:"symbol"`
+			BaseCoin     string  `json:"baseCoin"`
+			QuoteCoin    string  `json:"quoteCoin"`
+			SettleCoin   string  `json:"settleCoin"`
+			ContractSize float64 `json:"contractSize"`
+			PriceScale   int     `json:"priceScale"`
+			VolScale     int     `json:"volScale"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &apiResp); err != nil {
+		return nil, fmt.Errorf("failed to parse MEXC contract detail: %w", err)
+	}
+	if !apiResp.Success || apiResp.Code != 0 {
+		return nil, fmt.Errorf("MEXC contract detail error: success=%v, code=%d", apiResp.Success, apiResp.Code)
+	}
+
+	contracts := make([]model.ContractMeta, 0, len(apiResp.Data))
+	for _, c := range apiResp.Data {
+		contracts = append(contracts, model.ContractMeta{
+			Exchange:     "mexc",
+			Symbol:       c.Symbol,
+			BaseCoin:     c.BaseCoin,
+			QuoteCoin:    c.QuoteCoin,
+			SettleCoin:   c.SettleCoin,
+			ContractSize: c.ContractSize,
+			PriceScale:   c.PriceScale,
+			VolScale:     c.VolScale,
+		})
+	}
+	return contracts, nil
+}
@@ -0,0 +1,224 @@
+package api
+
+import (
+	"github.com/Ravierin/BudgetTracker/backend/internal/instrument"
+	"github.com/Ravierin/BudgetTracker/backend/internal/model"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// GetOpenPositions returns the USDT perpetual positions currently held
+// (/v5/position/list)
+func (b *BybitClient) GetOpenPositions(ctx context.Context) ([]model.OpenPosition, error) {
+	endpoint := "/v5/position/list"
+	var positions []model.OpenPosition
+	cursor := ""
+
+	for {
+		params := url.Values{}
+		params.Set("category", "linear")
+		params.Set("settleCoin", "USDT")
+		params.Set("limit", "200")
+		if cursor != "" {
+			params.Set("cursor", cursor)
+		}
+
+		queryString := params.Encode()
+		timestamp := strconv.FormatInt(time.Now().UnixMilli(), 10)
+		signature := b.signV5("GET", endpoint, queryString, timestamp)
+
+		req, err := http.NewRequestWithContext(ctx, "GET", b.baseURL+endpoint+"?"+queryString, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		req.Header.Set("X-BAPI-API-KEY", b.apiKey)
+		req.Header.Set("X-BAPI-SIGN", signature)
+		req.Header.Set("X-BAPI-TIMESTAMP", timestamp)
+		req.Header.Set("X-BAPI-RECV-WINDOW", "30000")
+
+		resp, err := newHTTPClient(b.ctx).Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		var apiResp struct {
+			RetCode int    `json:"retCode"`
+			RetMsg  string `json:"retMsg"`
+			Result  struct {
+				List []struct {
+					Symbol        string `json:"symbol"`
+					Side          string `json:"side"`
+					Size          string `json:"size"`
+					AvgPrice      string `json:"avgPrice"`
+					MarkPrice     string `json:"markPrice"`
+					PositionValue string `json:"positionValue"`
+					UnrealisedPnl string `json:"unrealisedPnl"`
+					Leverage      string `json:"leverage"`
+					LiqPrice      string `json:"liqPrice"`
+					UpdatedTime   string `json:"updatedTime"`
+				} `json:"list"`
+				NextPageCursor string `json:"nextPageCursor"`
+			} `json:"result"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&apiResp)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		if apiResp.RetCode != 0 {
+			return nil, fmt.Errorf("Bybit API error: %s", apiResp.RetMsg)
+		}
+
+		for _, item := range apiResp.Result.List {
+			size, _ := strconv.ParseFloat(item.Size, 64)
+			// Flat one-way positions are listed with side "" and size 0
+			if size == 0 {
+				continue
+			}
+
+			parse := func(v string) float64 {
+				f, _ := strconv.ParseFloat(v, 64)
+				return f
+			}
+			leverage, _ := strconv.ParseFloat(item.Leverage, 64)
+			updated, _ := strconv.ParseInt(item.UpdatedTime, 10, 64)
+
+			positions = append(positions, model.OpenPosition{
+				Exchange:         "bybit",
+				Symbol:           item.Symbol,
+				Instrument:       instrument.Normalize("bybit", item.Symbol).Key(),
+				Side:             item.Side,
+				Size:             size,
+				EntryPrice:       parse(item.AvgPrice),
+				MarkPrice:        parse(item.MarkPrice),
+				PositionValue:    parse(item.PositionValue),
+				UnrealizedPnl:    parse(item.UnrealisedPnl),
+				Leverage:         int(leverage),
+				LiquidationPrice: parse(item.LiqPrice),
+				UpdatedAt:        time.UnixMilli(updated).UTC(),
+			})
+		}
+
+		cursor = apiResp.Result.NextPageCursor
+		if cursor == "" || len(apiResp.Result.List) == 0 {
+			break
+		}
+	}
+
+	return positions, nil
+}
+
+// GetOpenPositions returns the positions currently held
+// (/api/v1/private/position/open_positions). MEXC reports neither mark
+// price nor unrealized PnL there, so both come from the fair price of
+// each symbol, which MEXC marks positions to.
+func (m *MEXClient) GetOpenPositions(ctx context.Context) ([]model.OpenPosition, error) {
+	body, err := m.doRequestV1(ctx, "/api/v1/private/position/open_positions", map[string]string{})
+	if err != nil {
+		return nil, err
+	}
+
+	var resp struct {
+		Success bool `json:"success"`
+		Code    int  `json:"code"`
+		Data    []struct {
+			Symbol         string  `json:"symbol"`
+			PositionType   int     `json:"positionType"` // 1=long, 2=short
+			HoldVol        float64 `json:"holdVol"`
+			HoldAvgPrice   float64 `json:"holdAvgPrice"`
+			LiquidatePrice float64 `json:"liquidatePrice"`
+			Leverage       int     `json:"leverage"`
+			UpdateTime     int64   `json:"updateTime"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse MEXC open positions: %w", err)
+	}
+	if !resp.Success || resp.Code != 0 {
+		return nil, fmt.Errorf("MEXC API error: success=%v, code=%d", resp.Success, resp.Code)
+	}
+
+	fairPrices := make(map[string]float64)
+	positions := make([]model.OpenPosition, 0, len(resp.Data))
+	for _, pos := range resp.Data {
+		if pos.HoldVol == 0 {
+			continue
+		}
+
+		markPrice, ok := fairPrices[pos.Symbol]
+		if !ok {
+			if markPrice, err = m.getFairPrice(ctx, pos.Symbol); err != nil {
+				return nil, err
+			}
+			fairPrices[pos.Symbol] = markPrice
+		}
+
+		side, direction := "Buy", 1.0
+		if pos.PositionType == 2 {
+			side, direction = "Sell", -1.0
+		}
+		size := pos.HoldVol * GetContractSize(pos.Symbol)
+
+		positions = append(positions, model.OpenPosition{
+			Exchange:         "mexc",
+			Symbol:           pos.Symbol,
+			Instrument:       instrument.Normalize("mexc", pos.Symbol).Key(),
+			Side:             side,
+			Size:             size,
+			EntryPrice:       pos.HoldAvgPrice,
+			MarkPrice:        markPrice,
+			PositionValue:    size * markPrice,
+			UnrealizedPnl:    (markPrice - pos.HoldAvgPrice) * size * direction,
+			Leverage:         pos.Leverage,
+			LiquidationPrice: pos.LiquidatePrice,
+			UpdatedAt:        time.UnixMilli(pos.UpdateTime).UTC(),
+		})
+	}
+
+	return positions, nil
+}
+
+// getFairPrice fetches the public fair price of a symbol
+// (/api/v1/contract/fair_price/{symbol})
+func (m *MEXClient) getFairPrice(ctx context.Context, symbol string) (float64, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", m.baseURL+"/api/v1/contract/fair_price/"+url.PathEscape(symbol), nil)
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("MEXC fair price error: status=%d, body=%s", resp.StatusCode, string(body))
+	}
+
+	var apiResp struct {
+		Success bool `json:"success"`
+		Code    int  `json:"code"`
+		Data    struct {
+			FairPrice float64 `json:"fairPrice"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &apiResp); err != nil {
+		return 0, fmt.Errorf("failed to parse MEXC fair price: %w", err)
+	}
+	if !apiResp.Success || apiResp.Code != 0 {
+		return 0, fmt.Errorf("MEXC fair price error: success=%v, code=%d", apiResp.Success, apiResp.Code)
+	}
+	return apiResp.Data.FairPrice, nil
+}
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"github.com/Ravierin/BudgetTracker/backend/internal/model"
+	"encoding/json"
+	"fmt"
+)
+
+// Kinds of raw exchange records. Each names the endpoint a payload came from
+// and so the mapper that turns it into a position.
+const (
+	RawBybitClosedPnl      = "bybit_closed_pnl"
+	RawBybitExecution      = "bybit_execution"
+	RawMEXCHistoryPosition = "mexc_history_position"
+)
+
+// MapRawRecord maps a stored payload with the current mappers. Syncs map
+// every fetched record through here too, so reprocessing gives the same
+// result a fresh fetch would.
+func MapRawRecord(rec model.RawExchangeRecord) (model.Position, error) {
+	switch rec.Kind {
+	case RawBybitClosedPnl, RawBybitExecution:
+		var item map[string]interface{}
+		if err := json.Unmarshal(rec.Payload, &item); err != nil {
+			return model.Position{}, fmt.Errorf("invalid %s payload: %w", rec.Kind, err)
+		}
+		if rec.Kind == RawBybitExecution {
+			return parseExecutionItem(item), nil
+		}
+		return parseClosePnlItem(item), nil
+	case RawMEXCHistoryPosition:
+		return parseMEXCHistoryPosition(rec.Payload)
+	default:
+		return model.Position{}, fmt.Errorf("unknown raw record kind: %s", rec.Kind)
+	}
+}
+
+func mapRawRecords(records []model.RawExchangeRecord) ([]model.Position, error) {
+	positions := make([]model.Position, 0, len(records))
+	for _, rec := range records {
+		p, err := MapRawRecord(rec)
+		if err != nil {
+			return nil, fmt.Errorf("%s %s: %w", rec.Exchange, rec.ExternalID, err)
+		}
+		positions = append(positions, p)
+	}
+	return positions, nil
+}
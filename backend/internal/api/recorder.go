@@ -0,0 +1,296 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// redacted replaces credentials in recorded exchanges
+const redacted = "REDACTED"
+
+// secretHeaders and secretParams carry API keys or signatures for Bybit and
+// MEXC; they are never written to disk
+var (
+	secretHeaders = []string{"X-BAPI-API-KEY", "X-BAPI-SIGN", "ApiKey", "Signature", "Authorization"}
+	secretParams  = []string{"api_key", "apiKey", "sign", "signature"}
+)
+
+// Interaction is one recorded request/response pair
+type Interaction struct {
+	RecordedAt time.Time `json:"recordedAt"`
+	Request    struct {
+		Method string      `json:"method"`
+		URL    string      `json:"url"`
+		Header http.Header `json:"header,omitempty"`
+		Body   string      `json:"body,omitempty"`
+	} `json:"request"`
+	Response struct {
+		Status int             `json:"status"`
+		Header http.Header     `json:"header,omitempty"`
+		Body   json.RawMessage `json:"body"`
+	} `json:"response"`
+}
+
+// Recorder is an http.RoundTripper that passes requests through and writes
+// each exchange to its own file in Dir, credentials redacted
+type Recorder struct {
+	Dir  string
+	Next http.RoundTripper
+
+	mu  sync.Mutex
+	seq int
+}
+
+func NewRecorder(dir string) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &Recorder{Dir: dir, Next: http.DefaultTransport}, nil
+}
+
+func (rec *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		data, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		reqBody = data
+		req.Body = io.NopCloser(bytes.NewReader(data))
+	}
+
+	resp, err := rec.Next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	secrets := secretValues(req)
+	var in Interaction
+	in.RecordedAt = time.Now().UTC()
+	in.Request.Method = req.Method
+	in.Request.URL = redactURL(req.URL)
+	in.Request.Header = redactHeader(req.Header, secrets)
+	in.Request.Body = string(redactBody(reqBody, secrets))
+	in.Response.Status = resp.StatusCode
+	in.Response.Header = redactHeader(resp.Header, secrets)
+	in.Response.Body = rawBody(redactBody(respBody, secrets))
+
+	if err := rec.write(&in, req.URL); err != nil {
+		// Recording is diagnostics; never fail the sync over it
+		log.Printf("[recorder] failed to record %s: %v", req.URL.Path, err)
+	}
+	return resp, nil
+}
+
+func (rec *Recorder) write(in *Interaction, u *url.URL) error {
+	rec.mu.Lock()
+	rec.seq++
+	seq := rec.seq
+	rec.mu.Unlock()
+
+	name := fmt.Sprintf("%s-%04d-%s%s.json",
+		in.RecordedAt.Format("20060102T150405.000"), seq,
+		strings.ReplaceAll(u.Hostname(), ".", "_"),
+		strings.ReplaceAll(u.Path, "/", "_"))
+
+	data, err := json.MarshalIndent(in, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(rec.Dir, name), data, 0o600)
+}
+
+// Replayer is an http.RoundTripper that answers from recorded files
+// instead of the network. Requests are matched on method and path, each
+// recording used once in the order it was made, since query strings carry
+// timestamps that never repeat.
+type Replayer struct {
+	mu      sync.Mutex
+	pending map[string][]*Interaction
+}
+
+// NewReplayer loads every recording in dir
+func NewReplayer(dir string) (*Replayer, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	r := &Replayer{pending: make(map[string][]*Interaction)}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var in Interaction
+		if err := json.Unmarshal(data, &in); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		u, err := url.Parse(in.Request.URL)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		key := in.Request.Method + " " + u.Path
+		r.pending[key] = append(r.pending[key], &in)
+	}
+	return r, nil
+}
+
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	key := req.Method + " " + req.URL.Path
+
+	r.mu.Lock()
+	queue := r.pending[key]
+	if len(queue) == 0 {
+		r.mu.Unlock()
+		return nil, fmt.Errorf("replay: no recording left for %s", key)
+	}
+	in := queue[0]
+	r.pending[key] = queue[1:]
+	r.mu.Unlock()
+
+	if req.Body != nil {
+		req.Body.Close()
+	}
+
+	body := in.ResponseBody()
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", in.Response.Status, http.StatusText(in.Response.Status)),
+		StatusCode:    in.Response.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        in.Response.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// ReadRecordedBody returns the response body of a single recording, for
+// feeding fixtures straight to a parser
+func ReadRecordedBody(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var in Interaction
+	if err := json.Unmarshal(data, &in); err != nil {
+		return nil, err
+	}
+	return in.ResponseBody(), nil
+}
+
+// ResponseBody returns the body as the exchange sent it
+func (in *Interaction) ResponseBody() []byte {
+	body := []byte(in.Response.Body)
+	var text string
+	if json.Unmarshal(body, &text) == nil {
+		return []byte(text)
+	}
+	return body
+}
+
+// isSecret reports whether a header or query parameter name is one of
+// names; the comparison ignores case, since headers set directly on the map
+// skip canonicalization
+func isSecret(name string, names []string) bool {
+	for _, n := range names {
+		if strings.EqualFold(name, n) {
+			return true
+		}
+	}
+	return false
+}
+
+// secretValues collects the credentials a request carries, so copies of
+// them can be removed wherever they are echoed
+func secretValues(req *http.Request) []string {
+	var values []string
+	for name, vs := range req.Header {
+		if isSecret(name, secretHeaders) {
+			values = append(values, vs...)
+		}
+	}
+	for name, vs := range req.URL.Query() {
+		if isSecret(name, secretParams) {
+			values = append(values, vs...)
+		}
+	}
+	return values
+}
+
+// redactHeader replaces secret headers and any other header value that
+// contains a credential
+func redactHeader(h http.Header, secrets []string) http.Header {
+	h = h.Clone()
+	for name, vs := range h {
+		for i, v := range vs {
+			if isSecret(name, secretHeaders) {
+				vs[i] = redacted
+			} else {
+				vs[i] = string(redactBody([]byte(v), secrets))
+			}
+		}
+	}
+	return h
+}
+
+// redactBody removes credential values the exchange echoes back, such as
+// the API key inside Bybit's "error sign!" message. Short values are kept:
+// replacing them would mangle unrelated text.
+func redactBody(body []byte, secrets []string) []byte {
+	for _, v := range secrets {
+		if len(v) >= 8 {
+			body = bytes.ReplaceAll(body, []byte(v), []byte(redacted))
+		}
+	}
+	return body
+}
+
+func redactURL(u *url.URL) string {
+	c := *u
+	q := c.Query()
+	changed := false
+	for name := range q {
+		if isSecret(name, secretParams) {
+			q.Set(name, redacted)
+			changed = true
+		}
+	}
+	if changed {
+		c.RawQuery = q.Encode()
+	}
+	return c.String()
+}
+
+// rawBody keeps JSON bodies readable in the recording and wraps anything
+// else (HTML error pages, empty bodies) as a JSON string, which the
+// Replayer unwraps again
+func rawBody(body []byte) json.RawMessage {
+	if json.Valid(body) {
+		return body
+	}
+	quoted, _ := json.Marshal(string(body))
+	return quoted
+}
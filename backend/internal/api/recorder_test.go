@@ -0,0 +1,223 @@
+package api
+
+import (
+	"github.com/Ravierin/BudgetTracker/backend/internal/fakeexchange"
+	"github.com/Ravierin/BudgetTracker/backend/internal/model"
+	"context"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// useTransport routes exchange requests through rt for the rest of the test
+func useTransport(t *testing.T, rt http.RoundTripper) {
+	t.Helper()
+	prev := Transport
+	Transport = rt
+	t.Cleanup(func() { Transport = prev })
+}
+
+func useContractSizes(t *testing.T, sizes map[string]float64) {
+	t.Helper()
+	SetMEXCContractSizes(sizes)
+	t.Cleanup(func() { SetMEXCContractSizes(nil) })
+}
+
+func TestReplayMapsRecordings(t *testing.T) {
+	bybitChunk := func(ctx context.Context) ([]model.Position, error) {
+		records, err := NewBybitClient(ctx, "key", "secret").fetchClosePnlChunk(ctx, 0, time.Now().UnixMilli())
+		if err != nil {
+			return nil, err
+		}
+		return mapRawRecords(records)
+	}
+	mexcHistory := func(ctx context.Context) ([]model.Position, error) {
+		positions, _, err := NewMEXClient(ctx, "key", "secret").FetchPositions(ctx)
+		return positions, err
+	}
+
+	tests := []struct {
+		name  string
+		dir   string
+		sizes map[string]float64
+		fetch func(ctx context.Context) ([]model.Position, error)
+		want  []model.Position
+	}{
+		{
+			name:  "bybit closed pnl",
+			dir:   "bybit_closed_pnl",
+			fetch: bybitChunk,
+			want: []model.Position{
+				{OrderID: "fb-00001", Exchange: "bybit", Symbol: "ETHUSDT", Volume: 1359.7543, Leverage: 10, ClosedPnl: -5.4938, Side: "Sell", UpdatedAt: time.UnixMilli(1792360378555)},
+				{OrderID: "fb-00002", Exchange: "bybit", Symbol: "BTCUSDT", Volume: 90.116, Leverage: 20, ClosedPnl: 1.5715, Side: "Buy", UpdatedAt: time.UnixMilli(1792358025767)},
+				{OrderID: "fb-00005", Exchange: "bybit", Symbol: "XRPUSDT", Volume: 890.5447, Leverage: 10, ClosedPnl: -14.358, Side: "Sell", UpdatedAt: time.UnixMilli(1792338990582)},
+			},
+		},
+		{
+			name:  "mexc history with contract sizes",
+			dir:   "mexc_history_positions",
+			sizes: map[string]float64{"BTC_USDT": 0.0001, "ETH_USDT": 0.01, "XRP_USDT": 1},
+			fetch: mexcHistory,
+			want: []model.Position{
+				{OrderID: "900004", Exchange: "mexc", Symbol: "BTC_USDT", Volume: 128.67681477, Leverage: 10, ClosedPnl: -11.2486, Side: "Sell", UpdatedAt: time.UnixMilli(1792340949425)},
+				{OrderID: "900079", Exchange: "mexc", Symbol: "ETH_USDT", Volume: 1971.766476, Leverage: 5, ClosedPnl: -29.3914, Side: "Buy", UpdatedAt: time.UnixMilli(1792027749425)},
+				{OrderID: "900066", Exchange: "mexc", Symbol: "XRP_USDT", Volume: 133.1622, Leverage: 5, ClosedPnl: 19.1415, Side: "Sell", UpdatedAt: time.UnixMilli(1792265349425)},
+			},
+		},
+		{
+			// Before contract metadata every symbol was taken as 10 per contract
+			name:  "mexc history with legacy sizes",
+			dir:   "mexc_history_positions",
+			fetch: mexcHistory,
+			want: []model.Position{
+				{OrderID: "900004", Exchange: "mexc", Symbol: "BTC_USDT", Volume: 12867681.477, Leverage: 10, ClosedPnl: -11.2486, Side: "Sell", UpdatedAt: time.UnixMilli(1792340949425)},
+				{OrderID: "900079", Exchange: "mexc", Symbol: "ETH_USDT", Volume: 1971766.476, Leverage: 5, ClosedPnl: -29.3914, Side: "Buy", UpdatedAt: time.UnixMilli(1792027749425)},
+				{OrderID: "900066", Exchange: "mexc", Symbol: "XRP_USDT", Volume: 1331.622, Leverage: 5, ClosedPnl: 19.1415, Side: "Sell", UpdatedAt: time.UnixMilli(1792265349425)},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			replayer, err := NewReplayer(filepath.Join("testdata", tt.dir))
+			if err != nil {
+				t.Fatal(err)
+			}
+			useTransport(t, replayer)
+			useContractSizes(t, tt.sizes)
+
+			got, err := tt.fetch(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d positions, want %d", len(got), len(tt.want))
+			}
+			for i, want := range tt.want {
+				p := got[i]
+				if p.OrderID != want.OrderID || p.Exchange != want.Exchange || p.Symbol != want.Symbol ||
+					p.Leverage != want.Leverage || p.Side != want.Side || !p.UpdatedAt.Equal(want.UpdatedAt) ||
+					math.Abs(p.ClosedPnl-want.ClosedPnl) > 1e-9 ||
+					math.Abs(p.Volume-want.Volume) > 1e-9*math.Max(1, want.Volume) {
+					t.Errorf("position %d = %+v, want %+v", i, p, want)
+				}
+			}
+		})
+	}
+}
+
+func TestReplayRunsOutOfRecordings(t *testing.T) {
+	replayer, err := NewReplayer(filepath.Join("testdata", "mexc_history_positions"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	useTransport(t, replayer)
+
+	ctx := context.Background()
+	client := NewMEXClient(ctx, "key", "secret")
+	if _, _, err := client.FetchPositions(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := client.FetchPositions(ctx); err == nil || !strings.Contains(err.Error(), "no recording left") {
+		t.Fatalf("second fetch: %v, want the replay to run out", err)
+	}
+}
+
+// roundTripFunc lets a test observe requests on their way out
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestRecorderRedactsCredentials(t *testing.T) {
+	scenario, err := fakeexchange.LoadScenario("default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(fakeexchange.NewServer(scenario))
+	defer srv.Close()
+
+	prevBybit, prevMEXC := BybitBaseURL, MEXCBaseURL
+	BybitBaseURL, MEXCBaseURL = srv.URL, srv.URL
+	t.Cleanup(func() { BybitBaseURL, MEXCBaseURL = prevBybit, prevMEXC })
+
+	dir := t.TempDir()
+	recorder, err := NewRecorder(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Collect every signature actually sent, to look for it on disk
+	var signatures []string
+	recorder.Next = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		for _, name := range []string{"X-BAPI-SIGN", "Signature"} {
+			if v := req.Header.Get(name); v != "" {
+				signatures = append(signatures, v)
+			}
+		}
+		return http.DefaultTransport.RoundTrip(req)
+	})
+	useTransport(t, recorder)
+
+	ctx := context.Background()
+	if _, err := NewBybitClient(ctx, scenario.APIKey, scenario.APISecret).GetBalance(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := NewMEXClient(ctx, scenario.APIKey, scenario.APISecret).FetchPositions(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// A rejected key is echoed back in Bybit's error message
+	wrongKey := "wrong-key-0123456789"
+	if _, err := NewBybitClient(ctx, wrongKey, scenario.APISecret).GetBalance(ctx); err == nil {
+		t.Fatal("expected the fake exchange to reject an unknown key")
+	}
+	if len(signatures) == 0 {
+		t.Fatal("no signed requests were sent")
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Fatal("nothing was recorded")
+	}
+	secrets := append([]string{scenario.APIKey, scenario.APISecret, wrongKey}, signatures...)
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, secret := range secrets {
+			if strings.Contains(string(data), secret) {
+				t.Errorf("%s contains credential %q", filepath.Base(path), secret)
+			}
+		}
+	}
+}
+
+func TestRedactURLAndHeader(t *testing.T) {
+	req := httptest.NewRequest("GET", "https://api.example.com/v1/x?symbol=BTC&API_KEY=key-0123456789&Signature=sig-0123456789", nil)
+	// Set on the map directly, the header keeps its non-canonical name
+	req.Header["apikey"] = []string{"key-0123456789"}
+	req.Header.Set("X-Echo", "rejected key-0123456789")
+
+	u := redactURL(req.URL)
+	if strings.Contains(u, "0123456789") || !strings.Contains(u, "symbol=BTC") {
+		t.Errorf("redacted URL %s", u)
+	}
+
+	h := redactHeader(req.Header, secretValues(req))
+	if got := h["apikey"][0]; got != redacted {
+		t.Errorf("apikey header = %q, want it redacted", got)
+	}
+	if got := h.Get("X-Echo"); got != "rejected "+redacted {
+		t.Errorf("echoed header = %q, want the key redacted", got)
+	}
+	if req.Header.Get("X-Echo") != "rejected key-0123456789" {
+		t.Error("redactHeader modified the request header")
+	}
+}
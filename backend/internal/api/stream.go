@@ -0,0 +1,84 @@
+package api
+
+import (
+	"github.com/Ravierin/BudgetTracker/backend/internal/model"
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Private websocket stream URLs, overridden from config like the REST base URLs
+var (
+	BybitStreamURL = "wss://stream.bybit.com/v5/private"
+	MEXCStreamURL  = "wss://contract.mexc.com/edge"
+)
+
+const (
+	streamPingInterval = 20 * time.Second
+	// streamReadTimeout fails a session that hears nothing, not even a pong
+	streamReadTimeout  = 60 * time.Second
+	streamWriteTimeout = 10 * time.Second
+)
+
+// StreamEvent is what a private stream message changed. Closed positions
+// are provisional: the next REST sync overwrites them with the exchange's
+// closed PnL record. Open entries with Size 0 mean the position is flat.
+// Connected marks the empty event sent once a session is subscribed.
+type StreamEvent struct {
+	Connected bool
+	Closed    []model.Position
+	Open      []model.OpenPosition
+}
+
+func dialStream(ctx context.Context, url string) (*websocket.Conn, error) {
+	dialer := websocket.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		HandshakeTimeout: 10 * time.Second,
+	}
+	conn, _, err := dialer.DialContext(ctx, url, nil)
+	return conn, err
+}
+
+func writeStream(conn *websocket.Conn, v interface{}) error {
+	conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+	return conn.WriteJSON(v)
+}
+
+func readStream(conn *websocket.Conn, v interface{}) error {
+	conn.SetReadDeadline(time.Now().Add(streamReadTimeout))
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// keepAlive sends ping every streamPingInterval until stop is called. It is
+// the only writer once started. Cancelling ctx closes conn, which ends the
+// session's blocked read.
+func keepAlive(ctx context.Context, conn *websocket.Conn, ping interface{}) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(streamPingInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := writeStream(conn, ping); err != nil {
+					conn.Close()
+					return
+				}
+			case <-ctx.Done():
+				conn.Close()
+				return
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() { close(done) }
+}
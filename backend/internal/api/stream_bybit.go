@@ -0,0 +1,229 @@
+package api
+
+import (
+	"github.com/Ravierin/BudgetTracker/backend/internal/instrument"
+	"github.com/Ravierin/BudgetTracker/backend/internal/model"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+type bybitStreamMessage struct {
+	Op      string          `json:"op"`
+	Success *bool           `json:"success"`
+	RetMsg  string          `json:"ret_msg"`
+	Topic   string          `json:"topic"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// bybitCloseFill accumulates the closing fills of one order
+type bybitCloseFill struct {
+	position model.Position
+	seen     map[string]bool
+}
+
+// StreamSession authenticates to the private stream, subscribes to the
+// position and execution topics and passes every change to fn until the
+// connection fails or ctx ends
+func (b *BybitClient) StreamSession(ctx context.Context, fn func(StreamEvent)) error {
+	conn, err := dialStream(ctx, BybitStreamURL)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// Auth signature: hex(HMAC-SHA256(secret, "GET/realtime" + expires))
+	expires := time.Now().Add(10 * time.Second).UnixMilli()
+	h := hmac.New(sha256.New, []byte(b.apiSecret))
+	h.Write([]byte(fmt.Sprintf("GET/realtime%d", expires)))
+	signature := hex.EncodeToString(h.Sum(nil))
+
+	requests := []map[string]interface{}{
+		{"op": "auth", "args": []interface{}{b.apiKey, expires, signature}},
+		{"op": "subscribe", "args": []string{"position", "execution"}},
+	}
+	for _, req := range requests {
+		if err := writeStream(conn, req); err != nil {
+			return err
+		}
+		var resp bybitStreamMessage
+		if err := readStream(conn, &resp); err != nil {
+			return err
+		}
+		if resp.Success == nil || !*resp.Success {
+			return fmt.Errorf("Bybit stream %s failed: %s", req["op"], resp.RetMsg)
+		}
+	}
+
+	stop := keepAlive(ctx, conn, map[string]string{"op": "ping"})
+	defer stop()
+	fn(StreamEvent{Connected: true})
+
+	// Executions carry neither entry price nor leverage; both come from the
+	// position topic, which Bybit pushes before the fills of a close
+	positions := make(map[string]model.OpenPosition)
+	fills := make(map[string]*bybitCloseFill)
+
+	for {
+		var msg bybitStreamMessage
+		if err := readStream(conn, &msg); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+
+		switch msg.Topic {
+		case "position":
+			open, err := parseBybitStreamPositions(msg.Data)
+			if err != nil {
+				return err
+			}
+			for _, p := range open {
+				if p.Size > 0 {
+					positions[p.Symbol] = p
+				}
+			}
+			fn(StreamEvent{Open: open})
+		case "execution":
+			closed, err := bybitStreamCloses(msg.Data, positions, fills)
+			if err != nil {
+				return err
+			}
+			if len(closed) > 0 {
+				fn(StreamEvent{Closed: closed})
+			}
+		}
+
+		// Orders finish within seconds; forget fills of older ones
+		for orderID, f := range fills {
+			if time.Since(f.position.UpdatedAt) > time.Hour {
+				delete(fills, orderID)
+			}
+		}
+	}
+}
+
+func parseBybitStreamPositions(data json.RawMessage) ([]model.OpenPosition, error) {
+	var items []struct {
+		Category      string `json:"category"`
+		Symbol        string `json:"symbol"`
+		Side          string `json:"side"`
+		Size          string `json:"size"`
+		EntryPrice    string `json:"entryPrice"`
+		MarkPrice     string `json:"markPrice"`
+		PositionValue string `json:"positionValue"`
+		UnrealisedPnl string `json:"unrealisedPnl"`
+		Leverage      string `json:"leverage"`
+		LiqPrice      string `json:"liqPrice"`
+		UpdatedTime   string `json:"updatedTime"`
+	}
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, fmt.Errorf("failed to parse Bybit position message: %w", err)
+	}
+
+	parse := func(v string) float64 {
+		f, _ := strconv.ParseFloat(v, 64)
+		return f
+	}
+
+	var positions []model.OpenPosition
+	for _, item := range items {
+		if item.Category != "" && item.Category != "linear" {
+			continue
+		}
+		updated, _ := strconv.ParseInt(item.UpdatedTime, 10, 64)
+		positions = append(positions, model.OpenPosition{
+			Exchange:         "bybit",
+			Symbol:           item.Symbol,
+			Instrument:       instrument.Normalize("bybit", item.Symbol).Key(),
+			Side:             item.Side,
+			Size:             parse(item.Size),
+			EntryPrice:       parse(item.EntryPrice),
+			MarkPrice:        parse(item.MarkPrice),
+			PositionValue:    parse(item.PositionValue),
+			UnrealizedPnl:    parse(item.UnrealisedPnl),
+			Leverage:         int(parse(item.Leverage)),
+			LiquidationPrice: parse(item.LiqPrice),
+			UpdatedAt:        time.UnixMilli(updated).UTC(),
+		})
+	}
+	return positions, nil
+}
+
+// bybitStreamCloses folds closing fills into per-order positions and
+// returns the orders that changed. Volume is the entry value of the closed
+// size, as in closed PnL records; PnL is net of the closing fees only.
+func bybitStreamCloses(data json.RawMessage, positions map[string]model.OpenPosition, fills map[string]*bybitCloseFill) ([]model.Position, error) {
+	var items []struct {
+		Category   string `json:"category"`
+		Symbol     string `json:"symbol"`
+		OrderID    string `json:"orderId"`
+		ExecID     string `json:"execId"`
+		Side       string `json:"side"`
+		ExecPrice  string `json:"execPrice"`
+		ExecType   string `json:"execType"`
+		ExecFee    string `json:"execFee"`
+		ExecPnl    string `json:"execPnl"`
+		ClosedSize string `json:"closedSize"`
+		ExecTime   string `json:"execTime"`
+	}
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, fmt.Errorf("failed to parse Bybit execution message: %w", err)
+	}
+
+	parse := func(v string) float64 {
+		f, _ := strconv.ParseFloat(v, 64)
+		return f
+	}
+
+	var changed []string
+	for _, item := range items {
+		closedSize := parse(item.ClosedSize)
+		if (item.Category != "" && item.Category != "linear") || item.ExecType != "Trade" || closedSize == 0 {
+			continue
+		}
+
+		f, ok := fills[item.OrderID]
+		if !ok {
+			f = &bybitCloseFill{
+				position: model.Position{OrderID: item.OrderID, Exchange: "bybit", Symbol: item.Symbol, Side: item.Side, Leverage: 1},
+				seen:     make(map[string]bool),
+			}
+			fills[item.OrderID] = f
+		}
+		if f.seen[item.ExecID] {
+			continue
+		}
+		f.seen[item.ExecID] = true
+
+		entryPrice := parse(item.ExecPrice)
+		if open, ok := positions[item.Symbol]; ok {
+			entryPrice = open.EntryPrice
+			if open.Leverage > 0 {
+				f.position.Leverage = open.Leverage
+			}
+		}
+		execTime, _ := strconv.ParseInt(item.ExecTime, 10, 64)
+
+		f.position.Volume += closedSize * entryPrice
+		f.position.ClosedPnl += parse(item.ExecPnl) - parse(item.ExecFee)
+		f.position.UpdatedAt = time.UnixMilli(execTime).UTC()
+		changed = append(changed, item.OrderID)
+	}
+
+	var closed []model.Position
+	emitted := make(map[string]bool)
+	for _, orderID := range changed {
+		if !emitted[orderID] {
+			emitted[orderID] = true
+			closed = append(closed, fills[orderID].position)
+		}
+	}
+	return closed, nil
+}
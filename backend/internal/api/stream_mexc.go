@@ -0,0 +1,126 @@
+package api
+
+import (
+	"github.com/Ravierin/BudgetTracker/backend/internal/instrument"
+	"github.com/Ravierin/BudgetTracker/backend/internal/model"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// mexcPositionClosed is the state of a push.personal.position update for a
+// position that was closed out
+const mexcPositionClosed = 3
+
+type mexcStreamMessage struct {
+	Channel string          `json:"channel"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// StreamSession logs in to the contract stream, which then pushes every
+// private channel, and passes position closes to fn until the connection
+// fails or ctx ends
+func (m *MEXClient) StreamSession(ctx context.Context, fn func(StreamEvent)) error {
+	conn, err := dialStream(ctx, MEXCStreamURL)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// Login signature: hex(HMAC-SHA256(secret, apiKey + reqTime))
+	reqTime := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	h := hmac.New(sha256.New, []byte(m.apiSecret))
+	h.Write([]byte(m.apiKey + reqTime))
+
+	login := map[string]interface{}{
+		"method": "login",
+		"param": map[string]string{
+			"apiKey":    m.apiKey,
+			"reqTime":   reqTime,
+			"signature": hex.EncodeToString(h.Sum(nil)),
+		},
+	}
+	if err := writeStream(conn, login); err != nil {
+		return err
+	}
+	var resp mexcStreamMessage
+	if err := readStream(conn, &resp); err != nil {
+		return err
+	}
+	var result string
+	json.Unmarshal(resp.Data, &result)
+	if resp.Channel != "rs.login" || result != "success" {
+		return fmt.Errorf("MEXC stream login failed: %s %s", resp.Channel, string(resp.Data))
+	}
+
+	stop := keepAlive(ctx, conn, map[string]string{"method": "ping"})
+	defer stop()
+	fn(StreamEvent{Connected: true})
+
+	for {
+		var msg mexcStreamMessage
+		if err := readStream(conn, &msg); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+
+		switch msg.Channel {
+		case "rs.error":
+			return fmt.Errorf("MEXC stream error: %s", string(msg.Data))
+		case "push.personal.position":
+			ev, err := parseMEXCStreamPosition(msg.Data)
+			if err != nil {
+				return err
+			}
+			if len(ev.Closed) > 0 {
+				fn(ev)
+			}
+		}
+	}
+}
+
+// parseMEXCStreamPosition maps a closed position update with the history
+// mapper, which reads the same fields. Updates carry realised PnL but not
+// always closeProfitLoss, the field history records use.
+func parseMEXCStreamPosition(data json.RawMessage) (StreamEvent, error) {
+	var update struct {
+		State           int      `json:"state"`
+		CloseProfitLoss *float64 `json:"closeProfitLoss"`
+		Realised        float64  `json:"realised"`
+	}
+	if err := json.Unmarshal(data, &update); err != nil {
+		return StreamEvent{}, fmt.Errorf("failed to parse MEXC position update: %w", err)
+	}
+	if update.State != mexcPositionClosed {
+		return StreamEvent{}, nil
+	}
+
+	p, err := parseMEXCHistoryPosition(data)
+	if err != nil {
+		return StreamEvent{}, err
+	}
+	if update.CloseProfitLoss == nil {
+		p.ClosedPnl = update.Realised
+	}
+	if p.UpdatedAt.UnixMilli() == 0 {
+		p.UpdatedAt = time.Now()
+	}
+
+	return StreamEvent{
+		Closed: []model.Position{p},
+		Open: []model.OpenPosition{{
+			Exchange:   "mexc",
+			Symbol:     p.Symbol,
+			Instrument: instrument.Normalize("mexc", p.Symbol).Key(),
+			Side:       p.Side,
+			UpdatedAt:  p.UpdatedAt.UTC(),
+		}},
+	}, nil
+}
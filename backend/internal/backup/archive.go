@@ -0,0 +1,336 @@
+package backup
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	// FormatName identifies BudgetTracker archives
+	FormatName = "budgettracker-backup"
+	// Version is bumped whenever the archive layout changes incompatibly
+	Version = 1
+)
+
+// ErrInvalidArchive wraps every error caused by the archive contents rather
+// than by I/O
+var ErrInvalidArchive = errors.New("invalid backup")
+
+// Record kinds, written in this order
+const (
+	KindPosition      = "position"
+	KindWithdrawal    = "withdrawal"
+	KindMonthlyIncome = "monthly_income"
+	KindSetting       = "setting"
+	KindImportProfile = "import_profile"
+	KindAPIKey        = "api_key"
+)
+
+// sections maps record kinds to their array name in JSON archives
+var sections = map[string]string{
+	KindPosition:      "positions",
+	KindWithdrawal:    "withdrawals",
+	KindMonthlyIncome: "monthlyIncomes",
+	KindSetting:       "settings",
+	KindImportProfile: "importProfiles",
+	KindAPIKey:        "apiKeys",
+}
+
+// Header describes an archive. KeySalt is set when API keys are encrypted.
+type Header struct {
+	Format    string    `json:"format"`
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"createdAt"`
+	KeySalt   string    `json:"keySalt,omitempty"`
+}
+
+// Validate rejects archives from other tools or newer versions
+func (h Header) Validate() error {
+	if h.Format != FormatName {
+		return fmt.Errorf("%w: not a BudgetTracker backup (format %q)", ErrInvalidArchive, h.Format)
+	}
+	if h.Version != Version {
+		return fmt.Errorf("%w: unsupported version %d (expected %d)", ErrInvalidArchive, h.Version, Version)
+	}
+	return nil
+}
+
+// Writer streams records of one kind after another
+type Writer interface {
+	WriteRecord(kind string, v interface{}) error
+	Close() error
+}
+
+// NewWriter writes the header and returns a writer for "json" or "ndjson"
+// (gzip-compressed, one record per line)
+func NewWriter(format string, w io.Writer, h Header) (Writer, error) {
+	switch format {
+	case "", "json":
+		return newJSONWriter(w, h)
+	case "ndjson":
+		return newNDJSONWriter(w, h)
+	default:
+		return nil, fmt.Errorf("unsupported backup format: %s", format)
+	}
+}
+
+// jsonWriter emits a single object with one array per section. Records must
+// arrive grouped by kind.
+type jsonWriter struct {
+	w       *bufio.Writer
+	kind    string
+	written map[string]bool
+	first   bool
+}
+
+func newJSONWriter(w io.Writer, h Header) (*jsonWriter, error) {
+	header, err := json.Marshal(h)
+	if err != nil {
+		return nil, err
+	}
+
+	jw := &jsonWriter{w: bufio.NewWriter(w), written: make(map[string]bool)}
+	// Open the header object and leave it unterminated for the sections
+	if _, err := jw.w.Write(header[:len(header)-1]); err != nil {
+		return nil, err
+	}
+	return jw, nil
+}
+
+func (j *jsonWriter) WriteRecord(kind string, v interface{}) error {
+	if kind != j.kind {
+		if err := j.openSection(kind); err != nil {
+			return err
+		}
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if !j.first {
+		j.w.WriteByte(',')
+	}
+	j.first = false
+	_, err = j.w.Write(data)
+	return err
+}
+
+func (j *jsonWriter) openSection(kind string) error {
+	section, ok := sections[kind]
+	if !ok {
+		return fmt.Errorf("unknown record kind: %s", kind)
+	}
+	if j.written[kind] {
+		return fmt.Errorf("records of kind %s are not contiguous", kind)
+	}
+	if j.kind != "" {
+		j.w.WriteByte(']')
+	}
+	j.kind = kind
+	j.written[kind] = true
+	j.first = true
+	_, err := fmt.Fprintf(j.w, `,%q:[`, section)
+	return err
+}
+
+func (j *jsonWriter) Close() error {
+	if j.kind != "" {
+		j.w.WriteByte(']')
+	}
+	j.w.WriteByte('}')
+	return j.w.Flush()
+}
+
+// ndjsonLine is one line of an NDJSON archive; the first line is the header
+type ndjsonLine struct {
+	Kind string          `json:"kind"`
+	Data json.RawMessage `json:"data"`
+}
+
+type ndjsonWriter struct {
+	gz  *gzip.Writer
+	enc *json.Encoder
+}
+
+func newNDJSONWriter(w io.Writer, h Header) (*ndjsonWriter, error) {
+	gz := gzip.NewWriter(w)
+	nw := &ndjsonWriter{gz: gz, enc: json.NewEncoder(gz)}
+	if err := nw.WriteRecord("header", h); err != nil {
+		return nil, err
+	}
+	return nw, nil
+}
+
+func (n *ndjsonWriter) WriteRecord(kind string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return n.enc.Encode(ndjsonLine{Kind: kind, Data: data})
+}
+
+func (n *ndjsonWriter) Close() error {
+	return n.gz.Close()
+}
+
+// Reader reads an archive written by NewWriter, in either format. The
+// header is read and validated up front.
+type Reader struct {
+	Header Header
+
+	gz      *gzip.Reader
+	dec     *json.Decoder
+	ndjson  bool
+	section string // JSON archives: pending section key after the header
+}
+
+// NewReader detects gzip-compressed NDJSON by its magic bytes and otherwise
+// expects a JSON archive
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReader(r)
+	magic, _ := br.Peek(2)
+
+	var rd *Reader
+	var err error
+	if bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		rd, err = newNDJSONReader(br)
+	} else {
+		rd, err = newJSONReader(br)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := rd.Header.Validate(); err != nil {
+		rd.Close()
+		return nil, err
+	}
+	return rd, nil
+}
+
+func newNDJSONReader(r io.Reader) (*Reader, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	rd := &Reader{gz: gz, dec: json.NewDecoder(gz), ndjson: true}
+	var line ndjsonLine
+	if err := rd.dec.Decode(&line); err != nil {
+		gz.Close()
+		return nil, fmt.Errorf("%w: read header: %v", ErrInvalidArchive, err)
+	}
+	if line.Kind != "header" {
+		gz.Close()
+		return nil, fmt.Errorf("%w: archive does not start with a header", ErrInvalidArchive)
+	}
+	if err := json.Unmarshal(line.Data, &rd.Header); err != nil {
+		gz.Close()
+		return nil, fmt.Errorf("%w: read header: %v", ErrInvalidArchive, err)
+	}
+	return rd, nil
+}
+
+// newJSONReader consumes header fields up to the first section array, so
+// records can then be decoded one at a time
+func newJSONReader(r io.Reader) (*Reader, error) {
+	rd := &Reader{dec: json.NewDecoder(r)}
+	if tok, err := rd.dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, fmt.Errorf("%w: expected a JSON object", ErrInvalidArchive)
+	}
+
+	fields := map[string]interface{}{
+		"format":    &rd.Header.Format,
+		"version":   &rd.Header.Version,
+		"createdAt": &rd.Header.CreatedAt,
+		"keySalt":   &rd.Header.KeySalt,
+	}
+	for rd.dec.More() {
+		tok, err := rd.dec.Token()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+		}
+		key, _ := tok.(string)
+
+		target, ok := fields[key]
+		if !ok {
+			rd.section = key
+			return rd, nil
+		}
+		if err := rd.dec.Decode(target); err != nil {
+			return nil, fmt.Errorf("%w: read header field %s: %v", ErrInvalidArchive, key, err)
+		}
+	}
+	return rd, nil
+}
+
+// Records calls fn for every record in archive order
+func (rd *Reader) Records(fn func(kind string, data json.RawMessage) error) error {
+	if rd.ndjson {
+		for {
+			var line ndjsonLine
+			if err := rd.dec.Decode(&line); err == io.EOF {
+				return nil
+			} else if err != nil {
+				return fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+			}
+			if _, ok := sections[line.Kind]; !ok {
+				return fmt.Errorf("%w: unknown record kind: %s", ErrInvalidArchive, line.Kind)
+			}
+			if err := fn(line.Kind, line.Data); err != nil {
+				return err
+			}
+		}
+	}
+
+	kinds := make(map[string]string, len(sections))
+	for kind, section := range sections {
+		kinds[section] = kind
+	}
+
+	for rd.section != "" {
+		kind, ok := kinds[rd.section]
+		if !ok {
+			return fmt.Errorf("%w: unknown section: %s", ErrInvalidArchive, rd.section)
+		}
+		if tok, err := rd.dec.Token(); err != nil || tok != json.Delim('[') {
+			return fmt.Errorf("%w: %s is not an array", ErrInvalidArchive, rd.section)
+		}
+		for rd.dec.More() {
+			var record json.RawMessage
+			if err := rd.dec.Decode(&record); err != nil {
+				return fmt.Errorf("%w: read %s: %v", ErrInvalidArchive, rd.section, err)
+			}
+			if err := fn(kind, record); err != nil {
+				return err
+			}
+		}
+		if _, err := rd.dec.Token(); err != nil {
+			return fmt.Errorf("%w: read %s: %v", ErrInvalidArchive, rd.section, err)
+		}
+
+		rd.section = ""
+		if rd.dec.More() {
+			tok, err := rd.dec.Token()
+			if err != nil {
+				return fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+			}
+			rd.section, _ = tok.(string)
+		}
+	}
+	return nil
+}
+
+func (rd *Reader) Close() error {
+	if rd.gz != nil {
+		return rd.gz.Close()
+	}
+	return nil
+}
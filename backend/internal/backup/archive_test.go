@@ -0,0 +1,185 @@
+package backup
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testHeader = Header{
+	Format:    FormatName,
+	Version:   Version,
+	CreatedAt: time.Date(2025, 5, 2, 8, 30, 0, 0, time.UTC),
+	KeySalt:   "c2FsdHNhbHRzYWx0c2FsdA==",
+}
+
+type testRecord struct {
+	kind string
+	data string
+}
+
+var testRecords = []testRecord{
+	{KindPosition, `{"orderId":"1","closedPnl":1.5}`},
+	{KindPosition, `{"orderId":"2","closedPnl":-2}`},
+	{KindWithdrawal, `{"exchange":"bybit","amount":100}`},
+	{KindMonthlyIncome, `{"exchange":"mexc","pnl":3}`},
+	{KindSetting, `{"key":"theme","value":"dark"}`},
+	{KindImportProfile, `{"name":"venue"}`},
+	{KindAPIKey, `{"exchange":"bybit","apiKey":"sealed"}`},
+}
+
+func writeArchive(t *testing.T, format string, h Header, records []testRecord) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := NewWriter(format, &buf, h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range records {
+		if err := w.WriteRecord(r.kind, json.RawMessage(r.data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func readArchive(t *testing.T, data []byte) (Header, []testRecord) {
+	t.Helper()
+	rd, err := NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rd.Close()
+
+	var records []testRecord
+	err = rd.Records(func(kind string, data json.RawMessage) error {
+		records = append(records, testRecord{kind, string(data)})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rd.Header, records
+}
+
+func TestArchiveRoundTrip(t *testing.T) {
+	for _, format := range []string{"json", "ndjson"} {
+		for _, records := range [][]testRecord{testRecords, nil} {
+			data := writeArchive(t, format, testHeader, records)
+			if compressed := bytes.HasPrefix(data, []byte{0x1f, 0x8b}); compressed != (format == "ndjson") {
+				t.Errorf("%s archive compressed = %v", format, compressed)
+			}
+
+			h, got := readArchive(t, data)
+			if h.Format != testHeader.Format || h.Version != testHeader.Version ||
+				!h.CreatedAt.Equal(testHeader.CreatedAt) || h.KeySalt != testHeader.KeySalt {
+				t.Errorf("%s header %+v, want %+v", format, h, testHeader)
+			}
+			if len(got) != len(records) {
+				t.Fatalf("%s: read %d records, want %d", format, len(got), len(records))
+			}
+			for i, want := range records {
+				if got[i] != want {
+					t.Errorf("%s record %d = %+v, want %+v", format, i, got[i], want)
+				}
+			}
+		}
+	}
+}
+
+func TestJSONWriterNeedsGroupedKinds(t *testing.T) {
+	w, err := NewWriter("json", &bytes.Buffer{}, testHeader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, kind := range []string{KindPosition, KindWithdrawal} {
+		if err := w.WriteRecord(kind, struct{}{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.WriteRecord(KindPosition, struct{}{}); err == nil || !strings.Contains(err.Error(), "not contiguous") {
+		t.Errorf("scattered kind: %v, want a contiguity error", err)
+	}
+	if err := w.WriteRecord("trade", struct{}{}); err == nil {
+		t.Error("expected an error for an unknown kind")
+	}
+
+	if _, err := NewWriter("zip", &bytes.Buffer{}, testHeader); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
+
+func TestHeaderValidate(t *testing.T) {
+	if err := testHeader.Validate(); err != nil {
+		t.Fatalf("valid header: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		header Header
+	}{
+		{"no format", Header{Version: Version}},
+		{"other format", Header{Format: "other-tool", Version: Version}},
+		{"no version", Header{Format: FormatName}},
+		{"newer version", Header{Format: FormatName, Version: Version + 1}},
+	}
+	for _, tt := range tests {
+		if err := tt.header.Validate(); !errors.Is(err, ErrInvalidArchive) {
+			t.Errorf("%s: %v, want ErrInvalidArchive", tt.name, err)
+		}
+	}
+}
+
+func TestReaderRejectsInvalidArchives(t *testing.T) {
+	newer := testHeader
+	newer.Version = Version + 1
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"not JSON", []byte("position,1\n")},
+		{"JSON array", []byte(`[{"format":"budgettracker-backup"}]`)},
+		{"other format", []byte(`{"format":"other","version":1,"positions":[]}`)},
+		{"newer JSON version", writeArchive(t, "json", newer, nil)},
+		{"newer NDJSON version", writeArchive(t, "ndjson", newer, nil)},
+		{"bad header field", []byte(`{"format":"budgettracker-backup","version":"one"}`)},
+	}
+	for _, tt := range tests {
+		if _, err := NewReader(bytes.NewReader(tt.data)); !errors.Is(err, ErrInvalidArchive) {
+			t.Errorf("%s: %v, want ErrInvalidArchive", tt.name, err)
+		}
+	}
+
+	// An NDJSON archive must start with its header
+	var headerless bytes.Buffer
+	gz := gzip.NewWriter(&headerless)
+	json.NewEncoder(gz).Encode(ndjsonLine{Kind: KindPosition, Data: json.RawMessage(`{}`)})
+	gz.Close()
+	if _, err := NewReader(&headerless); !errors.Is(err, ErrInvalidArchive) {
+		t.Errorf("headerless NDJSON: %v, want ErrInvalidArchive", err)
+	}
+}
+
+func TestRecordsRejectUnknownSections(t *testing.T) {
+	data := []byte(`{"format":"budgettracker-backup","version":1,"positions":[{}],"trades":[{}]}`)
+	rd, err := NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	err = rd.Records(func(string, json.RawMessage) error {
+		n++
+		return nil
+	})
+	if !errors.Is(err, ErrInvalidArchive) || n != 1 {
+		t.Errorf("got %d records and %v, want 1 and ErrInvalidArchive", n, err)
+	}
+}
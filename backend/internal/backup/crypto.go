@@ -0,0 +1,94 @@
+package backup
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+const pbkdf2Iterations = 600000
+
+// ErrWrongPassphrase is returned when encrypted API keys cannot be opened
+var ErrWrongPassphrase = errors.New("wrong backup passphrase")
+
+// KeyCipher encrypts API key secrets with AES-256-GCM under a key derived
+// from a passphrase
+type KeyCipher struct {
+	aead cipher.AEAD
+	salt []byte
+}
+
+// NewKeyCipher derives a key from the passphrase. A nil salt generates a
+// fresh one, as done when writing a backup.
+func NewKeyCipher(passphrase string, salt []byte) (*KeyCipher, error) {
+	if passphrase == "" {
+		return nil, fmt.Errorf("a passphrase is required to back up or restore API keys")
+	}
+	if salt == nil {
+		salt = make([]byte, 16)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, err
+		}
+	}
+
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, pbkdf2Iterations, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &KeyCipher{aead: aead, salt: salt}, nil
+}
+
+// NewKeyCipherForHeader recreates the cipher of an archive from its salt
+func NewKeyCipherForHeader(passphrase string, h Header) (*KeyCipher, error) {
+	salt, err := base64.StdEncoding.DecodeString(h.KeySalt)
+	if err != nil || len(salt) == 0 {
+		return nil, fmt.Errorf("backup has no valid key salt")
+	}
+	return NewKeyCipher(passphrase, salt)
+}
+
+// Salt returns the base64 salt to store in the archive header
+func (c *KeyCipher) Salt() string {
+	return base64.StdEncoding.EncodeToString(c.salt)
+}
+
+// Encrypt returns base64(nonce | ciphertext)
+func (c *KeyCipher) Encrypt(plaintext string) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := c.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (c *KeyCipher) Decrypt(encoded string) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("invalid encrypted value: %w", err)
+	}
+	if len(sealed) < c.aead.NonceSize() {
+		return "", fmt.Errorf("invalid encrypted value")
+	}
+
+	nonce, ciphertext := sealed[:c.aead.NonceSize()], sealed[c.aead.NonceSize():]
+	plaintext, err := c.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", ErrWrongPassphrase
+	}
+	return string(plaintext), nil
+}
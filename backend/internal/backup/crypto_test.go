@@ -0,0 +1,60 @@
+package backup
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestKeyCipherRoundTrip(t *testing.T) {
+	c, err := NewKeyCipher("correct horse", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := c.Encrypt("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := c.Encrypt("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == second {
+		t.Error("encrypting twice gave the same ciphertext, want a fresh nonce each time")
+	}
+
+	// Restores rebuild the cipher from the salt in the header
+	restored, err := NewKeyCipherForHeader("correct horse", Header{KeySalt: c.Salt()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, sealed := range []string{first, second} {
+		if got, err := restored.Decrypt(sealed); err != nil || got != "secret" {
+			t.Errorf("Decrypt = %q, %v, want secret", got, err)
+		}
+	}
+
+	wrong, err := NewKeyCipherForHeader("battery staple", Header{KeySalt: c.Salt()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wrong.Decrypt(first); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("wrong passphrase: %v, want ErrWrongPassphrase", err)
+	}
+
+	for _, bad := range []string{"not base64!", "c2hvcnQ="} {
+		if _, err := restored.Decrypt(bad); err == nil || errors.Is(err, ErrWrongPassphrase) {
+			t.Errorf("Decrypt(%q): %v, want an invalid value error", bad, err)
+		}
+	}
+}
+
+func TestKeyCipherNeedsPassphraseAndSalt(t *testing.T) {
+	if _, err := NewKeyCipher("", nil); err == nil {
+		t.Error("expected an error without a passphrase")
+	}
+	for _, salt := range []string{"", "not base64!", "===="} {
+		if _, err := NewKeyCipherForHeader("correct horse", Header{KeySalt: salt}); err == nil {
+			t.Errorf("salt %q: expected an error", salt)
+		}
+	}
+}
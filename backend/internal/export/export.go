@@ -0,0 +1,153 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// RowWriter writes a table one row at a time so exports never hold the
+// whole result set in memory
+type RowWriter interface {
+	WriteRow(values []interface{}) error
+	// Close finishes the output
+	Close() error
+	// Discard releases the writer without finishing the output; it does
+	// nothing after Close
+	Discard()
+}
+
+// NewRowWriter returns a writer for the given format ("csv" or "xlsx")
+func NewRowWriter(format string, w io.Writer) (RowWriter, error) {
+	switch format {
+	case "", "csv":
+		return &csvWriter{w: csv.NewWriter(w)}, nil
+	case "xlsx":
+		return newXLSXWriter(w)
+	default:
+		return nil, fmt.Errorf("unsupported format: %s", format)
+	}
+}
+
+// ContentType returns the MIME type of a format
+func ContentType(format string) string {
+	if format == "xlsx" {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+type csvWriter struct {
+	w    *csv.Writer
+	rows int
+}
+
+func (c *csvWriter) WriteRow(values []interface{}) error {
+	record := make([]string, len(values))
+	for i, v := range values {
+		record[i] = formatValue(v)
+	}
+	if err := c.w.Write(record); err != nil {
+		return err
+	}
+
+	// Flush periodically so the response starts streaming right away
+	c.rows++
+	if c.rows%500 == 0 {
+		c.w.Flush()
+	}
+	return c.w.Error()
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvWriter) Discard() {}
+
+func formatValue(v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		return escapeFormula(val)
+	case int:
+		return strconv.Itoa(val)
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case time.Time:
+		return val.UTC().Format(time.RFC3339)
+	default:
+		return fmt.Sprint(val)
+	}
+}
+
+// escapeFormula prefixes text a spreadsheet would evaluate as a formula
+// with an apostrophe, so values like "=HYPERLINK(...)" stay plain text
+func escapeFormula(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// xlsxWriter uses the excelize stream writer, which spills rows to a
+// temporary file instead of keeping the sheet in memory
+type xlsxWriter struct {
+	out    io.Writer
+	file   *excelize.File
+	sw     *excelize.StreamWriter
+	row    int
+	closed bool
+}
+
+func newXLSXWriter(w io.Writer) (*xlsxWriter, error) {
+	f := excelize.NewFile()
+	sw, err := f.NewStreamWriter("Sheet1")
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &xlsxWriter{out: w, file: f, sw: sw}, nil
+}
+
+func (x *xlsxWriter) WriteRow(values []interface{}) error {
+	x.row++
+	cell, err := excelize.CoordinatesToCellName(1, x.row)
+	if err != nil {
+		return err
+	}
+
+	row := make([]interface{}, len(values))
+	for i, v := range values {
+		switch val := v.(type) {
+		case time.Time:
+			row[i] = val.UTC().Format("2006-01-02 15:04:05")
+		case string:
+			row[i] = escapeFormula(val)
+		default:
+			row[i] = v
+		}
+	}
+	return x.sw.SetRow(cell, row)
+}
+
+func (x *xlsxWriter) Close() error {
+	x.closed = true
+	defer x.file.Close()
+	if err := x.sw.Flush(); err != nil {
+		return err
+	}
+	return x.file.Write(x.out)
+}
+
+func (x *xlsxWriter) Discard() {
+	if !x.closed {
+		x.closed = true
+		x.file.Close()
+	}
+}
@@ -0,0 +1,99 @@
+package export
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+var (
+	testHeader = []interface{}{"ID", "Symbol", "Side", "Volume", "Leverage", "Closed PnL", "Date"}
+	testRows   = [][]interface{}{
+		{1, "BTCUSDT", "Buy", 1234.5, 10, -0.25, time.Date(2025, 5, 2, 8, 30, 15, 0, time.UTC)},
+		{2, "=HYPERLINK(\"x\")", "Sell", 0.0001, 1, 3.0, time.Date(2025, 5, 3, 12, 0, 0, 0, time.FixedZone("UTC+3", 3*3600))},
+	}
+)
+
+func writeRows(t *testing.T, format string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	rw, err := NewRowWriter(format, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rw.Discard()
+	for _, row := range append([][]interface{}{testHeader}, testRows...) {
+		if err := rw.WriteRow(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := rw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestCSVWriter(t *testing.T) {
+	want := "ID,Symbol,Side,Volume,Leverage,Closed PnL,Date\n" +
+		"1,BTCUSDT,Buy,1234.5,10,-0.25,2025-05-02T08:30:15Z\n" +
+		"2,\"'=HYPERLINK(\"\"x\"\")\",Sell,0.0001,1,3,2025-05-03T09:00:00Z\n"
+	if got := string(writeRows(t, "csv")); got != want {
+		t.Errorf("CSV output:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestXLSXRoundTrip(t *testing.T) {
+	f, err := excelize.OpenReader(bytes.NewReader(writeRows(t, "xlsx")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	got, err := f.GetRows("Sheet1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"ID", "Symbol", "Side", "Volume", "Leverage", "Closed PnL", "Date"},
+		{"1", "BTCUSDT", "Buy", "1234.5", "10", "-0.25", "2025-05-02 08:30:15"},
+		{"2", "'=HYPERLINK(\"x\")", "Sell", "0.0001", "1", "3", "2025-05-03 09:00:00"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("sheet rows %q, want %q", got, want)
+	}
+
+	// Numbers stay numbers, not text
+	if typ, err := f.GetCellType("Sheet1", "D2"); err != nil || typ == excelize.CellTypeSharedString || typ == excelize.CellTypeInlineString {
+		t.Errorf("volume cell type %v, %v, want a number", typ, err)
+	}
+}
+
+func TestEscapeFormula(t *testing.T) {
+	tests := map[string]string{
+		"":            "",
+		"BTCUSDT":     "BTCUSDT",
+		"=1+1":        "'=1+1",
+		"+1":          "'+1",
+		"-1":          "'-1",
+		"@SUM(A1:A2)": "'@SUM(A1:A2)",
+		"\t=1":        "'\t=1",
+		"\r=1":        "'\r=1",
+		"a=1":         "a=1",
+		" =1":         " =1",
+		"'=already":   "'=already",
+	}
+	for in, want := range tests {
+		if got := escapeFormula(in); got != want {
+			t.Errorf("escapeFormula(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestNewRowWriterRejectsUnknownFormat(t *testing.T) {
+	if _, err := NewRowWriter("pdf", &bytes.Buffer{}); err == nil {
+		t.Error("expected an unsupported format error")
+	}
+}
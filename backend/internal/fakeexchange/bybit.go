@@ -0,0 +1,286 @@
+package fakeexchange
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Bybit limits closed PnL queries to 7-day windows within the last 2 years
+const (
+	bybitMaxWindow  = 7 * 24 * time.Hour
+	bybitMaxHistory = 2 * 365 * 24 * time.Hour
+)
+
+type bybitResponse struct {
+	RetCode    int         `json:"retCode"`
+	RetMsg     string      `json:"retMsg"`
+	Result     interface{} `json:"result"`
+	RetExtInfo struct{}    `json:"retExtInfo"`
+	Time       int64       `json:"time"`
+}
+
+func (s *Server) writeBybit(w http.ResponseWriter, result interface{}) {
+	writeJSON(w, bybitResponse{RetMsg: "OK", Result: result, Time: s.now().UnixMilli()})
+}
+
+func (s *Server) writeBybitError(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, bybitResponse{RetCode: code, RetMsg: msg, Result: struct{}{}, Time: s.now().UnixMilli()})
+}
+
+// bybitAuth checks the V5 HMAC signature: hex(HMAC-SHA256(secret,
+// timestamp + apiKey + recvWindow + queryString))
+func (s *Server) bybitAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		apiKey := r.Header.Get("X-BAPI-API-KEY")
+		timestamp := r.Header.Get("X-BAPI-TIMESTAMP")
+		recvWindow := r.Header.Get("X-BAPI-RECV-WINDOW")
+		if recvWindow == "" {
+			recvWindow = "5000"
+		}
+
+		if apiKey != s.scenario.APIKey {
+			s.writeBybitError(w, 10003, "API key is invalid.")
+			return
+		}
+
+		ts, err := strconv.ParseInt(timestamp, 10, 64)
+		window, werr := strconv.ParseInt(recvWindow, 10, 64)
+		if err != nil || werr != nil {
+			s.writeBybitError(w, 10002, "invalid request, please check your server timestamp or recv_window param")
+			return
+		}
+		if now := s.now().UnixMilli(); ts > now+1000 || now-ts > window {
+			s.writeBybitError(w, 10002, "invalid request, please check your server timestamp or recv_window param")
+			return
+		}
+
+		mac := hmac.New(sha256.New, []byte(s.scenario.APISecret))
+		mac.Write([]byte(timestamp + apiKey + recvWindow + r.URL.RawQuery))
+		expected := hex.EncodeToString(mac.Sum(nil))
+		if !hmac.Equal([]byte(expected), []byte(r.Header.Get("X-BAPI-SIGN"))) {
+			s.writeBybitError(w, 10004, "error sign! origin_string["+timestamp+apiKey+recvWindow+r.URL.RawQuery+"]")
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// bybitTimeRange resolves startTime/endTime the way Bybit does: a missing
+// bound is filled in to make a 7-day window, ending now by default
+func (s *Server) bybitTimeRange(r *http.Request) (start, end int64, msg string) {
+	q := r.URL.Query()
+	now := s.now().UnixMilli()
+	window := bybitMaxWindow.Milliseconds()
+
+	parse := func(name string) (int64, bool) {
+		v := q.Get(name)
+		if v == "" {
+			return 0, true
+		}
+		n, err := strconv.ParseInt(v, 10, 64)
+		return n, err == nil
+	}
+	start, okStart := parse("startTime")
+	end, okEnd := parse("endTime")
+	if !okStart || !okEnd {
+		return 0, 0, "params error: startTime or endTime is invalid"
+	}
+
+	switch {
+	case start == 0 && end == 0:
+		end = now
+		start = now - window
+	case start == 0:
+		start = end - window
+	case end == 0:
+		end = start + window
+	}
+
+	if end < start {
+		return 0, 0, "params error: endTime must be greater than startTime"
+	}
+	if end-start > window {
+		return 0, 0, "params error: The sum of startTime and endTime cannot exceed 7 days"
+	}
+	if start < now-bybitMaxHistory.Milliseconds() {
+		return 0, 0, "params error: Can't query earlier than 2 years"
+	}
+	return start, end, ""
+}
+
+func (s *Server) bybitClosedPnl(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	category := q.Get("category")
+	if category != "linear" && category != "inverse" {
+		s.writeBybitError(w, 10001, "params error: Category is invalid")
+		return
+	}
+
+	start, end, msg := s.bybitTimeRange(r)
+	if msg != "" {
+		s.writeBybitError(w, 10001, msg)
+		return
+	}
+
+	offset, size, ok := pageParams(q.Get("cursor"), q.Get("limit"), 50, 100)
+	if !ok {
+		s.writeBybitError(w, 10001, "params error: cursor or limit is invalid")
+		return
+	}
+
+	var items []BybitClosedPnl
+	for _, item := range s.scenario.Bybit.ClosedPnl {
+		t, _ := strconv.ParseInt(item.UpdatedTime, 10, 64)
+		if t >= start && t <= end && (q.Get("symbol") == "" || item.Symbol == q.Get("symbol")) {
+			items = append(items, item)
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		ti, _ := strconv.ParseInt(items[i].UpdatedTime, 10, 64)
+		tj, _ := strconv.ParseInt(items[j].UpdatedTime, 10, 64)
+		return ti > tj
+	})
+
+	from, to, next := page(len(items), offset, size)
+	s.writeBybit(w, map[string]interface{}{
+		"category":       category,
+		"list":           nonNil(items[from:to]),
+		"nextPageCursor": cursorString(next),
+	})
+}
+
+// bybitClosedOptions serves get-closed-positions, which Bybit only offers
+// for options; linear accounts get an error and fall back to closed-pnl
+func (s *Server) bybitClosedOptions(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("category") != "option" {
+		s.writeBybitError(w, 10001, "params error: Category is invalid")
+		return
+	}
+	s.writeBybit(w, map[string]interface{}{
+		"category":       "option",
+		"list":           []interface{}{},
+		"nextPageCursor": "",
+	})
+}
+
+func (s *Server) bybitExecutions(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	category := q.Get("category")
+	if category == "" {
+		s.writeBybitError(w, 10001, "params error: Category is invalid")
+		return
+	}
+
+	start, end, msg := s.bybitTimeRange(r)
+	if msg != "" {
+		s.writeBybitError(w, 10001, msg)
+		return
+	}
+
+	offset, size, ok := pageParams(q.Get("cursor"), q.Get("limit"), 50, 100)
+	if !ok {
+		s.writeBybitError(w, 10001, "params error: cursor or limit is invalid")
+		return
+	}
+
+	var items []BybitExecution
+	for _, item := range s.scenario.Bybit.Executions {
+		t, _ := strconv.ParseInt(item.ExecTime, 10, 64)
+		if t >= start && t <= end && (q.Get("symbol") == "" || item.Symbol == q.Get("symbol")) {
+			items = append(items, item)
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		ti, _ := strconv.ParseInt(items[i].ExecTime, 10, 64)
+		tj, _ := strconv.ParseInt(items[j].ExecTime, 10, 64)
+		return ti > tj
+	})
+
+	from, to, next := page(len(items), offset, size)
+	s.writeBybit(w, map[string]interface{}{
+		"category":       category,
+		"list":           nonNil(items[from:to]),
+		"nextPageCursor": cursorString(next),
+	})
+}
+
+func (s *Server) bybitWalletBalance(w http.ResponseWriter, r *http.Request) {
+	accountType := r.URL.Query().Get("accountType")
+	if accountType != "UNIFIED" && accountType != "CONTRACT" {
+		s.writeBybitError(w, 10001, "params error: accountType only support UNIFIED or CONTRACT")
+		return
+	}
+
+	equity := s.scenario.Bybit.TotalEquity
+	if equity == "" {
+		equity = "0"
+	}
+	s.writeBybit(w, map[string]interface{}{
+		"list": []map[string]interface{}{{
+			"accountType":            accountType,
+			"totalEquity":            equity,
+			"totalWalletBalance":     equity,
+			"totalAvailableBalance":  equity,
+			"totalPerpUPL":           "0",
+			"totalInitialMargin":     "0",
+			"totalMaintenanceMargin": "0",
+			"coin":                   []interface{}{},
+		}},
+	})
+}
+
+// bybitPositionList serves open positions; linear queries need a symbol or
+// a settle coin, as on Bybit
+func (s *Server) bybitPositionList(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	category := q.Get("category")
+	if category != "linear" && category != "inverse" {
+		s.writeBybitError(w, 10001, "params error: Category is invalid")
+		return
+	}
+	if q.Get("symbol") == "" && q.Get("settleCoin") == "" {
+		s.writeBybitError(w, 10001, "params error: symbol or settleCoin is required")
+		return
+	}
+
+	offset, size, ok := pageParams(q.Get("cursor"), q.Get("limit"), 20, 200)
+	if !ok {
+		s.writeBybitError(w, 10001, "params error: cursor or limit is invalid")
+		return
+	}
+
+	var items []BybitOpenPosition
+	for _, item := range s.scenario.Bybit.OpenPositions {
+		if q.Get("symbol") == "" || item.Symbol == q.Get("symbol") {
+			items = append(items, item)
+		}
+	}
+
+	from, to, next := page(len(items), offset, size)
+	s.writeBybit(w, map[string]interface{}{
+		"category":       category,
+		"list":           nonNil(items[from:to]),
+		"nextPageCursor": cursorString(next),
+	})
+}
+
+func cursorString(next int) string {
+	if next < 0 {
+		return ""
+	}
+	return strconv.Itoa(next)
+}
+
+// nonNil keeps empty pages encoded as [] rather than null
+func nonNil[T any](items []T) []T {
+	if items == nil {
+		return []T{}
+	}
+	return items
+}
@@ -0,0 +1,138 @@
+package fakeexchange
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+// mexcRequestWindow is how far Request-Time may be from the server clock
+const mexcRequestWindow = 10000
+
+type mexcResponse struct {
+	Success bool        `json:"success"`
+	Code    int         `json:"code"`
+	Message string      `json:"message,omitempty"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+func writeMEXC(w http.ResponseWriter, data interface{}) {
+	writeJSON(w, mexcResponse{Success: true, Data: data})
+}
+
+func writeMEXCError(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, mexcResponse{Code: code, Message: msg})
+}
+
+// mexcAuth checks the contract V1 signature: hex(HMAC-SHA256(secret,
+// apiKey + Request-Time + queryString)), the query sorted by key
+func (s *Server) mexcAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		apiKey := r.Header.Get("ApiKey")
+		requestTime := r.Header.Get("Request-Time")
+
+		if apiKey != s.scenario.APIKey {
+			writeMEXCError(w, 401, "Not logged in or login has expired")
+			return
+		}
+
+		ts, err := strconv.ParseInt(requestTime, 10, 64)
+		if err != nil {
+			writeMEXCError(w, 513, "Invalid request time")
+			return
+		}
+		if diff := s.now().UnixMilli() - ts; diff > mexcRequestWindow || diff < -mexcRequestWindow {
+			writeMEXCError(w, 513, "Invalid request time")
+			return
+		}
+
+		mac := hmac.New(sha256.New, []byte(s.scenario.APISecret))
+		mac.Write([]byte(apiKey + requestTime + r.URL.RawQuery))
+		expected := hex.EncodeToString(mac.Sum(nil))
+		if !hmac.Equal([]byte(expected), []byte(r.Header.Get("Signature"))) {
+			writeMEXCError(w, 602, "Signature verification failed!")
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+func (s *Server) mexcHistoryPositions(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	pageNum := 1
+	if v := q.Get("page_num"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			writeMEXCError(w, 600, "Parameter error: page_num")
+			return
+		}
+		pageNum = n
+	}
+	_, size, ok := pageParams("", q.Get("page_size"), 20, 100)
+	if !ok {
+		writeMEXCError(w, 600, "Parameter error: page_size")
+		return
+	}
+
+	var items []MEXCHistoryPosition
+	for _, item := range s.scenario.MEXC.HistoryPositions {
+		if q.Get("symbol") == "" || item.Symbol == q.Get("symbol") {
+			items = append(items, item)
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].UpdateTime > items[j].UpdateTime })
+
+	from, to, _ := page(len(items), (pageNum-1)*size, size)
+	writeMEXC(w, nonNil(items[from:to]))
+}
+
+func (s *Server) mexcOpenPositions(w http.ResponseWriter, r *http.Request) {
+	var items []MEXCOpenPosition
+	for _, item := range s.scenario.MEXC.OpenPositions {
+		if r.URL.Query().Get("symbol") == "" || item.Symbol == r.URL.Query().Get("symbol") {
+			items = append(items, item)
+		}
+	}
+	writeMEXC(w, nonNil(items))
+}
+
+// mexcFairPrice is public
+func (s *Server) mexcFairPrice(w http.ResponseWriter, r *http.Request) {
+	symbol := r.PathValue("symbol")
+	price, ok := s.scenario.MEXC.FairPrices[symbol]
+	if !ok {
+		writeMEXCError(w, 1001, "contract not exist")
+		return
+	}
+	writeMEXC(w, map[string]interface{}{
+		"symbol":    symbol,
+		"fairPrice": price,
+		"timestamp": s.now().UnixMilli(),
+	})
+}
+
+func (s *Server) mexcAssets(w http.ResponseWriter, r *http.Request) {
+	writeMEXC(w, nonNil(s.scenario.MEXC.Assets))
+}
+
+// mexcContractDetail is public: all contracts, or a single object when a
+// symbol is given
+func (s *Server) mexcContractDetail(w http.ResponseWriter, r *http.Request) {
+	symbol := r.URL.Query().Get("symbol")
+	if symbol == "" {
+		writeMEXC(w, nonNil(s.scenario.MEXC.Contracts))
+		return
+	}
+	for _, c := range s.scenario.MEXC.Contracts {
+		if c.Symbol == symbol {
+			writeMEXC(w, c)
+			return
+		}
+	}
+	writeMEXCError(w, 1001, "contract not exist")
+}
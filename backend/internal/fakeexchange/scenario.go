@@ -0,0 +1,245 @@
+package fakeexchange
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+//go:embed scenarios/*.json
+var scenarios embed.FS
+
+// Scenario is the seeded account state served by the fake exchange. Records
+// use the exchanges' own JSON shapes, so payloads captured from the real
+// APIs can be pasted in as they are.
+type Scenario struct {
+	APIKey    string `json:"apiKey"`
+	APISecret string `json:"apiSecret"`
+	// Anchor is the moment the recorded timestamps were taken at. On load
+	// every timestamp is shifted so Anchor becomes "now", which keeps the
+	// data inside the exchanges' history windows however old the file is.
+	Anchor time.Time     `json:"anchor"`
+	Bybit  BybitScenario `json:"bybit"`
+	MEXC   MEXCScenario  `json:"mexc"`
+}
+
+type BybitScenario struct {
+	ClosedPnl     []BybitClosedPnl    `json:"closedPnl"`
+	Executions    []BybitExecution    `json:"executions"`
+	OpenPositions []BybitOpenPosition `json:"openPositions"`
+	TotalEquity   string              `json:"totalEquity"`
+	// StreamExecutions are pushed on the private stream's execution topic,
+	// stamped with the time of the push
+	StreamExecutions []BybitExecution `json:"streamExecutions"`
+}
+
+// BybitClosedPnl is one /v5/position/closed-pnl list item
+type BybitClosedPnl struct {
+	Symbol        string `json:"symbol"`
+	OrderID       string `json:"orderId"`
+	Side          string `json:"side"`
+	Qty           string `json:"qty"`
+	OrderPrice    string `json:"orderPrice"`
+	OrderType     string `json:"orderType"`
+	ExecType      string `json:"execType"`
+	ClosedSize    string `json:"closedSize"`
+	CumEntryValue string `json:"cumEntryValue"`
+	AvgEntryPrice string `json:"avgEntryPrice"`
+	CumExitValue  string `json:"cumExitValue"`
+	AvgExitPrice  string `json:"avgExitPrice"`
+	ClosedPnl     string `json:"closedPnl"`
+	FillCount     string `json:"fillCount"`
+	Leverage      string `json:"leverage"`
+	CreatedTime   string `json:"createdTime"`
+	UpdatedTime   string `json:"updatedTime"`
+}
+
+// BybitExecution is one /v5/execution/list item
+type BybitExecution struct {
+	Symbol     string `json:"symbol"`
+	OrderID    string `json:"orderId"`
+	ExecID     string `json:"execId"`
+	Side       string `json:"side"`
+	ExecPrice  string `json:"execPrice"`
+	ExecQty    string `json:"execQty"`
+	ExecValue  string `json:"execValue"`
+	ExecFee    string `json:"execFee"`
+	ExecType   string `json:"execType"`
+	ClosedSize string `json:"closedSize"`
+	ClosedPnl  string `json:"closedPnl"`
+	ExecPnl    string `json:"execPnl,omitempty"`
+	Leverage   string `json:"leverage"`
+	ExecTime   string `json:"execTime"`
+}
+
+// BybitOpenPosition is one /v5/position/list item
+type BybitOpenPosition struct {
+	Symbol        string `json:"symbol"`
+	PositionIdx   int    `json:"positionIdx"`
+	Side          string `json:"side"`
+	Size          string `json:"size"`
+	AvgPrice      string `json:"avgPrice"`
+	MarkPrice     string `json:"markPrice"`
+	PositionValue string `json:"positionValue"`
+	UnrealisedPnl string `json:"unrealisedPnl"`
+	Leverage      string `json:"leverage"`
+	LiqPrice      string `json:"liqPrice"`
+	CreatedTime   string `json:"createdTime"`
+	UpdatedTime   string `json:"updatedTime"`
+}
+
+type MEXCScenario struct {
+	HistoryPositions []MEXCHistoryPosition `json:"historyPositions"`
+	OpenPositions    []MEXCOpenPosition    `json:"openPositions"`
+	Assets           []MEXCAsset           `json:"assets"`
+	Contracts        []MEXCContract        `json:"contracts"`
+	// FairPrices by symbol, served by /api/v1/contract/fair_price
+	FairPrices map[string]float64 `json:"fairPrices"`
+	// StreamPositions are pushed on the private stream as
+	// push.personal.position updates, stamped with the time of the push
+	StreamPositions []MEXCStreamPosition `json:"streamPositions"`
+}
+
+// MEXCHistoryPosition is one /api/v1/private/position/list/history_positions item
+type MEXCHistoryPosition struct {
+	PositionID      int64   `json:"positionId"`
+	Symbol          string  `json:"symbol"`
+	PositionType    int     `json:"positionType"`
+	OpenType        int     `json:"openType"`
+	State           int     `json:"state"`
+	HoldVol         float64 `json:"holdVol"`
+	CloseVol        float64 `json:"closeVol"`
+	OpenAvgPrice    float64 `json:"openAvgPrice"`
+	CloseAvgPrice   float64 `json:"closeAvgPrice"`
+	Leverage        int     `json:"leverage"`
+	CloseProfitLoss float64 `json:"closeProfitLoss"`
+	Realised        float64 `json:"realised"`
+	HoldFee         float64 `json:"holdFee"`
+	Im              float64 `json:"im"`
+	Oim             float64 `json:"oim"`
+	CreateTime      int64   `json:"createTime"`
+	UpdateTime      int64   `json:"updateTime"`
+}
+
+// MEXCOpenPosition is one /api/v1/private/position/open_positions item
+type MEXCOpenPosition struct {
+	PositionID     int64   `json:"positionId"`
+	Symbol         string  `json:"symbol"`
+	PositionType   int     `json:"positionType"`
+	OpenType       int     `json:"openType"`
+	State          int     `json:"state"`
+	HoldVol        float64 `json:"holdVol"`
+	HoldAvgPrice   float64 `json:"holdAvgPrice"`
+	OpenAvgPrice   float64 `json:"openAvgPrice"`
+	LiquidatePrice float64 `json:"liquidatePrice"`
+	Leverage       int     `json:"leverage"`
+	Im             float64 `json:"im"`
+	Oim            float64 `json:"oim"`
+	CreateTime     int64   `json:"createTime"`
+	UpdateTime     int64   `json:"updateTime"`
+}
+
+// MEXCStreamPosition is one push.personal.position update; unlike history
+// records it has no closeProfitLoss
+type MEXCStreamPosition struct {
+	PositionID     int64   `json:"positionId"`
+	Symbol         string  `json:"symbol"`
+	PositionType   int     `json:"positionType"`
+	OpenType       int     `json:"openType"`
+	State          int     `json:"state"`
+	HoldVol        float64 `json:"holdVol"`
+	CloseVol       float64 `json:"closeVol"`
+	HoldAvgPrice   float64 `json:"holdAvgPrice"`
+	OpenAvgPrice   float64 `json:"openAvgPrice"`
+	CloseAvgPrice  float64 `json:"closeAvgPrice"`
+	LiquidatePrice float64 `json:"liquidatePrice"`
+	Leverage       int     `json:"leverage"`
+	Im             float64 `json:"im"`
+	Oim            float64 `json:"oim"`
+	HoldFee        float64 `json:"holdFee"`
+	Realised       float64 `json:"realised"`
+	UpdateTime     int64   `json:"updateTime"`
+}
+
+// MEXCContract is one /api/v1/contract/detail item
+type MEXCContract struct {
+	Symbol       string  `json:"symbol"`
+	DisplayName  string  `json:"displayName"`
+	BaseCoin     string  `json:"baseCoin"`
+	QuoteCoin    string  `json:"quoteCoin"`
+	SettleCoin   string  `json:"settleCoin"`
+	ContractSize float64 `json:"contractSize"`
+	PriceScale   int     `json:"priceScale"`
+	VolScale     int     `json:"volScale"`
+	MinVol       float64 `json:"minVol"`
+	MaxVol       float64 `json:"maxVol"`
+}
+
+// MEXCAsset is one /api/v1/private/account/assets item
+type MEXCAsset struct {
+	Currency         string `json:"currency"`
+	PositionMargin   string `json:"positionMargin"`
+	AvailableBalance string `json:"availableBalance"`
+	CashBalance      string `json:"cashBalance"`
+	FrozenBalance    string `json:"frozenBalance"`
+	Equity           string `json:"equity"`
+	Unrealized       string `json:"unrealized"`
+}
+
+// LoadScenario reads a scenario file, or a built-in one when path names
+// a file under scenarios/ without the .json suffix (e.g. "default")
+func LoadScenario(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		data, err = scenarios.ReadFile("scenarios/" + path + ".json")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scenario %s: %w", path, err)
+	}
+
+	var s Scenario
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to parse scenario %s: %w", path, err)
+	}
+	if s.APIKey == "" || s.APISecret == "" {
+		return nil, fmt.Errorf("scenario %s: apiKey and apiSecret are required", path)
+	}
+
+	if !s.Anchor.IsZero() {
+		s.shift(time.Since(s.Anchor))
+	}
+	return &s, nil
+}
+
+// shift moves every timestamp forward by d
+func (s *Scenario) shift(d time.Duration) {
+	ms := d.Milliseconds()
+	shiftString := func(v *string) {
+		if t, err := strconv.ParseInt(*v, 10, 64); err == nil {
+			*v = strconv.FormatInt(t+ms, 10)
+		}
+	}
+
+	for i := range s.Bybit.ClosedPnl {
+		shiftString(&s.Bybit.ClosedPnl[i].CreatedTime)
+		shiftString(&s.Bybit.ClosedPnl[i].UpdatedTime)
+	}
+	for i := range s.Bybit.Executions {
+		shiftString(&s.Bybit.Executions[i].ExecTime)
+	}
+	for i := range s.Bybit.OpenPositions {
+		shiftString(&s.Bybit.OpenPositions[i].CreatedTime)
+		shiftString(&s.Bybit.OpenPositions[i].UpdatedTime)
+	}
+	for i := range s.MEXC.HistoryPositions {
+		s.MEXC.HistoryPositions[i].CreateTime += ms
+		s.MEXC.HistoryPositions[i].UpdateTime += ms
+	}
+	for i := range s.MEXC.OpenPositions {
+		s.MEXC.OpenPositions[i].CreateTime += ms
+		s.MEXC.OpenPositions[i].UpdateTime += ms
+	}
+}
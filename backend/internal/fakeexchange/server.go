@@ -0,0 +1,85 @@
+package fakeexchange
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Server answers the subset of the Bybit V5 and MEXC contract V1 private
+// APIs and streams the exchange clients use, from a seeded Scenario.
+// Requests are authenticated the way the real exchanges do it, so a client
+// that signs incorrectly fails here as well.
+type Server struct {
+	scenario *Scenario
+	mux      *http.ServeMux
+	now      func() time.Time
+}
+
+func NewServer(scenario *Scenario) *Server {
+	s := &Server{
+		scenario: scenario,
+		mux:      http.NewServeMux(),
+		now:      time.Now,
+	}
+
+	s.mux.HandleFunc("GET /v5/position/closed-pnl", s.bybitAuth(s.bybitClosedPnl))
+	s.mux.HandleFunc("GET /v5/position/get-closed-positions", s.bybitAuth(s.bybitClosedOptions))
+	s.mux.HandleFunc("GET /v5/execution/list", s.bybitAuth(s.bybitExecutions))
+	s.mux.HandleFunc("GET /v5/account/wallet-balance", s.bybitAuth(s.bybitWalletBalance))
+	s.mux.HandleFunc("GET /v5/position/list", s.bybitAuth(s.bybitPositionList))
+	s.mux.HandleFunc("GET /v5/private", s.bybitStream)
+
+	s.mux.HandleFunc("GET /api/v1/private/position/list/history_positions", s.mexcAuth(s.mexcHistoryPositions))
+	s.mux.HandleFunc("GET /api/v1/private/account/assets", s.mexcAuth(s.mexcAssets))
+	s.mux.HandleFunc("GET /api/v1/private/position/open_positions", s.mexcAuth(s.mexcOpenPositions))
+	s.mux.HandleFunc("GET /api/v1/contract/detail", s.mexcContractDetail)
+	s.mux.HandleFunc("GET /api/v1/contract/fair_price/{symbol}", s.mexcFairPrice)
+	s.mux.HandleFunc("GET /edge", s.mexcStream)
+
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log.Printf("[fakeexchange] %s %s", r.Method, r.URL.RequestURI())
+	s.mux.ServeHTTP(w, r)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// pageParams reads an offset cursor and a page size bounded to [1, max]
+func pageParams(cursor, limit string, def, max int) (offset, size int, ok bool) {
+	size = def
+	if limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 {
+			return 0, 0, false
+		}
+		size = min(n, max)
+	}
+	if cursor != "" {
+		n, err := strconv.Atoi(cursor)
+		if err != nil || n < 0 {
+			return 0, 0, false
+		}
+		offset = n
+	}
+	return offset, size, true
+}
+
+// page slices one page out of n items and returns the next offset, or -1
+// on the last page
+func page(n, offset, size int) (start, end, next int) {
+	start = max(0, min(offset, n))
+	end = min(start+size, n)
+	next = -1
+	if end < n {
+		next = end
+	}
+	return start, end, next
+}
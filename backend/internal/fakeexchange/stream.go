@@ -0,0 +1,226 @@
+package fakeexchange
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/websocket"
+)
+
+var upgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// bybitStream serves the V5 private stream: auth, then a subscription,
+// then the open positions, the scenario's stream executions and the
+// positions they left. Pings are answered until the client hangs up.
+func (s *Server) bybitStream(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	var auth struct {
+		Op   string            `json:"op"`
+		Args []json.RawMessage `json:"args"`
+	}
+	if err := conn.ReadJSON(&auth); err != nil {
+		return
+	}
+	if msg := s.bybitStreamAuth(auth.Op, auth.Args); msg != "" {
+		conn.WriteJSON(map[string]interface{}{"success": false, "ret_msg": msg, "op": "auth"})
+		return
+	}
+	conn.WriteJSON(map[string]interface{}{"success": true, "ret_msg": "", "op": "auth"})
+
+	for {
+		var req struct {
+			Op   string   `json:"op"`
+			Args []string `json:"args"`
+		}
+		if err := conn.ReadJSON(&req); err != nil {
+			return
+		}
+		if req.Op == "ping" {
+			conn.WriteJSON(map[string]interface{}{"success": true, "ret_msg": "pong", "op": "ping"})
+			continue
+		}
+		if req.Op != "subscribe" {
+			conn.WriteJSON(map[string]interface{}{"success": false, "ret_msg": "unknown op " + req.Op, "op": req.Op})
+			continue
+		}
+		conn.WriteJSON(map[string]interface{}{"success": true, "ret_msg": "", "op": "subscribe"})
+		break
+	}
+
+	positions := append([]BybitOpenPosition(nil), s.scenario.Bybit.OpenPositions...)
+	s.pushBybit(conn, "position", bybitStreamPositions(positions))
+
+	now := strconv.FormatInt(s.now().UnixMilli(), 10)
+	var executions []map[string]string
+	for _, e := range s.scenario.Bybit.StreamExecutions {
+		executions = append(executions, map[string]string{
+			"category": "linear", "symbol": e.Symbol, "orderId": e.OrderID, "execId": e.ExecID,
+			"side": e.Side, "execPrice": e.ExecPrice, "execQty": e.ExecQty, "execValue": e.ExecValue,
+			"execFee": e.ExecFee, "execType": e.ExecType, "closedSize": e.ClosedSize,
+			"execPnl": e.ExecPnl, "execTime": now,
+		})
+		closePosition(positions, e.Symbol, e.ClosedSize, now)
+	}
+	if len(executions) > 0 {
+		s.pushBybit(conn, "execution", executions)
+		s.pushBybit(conn, "position", bybitStreamPositions(positions))
+	}
+
+	for {
+		var req struct {
+			Op string `json:"op"`
+		}
+		if err := conn.ReadJSON(&req); err != nil {
+			return
+		}
+		if req.Op == "ping" {
+			conn.WriteJSON(map[string]interface{}{"success": true, "ret_msg": "pong", "op": "ping"})
+		}
+	}
+}
+
+// bybitStreamAuth checks args [apiKey, expires, hex(HMAC-SHA256(secret,
+// "GET/realtime" + expires))] and returns the error message, if any
+func (s *Server) bybitStreamAuth(op string, args []json.RawMessage) string {
+	if op != "auth" || len(args) != 3 {
+		return "Params Error"
+	}
+	var apiKey, signature string
+	var expires int64
+	if json.Unmarshal(args[0], &apiKey) != nil || json.Unmarshal(args[1], &expires) != nil || json.Unmarshal(args[2], &signature) != nil {
+		return "Params Error"
+	}
+	if apiKey != s.scenario.APIKey {
+		return "API key is invalid."
+	}
+	if expires < s.now().UnixMilli() {
+		return "Params Error: auth expired"
+	}
+
+	mac := hmac.New(sha256.New, []byte(s.scenario.APISecret))
+	mac.Write([]byte(fmt.Sprintf("GET/realtime%d", expires)))
+	if !hmac.Equal([]byte(hex.EncodeToString(mac.Sum(nil))), []byte(signature)) {
+		return "Invalid sign"
+	}
+	return ""
+}
+
+func (s *Server) pushBybit(conn *websocket.Conn, topic string, data interface{}) {
+	log.Printf("[fakeexchange] push %s", topic)
+	conn.WriteJSON(map[string]interface{}{
+		"id":           fmt.Sprintf("%s-%d", topic, s.now().UnixNano()),
+		"topic":        topic,
+		"creationTime": s.now().UnixMilli(),
+		"data":         data,
+	})
+}
+
+// bybitStreamPositions maps list items to the stream's shape, which names
+// the average price entryPrice
+func bybitStreamPositions(positions []BybitOpenPosition) []map[string]string {
+	items := []map[string]string{}
+	for _, p := range positions {
+		items = append(items, map[string]string{
+			"category": "linear", "symbol": p.Symbol, "side": p.Side, "size": p.Size,
+			"entryPrice": p.AvgPrice, "markPrice": p.MarkPrice, "positionValue": p.PositionValue,
+			"unrealisedPnl": p.UnrealisedPnl, "leverage": p.Leverage, "liqPrice": p.LiqPrice,
+			"updatedTime": p.UpdatedTime,
+		})
+	}
+	return items
+}
+
+// closePosition reduces the position of symbol by closedSize, scaling its
+// value and unrealised PnL
+func closePosition(positions []BybitOpenPosition, symbol, closedSize, now string) {
+	closed, _ := strconv.ParseFloat(closedSize, 64)
+	for i := range positions {
+		p := &positions[i]
+		size, _ := strconv.ParseFloat(p.Size, 64)
+		if p.Symbol != symbol || size == 0 || closed == 0 {
+			continue
+		}
+
+		remaining := max(size-closed, 0)
+		scale := func(v string) string {
+			f, _ := strconv.ParseFloat(v, 64)
+			return strconv.FormatFloat(f*remaining/size, 'f', -1, 64)
+		}
+		p.PositionValue = scale(p.PositionValue)
+		p.UnrealisedPnl = scale(p.UnrealisedPnl)
+		p.Size = strconv.FormatFloat(remaining, 'f', -1, 64)
+		p.UpdatedTime = now
+		if remaining == 0 {
+			p.Side = ""
+		}
+	}
+}
+
+// mexcStream serves the contract stream: login, then the scenario's
+// stream positions. Pings are answered until the client hangs up.
+func (s *Server) mexcStream(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	var login struct {
+		Method string `json:"method"`
+		Param  struct {
+			APIKey    string `json:"apiKey"`
+			ReqTime   string `json:"reqTime"`
+			Signature string `json:"signature"`
+		} `json:"param"`
+	}
+	if err := conn.ReadJSON(&login); err != nil {
+		return
+	}
+
+	mac := hmac.New(sha256.New, []byte(s.scenario.APISecret))
+	mac.Write([]byte(login.Param.APIKey + login.Param.ReqTime))
+	expected := hex.EncodeToString(mac.Sum(nil))
+	if login.Method != "login" || login.Param.APIKey != s.scenario.APIKey || !hmac.Equal([]byte(expected), []byte(login.Param.Signature)) {
+		s.pushMEXC(conn, "rs.error", "authentication failed!")
+		return
+	}
+	s.pushMEXC(conn, "rs.login", "success")
+
+	for _, p := range s.scenario.MEXC.StreamPositions {
+		p.UpdateTime = s.now().UnixMilli()
+		s.pushMEXC(conn, "push.personal.position", p)
+	}
+
+	for {
+		var req struct {
+			Method string `json:"method"`
+		}
+		if err := conn.ReadJSON(&req); err != nil {
+			return
+		}
+		if req.Method == "ping" {
+			s.pushMEXC(conn, "pong", s.now().UnixMilli())
+		}
+	}
+}
+
+func (s *Server) pushMEXC(conn *websocket.Conn, channel string, data interface{}) {
+	conn.WriteJSON(map[string]interface{}{
+		"channel": channel,
+		"data":    data,
+		"ts":      s.now().UnixMilli(),
+	})
+}
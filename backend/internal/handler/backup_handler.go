@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"github.com/Ravierin/BudgetTracker/backend/internal/backup"
+	"github.com/Ravierin/BudgetTracker/backend/internal/service"
+	"github.com/Ravierin/BudgetTracker/backend/pkg/websocket"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// maxRestoreSize caps uploaded archives; NDJSON backups are gzip-compressed
+const maxRestoreSize = 500 << 20
+
+// passphraseHeader carries the passphrase that encrypts API keys, so it never
+// ends up in URLs or access logs
+const passphraseHeader = "X-Backup-Passphrase"
+
+type BackupHandler struct {
+	service *service.BackupService
+	wsHub   *websocket.Hub
+}
+
+func NewBackupHandler(service *service.BackupService, wsHub *websocket.Hub) *BackupHandler {
+	return &BackupHandler{
+		service: service,
+		wsHub:   wsHub,
+	}
+}
+
+// Backup streams a full archive. ?format=ndjson selects gzipped NDJSON and
+// ?includeKeys=true adds API keys encrypted with the passphrase header.
+func (h *BackupHandler) Backup(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	includeKeys, _ := strconv.ParseBool(q.Get("includeKeys"))
+
+	opts := service.BackupOptions{
+		Format:      q.Get("format"),
+		IncludeKeys: includeKeys,
+		Passphrase:  r.Header.Get(passphraseHeader),
+	}
+
+	ext, contentType := "json", "application/json"
+	switch opts.Format {
+	case "", "json":
+	case "ndjson":
+		ext, contentType = "ndjson.gz", "application/gzip"
+	default:
+		http.Error(w, "Invalid format", http.StatusBadRequest)
+		return
+	}
+	if includeKeys && opts.Passphrase == "" {
+		http.Error(w, passphraseHeader+" header is required with includeKeys", http.StatusBadRequest)
+		return
+	}
+
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	filename := fmt.Sprintf("budgettracker-backup-%s.%s", time.Now().Format("2006-01-02"), ext)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	// As with exports, a failure after streaming started truncates the
+	// archive, which restore then rejects
+	if err := h.service.Backup(r.Context(), w, opts); err != nil {
+		log.Printf("[backup] Backup failed: %v", err)
+	}
+}
+
+// Restore applies an uploaded archive with ?mode=merge (default) or
+// ?mode=replace. Encrypted API keys are restored only when the passphrase
+// header is present.
+func (h *BackupHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRestoreSize)
+	http.NewResponseController(w).SetReadDeadline(time.Time{})
+
+	opts := service.RestoreOptions{
+		Mode:       r.URL.Query().Get("mode"),
+		Passphrase: r.Header.Get(passphraseHeader),
+	}
+	if opts.Mode != "" && opts.Mode != "merge" && opts.Mode != "replace" {
+		http.Error(w, "Invalid mode", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.service.Restore(r.Context(), r.Body, opts)
+	if errors.Is(err, backup.ErrInvalidArchive) || errors.Is(err, backup.ErrWrongPassphrase) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.wsHub.Publish(websocket.TopicBackup, websocket.EventBackupRestored, result)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"github.com/Ravierin/BudgetTracker/backend/internal/repository"
+	"github.com/Ravierin/BudgetTracker/backend/internal/service"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+type ChangeHandler struct {
+	service *service.ChangeService
+}
+
+func NewChangeHandler(service *service.ChangeService) *ChangeHandler {
+	return &ChangeHandler{service: service}
+}
+
+// GetChanges serves the change feed: ?since=<cursor>&limit=<n>
+func (h *ChangeHandler) GetChanges(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	var limit int
+	if v := q.Get("limit"); v != "" {
+		var err error
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			http.Error(w, fmt.Sprintf("invalid limit: %s", v), http.StatusBadRequest)
+			return
+		}
+		limit = min(limit, maxPageSize)
+	}
+
+	feed, err := h.service.GetChanges(r.Context(), q.Get("since"), limit)
+	if errors.Is(err, repository.ErrInvalidCursor) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(feed)
+}
@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"github.com/Ravierin/BudgetTracker/backend/internal/model"
+	"github.com/Ravierin/BudgetTracker/backend/internal/service"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type ContractHandler struct {
+	service *service.ContractService
+}
+
+func NewContractHandler(service *service.ContractService) *ContractHandler {
+	return &ContractHandler{service: service}
+}
+
+func (h *ContractHandler) GetContracts(w http.ResponseWriter, r *http.Request) {
+	contracts, err := h.service.GetContracts(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if contracts == nil {
+		contracts = []model.ContractMeta{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(contracts)
+}
+
+// RefreshContracts fetches the contract listing now instead of waiting for
+// the daily refresh and returns the volume rescale it makes pending
+func (h *ContractHandler) RefreshContracts(w http.ResponseWriter, r *http.Request) {
+	result, err := h.service.Refresh(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// SetOverride stores {"contractSize": 0.0001} for the symbol in the URL;
+// {"contractSize": null} goes back to the listed size
+func (h *ContractHandler) SetOverride(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		ContractSize *float64 `json:"contractSize"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if body.ContractSize != nil && *body.ContractSize <= 0 {
+		http.Error(w, "contractSize must be positive", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.service.SetOverride(r.Context(), mux.Vars(r)["symbol"], body.ContractSize)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// BackfillVolumes rescales stored MEXC volumes to the current contract
+// sizes. With ?dryRun=true it only returns the preview.
+func (h *ContractHandler) BackfillVolumes(w http.ResponseWriter, r *http.Request) {
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dryRun"))
+
+	result, err := h.service.BackfillVolumes(r.Context(), dryRun)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
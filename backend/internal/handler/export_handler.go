@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"github.com/Ravierin/BudgetTracker/backend/internal/export"
+	"github.com/Ravierin/BudgetTracker/backend/internal/model"
+	"github.com/Ravierin/BudgetTracker/backend/internal/service"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+type ExportHandler struct {
+	positionService   *service.PositionService
+	withdrawalService *service.WithdrawalService
+}
+
+func NewExportHandler(positionService *service.PositionService, withdrawalService *service.WithdrawalService) *ExportHandler {
+	return &ExportHandler{
+		positionService:   positionService,
+		withdrawalService: withdrawalService,
+	}
+}
+
+// Export streams positions, withdrawals or monthly income as CSV or XLSX,
+// honoring the filters of the matching list endpoint
+func (h *ExportHandler) Export(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	kind := mux.Vars(r)["kind"]
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "csv"
+	}
+	if format != "csv" && format != "xlsx" {
+		http.Error(w, "Invalid format", http.StatusBadRequest)
+		return
+	}
+
+	var write func(export.RowWriter) error
+	switch kind {
+	case "positions":
+		query, err := parsePositionQuery(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		query.Limit, query.Cursor = 0, ""
+
+		write = func(rw export.RowWriter) error {
+			if err := rw.WriteRow([]interface{}{"ID", "Order ID", "Exchange", "Account", "Symbol", "Instrument", "Side", "Volume", "Leverage", "Closed PnL", "Date"}); err != nil {
+				return err
+			}
+			return h.positionService.StreamPositions(ctx, query, func(p model.Position) error {
+				return rw.WriteRow([]interface{}{p.ID, p.OrderID, p.Exchange, p.Account, p.Symbol, p.Instrument, p.Side, p.Volume, p.Leverage, p.ClosedPnl, p.UpdatedAt})
+			})
+		}
+
+	case "withdrawals":
+		filter, err := parseWithdrawalFilter(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		write = func(rw export.RowWriter) error {
+			if err := rw.WriteRow([]interface{}{"ID", "Exchange", "Amount", "Currency", "Date"}); err != nil {
+				return err
+			}
+			return h.withdrawalService.StreamWithdrawals(ctx, filter, func(wd model.Withdrawal) error {
+				return rw.WriteRow([]interface{}{wd.ID, wd.Exchange, wd.Amount, wd.Currency, wd.CreatedAt})
+			})
+		}
+
+	case "monthly-income":
+		filter, err := parsePositionFilter(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		write = func(rw export.RowWriter) error {
+			// One row per month and exchange, small enough to load at once
+			incomes, err := h.positionService.AggregateMonthlyPnl(ctx, filter)
+			if err != nil {
+				return err
+			}
+			if err := rw.WriteRow([]interface{}{"Month", "Exchange", "Volume", "PnL"}); err != nil {
+				return err
+			}
+			for _, i := range incomes {
+				if err := rw.WriteRow([]interface{}{i.CreatedAt.Format("2006-01"), i.Exchange, i.Amount, i.PNL}); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+	default:
+		http.Error(w, "Unknown export type", http.StatusNotFound)
+		return
+	}
+
+	// Large exports may take longer than the server-wide write timeout
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	filename := fmt.Sprintf("%s-%s.%s", kind, time.Now().Format("2006-01-02"), format)
+	w.Header().Set("Content-Type", export.ContentType(format))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	rw, err := export.NewRowWriter(format, w)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// Frees the XLSX temp files when the export fails before Close
+	defer rw.Discard()
+
+	// Headers are already sent once rows start streaming, so failures can
+	// only be logged and the response truncated
+	if err := write(rw); err != nil {
+		log.Printf("[export] %s export failed: %v", kind, err)
+		return
+	}
+	if err := rw.Close(); err != nil {
+		log.Printf("[export] %s export failed: %v", kind, err)
+	}
+}
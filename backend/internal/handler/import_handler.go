@@ -0,0 +1,164 @@
+package handler
+
+import (
+	"github.com/Ravierin/BudgetTracker/backend/internal/model"
+	"github.com/Ravierin/BudgetTracker/backend/internal/repository"
+	"github.com/Ravierin/BudgetTracker/backend/internal/service"
+	"github.com/Ravierin/BudgetTracker/backend/pkg/websocket"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+const maxImportSize = 50 << 20
+
+type ImportHandler struct {
+	service *service.ImportService
+	wsHub   *websocket.Hub
+}
+
+func NewImportHandler(service *service.ImportService, wsHub *websocket.Hub) *ImportHandler {
+	return &ImportHandler{
+		service: service,
+		wsHub:   wsHub,
+	}
+}
+
+// ImportPositions imports an exchange CSV statement sent either as the raw
+// request body or as the "file" field of a multipart form.
+// With ?dryRun=true it only returns the new/updated/conflicting preview.
+func (h *ImportHandler) ImportPositions(w http.ResponseWriter, r *http.Request) {
+	format := mux.Vars(r)["format"]
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dryRun"))
+
+	body, closeBody, err := importBody(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer closeBody()
+
+	ctx := r.Context()
+	result, err := h.service.ImportPositions(ctx, format, body, dryRun)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !dryRun && len(result.New)+len(result.Updated) > 0 {
+		h.wsHub.Publish(websocket.ExchangeTopic(websocket.TopicPositions, format), websocket.EventPositionsImported, websocket.PositionsImported{
+			Exchange: format,
+			Count:    len(result.New) + len(result.Updated),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// ImportWithProfile imports a CSV using the column mapping of the profile
+// named in the URL. Nothing is written if any line fails validation.
+func (h *ImportHandler) ImportWithProfile(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dryRun"))
+
+	body, closeBody, err := importBody(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer closeBody()
+
+	ctx := r.Context()
+	result, err := h.service.ImportWithProfile(ctx, name, body, dryRun)
+	if errors.Is(err, repository.ErrNotFound) {
+		http.Error(w, "Import profile not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if result.Applied {
+		h.wsHub.Publish(websocket.ExchangeTopic(websocket.TopicPositions, result.Format), websocket.EventPositionsImported, websocket.PositionsImported{
+			Exchange: result.Format,
+			Count:    len(result.New) + len(result.Updated),
+		})
+	}
+
+	status := http.StatusOK
+	if len(result.Errors) > 0 {
+		status = http.StatusUnprocessableEntity
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(result)
+}
+
+func (h *ImportHandler) GetProfiles(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	profiles, err := h.service.GetProfiles(ctx)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if profiles == nil {
+		profiles = []model.ImportProfile{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(profiles)
+}
+
+// SaveProfile creates or replaces a column-mapping profile by name
+func (h *ImportHandler) SaveProfile(w http.ResponseWriter, r *http.Request) {
+	var profile model.ImportProfile
+	if err := json.NewDecoder(r.Body).Decode(&profile); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	if err := h.service.SaveProfile(ctx, &profile); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(profile)
+}
+
+func (h *ImportHandler) DeleteProfile(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if err := h.service.DeleteProfile(ctx, mux.Vars(r)["name"]); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": "deleted"})
+}
+
+// importBody returns the uploaded CSV, limited to maxImportSize
+func importBody(w http.ResponseWriter, r *http.Request) (io.Reader, func(), error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		return r.Body, func() {}, nil
+	}
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		return nil, nil, err
+	}
+	return file, func() { file.Close() }, nil
+}
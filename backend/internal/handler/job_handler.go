@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"github.com/Ravierin/BudgetTracker/backend/pkg/scheduler"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+type JobHandler struct {
+	jobs *scheduler.Scheduler
+}
+
+func NewJobHandler(jobs *scheduler.Scheduler) *JobHandler {
+	return &JobHandler{jobs: jobs}
+}
+
+// GetJobs returns the schedule and state of every background job
+func (h *JobHandler) GetJobs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.jobs.Jobs())
+}
+
+// PauseJob stops a job from running until it is resumed; a run in
+// progress is left to finish
+func (h *JobHandler) PauseJob(w http.ResponseWriter, r *http.Request) {
+	h.apply(w, r, h.jobs.Pause)
+}
+
+func (h *JobHandler) ResumeJob(w http.ResponseWriter, r *http.Request) {
+	h.apply(w, r, h.jobs.Resume)
+}
+
+// RunJob runs a job now, or right after its current run
+func (h *JobHandler) RunJob(w http.ResponseWriter, r *http.Request) {
+	h.apply(w, r, h.jobs.Trigger)
+}
+
+// apply runs an action on the job named in the URL and returns its status
+func (h *JobHandler) apply(w http.ResponseWriter, r *http.Request, action func(name string) error) {
+	name := mux.Vars(r)["name"]
+
+	if err := action(name); err != nil {
+		switch {
+		case errors.Is(err, scheduler.ErrUnknownJob):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, scheduler.ErrJobPaused):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	status, err := h.jobs.Job(name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
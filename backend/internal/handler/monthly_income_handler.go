@@ -25,10 +25,15 @@ func NewMonthlyIncomeHandler(positionService *service.PositionService, wsHub *we
 
 func (h *MonthlyIncomeHandler) GetAllMonthlyIncomes(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	exchange := r.URL.Query().Get("exchange")
+
+	filter, err := parsePositionFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	// Aggregate monthly PnL from positions
-	incomes, err := h.positionService.AggregateMonthlyPnl(ctx, exchange)
+	incomes, err := h.positionService.AggregateMonthlyPnl(ctx, filter)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -42,6 +47,8 @@ func (h *MonthlyIncomeHandler) GetAllMonthlyIncomes(w http.ResponseWriter, r *ht
 	json.NewEncoder(w).Encode(incomes)
 }
 
+// GetMonthlyIncome returns the totals of one month (id is YYYY-MM), summed
+// across exchanges unless the exchange query param is set
 func (h *MonthlyIncomeHandler) GetMonthlyIncome(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, err := time.Parse("2006-01", vars["id"])
@@ -51,19 +58,31 @@ func (h *MonthlyIncomeHandler) GetMonthlyIncome(w http.ResponseWriter, r *http.R
 	}
 
 	ctx := r.Context()
-	incomes, err := h.positionService.AggregateMonthlyPnl(ctx, "")
+	exchange := r.URL.Query().Get("exchange")
+	incomes, err := h.positionService.AggregateMonthlyPnl(ctx, model.PositionFilter{
+		Exchange: exchange,
+		From:     id,
+		To:       id.AddDate(0, 1, 0),
+	})
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	if len(incomes) == 0 {
+		http.Error(w, "Monthly income not found", http.StatusNotFound)
+		return
+	}
+
+	total := model.MonthlyIncome{Exchange: exchange, CreatedAt: id}
+	if len(incomes) == 1 {
+		total.Exchange = incomes[0].Exchange
+	}
 	for _, income := range incomes {
-		if income.CreatedAt.Year() == id.Year() && income.CreatedAt.Month() == id.Month() {
-			w.Header().Set("Content-Type", "application/json")
-			json.NewEncoder(w).Encode(income)
-			return
-		}
+		total.Amount += income.Amount
+		total.PNL += income.PNL
 	}
 
-	http.Error(w, "Monthly income not found", http.StatusNotFound)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(total)
 }
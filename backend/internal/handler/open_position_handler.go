@@ -0,0 +1,30 @@
+package handler
+
+import (
+	"github.com/Ravierin/BudgetTracker/backend/internal/service"
+	"encoding/json"
+	"net/http"
+)
+
+type OpenPositionHandler struct {
+	service *service.OpenPositionService
+}
+
+func NewOpenPositionHandler(service *service.OpenPositionService) *OpenPositionHandler {
+	return &OpenPositionHandler{service: service}
+}
+
+// GetOpenPositions returns the latest open position snapshot; refresh=true
+// fetches it from the exchanges first
+func (h *OpenPositionHandler) GetOpenPositions(w http.ResponseWriter, r *http.Request) {
+	refresh := r.URL.Query().Get("refresh") == "true"
+
+	result, err := h.service.GetOpenPositions(r.Context(), refresh)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
@@ -2,9 +2,11 @@ package handler
 
 import (
 	"github.com/Ravierin/BudgetTracker/backend/internal/model"
+	"github.com/Ravierin/BudgetTracker/backend/internal/repository"
 	"github.com/Ravierin/BudgetTracker/backend/internal/service"
 	"github.com/Ravierin/BudgetTracker/backend/pkg/websocket"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
@@ -24,19 +26,22 @@ func NewPositionHandler(service *service.PositionService, wsHub *websocket.Hub)
 	}
 }
 
+// GetAllPositions lists positions matching the query filters. When a limit is
+// given, the cursor of the next page is returned in the X-Next-Cursor header.
 func (h *PositionHandler) GetAllPositions(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	exchange := r.URL.Query().Get("exchange")
-	var positions []model.Position
-	var err error
-
-	if exchange != "" {
-		positions, err = h.service.GetPositionsByExchange(ctx, exchange)
-	} else {
-		positions, err = h.service.GetAllPositions(ctx)
+	query, err := parsePositionQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
+	positions, nextCursor, err := h.service.QueryPositions(ctx, query)
+	if errors.Is(err, repository.ErrInvalidCursor) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -46,6 +51,9 @@ func (h *PositionHandler) GetAllPositions(w http.ResponseWriter, r *http.Request
 		positions = []model.Position{}
 	}
 
+	if nextCursor != "" {
+		w.Header().Set("X-Next-Cursor", nextCursor)
+	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(positions)
 }
@@ -59,21 +67,51 @@ func (h *PositionHandler) GetPosition(w http.ResponseWriter, r *http.Request) {
 	}
 
 	ctx := r.Context()
-	positions, err := h.service.GetAllPositions(ctx)
+	position, err := h.service.GetPosition(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		http.Error(w, "Position not found", http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	for _, p := range positions {
-		if p.ID == id {
-			w.Header().Set("Content-Type", "application/json")
-			json.NewEncoder(w).Encode(p)
-			return
-		}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(position)
+}
+
+// GetPositionStats returns trade counts and PnL totals for the filtered
+// positions, optionally grouped by exchange, symbol or instrument
+func (h *PositionHandler) GetPositionStats(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	filter, err := parsePositionFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	http.Error(w, "Position not found", http.StatusNotFound)
+	groupBy := r.URL.Query().Get("groupBy")
+	switch groupBy {
+	case "", "exchange", "symbol", "instrument":
+	default:
+		http.Error(w, "Invalid groupBy", http.StatusBadRequest)
+		return
+	}
+
+	stats, err := h.service.GetPositionStats(ctx, filter, groupBy)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if stats == nil {
+		stats = []model.PositionStats{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
 }
 
 func (h *PositionHandler) CreatePosition(w http.ResponseWriter, r *http.Request) {
@@ -93,10 +131,7 @@ func (h *PositionHandler) CreatePosition(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	h.wsHub.Broadcast(map[string]interface{}{
-		"type": "position_created",
-		"data": position,
-	})
+	h.wsHub.Publish(websocket.ExchangeTopic(websocket.TopicPositions, position.Exchange), websocket.EventPositionCreated, position)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
@@ -117,10 +152,7 @@ func (h *PositionHandler) DeletePosition(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	h.wsHub.Broadcast(map[string]interface{}{
-		"type":   "position_deleted",
-		"postId": id,
-	})
+	h.wsHub.Publish(websocket.TopicPositions, websocket.EventPositionDeleted, websocket.PositionDeleted{PositionID: id})
 
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{"status": "deleted"})
@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"github.com/Ravierin/BudgetTracker/backend/internal/instrument"
+	"github.com/Ravierin/BudgetTracker/backend/internal/model"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const maxPageSize = 1000
+
+// parseDateParam accepts either a plain date (2006-01-02) or RFC3339
+func parseDateParam(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// parsePositionFilter reads exchange, account, symbol, instrument, side, from,
+// to and pnl query params. instrument takes any symbol style (BTCUSDT,
+// BTC_USDT, BTC/USDT) and matches that instrument on every exchange.
+func parsePositionFilter(r *http.Request) (model.PositionFilter, error) {
+	q := r.URL.Query()
+
+	filter := model.PositionFilter{
+		Exchange: q.Get("exchange"),
+		Account:  q.Get("account"),
+		Symbol:   q.Get("symbol"),
+		Side:     q.Get("side"),
+		PnlSign:  q.Get("pnl"),
+	}
+	if v := q.Get("instrument"); v != "" {
+		filter.Instrument = instrument.Parse(v).Key()
+	}
+
+	var err error
+	if filter.From, err = parseDateParam(q.Get("from")); err != nil {
+		return filter, fmt.Errorf("invalid from date: %s", q.Get("from"))
+	}
+	if filter.To, err = parseDateParam(q.Get("to")); err != nil {
+		return filter, fmt.Errorf("invalid to date: %s", q.Get("to"))
+	}
+
+	switch filter.PnlSign {
+	case "", "positive", "negative":
+	default:
+		return filter, fmt.Errorf("invalid pnl filter: %s", filter.PnlSign)
+	}
+
+	return filter, nil
+}
+
+// parseWithdrawalFilter reads exchange, from and to query params
+func parseWithdrawalFilter(r *http.Request) (model.WithdrawalFilter, error) {
+	q := r.URL.Query()
+
+	filter := model.WithdrawalFilter{Exchange: q.Get("exchange")}
+	var err error
+	if filter.From, err = parseDateParam(q.Get("from")); err != nil {
+		return filter, fmt.Errorf("invalid from date: %s", q.Get("from"))
+	}
+	if filter.To, err = parseDateParam(q.Get("to")); err != nil {
+		return filter, fmt.Errorf("invalid to date: %s", q.Get("to"))
+	}
+	return filter, nil
+}
+
+// parsePositionQuery adds sort, order, limit and cursor params to the filter
+func parsePositionQuery(r *http.Request) (model.PositionQuery, error) {
+	filter, err := parsePositionFilter(r)
+	if err != nil {
+		return model.PositionQuery{}, err
+	}
+
+	q := r.URL.Query()
+	query := model.PositionQuery{
+		PositionFilter: filter,
+		SortBy:         q.Get("sort"),
+		Cursor:         q.Get("cursor"),
+	}
+
+	switch query.SortBy {
+	case "", "date", "closedPnl", "volume":
+	default:
+		return query, fmt.Errorf("invalid sort: %s", query.SortBy)
+	}
+
+	switch q.Get("order") {
+	case "", "desc":
+	case "asc":
+		query.Asc = true
+	default:
+		return query, fmt.Errorf("invalid order: %s", q.Get("order"))
+	}
+
+	if limit := q.Get("limit"); limit != "" {
+		query.Limit, err = strconv.Atoi(limit)
+		if err != nil || query.Limit <= 0 {
+			return query, fmt.Errorf("invalid limit: %s", limit)
+		}
+		if query.Limit > maxPageSize {
+			query.Limit = maxPageSize
+		}
+	}
+
+	return query, nil
+}
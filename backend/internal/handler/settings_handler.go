@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"github.com/Ravierin/BudgetTracker/backend/internal/model"
+	"github.com/Ravierin/BudgetTracker/backend/internal/service"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+type SettingsHandler struct {
+	service *service.SettingsService
+}
+
+func NewSettingsHandler(service *service.SettingsService) *SettingsHandler {
+	return &SettingsHandler{service: service}
+}
+
+func (h *SettingsHandler) GetSettings(w http.ResponseWriter, r *http.Request) {
+	settings, err := h.service.GetSettings(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if settings == nil {
+		settings = []model.Setting{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(settings)
+}
+
+// SaveSetting stores {"value": "..."} under the key in the URL
+func (h *SettingsHandler) SaveSetting(w http.ResponseWriter, r *http.Request) {
+	key := mux.Vars(r)["key"]
+
+	var body struct {
+		Value string `json:"value"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.SaveSetting(r.Context(), key, body.Value); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"key": key, "value": body.Value})
+}
+
+func (h *SettingsHandler) DeleteSetting(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.DeleteSetting(r.Context(), mux.Vars(r)["key"]); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": "deleted"})
+}
@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"github.com/Ravierin/BudgetTracker/backend/internal/model"
+	"github.com/Ravierin/BudgetTracker/backend/internal/service"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// defaultSyncRunLimit is how many runs /sync/runs returns by default
+const defaultSyncRunLimit = 50
+
+type SyncHandler struct {
+	service *service.SyncRunService
+}
+
+func NewSyncHandler(service *service.SyncRunService) *SyncHandler {
+	return &SyncHandler{service: service}
+}
+
+// GetSyncStatus returns the last successful sync and the current failure
+// streak of every account that has synced
+func (h *SyncHandler) GetSyncStatus(w http.ResponseWriter, r *http.Request) {
+	statuses, err := h.service.GetStatus(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if statuses == nil {
+		statuses = []model.SyncAccountStatus{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(statuses)
+}
+
+// GetSyncRuns returns the latest sync runs: ?exchange=bybit&limit=50
+func (h *SyncHandler) GetSyncRuns(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	limit := defaultSyncRunLimit
+	if v := q.Get("limit"); v != "" {
+		var err error
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			http.Error(w, fmt.Sprintf("invalid limit: %s", v), http.StatusBadRequest)
+			return
+		}
+		limit = min(limit, maxPageSize)
+	}
+
+	runs, err := h.service.GetRuns(r.Context(), q.Get("exchange"), limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if runs == nil {
+		runs = []model.SyncRun{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(runs)
+}
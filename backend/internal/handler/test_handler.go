@@ -12,10 +12,10 @@ import (
 )
 
 type TestHandler struct {
-	positionRepo *repository.PositionRepository
+	positionRepo repository.PositionStore
 }
 
-func NewTestHandler(positionRepo *repository.PositionRepository) *TestHandler {
+func NewTestHandler(positionRepo repository.PositionStore) *TestHandler {
 	return &TestHandler{positionRepo: positionRepo}
 }
 
@@ -66,7 +66,7 @@ func (h *TestHandler) GenerateTestPositions(w http.ResponseWriter, r *http.Reque
 	}
 
 	// Save all positions
-	if err := h.positionRepo.SavePositionBatch(ctx, positions); err != nil {
+	if _, err := h.positionRepo.SavePositionBatch(ctx, positions); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -25,18 +25,13 @@ func NewWithdrawalHandler(service *service.WithdrawalService, wsHub *websocket.H
 }
 
 func (h *WithdrawalHandler) GetAllWithdrawals(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
-
-	exchange := r.URL.Query().Get("exchange")
-	var withdrawals []model.Withdrawal
-	var err error
-
-	if exchange != "" {
-		withdrawals, err = h.service.GetWithdrawalsByExchange(ctx, exchange)
-	} else {
-		withdrawals, err = h.service.GetAllWithdrawals(ctx)
+	filter, err := parseWithdrawalFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
+	withdrawals, err := h.service.GetWithdrawals(r.Context(), filter)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -67,10 +62,7 @@ func (h *WithdrawalHandler) CreateWithdrawal(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	h.wsHub.Broadcast(map[string]interface{}{
-		"type": "withdrawal_created",
-		"data": withdrawal,
-	})
+	h.wsHub.Publish(websocket.ExchangeTopic(websocket.TopicWithdrawals, withdrawal.Exchange), websocket.EventWithdrawalCreated, withdrawal)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
@@ -91,10 +83,7 @@ func (h *WithdrawalHandler) DeleteWithdrawal(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	h.wsHub.Broadcast(map[string]interface{}{
-		"type":         "withdrawal_deleted",
-		"withdrawalId": id,
-	})
+	h.wsHub.Publish(websocket.TopicWithdrawals, websocket.EventWithdrawalDeleted, websocket.WithdrawalDeleted{WithdrawalID: id})
 
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{"status": "deleted"})
@@ -0,0 +1,136 @@
+package importer
+
+import (
+	"github.com/Ravierin/BudgetTracker/backend/internal/model"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ParseBybitClosedPnl parses the "Closed P&L" statement downloaded from the
+// Bybit website. Volume is the entry value (qty × entry price), matching
+// cumEntryValue used by the API sync.
+func ParseBybitClosedPnl(r io.Reader) ([]Row, []model.ImportRowError, error) {
+	symbolCols := []string{"Contracts", "Symbol", "Market"}
+	pnlCols := []string{"Closed P&L", "Closed PnL", "Realized P&L"}
+	timeCols := []string{"Trade Time(UTC+0)", "Trade Time", "Updated Time", "Time"}
+
+	t, err := openTable(r, symbolCols, pnlCols, timeCols)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	columns := bybitColumns{
+		symbol:     t.column(symbolCols...),
+		pnl:        t.column(pnlCols...),
+		time:       t.column(timeCols...),
+		side:       t.column("Closing Direction", "Side", "Direction"),
+		order:      t.column("Order ID", "Order No.", "OrderId"),
+		qty:        t.column("Qty", "Quantity", "Closed Qty", "Closed Size"),
+		entryPrice: t.column("Entry Price", "Avg Entry Price"),
+		entryValue: t.column("Entry Value", "Cum Entry Value"),
+		leverage:   t.column("Leverage"),
+	}
+
+	var rows []Row
+	var rowErrors []model.ImportRowError
+
+	for {
+		record, line, err := t.next()
+		if err == io.EOF {
+			break
+		}
+		if isRowError(err) {
+			rowErrors = append(rowErrors, model.ImportRowError{Line: line, Message: err.Error()})
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		p, has, err := parseBybitRecord(record, columns)
+		if err != nil {
+			rowErrors = append(rowErrors, model.ImportRowError{Line: line, Message: err.Error()})
+			continue
+		}
+		rows = append(rows, Row{Line: line, Position: p, Has: has})
+	}
+
+	return rows, rowErrors, nil
+}
+
+type bybitColumns struct {
+	symbol, pnl, time, side, order, qty, entryPrice, entryValue, leverage int
+}
+
+func parseBybitRecord(record []string, c bybitColumns) (model.Position, Field, error) {
+	p := model.Position{Exchange: "bybit", Leverage: 1}
+	var has Field
+
+	p.Symbol = strings.ToUpper(field(record, c.symbol))
+	if p.Symbol == "" {
+		return p, has, fmt.Errorf("missing symbol")
+	}
+
+	var err error
+	if p.ClosedPnl, err = ParseNumber(field(record, c.pnl)); err != nil {
+		return p, has, fmt.Errorf("invalid closed P&L %q", field(record, c.pnl))
+	}
+	if p.UpdatedAt, err = ParseTime(field(record, c.time), ""); err != nil {
+		return p, has, err
+	}
+
+	if c.side >= 0 {
+		if p.Side, err = NormalizeSide(field(record, c.side)); err != nil {
+			return p, has, err
+		}
+		has |= FieldSide
+	}
+
+	if value := field(record, c.entryValue); value != "" {
+		if p.Volume, err = ParseNumber(value); err != nil {
+			return p, has, fmt.Errorf("invalid entry value %q", value)
+		}
+		has |= FieldVolume
+	} else if field(record, c.qty) != "" && field(record, c.entryPrice) != "" {
+		qty, err := ParseNumber(field(record, c.qty))
+		if err != nil {
+			return p, has, fmt.Errorf("invalid qty %q", field(record, c.qty))
+		}
+		price, err := ParseNumber(field(record, c.entryPrice))
+		if err != nil {
+			return p, has, fmt.Errorf("invalid entry price %q", field(record, c.entryPrice))
+		}
+		p.Volume = qty * price
+		has |= FieldVolume
+	}
+
+	if leverage := field(record, c.leverage); leverage != "" {
+		l, err := ParseNumber(strings.TrimSuffix(strings.ToLower(leverage), "x"))
+		if err != nil {
+			return p, has, fmt.Errorf("invalid leverage %q", leverage)
+		}
+		p.Leverage = int(l)
+		has |= FieldLeverage
+	}
+
+	p.OrderID = field(record, c.order)
+	if p.OrderID == "" {
+		p.OrderID = syntheticOrderID(p)
+	}
+
+	return p, has, nil
+}
+
+// syntheticOrderID derives a stable ID for statements without order IDs,
+// so importing the same file twice does not duplicate rows
+func syntheticOrderID(p model.Position) string {
+	key := fmt.Sprintf("%s|%s|%s|%d|%s",
+		p.Exchange, p.Symbol, p.Side, p.UpdatedAt.UnixMilli(),
+		strconv.FormatFloat(p.ClosedPnl, 'f', -1, 64))
+	sum := sha1.Sum([]byte(key))
+	return "import_" + p.Exchange + "_" + hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,223 @@
+package importer
+
+import (
+	"github.com/Ravierin/BudgetTracker/backend/internal/model"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// headerSearchLines is how many leading lines may precede the header row
+// (exchange statements sometimes start with a title or account line)
+const headerSearchLines = 10
+
+// Row is a parsed position together with its CSV line number. Has lists
+// the optional fields the line gave a value for; the others hold defaults
+// (no side, zero volume, leverage 1) and must not replace stored values.
+type Row struct {
+	Line     int
+	Position model.Position
+	Has      Field
+}
+
+// Field flags an optional position field of a statement line
+type Field uint8
+
+const (
+	FieldSide Field = 1 << iota
+	FieldVolume
+	FieldLeverage
+)
+
+// ParseFunc converts a CSV statement into positions and per-line errors
+type ParseFunc func(r io.Reader) ([]Row, []model.ImportRowError, error)
+
+var parsers = map[string]ParseFunc{
+	"bybit": ParseBybitClosedPnl,
+	"mexc":  ParseMEXCHistory,
+}
+
+// Parse dispatches to the parser of an exchange statement format
+func Parse(format string, r io.Reader) ([]Row, []model.ImportRowError, error) {
+	parse, ok := parsers[format]
+	if !ok {
+		return nil, nil, fmt.Errorf("unsupported import format: %s", format)
+	}
+	return parse(r)
+}
+
+// table is a CSV reader that resolves columns by header name
+type table struct {
+	reader *csv.Reader
+	index  map[string]int
+}
+
+// openTable scans for the first line containing every required column
+func openTable(r io.Reader, required ...[]string) (*table, error) {
+	return openTableComma(r, ',', required...)
+}
+
+// openTableComma is openTable for files with another field delimiter
+func openTableComma(r io.Reader, comma rune, required ...[]string) (*table, error) {
+	reader := csv.NewReader(r)
+	reader.Comma = comma
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.TrimLeadingSpace = true
+
+	t := &table{reader: reader}
+	for i := 0; i < headerSearchLines; i++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		t.index = make(map[string]int, len(record))
+		for col, name := range record {
+			t.index[normalizeHeader(name)] = col
+		}
+
+		found := true
+		for _, aliases := range required {
+			if t.column(aliases...) < 0 {
+				found = false
+				break
+			}
+		}
+		if found {
+			return t, nil
+		}
+	}
+
+	names := make([]string, len(required))
+	for i, aliases := range required {
+		names[i] = aliases[0]
+	}
+	return nil, fmt.Errorf("header with columns %s not found", strings.Join(names, ", "))
+}
+
+// column returns the index of the first matching header, or -1
+func (t *table) column(aliases ...string) int {
+	for _, alias := range aliases {
+		if i, ok := t.index[normalizeHeader(alias)]; ok {
+			return i
+		}
+	}
+	return -1
+}
+
+// next returns the next non-empty record and its line number
+func (t *table) next() ([]string, int, error) {
+	for {
+		record, err := t.reader.Read()
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				return nil, parseErr.Line, err
+			}
+			return nil, 0, err
+		}
+		line, _ := t.reader.FieldPos(0)
+
+		for _, field := range record {
+			if strings.TrimSpace(field) != "" {
+				return record, line, nil
+			}
+		}
+	}
+}
+
+// isRowError reports whether a read error only affects the current line
+func isRowError(err error) bool {
+	var parseErr *csv.ParseError
+	return errors.As(err, &parseErr)
+}
+
+// field returns the trimmed value at index i, or "" for missing columns
+func field(record []string, i int) string {
+	if i < 0 || i >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[i])
+}
+
+// normalizeHeader lowercases and drops everything but letters and digits,
+// so "Closed P&L" and "closed_pnl" style differences do not matter
+func normalizeHeader(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// ParseNumber accepts thousands separators, exponents and a trailing
+// currency unit such as "1,234.5 USDT". A single comma not followed by
+// exactly three digits ("1,5") is read as a decimal comma.
+func ParseNumber(s string) (float64, error) {
+	s = strings.TrimSpace(strings.TrimRightFunc(strings.TrimSpace(s), unicode.IsLetter))
+	if i := strings.Index(s, ","); i >= 0 && strings.Count(s, ",") == 1 && !strings.Contains(s, ".") && len(s)-i-1 != 3 {
+		s = strings.Replace(s, ",", ".", 1)
+	}
+	s = strings.ReplaceAll(s, ",", "")
+	s = strings.ReplaceAll(s, " ", "")
+	if s == "" || s == "--" {
+		return 0, fmt.Errorf("empty number")
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+var timeLayouts = []string{
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006/01/02 15:04:05",
+	"2006-01-02T15:04:05",
+	time.RFC3339,
+	"2006-01-02",
+}
+
+// ParseTime tries the given layout first, then common statement layouts and
+// unix timestamps in milliseconds or seconds. Times without a zone are UTC.
+func ParseTime(s, layout string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if layout != "" {
+		return time.ParseInLocation(layout, s, time.UTC)
+	}
+
+	for _, l := range timeLayouts {
+		if t, err := time.ParseInLocation(l, s, time.UTC); err == nil {
+			return t, nil
+		}
+	}
+
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		if n > 1e12 {
+			return time.UnixMilli(n).UTC(), nil
+		}
+		return time.Unix(n, 0).UTC(), nil
+	}
+
+	return time.Time{}, fmt.Errorf("unrecognized time %q", s)
+}
+
+// NormalizeSide maps statement wording to the Buy/Sell sides used by sync
+func NormalizeSide(s string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "buy", "long", "open long", "close short", "1":
+		return "Buy", nil
+	case "sell", "short", "open short", "close long", "2":
+		return "Sell", nil
+	default:
+		return "", fmt.Errorf("unknown side %q", s)
+	}
+}
@@ -0,0 +1,246 @@
+package importer
+
+import (
+	"github.com/Ravierin/BudgetTracker/backend/internal/api"
+	"github.com/Ravierin/BudgetTracker/backend/internal/model"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseNumber(t *testing.T) {
+	tests := []struct {
+		in   string
+		want float64
+	}{
+		{"12", 12},
+		{" -3.5 ", -3.5},
+		{"1,5", 1.5},
+		{"-0,25", -0.25},
+		{"1,234", 1234},
+		{"1,234.5", 1234.5},
+		{"1,234,567.89", 1234567.89},
+		{"1 234.5", 1234.5},
+		{"1.5e-05", 1.5e-05},
+		{"2E+3", 2000},
+		{"1,5e-05", 1.5e-05},
+		{"12 USDT", 12},
+		{"-0.75USDT", -0.75},
+		{"1,234.5 USDT", 1234.5},
+		{"3.2e2 usdt", 320},
+	}
+	for _, tt := range tests {
+		got, err := ParseNumber(tt.in)
+		if err != nil {
+			t.Errorf("ParseNumber(%q): %v", tt.in, err)
+			continue
+		}
+		if math.Abs(got-tt.want) > 1e-12*math.Max(1, math.Abs(tt.want)) {
+			t.Errorf("ParseNumber(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+
+	for _, in := range []string{"", "  ", "--", "USDT", "USDT 12", "1.2.3", "abc"} {
+		if got, err := ParseNumber(in); err == nil {
+			t.Errorf("ParseNumber(%q) = %v, want an error", in, got)
+		}
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	want := time.Date(2025, 5, 2, 8, 30, 15, 0, time.UTC)
+	tests := []struct {
+		in, layout string
+		want       time.Time
+	}{
+		{"2025-05-02 08:30:15", "", want},
+		{" 2025-05-02 08:30:15 ", "", want},
+		{"2025/05/02 08:30:15", "", want},
+		{"2025-05-02T08:30:15", "", want},
+		{"2025-05-02T10:30:15+02:00", "", want},
+		{"2025-05-02 08:30", "", want.Truncate(time.Minute)},
+		{"2025-05-02", "", time.Date(2025, 5, 2, 0, 0, 0, 0, time.UTC)},
+		{"1746174615", "", want},
+		{"1746174615250", "", want.Add(250 * time.Millisecond)},
+		{"02.05.2025 08:30:15", "02.01.2006 15:04:05", want},
+	}
+	for _, tt := range tests {
+		got, err := ParseTime(tt.in, tt.layout)
+		if err != nil {
+			t.Errorf("ParseTime(%q, %q): %v", tt.in, tt.layout, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("ParseTime(%q, %q) = %v, want %v", tt.in, tt.layout, got, tt.want)
+		}
+	}
+
+	for _, in := range []string{"", "yesterday", "05/02/2025"} {
+		if _, err := ParseTime(in, ""); err == nil {
+			t.Errorf("ParseTime(%q): expected an error", in)
+		}
+	}
+	if _, err := ParseTime("2025-05-02 08:30:15", "02.01.2006"); err == nil {
+		t.Error("ParseTime with a mismatched layout: expected an error")
+	}
+}
+
+func TestNormalizeSide(t *testing.T) {
+	tests := map[string]string{
+		"buy":         "Buy",
+		" Long ":      "Buy",
+		"Open Long":   "Buy",
+		"close short": "Buy",
+		"1":           "Buy",
+		"SELL":        "Sell",
+		"short":       "Sell",
+		"open short":  "Sell",
+		"Close Long":  "Sell",
+		"2":           "Sell",
+	}
+	for in, want := range tests {
+		got, err := NormalizeSide(in)
+		if err != nil || got != want {
+			t.Errorf("NormalizeSide(%q) = %q, %v, want %q", in, got, err, want)
+		}
+	}
+
+	for _, in := range []string{"", "flat", "3"} {
+		if _, err := NormalizeSide(in); err == nil {
+			t.Errorf("NormalizeSide(%q): expected an error", in)
+		}
+	}
+}
+
+// checkRows compares parsed rows against the wanted lines and positions
+func checkRows(t *testing.T, got []Row, want []Row) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d rows %+v, want %d", len(got), got, len(want))
+	}
+	for i, w := range want {
+		g := got[i]
+		p, wp := g.Position, w.Position
+		if g.Line != w.Line || g.Has != w.Has ||
+			p.OrderID != wp.OrderID || p.Exchange != wp.Exchange || p.Symbol != wp.Symbol ||
+			p.Side != wp.Side || p.Leverage != wp.Leverage || !p.UpdatedAt.Equal(wp.UpdatedAt) ||
+			math.Abs(p.ClosedPnl-wp.ClosedPnl) > 1e-9 || math.Abs(p.Volume-wp.Volume) > 1e-9 {
+			t.Errorf("row %d = %+v, want %+v", i, g, w)
+		}
+	}
+}
+
+func TestParseBybitClosedPnl(t *testing.T) {
+	date := time.Date(2025, 5, 2, 8, 30, 15, 0, time.UTC)
+
+	t.Run("header after a title line", func(t *testing.T) {
+		csv := `Closed P&L Report,UID 123
+Contracts,Closing Direction,Qty,Entry Price,Closed P&L,Leverage,Order ID,Trade Time(UTC+0)
+BTCUSDT,Sell,0.01,60000,"1,234.5 USDT",10x,o-1,2025-05-02 08:30:15
+
+ethusdt,Buy,2,3000,-5,,o-2,1746174615
+XRPUSDT,Hold,1,1,1,,o-3,2025-05-02 08:30:15
+`
+		rows, rowErrors, err := ParseBybitClosedPnl(strings.NewReader(csv))
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkRows(t, rows, []Row{
+			{Line: 3, Has: FieldSide | FieldVolume | FieldLeverage, Position: model.Position{
+				OrderID: "o-1", Exchange: "bybit", Symbol: "BTCUSDT", Side: "Sell", Volume: 600, Leverage: 10, ClosedPnl: 1234.5, UpdatedAt: date,
+			}},
+			{Line: 5, Has: FieldSide | FieldVolume, Position: model.Position{
+				OrderID: "o-2", Exchange: "bybit", Symbol: "ETHUSDT", Side: "Buy", Volume: 6000, Leverage: 1, ClosedPnl: -5, UpdatedAt: date,
+			}},
+		})
+		if len(rowErrors) != 1 || rowErrors[0].Line != 6 {
+			t.Errorf("row errors %+v, want one on line 6", rowErrors)
+		}
+	})
+
+	t.Run("entry value and no order ID", func(t *testing.T) {
+		csv := `Symbol,Entry Value,Realized P&L,Time
+BTCUSDT,250.5,3,2025-05-02 08:30:15
+`
+		rows, _, err := ParseBybitClosedPnl(strings.NewReader(csv))
+		if err != nil {
+			t.Fatal(err)
+		}
+		p := model.Position{Exchange: "bybit", Symbol: "BTCUSDT", Volume: 250.5, Leverage: 1, ClosedPnl: 3, UpdatedAt: date}
+		p.OrderID = syntheticOrderID(p)
+		checkRows(t, rows, []Row{{Line: 2, Has: FieldVolume, Position: p}})
+
+		again, _, err := ParseBybitClosedPnl(strings.NewReader(csv))
+		if err != nil || len(again) != 1 || again[0].Position.OrderID != p.OrderID {
+			t.Errorf("second parse %+v, %v, want the same synthetic order ID", again, err)
+		}
+	})
+
+	t.Run("missing header", func(t *testing.T) {
+		csv := `Contracts,Qty,Trade Time(UTC+0)
+BTCUSDT,1,2025-05-02 08:30:15
+`
+		_, _, err := ParseBybitClosedPnl(strings.NewReader(csv))
+		if err == nil || !strings.Contains(err.Error(), "Closed P&L") {
+			t.Errorf("err = %v, want the missing P&L column named", err)
+		}
+	})
+}
+
+func TestParseMEXCHistory(t *testing.T) {
+	api.SetMEXCContractSizes(map[string]float64{"BTC_USDT": 0.0001})
+	t.Cleanup(func() { api.SetMEXCContractSizes(nil) })
+	date := time.Date(2025, 5, 2, 8, 30, 15, 0, time.UTC)
+
+	t.Run("position history", func(t *testing.T) {
+		csv := `Futures Trading Pair,Position ID,Direction,Leverage,Closed Volume,Avg. Open Price,Closed PnL,Close Time
+BTCUSDT,900001,Long,20X,100,60000,"12,5",2025-05-02 08:30:15
+ETH/USDT,900002,short,,3,3000,-1.5e-05 USDT,2025-05-02 08:30:15
+XRP_USDT,900003,long,5,,,not a number,2025-05-02 08:30:15
+`
+		rows, rowErrors, err := ParseMEXCHistory(strings.NewReader(csv))
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkRows(t, rows, []Row{
+			{Line: 2, Has: FieldSide | FieldVolume | FieldLeverage, Position: model.Position{
+				OrderID: "900001", Exchange: "mexc", Symbol: "BTC_USDT", Side: "Buy", Volume: 600, Leverage: 20, ClosedPnl: 12.5, UpdatedAt: date,
+			}},
+			{Line: 3, Has: FieldSide | FieldVolume, Position: model.Position{
+				OrderID: "900002", Exchange: "mexc", Symbol: "ETH_USDT", Side: "Sell", Volume: 3 * 3000 * api.GetContractSize("ETH_USDT"), Leverage: 1, ClosedPnl: -1.5e-05, UpdatedAt: date,
+			}},
+		})
+		if len(rowErrors) != 1 || rowErrors[0].Line != 4 {
+			t.Errorf("row errors %+v, want one on line 4", rowErrors)
+		}
+	})
+
+	t.Run("aliased columns", func(t *testing.T) {
+		csv := `Pair,Realized PnL,Closing Time
+BTC_USDT,4,1746174615000
+`
+		rows, _, err := ParseMEXCHistory(strings.NewReader(csv))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rows) != 1 || rows[0].Has != 0 || rows[0].Position.Symbol != "BTC_USDT" || !rows[0].Position.UpdatedAt.Equal(date) {
+			t.Errorf("rows %+v, want one BTC_USDT row without optional fields", rows)
+		}
+	})
+
+	t.Run("bybit statement", func(t *testing.T) {
+		csv := `Contracts,Closed P&L,Trade Time(UTC+0)
+BTCUSDT,1,2025-05-02 08:30:15
+`
+		if _, _, err := ParseMEXCHistory(strings.NewReader(csv)); err == nil {
+			t.Error("expected the MEXC parser to reject a Bybit header")
+		}
+	})
+}
+
+func TestParseUnsupportedFormat(t *testing.T) {
+	if _, _, err := Parse("kraken", strings.NewReader("")); err == nil {
+		t.Error("expected an unsupported format error")
+	}
+}
@@ -0,0 +1,208 @@
+package importer
+
+import (
+	"github.com/Ravierin/BudgetTracker/backend/internal/model"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ValidateProfile checks that a mapping profile names every required column
+// and uses a usable date format and delimiter
+func ValidateProfile(p model.ImportProfile) error {
+	required := []struct{ key, value string }{
+		{"name", p.Name},
+		{"exchange", p.Exchange},
+		{"symbolColumn", p.SymbolColumn},
+		{"sideColumn", p.SideColumn},
+		{"pnlColumn", p.PnlColumn},
+		{"dateColumn", p.DateColumn},
+	}
+	for _, r := range required {
+		if strings.TrimSpace(r.value) == "" {
+			return fmt.Errorf("%s is required", r.key)
+		}
+	}
+
+	if utf8.RuneCountInString(p.Delimiter) > 1 && p.Delimiter != `\t` {
+		return fmt.Errorf("delimiter must be a single character")
+	}
+
+	switch p.DateFormat {
+	case "", "unix", "unixms":
+	default:
+		layout := goLayout(p.DateFormat)
+		if _, err := time.Parse(layout, time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC).Format(layout)); err != nil {
+			return fmt.Errorf("invalid dateFormat: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// ParseMapped reads a CSV using the column mapping of a profile. Every row is
+// validated; invalid rows are reported with their line number.
+func ParseMapped(r io.Reader, p model.ImportProfile) ([]Row, []model.ImportRowError, error) {
+	if err := ValidateProfile(p); err != nil {
+		return nil, nil, err
+	}
+
+	comma := ','
+	if p.Delimiter != "" {
+		comma, _ = utf8.DecodeRuneInString(p.Delimiter)
+		if p.Delimiter == `\t` {
+			comma = '\t'
+		}
+	}
+
+	t, err := openTableComma(r, comma,
+		[]string{p.SymbolColumn}, []string{p.SideColumn}, []string{p.PnlColumn}, []string{p.DateColumn})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	columns := mappedColumns{
+		symbol:   t.column(p.SymbolColumn),
+		side:     t.column(p.SideColumn),
+		pnl:      t.column(p.PnlColumn),
+		date:     t.column(p.DateColumn),
+		volume:   -1,
+		leverage: -1,
+		orderID:  -1,
+	}
+	for _, optional := range []struct {
+		name string
+		col  *int
+	}{
+		{p.VolumeColumn, &columns.volume},
+		{p.LeverageColumn, &columns.leverage},
+		{p.OrderIDColumn, &columns.orderID},
+	} {
+		if optional.name == "" {
+			continue
+		}
+		if *optional.col = t.column(optional.name); *optional.col < 0 {
+			return nil, nil, fmt.Errorf("column %q not found", optional.name)
+		}
+	}
+
+	var rows []Row
+	var rowErrors []model.ImportRowError
+
+	for {
+		record, line, err := t.next()
+		if err == io.EOF {
+			break
+		}
+		if isRowError(err) {
+			rowErrors = append(rowErrors, model.ImportRowError{Line: line, Message: err.Error()})
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		pos, has, err := parseMappedRecord(record, columns, p)
+		if err != nil {
+			rowErrors = append(rowErrors, model.ImportRowError{Line: line, Message: err.Error()})
+			continue
+		}
+		rows = append(rows, Row{Line: line, Position: pos, Has: has})
+	}
+
+	return rows, rowErrors, nil
+}
+
+type mappedColumns struct {
+	symbol, side, pnl, date, volume, leverage, orderID int
+}
+
+func parseMappedRecord(record []string, c mappedColumns, profile model.ImportProfile) (model.Position, Field, error) {
+	p := model.Position{Exchange: profile.Exchange, Leverage: 1}
+	has := FieldSide
+
+	p.Symbol = strings.ToUpper(field(record, c.symbol))
+	if p.Symbol == "" {
+		return p, has, fmt.Errorf("missing symbol")
+	}
+
+	var err error
+	if p.Side, err = NormalizeSide(field(record, c.side)); err != nil {
+		return p, has, err
+	}
+	if p.ClosedPnl, err = ParseNumber(field(record, c.pnl)); err != nil {
+		return p, has, fmt.Errorf("invalid PnL %q", field(record, c.pnl))
+	}
+	if p.UpdatedAt, err = parseMappedTime(field(record, c.date), profile.DateFormat); err != nil {
+		return p, has, fmt.Errorf("invalid date %q", field(record, c.date))
+	}
+
+	if value := field(record, c.volume); value != "" {
+		if p.Volume, err = ParseNumber(value); err != nil {
+			return p, has, fmt.Errorf("invalid volume %q", value)
+		}
+		if p.Volume < 0 {
+			return p, has, fmt.Errorf("negative volume %q", value)
+		}
+		has |= FieldVolume
+	}
+
+	if value := field(record, c.leverage); value != "" {
+		l, err := ParseNumber(strings.TrimSuffix(strings.ToLower(value), "x"))
+		if err != nil || l < 1 || l != math.Trunc(l) {
+			return p, has, fmt.Errorf("invalid leverage %q, want a whole number", value)
+		}
+		p.Leverage = int(l)
+		has |= FieldLeverage
+	}
+
+	p.OrderID = field(record, c.orderID)
+	if p.OrderID == "" {
+		p.OrderID = syntheticOrderID(p)
+	}
+
+	return p, has, nil
+}
+
+func parseMappedTime(s, format string) (time.Time, error) {
+	switch format {
+	case "unix", "unixms":
+		n, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if format == "unixms" {
+			return time.UnixMilli(n).UTC(), nil
+		}
+		return time.Unix(n, 0).UTC(), nil
+	case "":
+		return ParseTime(s, "")
+	default:
+		return ParseTime(s, goLayout(format))
+	}
+}
+
+// layoutTokens translates YYYY-MM-DD HH:mm:ss style patterns into Go layouts;
+// "YYYY" is listed before "YY" so the longer token wins
+var layoutTokens = strings.NewReplacer(
+	"YYYY", "2006",
+	"YY", "06",
+	"MM", "01",
+	"DD", "02",
+	"HH", "15",
+	"mm", "04",
+	"ss", "05",
+	"SSS", "000",
+)
+
+// goLayout returns format unchanged if it already is a Go layout
+func goLayout(format string) string {
+	if strings.Contains(format, "2006") {
+		return format
+	}
+	return layoutTokens.Replace(format)
+}
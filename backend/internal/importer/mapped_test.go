@@ -0,0 +1,214 @@
+package importer
+
+import (
+	"github.com/Ravierin/BudgetTracker/backend/internal/model"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testProfile = model.ImportProfile{
+	Name:         "venue",
+	Exchange:     "venue",
+	SymbolColumn: "Market",
+	SideColumn:   "Side",
+	PnlColumn:    "Profit",
+	DateColumn:   "Closed",
+}
+
+func TestValidateProfile(t *testing.T) {
+	valid := []func(p *model.ImportProfile){
+		func(p *model.ImportProfile) {},
+		func(p *model.ImportProfile) { p.Delimiter = ";" },
+		func(p *model.ImportProfile) { p.Delimiter = `\t` },
+		func(p *model.ImportProfile) { p.DateFormat = "unix" },
+		func(p *model.ImportProfile) { p.DateFormat = "unixms" },
+		func(p *model.ImportProfile) { p.DateFormat = "DD.MM.YYYY HH:mm:ss.SSS" },
+		func(p *model.ImportProfile) { p.DateFormat = "2006-01-02T15:04:05Z07:00" },
+	}
+	for i, edit := range valid {
+		p := testProfile
+		edit(&p)
+		if err := ValidateProfile(p); err != nil {
+			t.Errorf("valid profile %d: %v", i, err)
+		}
+	}
+
+	invalid := []struct {
+		edit func(p *model.ImportProfile)
+		want string
+	}{
+		{func(p *model.ImportProfile) { p.Name = " " }, "name is required"},
+		{func(p *model.ImportProfile) { p.Exchange = "" }, "exchange is required"},
+		{func(p *model.ImportProfile) { p.SideColumn = "" }, "sideColumn is required"},
+		{func(p *model.ImportProfile) { p.PnlColumn = "" }, "pnlColumn is required"},
+		{func(p *model.ImportProfile) { p.DateColumn = "" }, "dateColumn is required"},
+		{func(p *model.ImportProfile) { p.Delimiter = ";;" }, "single character"},
+		{func(p *model.ImportProfile) { p.Delimiter = `\n` }, "single character"},
+	}
+	for _, tt := range invalid {
+		p := testProfile
+		tt.edit(&p)
+		if err := ValidateProfile(p); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ValidateProfile(%+v) = %v, want %q", p, err, tt.want)
+		}
+	}
+}
+
+func TestGoLayout(t *testing.T) {
+	tests := map[string]string{
+		"YYYY-MM-DD HH:mm:ss":     "2006-01-02 15:04:05",
+		"DD.MM.YY HH:mm":          "02.01.06 15:04",
+		"MM/DD/YYYY":              "01/02/2006",
+		"YYYY-MM-DD HH:mm:ss.SSS": "2006-01-02 15:04:05.000",
+		"2006-01-02 15:04":        "2006-01-02 15:04",
+	}
+	for format, want := range tests {
+		if got := goLayout(format); got != want {
+			t.Errorf("goLayout(%q) = %q, want %q", format, got, want)
+		}
+	}
+}
+
+func TestParseMapped(t *testing.T) {
+	date := time.Date(2025, 5, 2, 8, 30, 15, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		profile func(p *model.ImportProfile)
+		csv     string
+		want    []Row
+	}{
+		{
+			name: "literal tab delimiter and tokens",
+			profile: func(p *model.ImportProfile) {
+				p.Delimiter = `\t`
+				p.DateFormat = "DD.MM.YY HH:mm:ss.SSS"
+				p.VolumeColumn = "Size"
+				p.LeverageColumn = "Lev"
+				p.OrderIDColumn = "Ref"
+			},
+			csv: "Ref\tMarket\tSide\tProfit\tSize\tLev\tClosed\n" +
+				"r-1\tbtcusdt\tlong\t1,5\t250\t5x\t02.05.25 08:30:15.250\n",
+			want: []Row{{Line: 2, Has: FieldSide | FieldVolume | FieldLeverage, Position: model.Position{
+				OrderID: "r-1", Exchange: "venue", Symbol: "BTCUSDT", Side: "Buy", Volume: 250, Leverage: 5, ClosedPnl: 1.5, UpdatedAt: date.Add(250 * time.Millisecond),
+			}}},
+		},
+		{
+			name: "unix seconds and a single character delimiter",
+			profile: func(p *model.ImportProfile) {
+				p.Delimiter = ";"
+				p.DateFormat = "unix"
+				p.OrderIDColumn = "Ref"
+			},
+			csv: "Market;Side;Profit;Closed;Ref\nETHUSDT;sell;-2;1746174615;r-2\n",
+			want: []Row{{Line: 2, Has: FieldSide, Position: model.Position{
+				OrderID: "r-2", Exchange: "venue", Symbol: "ETHUSDT", Side: "Sell", Leverage: 1, ClosedPnl: -2, UpdatedAt: date,
+			}}},
+		},
+		{
+			name: "unix milliseconds",
+			profile: func(p *model.ImportProfile) {
+				p.DateFormat = "unixms"
+				p.OrderIDColumn = "Ref"
+			},
+			csv: "Market,Side,Profit,Closed,Ref\nETHUSDT,sell,-2,1746174615250,r-3\n",
+			want: []Row{{Line: 2, Has: FieldSide, Position: model.Position{
+				OrderID: "r-3", Exchange: "venue", Symbol: "ETHUSDT", Side: "Sell", Leverage: 1, ClosedPnl: -2, UpdatedAt: date.Add(250 * time.Millisecond),
+			}}},
+		},
+		{
+			name: "month and minute tokens are not confused",
+			profile: func(p *model.ImportProfile) {
+				p.DateFormat = "YYYY/MM/DD mm:HH"
+				p.OrderIDColumn = "Ref"
+			},
+			csv: "Market,Side,Profit,Closed,Ref\nETHUSDT,sell,-2,2025/05/02 30:08,r-4\n",
+			want: []Row{{Line: 2, Has: FieldSide, Position: model.Position{
+				OrderID: "r-4", Exchange: "venue", Symbol: "ETHUSDT", Side: "Sell", Leverage: 1, ClosedPnl: -2, UpdatedAt: date.Truncate(time.Minute),
+			}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := testProfile
+			tt.profile(&p)
+			rows, rowErrors, err := ParseMapped(strings.NewReader(tt.csv), p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rowErrors) != 0 {
+				t.Fatalf("row errors %+v", rowErrors)
+			}
+			checkRows(t, rows, tt.want)
+		})
+	}
+}
+
+func TestParseMappedWithoutOptionalColumns(t *testing.T) {
+	csv := "Market,Side,Profit,Closed,Size\nBTCUSDT,buy,4,2025-05-02 08:30:15,100\n"
+
+	rows, _, err := ParseMapped(strings.NewReader(csv), testProfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := model.Position{Exchange: "venue", Symbol: "BTCUSDT", Side: "Buy", Leverage: 1, ClosedPnl: 4, UpdatedAt: time.Date(2025, 5, 2, 8, 30, 15, 0, time.UTC)}
+	p.OrderID = syntheticOrderID(p)
+	checkRows(t, rows, []Row{{Line: 2, Has: FieldSide, Position: p}})
+
+	// A mapped column the file lacks fails the whole import
+	profile := testProfile
+	profile.LeverageColumn = "Lev"
+	if _, _, err := ParseMapped(strings.NewReader(csv), profile); err == nil || !strings.Contains(err.Error(), `"Lev"`) {
+		t.Errorf("err = %v, want the missing Lev column named", err)
+	}
+}
+
+func TestParseMappedRowErrors(t *testing.T) {
+	profile := testProfile
+	profile.VolumeColumn = "Size"
+	profile.LeverageColumn = "Lev"
+	profile.DateFormat = "unix"
+	csv := `Export of closed trades
+Market,Side,Profit,Closed,Size,Lev
+BTCUSDT,buy,1,1746174615,10,2
+BTCUSDT,flat,1,1746174615,10,2
+BTCUSDT,buy,lots,1746174615,10,2
+
+BTCUSDT,buy,1,2025-05-02,10,2
+,buy,1,1746174615,10,2
+BTCUSDT,buy,1,1746174615,-10,2
+BTCUSDT,buy,1,1746174615,10,2.5x
+BTCUSDT,buy,1,1746174615,10,0
+BTCUSDT,sell,1,1746174615,10,3x
+`
+	rows, rowErrors, err := ParseMapped(strings.NewReader(csv), profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[0].Line != 3 || rows[1].Line != 12 || rows[1].Position.Leverage != 3 {
+		t.Errorf("rows %+v, want lines 3 and 12", rows)
+	}
+
+	want := []struct {
+		line    int
+		message string
+	}{
+		{4, "unknown side"},
+		{5, "invalid PnL"},
+		{7, "invalid date"},
+		{8, "missing symbol"},
+		{9, "negative volume"},
+		{10, "invalid leverage"},
+		{11, "invalid leverage"},
+	}
+	if len(rowErrors) != len(want) {
+		t.Fatalf("got %d row errors %+v, want %d", len(rowErrors), rowErrors, len(want))
+	}
+	for i, w := range want {
+		if rowErrors[i].Line != w.line || !strings.Contains(rowErrors[i].Message, w.message) {
+			t.Errorf("row error %d = %+v, want line %d with %q", i, rowErrors[i], w.line, w.message)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package importer
+
+import (
+	"github.com/Ravierin/BudgetTracker/backend/internal/api"
+	"github.com/Ravierin/BudgetTracker/backend/internal/model"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ParseMEXCHistory parses the futures "Position History" statement from the
+// MEXC website. Like the API mapper, volume is close volume (contracts) ×
+// open price × contract size, and the position ID becomes the order ID.
+func ParseMEXCHistory(r io.Reader) ([]Row, []model.ImportRowError, error) {
+	symbolCols := []string{"Futures Trading Pair", "Pair", "Symbol", "Contract"}
+	pnlCols := []string{"Closed PnL", "Realized PnL", "Close PnL", "Closing PNL", "Profit"}
+	timeCols := []string{"Close Time", "Closing Time", "Update Time", "Time"}
+
+	t, err := openTable(r, symbolCols, pnlCols, timeCols)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	columns := mexcColumns{
+		symbol:    t.column(symbolCols...),
+		pnl:       t.column(pnlCols...),
+		time:      t.column(timeCols...),
+		position:  t.column("Position ID", "PositionId", "Order ID"),
+		side:      t.column("Direction", "Position Type", "Side"),
+		leverage:  t.column("Leverage"),
+		closeVol:  t.column("Closed Volume", "Close Vol", "Close Volume", "Volume"),
+		openPrice: t.column("Avg. Open Price", "Open Avg Price", "Avg Entry Price", "Open Price"),
+	}
+
+	var rows []Row
+	var rowErrors []model.ImportRowError
+
+	for {
+		record, line, err := t.next()
+		if err == io.EOF {
+			break
+		}
+		if isRowError(err) {
+			rowErrors = append(rowErrors, model.ImportRowError{Line: line, Message: err.Error()})
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		p, has, err := parseMEXCRecord(record, columns)
+		if err != nil {
+			rowErrors = append(rowErrors, model.ImportRowError{Line: line, Message: err.Error()})
+			continue
+		}
+		rows = append(rows, Row{Line: line, Position: p, Has: has})
+	}
+
+	return rows, rowErrors, nil
+}
+
+type mexcColumns struct {
+	symbol, pnl, time, position, side, leverage, closeVol, openPrice int
+}
+
+func parseMEXCRecord(record []string, c mexcColumns) (model.Position, Field, error) {
+	p := model.Position{Exchange: "mexc", Leverage: 1}
+	var has Field
+
+	p.Symbol = mexcSymbol(field(record, c.symbol))
+	if p.Symbol == "" {
+		return p, has, fmt.Errorf("missing symbol")
+	}
+
+	var err error
+	if p.ClosedPnl, err = ParseNumber(field(record, c.pnl)); err != nil {
+		return p, has, fmt.Errorf("invalid closed PnL %q", field(record, c.pnl))
+	}
+	if p.UpdatedAt, err = ParseTime(field(record, c.time), ""); err != nil {
+		return p, has, err
+	}
+
+	if c.side >= 0 {
+		if p.Side, err = NormalizeSide(field(record, c.side)); err != nil {
+			return p, has, err
+		}
+		has |= FieldSide
+	}
+
+	if leverage := field(record, c.leverage); leverage != "" {
+		l, err := ParseNumber(strings.TrimSuffix(strings.ToLower(leverage), "x"))
+		if err != nil {
+			return p, has, fmt.Errorf("invalid leverage %q", leverage)
+		}
+		p.Leverage = int(l)
+		has |= FieldLeverage
+	}
+
+	if field(record, c.closeVol) != "" && field(record, c.openPrice) != "" {
+		closeVol, err := ParseNumber(field(record, c.closeVol))
+		if err != nil {
+			return p, has, fmt.Errorf("invalid close volume %q", field(record, c.closeVol))
+		}
+		openPrice, err := ParseNumber(field(record, c.openPrice))
+		if err != nil {
+			return p, has, fmt.Errorf("invalid open price %q", field(record, c.openPrice))
+		}
+		p.Volume = closeVol * openPrice * api.GetContractSize(p.Symbol)
+		has |= FieldVolume
+	}
+
+	p.OrderID = field(record, c.position)
+	if p.OrderID == "" {
+		p.OrderID = syntheticOrderID(p)
+	}
+
+	return p, has, nil
+}
+
+// mexcSymbol converts statement symbols (BTCUSDT, BTC/USDT) to the BTC_USDT
+// form returned by the contract API
+func mexcSymbol(s string) string {
+	s = strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(s), "/", "_"))
+	if s == "" || strings.Contains(s, "_") {
+		return s
+	}
+	for _, quote := range []string{"USDT", "USDC", "USD"} {
+		if strings.HasSuffix(s, quote) && len(s) > len(quote) {
+			return strings.TrimSuffix(s, quote) + "_" + quote
+		}
+	}
+	return s
+}
@@ -0,0 +1,122 @@
+// Package instrument maps exchange symbols to canonical instruments, so the
+// same market is one instrument whether Bybit reports it as BTCUSDT or MEXC
+// as BTC_USDT
+package instrument
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Contract types
+const (
+	LinearPerpetual  = "linear_perpetual"
+	InversePerpetual = "inverse_perpetual"
+	LinearFutures    = "linear_futures"
+	InverseFutures   = "inverse_futures"
+)
+
+// quoteAssets are tried as symbol suffixes in order, longest match first
+var quoteAssets = []string{"USDT", "USDC", "BUSD", "USD"}
+
+var (
+	// expiryPattern matches 26DEC25 (Bybit USDC and USDT futures) and H25
+	// (month code and year of Bybit inverse futures)
+	expiryPattern = regexp.MustCompile(`^(\d{1,2}[A-Z]{3}\d{2}|[FGHJKMNQUVXZ]\d{2})$`)
+	// inverseFuturesPattern matches BTCUSDH25
+	inverseFuturesPattern = regexp.MustCompile(`^([A-Z0-9]+)USD([FGHJKMNQUVXZ]\d{2})$`)
+)
+
+// Instrument is the canonical form of an exchange symbol
+type Instrument struct {
+	Base         string
+	Quote        string
+	ContractType string
+	Expiry       string
+}
+
+// Key is the canonical name, e.g. BTC/USDT, or BTC/USD-26DEC25 for dated
+// futures. Symbols that could not be split keep their uppercased form.
+func (i Instrument) Key() string {
+	if i.Quote == "" {
+		return i.Base
+	}
+	key := i.Base + "/" + i.Quote
+	if i.Expiry != "" {
+		key += "-" + i.Expiry
+	}
+	return key
+}
+
+// Normalize maps a symbol as reported by an exchange. MEXC symbols are
+// BASE_QUOTE; Bybit and anything else (e.g. imported CSVs) go through Parse.
+func Normalize(exchange, symbol string) Instrument {
+	s := strings.ToUpper(strings.TrimSpace(symbol))
+	if exchange == "mexc" {
+		if base, quote, ok := strings.Cut(s, "_"); ok && base != "" && quote != "" {
+			return perpetual(base, quote)
+		}
+	}
+	return Parse(s)
+}
+
+// Parse accepts exchange symbols of any supported style as well as
+// canonical keys, so Parse(i.Key()) == i
+func Parse(symbol string) Instrument {
+	s := strings.ToUpper(strings.TrimSpace(symbol))
+
+	// Dated futures: BTC-26DEC25, BTCUSDT-26DEC25, BTC/USD-H25
+	if head, expiry, ok := cutLast(s, "-"); ok && expiryPattern.MatchString(expiry) {
+		i := Parse(head)
+		if i.Quote == "" {
+			// Bybit lists USDC futures by base asset alone
+			i = Instrument{Base: head, Quote: "USDC"}
+		}
+		i.Expiry = expiry
+		i.ContractType = LinearFutures
+		if i.Quote == "USD" {
+			i.ContractType = InverseFutures
+		}
+		return i
+	}
+
+	if m := inverseFuturesPattern.FindStringSubmatch(s); m != nil {
+		return Instrument{Base: m[1], Quote: "USD", ContractType: InverseFutures, Expiry: m[2]}
+	}
+
+	// ccxt style BTC/USDT:USDT names the settle asset after the colon
+	s, _, _ = strings.Cut(s, ":")
+	for _, sep := range []string{"/", "_", "-"} {
+		if base, quote, ok := strings.Cut(s, sep); ok && base != "" && quote != "" {
+			return perpetual(base, quote)
+		}
+	}
+
+	// Bybit USDC perpetuals are named BTCPERP
+	if base, ok := strings.CutSuffix(s, "PERP"); ok && base != "" {
+		return perpetual(base, "USDC")
+	}
+	for _, quote := range quoteAssets {
+		if base, ok := strings.CutSuffix(s, quote); ok && base != "" {
+			return perpetual(base, quote)
+		}
+	}
+
+	return Instrument{Base: s}
+}
+
+// perpetual builds a perpetual swap; USD-quoted ones are coin margined
+func perpetual(base, quote string) Instrument {
+	contractType := LinearPerpetual
+	if quote == "USD" {
+		contractType = InversePerpetual
+	}
+	return Instrument{Base: base, Quote: quote, ContractType: contractType}
+}
+
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i > 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
@@ -1,12 +1,20 @@
 package model
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 type Position struct {
 	ID           int       `json:"id"`
 	OrderID      string    `json:"orderId"`
 	Exchange     string    `json:"exchange"`
+	Account      string    `json:"account"`
 	Symbol       string    `json:"symbol"`
+	BaseAsset    string    `json:"baseAsset"`
+	QuoteAsset   string    `json:"quoteAsset"`
+	ContractType string    `json:"contractType"`
+	Instrument   string    `json:"instrument"`
 	Volume       float64   `json:"volume"`
 	Leverage     int       `json:"leverage"`
 	ClosedPnl    float64   `json:"closedPnl"`
@@ -14,6 +22,37 @@ type Position struct {
 	UpdatedAt    time.Time `json:"date"`
 }
 
+// PositionChanges reports what an upsert wrote: positions new to the store
+// and stored ones whose values changed. Rows written unchanged are in neither.
+type PositionChanges struct {
+	Added   []Position `json:"added"`
+	Changed []Position `json:"changed"`
+}
+
+// OpenPosition is a position currently held on an exchange. Size is in
+// base asset units; Side is "Buy" for longs and "Sell" for shorts.
+type OpenPosition struct {
+	Exchange         string    `json:"exchange"`
+	Symbol           string    `json:"symbol"`
+	Instrument       string    `json:"instrument"`
+	Side             string    `json:"side"`
+	Size             float64   `json:"size"`
+	EntryPrice       float64   `json:"entryPrice"`
+	MarkPrice        float64   `json:"markPrice"`
+	PositionValue    float64   `json:"positionValue"`
+	UnrealizedPnl    float64   `json:"unrealizedPnl"`
+	Leverage         int       `json:"leverage"`
+	LiquidationPrice float64   `json:"liquidationPrice"`
+	UpdatedAt        time.Time `json:"updatedAt"`
+}
+
+// OpenPositions is the latest open position snapshot across exchanges
+type OpenPositions struct {
+	Positions     []OpenPosition    `json:"positions"`
+	UnrealizedPnl float64           `json:"unrealizedPnl"`
+	Errors        map[string]string `json:"errors,omitempty"`
+}
+
 type ExchangeBalance struct {
 	Exchange string  `json:"exchange"`
 	Balance  float64 `json:"balance"`
@@ -27,6 +66,14 @@ type Withdrawal struct {
 	CreatedAt time.Time `json:"date"`
 }
 
+// WithdrawalFilter narrows a withdrawal listing; From is inclusive and To
+// exclusive, like PositionFilter
+type WithdrawalFilter struct {
+	Exchange string
+	From     time.Time
+	To       time.Time
+}
+
 type MonthlyIncome struct {
 	ID        int       `json:"id"`
 	Exchange  string    `json:"exchange"`
@@ -44,3 +91,248 @@ type APIKey struct {
 	CreatedAt time.Time `json:"createdAt"`
 	UpdatedAt time.Time `json:"updatedAt"`
 }
+
+type Setting struct {
+	Key       string    `json:"key"`
+	Value     string    `json:"value"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// DefaultAccount is used when an exchange has a single set of API keys
+const DefaultAccount = "default"
+
+// PositionFilter narrows position queries. Zero values mean "no filter".
+type PositionFilter struct {
+	Exchange   string
+	Account    string
+	Symbol     string // exchange symbol, matched exactly
+	Instrument string // canonical instrument key, e.g. BTC/USDT
+	Side       string
+	From       time.Time
+	To         time.Time
+	PnlSign    string // "positive", "negative" or empty
+}
+
+// PositionQuery is a filtered, sorted and keyset-paginated position lookup.
+type PositionQuery struct {
+	PositionFilter
+	SortBy string // "date", "closedPnl" or "volume"
+	Asc    bool
+	Limit  int
+	Cursor string
+}
+
+type PositionStats struct {
+	Exchange    string  `json:"exchange,omitempty"`
+	Symbol      string  `json:"symbol,omitempty"`
+	Instrument  string  `json:"instrument,omitempty"`
+	Trades      int     `json:"trades"`
+	Wins        int     `json:"wins"`
+	Losses      int     `json:"losses"`
+	TotalPnl    float64 `json:"totalPnl"`
+	TotalVolume float64 `json:"totalVolume"`
+}
+
+// ImportRowError reports a CSV line that could not be imported
+type ImportRowError struct {
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+// ImportChange pairs an imported row with the stored position it matched
+type ImportChange struct {
+	Line     int      `json:"line"`
+	Imported Position `json:"imported"`
+	Existing Position `json:"existing"`
+}
+
+// ImportResult previews or reports a position import. Conflicts share an
+// order ID with a stored position of another exchange or symbol and are
+// never written.
+type ImportResult struct {
+	Format    string           `json:"format"`
+	DryRun    bool             `json:"dryRun"`
+	New       []Position       `json:"new"`
+	Updated   []ImportChange   `json:"updated"`
+	Conflicts []ImportChange   `json:"conflicts"`
+	Unchanged int              `json:"unchanged"`
+	Errors    []ImportRowError `json:"errors"`
+	Applied   bool             `json:"applied"`
+}
+
+// ImportProfile maps the columns of a CSV from an unsupported venue onto
+// positions. Column values are header names; DateFormat is a Go layout,
+// a YYYY-MM-DD HH:mm:ss style pattern, "unix" or "unixms".
+type ImportProfile struct {
+	ID             int       `json:"id"`
+	Name           string    `json:"name"`
+	Exchange       string    `json:"exchange"`
+	SymbolColumn   string    `json:"symbolColumn"`
+	SideColumn     string    `json:"sideColumn"`
+	PnlColumn      string    `json:"pnlColumn"`
+	DateColumn     string    `json:"dateColumn"`
+	DateFormat     string    `json:"dateFormat"`
+	VolumeColumn   string    `json:"volumeColumn,omitempty"`
+	LeverageColumn string    `json:"leverageColumn,omitempty"`
+	OrderIDColumn  string    `json:"orderIdColumn,omitempty"`
+	Delimiter      string    `json:"delimiter,omitempty"`
+	CreatedAt      time.Time `json:"createdAt"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
+
+// ContractMeta is a futures contract as listed by the exchange. A manual
+// ContractSizeOverride takes precedence over the listed ContractSize.
+// AppliedContractSize is the size stored position volumes were computed
+// with (0 for the fixed pre-metadata guesses); volumes are rescaled when
+// the effective size moves away from it.
+type ContractMeta struct {
+	Exchange             string    `json:"exchange"`
+	Symbol               string    `json:"symbol"`
+	BaseCoin             string    `json:"baseCoin"`
+	QuoteCoin            string    `json:"quoteCoin"`
+	SettleCoin           string    `json:"settleCoin"`
+	ContractSize         float64   `json:"contractSize"`
+	PriceScale           int       `json:"priceScale"`
+	VolScale             int       `json:"volScale"`
+	ContractSizeOverride *float64  `json:"contractSizeOverride"`
+	AppliedContractSize  float64   `json:"appliedContractSize"`
+	UpdatedAt            time.Time `json:"updatedAt"`
+}
+
+// VolumeRescale is one symbol whose position volumes follow a new contract size
+type VolumeRescale struct {
+	Symbol    string  `json:"symbol"`
+	From      float64 `json:"from"`
+	To        float64 `json:"to"`
+	Positions int     `json:"positions"`
+}
+
+// VolumeBackfillResult reports the position volumes rescaled to the
+// current contract sizes
+type VolumeBackfillResult struct {
+	DryRun    bool            `json:"dryRun"`
+	Symbols   []VolumeRescale `json:"symbols"`
+	Positions int             `json:"positions"`
+	Applied   bool            `json:"applied"`
+}
+
+// RestoreResult counts the rows written by a backup restore
+type RestoreResult struct {
+	Mode           string `json:"mode"`
+	Positions      int    `json:"positions"`
+	Withdrawals    int    `json:"withdrawals"`
+	MonthlyIncomes int    `json:"monthlyIncomes"`
+	Settings       int    `json:"settings"`
+	ImportProfiles int    `json:"importProfiles"`
+	APIKeys        int    `json:"apiKeys"`
+}
+
+// RawExchangeRecord is an exchange payload as fetched, kept so positions can
+// be re-mapped after a mapper fix. Kind names the mapper that reads it.
+type RawExchangeRecord struct {
+	Exchange   string          `json:"exchange"`
+	ExternalID string          `json:"externalId"`
+	Kind       string          `json:"kind"`
+	Payload    json.RawMessage `json:"payload"`
+	FetchedAt  time.Time       `json:"fetchedAt"`
+}
+
+// ReprocessChange is a stored position whose payload now maps differently
+type ReprocessChange struct {
+	Before Position `json:"before"`
+	After  Position `json:"after"`
+}
+
+// ReprocessError is a stored payload the current mappers cannot read
+type ReprocessError struct {
+	Exchange   string `json:"exchange"`
+	ExternalID string `json:"externalId"`
+	Message    string `json:"message"`
+}
+
+// ReprocessResult reports a re-run of the mappers over stored payloads.
+// Missing counts payloads whose position no longer exists; deleted
+// positions are not recreated.
+type ReprocessResult struct {
+	Exchange  string            `json:"exchange,omitempty"`
+	DryRun    bool              `json:"dryRun"`
+	Records   int               `json:"records"`
+	Changed   []ReprocessChange `json:"changed"`
+	Unchanged int               `json:"unchanged"`
+	Missing   int               `json:"missing"`
+	Errors    []ReprocessError  `json:"errors"`
+	Applied   bool              `json:"applied"`
+}
+
+// Change log entities and operations. A reset entry means every row of its
+// entity, or of all three when Entity is empty, may have changed, e.g.
+// after a backup restore; mirrors should reload them.
+const (
+	ChangeEntityPosition   = "position"
+	ChangeEntityWithdrawal = "withdrawal"
+	ChangeEntitySetting    = "setting"
+
+	ChangeInsert = "insert"
+	ChangeUpdate = "update"
+	ChangeDelete = "delete"
+	ChangeReset  = "reset"
+)
+
+// Change is one committed write of a position, withdrawal or setting. Key is
+// the row's ID, or the setting key; Data is the row as written, and is empty
+// for deletes and resets.
+type Change struct {
+	ID        int64           `json:"id"`
+	Entity    string          `json:"entity"`
+	Op        string          `json:"op"`
+	Key       string          `json:"key"`
+	Data      json.RawMessage `json:"data,omitempty"`
+	CreatedAt time.Time       `json:"createdAt"`
+}
+
+// ChangeFeed is a page of the change log. Cursor is passed as since to get
+// the next page; it stays the same when nothing changed.
+type ChangeFeed struct {
+	Changes []Change `json:"changes"`
+	Cursor  string   `json:"cursor"`
+	HasMore bool     `json:"hasMore"`
+}
+
+// Sync run triggers and error classes
+const (
+	SyncTriggerInitial  = "initial"
+	SyncTriggerInterval = "interval"
+
+	SyncErrorTemporary = "temporary" // rate limits and timeouts, retried by the next run
+	SyncErrorExchange  = "exchange"  // any other exchange or mapping failure
+	SyncErrorStorage   = "storage"   // the positions could not be saved
+)
+
+// SyncRun is one sync attempt of an exchange account. Pages counts the
+// history pages requested; ErrorClass is empty when the run succeeded.
+type SyncRun struct {
+	ID           int64     `json:"id"`
+	Exchange     string    `json:"exchange"`
+	Account      string    `json:"account"`
+	Trigger      string    `json:"trigger"`
+	StartedAt    time.Time `json:"startedAt"`
+	FinishedAt   time.Time `json:"finishedAt"`
+	Pages        int       `json:"pages"`
+	Fetched      int       `json:"fetched"`
+	Inserted     int       `json:"inserted"`
+	Updated      int       `json:"updated"`
+	ErrorClass   string    `json:"errorClass,omitempty"`
+	ErrorMessage string    `json:"errorMessage,omitempty"`
+}
+
+// SyncAccountStatus sums up the runs of an exchange account: the last one
+// that succeeded, the last one overall, and how many have failed in a row
+// since the last success, starting at FailingSince.
+type SyncAccountStatus struct {
+	Exchange      string     `json:"exchange"`
+	Account       string     `json:"account"`
+	LastSuccess   *SyncRun   `json:"lastSuccess"`
+	LastRun       SyncRun    `json:"lastRun"`
+	FailureStreak int        `json:"failureStreak"`
+	FailingSince  *time.Time `json:"failingSince,omitempty"`
+}
@@ -0,0 +1,223 @@
+package repository
+
+import (
+	"github.com/Ravierin/BudgetTracker/backend/internal/model"
+	"github.com/Ravierin/BudgetTracker/backend/pkg/database"
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// restoreBatchSize bounds how many positions are buffered before an upsert batch
+const restoreBatchSize = 1000
+
+type BackupRepository struct {
+	db *database.Database
+}
+
+func NewBackupRepository(db *database.Database) *BackupRepository {
+	return &BackupRepository{db: db}
+}
+
+// Restore writes backup records inside a single transaction. Nothing is
+// visible to other connections until Commit.
+type Restore struct {
+	tx        pgx.Tx
+	replace   bool
+	positions []model.Position
+}
+
+// BeginRestore opens the restore transaction. In replace mode all restorable
+// tables are emptied first; API keys only when the backup carries them.
+func (r *BackupRepository) BeginRestore(ctx context.Context, replace, includeKeys bool) (RestoreWriter, error) {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := lockRollup(ctx, tx); err != nil {
+		tx.Rollback(ctx)
+		return nil, err
+	}
+
+	if replace {
+		tables := []string{"position", "position_daily_rollup", "withdrawal", "monthly_income", "settings", "import_profile"}
+		if includeKeys {
+			tables = append(tables, "api_keys")
+		}
+		for _, table := range tables {
+			if _, err := tx.Exec(ctx, "DELETE FROM "+table); err != nil {
+				tx.Rollback(ctx)
+				return nil, err
+			}
+		}
+	}
+
+	return &Restore{tx: tx, replace: replace}, nil
+}
+
+// AddPosition queues a position for upsert by order ID
+func (s *Restore) AddPosition(ctx context.Context, p model.Position) error {
+	s.positions = append(s.positions, p)
+	if len(s.positions) >= restoreBatchSize {
+		return s.flushPositions(ctx)
+	}
+	return nil
+}
+
+func (s *Restore) flushPositions(ctx context.Context) error {
+	if len(s.positions) == 0 {
+		return nil
+	}
+
+	batch := &pgx.Batch{}
+	for _, p := range s.positions {
+		p = withInstrument(p)
+		batch.Queue(upsertPositionQuery,
+			p.OrderID,
+			p.Exchange,
+			accountOrDefault(p.Account),
+			p.Symbol,
+			p.BaseAsset,
+			p.QuoteAsset,
+			p.ContractType,
+			p.Instrument,
+			p.Volume,
+			p.Leverage,
+			p.ClosedPnl,
+			p.Side,
+			p.UpdatedAt,
+		)
+	}
+	s.positions = s.positions[:0]
+
+	br := s.tx.SendBatch(ctx, batch)
+	for i := 0; i < batch.Len(); i++ {
+		if _, err := br.Exec(); err != nil {
+			br.Close()
+			return err
+		}
+	}
+	return br.Close()
+}
+
+// AddWithdrawal inserts a withdrawal under a new ID. When merging, an
+// identical row already present is skipped and false is returned.
+func (s *Restore) AddWithdrawal(ctx context.Context, w model.Withdrawal) (bool, error) {
+	query := `
+		INSERT INTO withdrawal (exchange, amount, currency, date)
+		SELECT $1, $2, $3, $4
+		WHERE $5 OR NOT EXISTS (
+			SELECT 1 FROM withdrawal
+			WHERE exchange = $1 AND amount = $2 AND currency = $3 AND date = $4
+		)
+	`
+	tag, err := s.tx.Exec(ctx, query, w.Exchange, w.Amount, w.Currency, w.CreatedAt, s.replace)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// AddMonthlyIncome is AddWithdrawal for monthly income rows
+func (s *Restore) AddMonthlyIncome(ctx context.Context, i model.MonthlyIncome) (bool, error) {
+	query := `
+		INSERT INTO monthly_income (exchange, amount, pnl, date)
+		SELECT $1, $2, $3, $4
+		WHERE $5 OR NOT EXISTS (
+			SELECT 1 FROM monthly_income
+			WHERE exchange = $1 AND amount = $2 AND pnl = $3 AND date = $4
+		)
+	`
+	tag, err := s.tx.Exec(ctx, query, i.Exchange, i.Amount, i.PNL, i.CreatedAt, s.replace)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (s *Restore) AddSetting(ctx context.Context, setting model.Setting) error {
+	query := `
+		INSERT INTO settings (key, value, updated_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (key) DO UPDATE SET
+			value = EXCLUDED.value,
+			updated_at = EXCLUDED.updated_at
+	`
+	_, err := s.tx.Exec(ctx, query, setting.Key, setting.Value, setting.UpdatedAt)
+	return err
+}
+
+func (s *Restore) AddImportProfile(ctx context.Context, p model.ImportProfile) error {
+	query := `
+		INSERT INTO import_profile (
+			name, exchange, symbol_column, side_column, pnl_column, date_column,
+			date_format, volume_column, leverage_column, order_id_column, delimiter,
+			created_at, updated_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13
+		)
+		ON CONFLICT (name) DO UPDATE SET
+			exchange = EXCLUDED.exchange,
+			symbol_column = EXCLUDED.symbol_column,
+			side_column = EXCLUDED.side_column,
+			pnl_column = EXCLUDED.pnl_column,
+			date_column = EXCLUDED.date_column,
+			date_format = EXCLUDED.date_format,
+			volume_column = EXCLUDED.volume_column,
+			leverage_column = EXCLUDED.leverage_column,
+			order_id_column = EXCLUDED.order_id_column,
+			delimiter = EXCLUDED.delimiter,
+			updated_at = EXCLUDED.updated_at
+	`
+	_, err := s.tx.Exec(ctx, query,
+		p.Name,
+		p.Exchange,
+		p.SymbolColumn,
+		p.SideColumn,
+		p.PnlColumn,
+		p.DateColumn,
+		p.DateFormat,
+		p.VolumeColumn,
+		p.LeverageColumn,
+		p.OrderIDColumn,
+		p.Delimiter,
+		p.CreatedAt,
+		p.UpdatedAt,
+	)
+	return err
+}
+
+func (s *Restore) AddAPIKey(ctx context.Context, k model.APIKey) error {
+	query := `
+		INSERT INTO api_keys (exchange, api_key, api_secret, is_active, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (exchange) DO UPDATE SET
+			api_key = EXCLUDED.api_key,
+			api_secret = EXCLUDED.api_secret,
+			is_active = EXCLUDED.is_active,
+			updated_at = EXCLUDED.updated_at
+	`
+	_, err := s.tx.Exec(ctx, query, k.Exchange, k.APIKey, k.APISecret, k.IsActive, k.CreatedAt, k.UpdatedAt)
+	return err
+}
+
+// Commit writes the remaining positions, rebuilds the daily rollup from the
+// restored table and commits
+func (s *Restore) Commit(ctx context.Context) error {
+	if err := s.flushPositions(ctx); err != nil {
+		return err
+	}
+	if _, err := rebuildRollup(ctx, s.tx); err != nil {
+		return err
+	}
+	if err := recordChanges(ctx, s.tx, []changeEntry{{op: model.ChangeReset}}); err != nil {
+		return err
+	}
+	return s.tx.Commit(ctx)
+}
+
+// Rollback discards the restore; it is a no-op after Commit
+func (s *Restore) Rollback(ctx context.Context) error {
+	return s.tx.Rollback(ctx)
+}
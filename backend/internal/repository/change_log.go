@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"github.com/Ravierin/BudgetTracker/backend/internal/model"
+	"encoding/json"
+	"strconv"
+)
+
+// changeEntry is a change log row about to be written with the change it
+// records. data is encoded as JSON; nil leaves it empty.
+type changeEntry struct {
+	entity string
+	op     string
+	key    string
+	data   interface{}
+}
+
+func (e changeEntry) encode() ([]byte, error) {
+	if e.data == nil {
+		return nil, nil
+	}
+	return json.Marshal(e.data)
+}
+
+// positionChangeEntries records the rows an upsert inserted and changed
+func positionChangeEntries(changes model.PositionChanges) []changeEntry {
+	entries := make([]changeEntry, 0, len(changes.Added)+len(changes.Changed))
+	for _, p := range changes.Added {
+		entries = append(entries, changeEntry{model.ChangeEntityPosition, model.ChangeInsert, strconv.Itoa(p.ID), p})
+	}
+	for _, p := range changes.Changed {
+		entries = append(entries, changeEntry{model.ChangeEntityPosition, model.ChangeUpdate, strconv.Itoa(p.ID), p})
+	}
+	return entries
+}
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"github.com/Ravierin/BudgetTracker/backend/internal/model"
+	"github.com/Ravierin/BudgetTracker/backend/pkg/database"
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// changeLogLockID is held from a transaction's first change log entry until
+// it commits. BIGSERIAL IDs are handed out at insert time, so without it a
+// later ID could commit first and readers past it would never see the
+// earlier one.
+const changeLogLockID = 727004
+
+type ChangeLogRepository struct {
+	db *database.Database
+}
+
+func NewChangeLogRepository(db *database.Database) *ChangeLogRepository {
+	return &ChangeLogRepository{db: db}
+}
+
+// recordChanges writes entries in tx, which must commit for them to show up
+func recordChanges(ctx context.Context, tx pgx.Tx, entries []changeEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, changeLogLockID); err != nil {
+		return err
+	}
+
+	batch := &pgx.Batch{}
+	for _, e := range entries {
+		data, err := e.encode()
+		if err != nil {
+			return err
+		}
+		batch.Queue(`INSERT INTO change_log (entity, op, entity_key, data) VALUES ($1, $2, $3, $4)`,
+			e.entity, e.op, e.key, data)
+	}
+	return tx.SendBatch(ctx, batch).Close()
+}
+
+func (r *ChangeLogRepository) GetChanges(ctx context.Context, after int64, limit int) ([]model.Change, error) {
+	query := `
+		SELECT id, entity, op, entity_key, data, created_at
+		FROM change_log
+		WHERE id > $1
+		ORDER BY id
+		LIMIT $2
+	`
+	rows, err := r.db.Pool.Query(ctx, query, after, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []model.Change
+	for rows.Next() {
+		var c model.Change
+		if err := rows.Scan(&c.ID, &c.Entity, &c.Op, &c.Key, &c.Data, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		changes = append(changes, c)
+	}
+	return changes, rows.Err()
+}
+
+func (r *ChangeLogRepository) LatestChangeID(ctx context.Context) (int64, error) {
+	var id int64
+	err := r.db.Pool.QueryRow(ctx, `SELECT COALESCE(MAX(id), 0) FROM change_log`).Scan(&id)
+	return id, err
+}
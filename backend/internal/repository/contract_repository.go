@@ -0,0 +1,170 @@
+package repository
+
+import (
+	"github.com/Ravierin/BudgetTracker/backend/internal/model"
+	"github.com/Ravierin/BudgetTracker/backend/pkg/database"
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type ContractRepository struct {
+	db *database.Database
+}
+
+func NewContractRepository(db *database.Database) *ContractRepository {
+	return &ContractRepository{db: db}
+}
+
+const contractColumns = `exchange, symbol, base_coin, quote_coin, settle_coin, contract_size,
+	price_scale, vol_scale, contract_size_override, applied_contract_size, updated_at`
+
+func (r *ContractRepository) GetContracts(ctx context.Context, exchange string) ([]model.ContractMeta, error) {
+	query := `SELECT ` + contractColumns + ` FROM contract_metadata WHERE exchange = $1 ORDER BY symbol`
+
+	rows, err := r.db.Pool.Query(ctx, query, exchange)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var contracts []model.ContractMeta
+	for rows.Next() {
+		var c model.ContractMeta
+		err := rows.Scan(&c.Exchange, &c.Symbol, &c.BaseCoin, &c.QuoteCoin, &c.SettleCoin, &c.ContractSize,
+			&c.PriceScale, &c.VolScale, &c.ContractSizeOverride, &c.AppliedContractSize, &c.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		contracts = append(contracts, c)
+	}
+	return contracts, rows.Err()
+}
+
+// SaveContracts upserts fetched listings, keeping overrides and applied sizes
+func (r *ContractRepository) SaveContracts(ctx context.Context, contracts []model.ContractMeta) error {
+	if len(contracts) == 0 {
+		return nil
+	}
+
+	query := `
+		INSERT INTO contract_metadata (
+			exchange, symbol, base_coin, quote_coin, settle_coin,
+			contract_size, price_scale, vol_scale, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW())
+		ON CONFLICT (exchange, symbol) DO UPDATE SET
+			base_coin = EXCLUDED.base_coin,
+			quote_coin = EXCLUDED.quote_coin,
+			settle_coin = EXCLUDED.settle_coin,
+			contract_size = EXCLUDED.contract_size,
+			price_scale = EXCLUDED.price_scale,
+			vol_scale = EXCLUDED.vol_scale,
+			updated_at = EXCLUDED.updated_at
+	`
+
+	batch := &pgx.Batch{}
+	for _, c := range contracts {
+		batch.Queue(query, c.Exchange, c.Symbol, c.BaseCoin, c.QuoteCoin, c.SettleCoin,
+			c.ContractSize, c.PriceScale, c.VolScale)
+	}
+
+	br := r.db.Pool.SendBatch(ctx, batch)
+	for i := 0; i < batch.Len(); i++ {
+		if _, err := br.Exec(); err != nil {
+			br.Close()
+			return err
+		}
+	}
+	return br.Close()
+}
+
+// SetContractOverride sets or, with a nil size, clears the manual contract
+// size. Symbols not listed yet get a row of their own.
+func (r *ContractRepository) SetContractOverride(ctx context.Context, exchange, symbol string, size *float64) error {
+	query := `
+		INSERT INTO contract_metadata (exchange, symbol, contract_size_override)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (exchange, symbol) DO UPDATE SET
+			contract_size_override = EXCLUDED.contract_size_override
+	`
+	_, err := r.db.Pool.Exec(ctx, query, exchange, symbol, size)
+	return err
+}
+
+func (r *ContractRepository) SetAppliedContractSize(ctx context.Context, exchange, symbol string, size float64) error {
+	query := `UPDATE contract_metadata SET applied_contract_size = $3 WHERE exchange = $1 AND symbol = $2`
+	_, err := r.db.Pool.Exec(ctx, query, exchange, symbol, size)
+	return err
+}
+
+// RescaleVolumes updates the volumes with a single statement, keeping the
+// daily rollup and the change log in step
+func (r *ContractRepository) RescaleVolumes(ctx context.Context, exchange, symbol string, from, to float64, dryRun bool) (int, error) {
+	if from <= 0 || to <= 0 {
+		return 0, errors.New("contract sizes must be positive")
+	}
+
+	if dryRun {
+		var n int
+		query := `SELECT COUNT(*) FROM position WHERE exchange = $1 AND symbol = $2`
+		err := r.db.Pool.QueryRow(ctx, query, exchange, symbol).Scan(&n)
+		return n, err
+	}
+
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockRollup(ctx, tx); err != nil {
+		return 0, err
+	}
+
+	query := `
+		UPDATE position SET volume = volume / $3 * $4, updated_at = NOW()
+		WHERE exchange = $1 AND symbol = $2
+		RETURNING ` + positionColumns
+	rows, err := tx.Query(ctx, query, exchange, symbol, from, to)
+	if err != nil {
+		return 0, err
+	}
+	var changes model.PositionChanges
+	var orderIDs []string
+	for rows.Next() {
+		p, err := scanPosition(rows)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		changes.Changed = append(changes.Changed, p)
+		orderIDs = append(orderIDs, p.OrderID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	keys, err := rollupKeysForOrders(ctx, tx, orderIDs)
+	if err != nil {
+		return 0, err
+	}
+	if err := refreshRollup(ctx, tx, keys); err != nil {
+		return 0, err
+	}
+	if err := recordChanges(ctx, tx, positionChangeEntries(changes)); err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE contract_metadata SET applied_contract_size = $3 WHERE exchange = $1 AND symbol = $2`,
+		exchange, symbol, to)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return len(orderIDs), nil
+}
@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"github.com/Ravierin/BudgetTracker/backend/internal/model"
+	"github.com/Ravierin/BudgetTracker/backend/pkg/database"
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const importProfileColumns = `
+	id, name, exchange, symbol_column, side_column, pnl_column, date_column,
+	date_format, volume_column, leverage_column, order_id_column, delimiter,
+	created_at, updated_at
+`
+
+type ImportProfileRepository struct {
+	db *database.Database
+}
+
+func NewImportProfileRepository(db *database.Database) *ImportProfileRepository {
+	return &ImportProfileRepository{db: db}
+}
+
+func (r *ImportProfileRepository) GetByName(ctx context.Context, name string) (*model.ImportProfile, error) {
+	query := `SELECT ` + importProfileColumns + ` FROM import_profile WHERE name = $1`
+
+	p, err := scanImportProfile(r.db.Pool.QueryRow(ctx, query, name))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+func (r *ImportProfileRepository) GetAll(ctx context.Context) ([]model.ImportProfile, error) {
+	query := `SELECT ` + importProfileColumns + ` FROM import_profile ORDER BY name`
+
+	rows, err := r.db.Pool.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var profiles []model.ImportProfile
+	for rows.Next() {
+		p, err := scanImportProfile(rows)
+		if err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, p)
+	}
+	return profiles, rows.Err()
+}
+
+func (r *ImportProfileRepository) Upsert(ctx context.Context, p *model.ImportProfile) error {
+	query := `
+		INSERT INTO import_profile (
+			name, exchange, symbol_column, side_column, pnl_column, date_column,
+			date_format, volume_column, leverage_column, order_id_column, delimiter
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
+		)
+		ON CONFLICT (name) DO UPDATE SET
+			exchange = EXCLUDED.exchange,
+			symbol_column = EXCLUDED.symbol_column,
+			side_column = EXCLUDED.side_column,
+			pnl_column = EXCLUDED.pnl_column,
+			date_column = EXCLUDED.date_column,
+			date_format = EXCLUDED.date_format,
+			volume_column = EXCLUDED.volume_column,
+			leverage_column = EXCLUDED.leverage_column,
+			order_id_column = EXCLUDED.order_id_column,
+			delimiter = EXCLUDED.delimiter,
+			updated_at = NOW()
+		RETURNING id, created_at, updated_at
+	`
+
+	return r.db.Pool.QueryRow(ctx, query,
+		p.Name,
+		p.Exchange,
+		p.SymbolColumn,
+		p.SideColumn,
+		p.PnlColumn,
+		p.DateColumn,
+		p.DateFormat,
+		p.VolumeColumn,
+		p.LeverageColumn,
+		p.OrderIDColumn,
+		p.Delimiter,
+	).Scan(&p.ID, &p.CreatedAt, &p.UpdatedAt)
+}
+
+func (r *ImportProfileRepository) Delete(ctx context.Context, name string) error {
+	query := `DELETE FROM import_profile WHERE name = $1`
+	_, err := r.db.Pool.Exec(ctx, query, name)
+	return err
+}
+
+func scanImportProfile(row pgx.Row) (model.ImportProfile, error) {
+	var p model.ImportProfile
+	err := row.Scan(
+		&p.ID,
+		&p.Name,
+		&p.Exchange,
+		&p.SymbolColumn,
+		&p.SideColumn,
+		&p.PnlColumn,
+		&p.DateColumn,
+		&p.DateFormat,
+		&p.VolumeColumn,
+		&p.LeverageColumn,
+		&p.OrderIDColumn,
+		&p.Delimiter,
+		&p.CreatedAt,
+		&p.UpdatedAt,
+	)
+	return p, err
+}
@@ -0,0 +1,1163 @@
+package repository
+
+import (
+	"github.com/Ravierin/BudgetTracker/backend/internal/model"
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// memoryData is every table of the in-memory backend. Repositories share one
+// instance behind a single lock, and a restore swaps in a modified copy.
+type memoryData struct {
+	positions      []model.Position
+	withdrawals    []model.Withdrawal
+	incomes        []model.MonthlyIncome
+	apiKeys        map[string]model.APIKey
+	importProfiles map[string]model.ImportProfile
+	settings       map[string]model.Setting
+	rawRecords     map[exchangeKey]model.RawExchangeRecord
+	contracts      map[exchangeKey]model.ContractMeta
+	changes        []model.Change
+	syncRuns       []model.SyncRun
+	nextID         int
+}
+
+// exchangeKey identifies rows keyed by exchange and an exchange-side ID
+type exchangeKey struct {
+	exchange, id string
+}
+
+func (d *memoryData) id() int {
+	d.nextID++
+	return d.nextID
+}
+
+// record appends change log entries; entries that cannot be encoded are
+// kept without data
+func (d *memoryData) record(entries ...changeEntry) {
+	for _, e := range entries {
+		data, _ := e.encode()
+		d.changes = append(d.changes, model.Change{
+			ID:        int64(len(d.changes) + 1),
+			Entity:    e.entity,
+			Op:        e.op,
+			Key:       e.key,
+			Data:      data,
+			CreatedAt: time.Now().UTC(),
+		})
+	}
+}
+
+func (d *memoryData) clone() *memoryData {
+	c := &memoryData{
+		positions:      append([]model.Position(nil), d.positions...),
+		withdrawals:    append([]model.Withdrawal(nil), d.withdrawals...),
+		incomes:        append([]model.MonthlyIncome(nil), d.incomes...),
+		apiKeys:        make(map[string]model.APIKey, len(d.apiKeys)),
+		importProfiles: make(map[string]model.ImportProfile, len(d.importProfiles)),
+		settings:       make(map[string]model.Setting, len(d.settings)),
+		rawRecords:     make(map[exchangeKey]model.RawExchangeRecord, len(d.rawRecords)),
+		contracts:      make(map[exchangeKey]model.ContractMeta, len(d.contracts)),
+		changes:        append([]model.Change(nil), d.changes...),
+		syncRuns:       append([]model.SyncRun(nil), d.syncRuns...),
+		nextID:         d.nextID,
+	}
+	for k, v := range d.apiKeys {
+		c.apiKeys[k] = v
+	}
+	for k, v := range d.importProfiles {
+		c.importProfiles[k] = v
+	}
+	for k, v := range d.settings {
+		c.settings[k] = v
+	}
+	for k, v := range d.rawRecords {
+		c.rawRecords[k] = v
+	}
+	for k, v := range d.contracts {
+		c.contracts[k] = v
+	}
+	return c
+}
+
+type memoryStore struct {
+	mu   sync.RWMutex
+	data *memoryData
+}
+
+// NewMemoryStores keeps everything in process memory. Nothing is persisted;
+// it exists so services can be exercised without a database.
+func NewMemoryStores() *Stores {
+	now := time.Now().UTC()
+	m := &memoryStore{data: &memoryData{
+		apiKeys:        make(map[string]model.APIKey),
+		importProfiles: make(map[string]model.ImportProfile),
+		settings:       make(map[string]model.Setting),
+		rawRecords:     make(map[exchangeKey]model.RawExchangeRecord),
+		contracts:      make(map[exchangeKey]model.ContractMeta),
+	}}
+	// Same seed rows as the 000001 migration
+	for _, exchange := range []string{"mexc", "bybit"} {
+		m.data.apiKeys[exchange] = model.APIKey{ID: m.data.id(), Exchange: exchange, CreatedAt: now, UpdatedAt: now}
+	}
+
+	return &Stores{
+		Positions:      &MemoryPositionRepository{m},
+		Withdrawals:    &MemoryWithdrawalRepository{m},
+		MonthlyIncomes: &MemoryMonthlyIncomeRepository{m},
+		APIKeys:        &MemoryAPIKeyRepository{m},
+		ImportProfiles: &MemoryImportProfileRepository{m},
+		Settings:       &MemorySettingsRepository{m},
+		RawRecords:     &MemoryRawRecordRepository{m},
+		Contracts:      &MemoryContractRepository{m},
+		Backup:         &MemoryBackupRepository{m},
+		Changes:        &MemoryChangeLogRepository{m},
+		SyncRuns:       &MemorySyncRunRepository{m},
+	}
+}
+
+type MemoryPositionRepository struct {
+	m *memoryStore
+}
+
+func (r *MemoryPositionRepository) SavePosition(ctx context.Context, position model.Position) error {
+	_, err := r.SavePositionBatch(ctx, []model.Position{position})
+	return err
+}
+
+// SavePositionBatch upserts by order ID like the SQL backends: an existing
+// row keeps its ID, exchange and account
+func (r *MemoryPositionRepository) SavePositionBatch(ctx context.Context, positions []model.Position) (model.PositionChanges, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	changes := upsertMemoryPositions(r.m.data, positions)
+	r.m.data.record(positionChangeEntries(changes)...)
+	return changes, nil
+}
+
+func upsertMemoryPositions(d *memoryData, positions []model.Position) model.PositionChanges {
+	var changes model.PositionChanges
+	index := make(map[string]int, len(d.positions))
+	for i, p := range d.positions {
+		index[p.OrderID] = i
+	}
+
+	for _, p := range positions {
+		// Microsecond precision, as stored by PostgreSQL and kept by cursors
+		p.UpdatedAt = p.UpdatedAt.UTC().Truncate(time.Microsecond)
+		p = withInstrument(p)
+		p.Account = accountOrDefault(p.Account)
+		if i, ok := index[p.OrderID]; ok {
+			existing := &d.positions[i]
+			if existing.Symbol == p.Symbol && existing.BaseAsset == p.BaseAsset && existing.QuoteAsset == p.QuoteAsset &&
+				existing.ContractType == p.ContractType && existing.Instrument == p.Instrument &&
+				existing.Volume == p.Volume && existing.Leverage == p.Leverage && existing.ClosedPnl == p.ClosedPnl &&
+				existing.Side == p.Side && existing.UpdatedAt.Equal(p.UpdatedAt) {
+				continue
+			}
+			p.ID = existing.ID
+			p.Exchange = existing.Exchange
+			p.Account = existing.Account
+			*existing = p
+			changes.Changed = append(changes.Changed, p)
+			continue
+		}
+		p.ID = d.id()
+		index[p.OrderID] = len(d.positions)
+		d.positions = append(d.positions, p)
+		changes.Added = append(changes.Added, p)
+	}
+	return changes
+}
+
+func (r *MemoryPositionRepository) GetAllPositions(ctx context.Context) ([]model.Position, error) {
+	positions, _, err := r.QueryPositions(ctx, model.PositionQuery{})
+	return positions, err
+}
+
+func (r *MemoryPositionRepository) GetPositionsByExchange(ctx context.Context, exchange string) ([]model.Position, error) {
+	positions, _, err := r.QueryPositions(ctx, model.PositionQuery{
+		PositionFilter: model.PositionFilter{Exchange: exchange},
+	})
+	return positions, err
+}
+
+func (r *MemoryPositionRepository) GetPositionsByDateRange(ctx context.Context, start, end time.Time) ([]model.Position, error) {
+	positions, _, err := r.QueryPositions(ctx, model.PositionQuery{
+		PositionFilter: model.PositionFilter{From: start, To: end},
+	})
+	return positions, err
+}
+
+func (r *MemoryPositionRepository) QueryPositions(ctx context.Context, q model.PositionQuery) ([]model.Position, string, error) {
+	positions, err := r.query(q)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var nextCursor string
+	if q.Limit > 0 && len(positions) > q.Limit {
+		positions = positions[:q.Limit]
+		last := positions[len(positions)-1]
+		nextCursor = encodePositionCursor(q.SortBy, last, floatSortValue(q.SortBy, last))
+	}
+
+	return positions, nextCursor, nil
+}
+
+func (r *MemoryPositionRepository) StreamPositions(ctx context.Context, q model.PositionQuery, fn func(model.Position) error) error {
+	positions, err := r.query(q)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range positions {
+		if err := fn(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// query mirrors buildPositionQuery: filter, keyset cursor, ordering by the
+// sort column then ID, and one extra row past the limit
+func (r *MemoryPositionRepository) query(q model.PositionQuery) ([]model.Position, error) {
+	column, ok := positionSortColumns[q.SortBy]
+	if !ok {
+		return nil, fmt.Errorf("invalid sort column: %s", q.SortBy)
+	}
+
+	var after *model.Position
+	if q.Cursor != "" {
+		c, err := decodePositionCursor(q.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		if positionSortColumns[c.SortBy] != column {
+			return nil, ErrInvalidCursor
+		}
+		after = &model.Position{ID: c.ID, UpdatedAt: time.UnixMicro(c.Date).UTC()}
+		if column != "date" {
+			value, err := c.Value.Float64()
+			if err != nil {
+				return nil, ErrInvalidCursor
+			}
+			after.ClosedPnl, after.Volume = value, value
+		}
+	}
+
+	r.m.mu.RLock()
+	positions, err := filterMemoryPositions(r.m.data.positions, q.PositionFilter)
+	r.m.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	// less orders ascending; descending queries flip it
+	less := func(a, b model.Position) bool {
+		switch column {
+		case "closed_pnl":
+			if a.ClosedPnl != b.ClosedPnl {
+				return a.ClosedPnl < b.ClosedPnl
+			}
+		case "volume":
+			if a.Volume != b.Volume {
+				return a.Volume < b.Volume
+			}
+		default:
+			if !a.UpdatedAt.Equal(b.UpdatedAt) {
+				return a.UpdatedAt.Before(b.UpdatedAt)
+			}
+		}
+		return a.ID < b.ID
+	}
+	before := func(a, b model.Position) bool {
+		if q.Asc {
+			return less(a, b)
+		}
+		return less(b, a)
+	}
+
+	if after != nil {
+		kept := positions[:0]
+		for _, p := range positions {
+			if before(*after, p) {
+				kept = append(kept, p)
+			}
+		}
+		positions = kept
+	}
+
+	sort.Slice(positions, func(i, j int) bool { return before(positions[i], positions[j]) })
+
+	if q.Limit > 0 && len(positions) > q.Limit+1 {
+		positions = positions[:q.Limit+1]
+	}
+	return positions, nil
+}
+
+// filterMemoryPositions returns a copy of the positions matching f
+func filterMemoryPositions(positions []model.Position, f model.PositionFilter) ([]model.Position, error) {
+	switch f.PnlSign {
+	case "", "positive", "negative":
+	default:
+		return nil, fmt.Errorf("invalid pnl sign: %s", f.PnlSign)
+	}
+
+	var matched []model.Position
+	for _, p := range positions {
+		switch {
+		case f.Exchange != "" && p.Exchange != f.Exchange,
+			f.Account != "" && p.Account != f.Account,
+			f.Symbol != "" && p.Symbol != f.Symbol,
+			f.Instrument != "" && p.Instrument != f.Instrument,
+			f.Side != "" && p.Side != f.Side,
+			!f.From.IsZero() && p.UpdatedAt.Before(f.From),
+			!f.To.IsZero() && !p.UpdatedAt.Before(f.To),
+			f.PnlSign == "positive" && p.ClosedPnl <= 0,
+			f.PnlSign == "negative" && p.ClosedPnl >= 0:
+			continue
+		}
+		matched = append(matched, p)
+	}
+	return matched, nil
+}
+
+func (r *MemoryPositionRepository) GetPositionByID(ctx context.Context, id int) (*model.Position, error) {
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+
+	for _, p := range r.m.data.positions {
+		if p.ID == id {
+			return &p, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *MemoryPositionRepository) GetPositionByOrderID(ctx context.Context, orderID string) (*model.Position, error) {
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+
+	for _, p := range r.m.data.positions {
+		if p.OrderID == orderID {
+			return &p, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *MemoryPositionRepository) GetPositionsByOrderIDs(ctx context.Context, orderIDs []string) (map[string]model.Position, error) {
+	wanted := make(map[string]bool, len(orderIDs))
+	for _, id := range orderIDs {
+		wanted[id] = true
+	}
+
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+
+	positions := make(map[string]model.Position)
+	for _, p := range r.m.data.positions {
+		if wanted[p.OrderID] {
+			positions[p.OrderID] = p
+		}
+	}
+	return positions, nil
+}
+
+func (r *MemoryPositionRepository) SumClosedPnl(ctx context.Context, filter model.PositionFilter) (float64, error) {
+	r.m.mu.RLock()
+	positions, err := filterMemoryPositions(r.m.data.positions, filter)
+	r.m.mu.RUnlock()
+	if err != nil {
+		return 0, err
+	}
+
+	var total float64
+	for _, p := range positions {
+		total += p.ClosedPnl
+	}
+	return total, nil
+}
+
+// AggregateMonthly groups by UTC calendar month and exchange, newest month first
+func (r *MemoryPositionRepository) AggregateMonthly(ctx context.Context, filter model.PositionFilter) ([]model.MonthlyIncome, error) {
+	r.m.mu.RLock()
+	positions, err := filterMemoryPositions(r.m.data.positions, filter)
+	r.m.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	type monthKey struct {
+		month    time.Time
+		exchange string
+	}
+	groups := make(map[monthKey]*model.MonthlyIncome)
+	var incomes []*model.MonthlyIncome
+	for _, p := range positions {
+		t := p.UpdatedAt.UTC()
+		key := monthKey{time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC), p.Exchange}
+		i, ok := groups[key]
+		if !ok {
+			i = &model.MonthlyIncome{Exchange: key.exchange, CreatedAt: key.month}
+			groups[key] = i
+			incomes = append(incomes, i)
+		}
+		i.Amount += p.Volume
+		i.PNL += p.ClosedPnl
+	}
+
+	sort.Slice(incomes, func(a, b int) bool {
+		if !incomes[a].CreatedAt.Equal(incomes[b].CreatedAt) {
+			return incomes[a].CreatedAt.After(incomes[b].CreatedAt)
+		}
+		return incomes[a].Exchange < incomes[b].Exchange
+	})
+
+	result := make([]model.MonthlyIncome, len(incomes))
+	for i, income := range incomes {
+		result[i] = *income
+	}
+	return result, nil
+}
+
+// GetPositionStats matches the SQL aggregate: without grouping there is
+// always exactly one row, even when nothing matches
+func (r *MemoryPositionRepository) GetPositionStats(ctx context.Context, filter model.PositionFilter, groupBy string) ([]model.PositionStats, error) {
+	column, ok := positionGroupColumns[groupBy]
+	if !ok {
+		return nil, fmt.Errorf("invalid group column: %s", groupBy)
+	}
+
+	r.m.mu.RLock()
+	positions, err := filterMemoryPositions(r.m.data.positions, filter)
+	r.m.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make(map[string]*model.PositionStats)
+	var keys []string
+	if column == "" {
+		groups[""] = &model.PositionStats{}
+		keys = append(keys, "")
+	}
+	for _, p := range positions {
+		var key string
+		switch column {
+		case "exchange":
+			key = p.Exchange
+		case "symbol":
+			key = p.Symbol
+		case "instrument":
+			key = p.Instrument
+		}
+
+		s, ok := groups[key]
+		if !ok {
+			s = &model.PositionStats{}
+			switch column {
+			case "exchange":
+				s.Exchange = key
+			case "symbol":
+				s.Symbol = key
+			case "instrument":
+				s.Instrument = key
+			}
+			groups[key] = s
+			keys = append(keys, key)
+		}
+		s.Trades++
+		if p.ClosedPnl > 0 {
+			s.Wins++
+		} else if p.ClosedPnl < 0 {
+			s.Losses++
+		}
+		s.TotalPnl += p.ClosedPnl
+		s.TotalVolume += p.Volume
+	}
+
+	sort.Strings(keys)
+	stats := make([]model.PositionStats, len(keys))
+	for i, key := range keys {
+		stats[i] = *groups[key]
+	}
+	return stats, nil
+}
+
+func (r *MemoryPositionRepository) DeletePosition(ctx context.Context, id int) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	d := r.m.data
+	for i, p := range d.positions {
+		if p.ID == id {
+			d.positions = append(d.positions[:i], d.positions[i+1:]...)
+			d.record(changeEntry{model.ChangeEntityPosition, model.ChangeDelete, strconv.Itoa(id), nil})
+			break
+		}
+	}
+	return nil
+}
+
+// NormalizeInstruments fills in the instrument of positions that lack one
+func (r *MemoryPositionRepository) NormalizeInstruments(ctx context.Context) (int64, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	var updated int64
+	for i, p := range r.m.data.positions {
+		if p.Instrument != "" {
+			continue
+		}
+		if p = withInstrument(p); p.Instrument != "" {
+			r.m.data.positions[i] = p
+			updated++
+		}
+	}
+	if updated > 0 {
+		r.m.data.record(changeEntry{model.ChangeEntityPosition, model.ChangeReset, "", nil})
+	}
+	return updated, nil
+}
+
+type MemoryWithdrawalRepository struct {
+	m *memoryStore
+}
+
+func (r *MemoryWithdrawalRepository) SaveWithdrawal(ctx context.Context, withdrawal model.Withdrawal) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	withdrawal.ID = r.m.data.id()
+	withdrawal.CreatedAt = withdrawal.CreatedAt.UTC()
+	r.m.data.withdrawals = append(r.m.data.withdrawals, withdrawal)
+	r.m.data.record(changeEntry{model.ChangeEntityWithdrawal, model.ChangeInsert, strconv.Itoa(withdrawal.ID), withdrawal})
+	return nil
+}
+
+func (r *MemoryWithdrawalRepository) GetAllWithdrawals(ctx context.Context) ([]model.Withdrawal, error) {
+	return r.filter(func(model.Withdrawal) bool { return true }), nil
+}
+
+func (r *MemoryWithdrawalRepository) GetWithdrawalsByExchange(ctx context.Context, exchange string) ([]model.Withdrawal, error) {
+	return r.filter(func(w model.Withdrawal) bool { return w.Exchange == exchange }), nil
+}
+
+// GetWithdrawalsByDateRange includes both bounds, like BETWEEN
+func (r *MemoryWithdrawalRepository) GetWithdrawalsByDateRange(ctx context.Context, start, end time.Time) ([]model.Withdrawal, error) {
+	return r.filter(func(w model.Withdrawal) bool {
+		return !w.CreatedAt.Before(start) && !w.CreatedAt.After(end)
+	}), nil
+}
+
+func (r *MemoryWithdrawalRepository) StreamWithdrawals(ctx context.Context, filter model.WithdrawalFilter, fn func(model.Withdrawal) error) error {
+	withdrawals := r.filter(func(w model.Withdrawal) bool {
+		return (filter.Exchange == "" || w.Exchange == filter.Exchange) &&
+			(filter.From.IsZero() || !w.CreatedAt.Before(filter.From)) &&
+			(filter.To.IsZero() || w.CreatedAt.Before(filter.To))
+	})
+	for _, w := range withdrawals {
+		if err := fn(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// filter returns matching withdrawals, newest first
+func (r *MemoryWithdrawalRepository) filter(match func(model.Withdrawal) bool) []model.Withdrawal {
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+
+	var withdrawals []model.Withdrawal
+	for _, w := range r.m.data.withdrawals {
+		if match(w) {
+			withdrawals = append(withdrawals, w)
+		}
+	}
+	sort.SliceStable(withdrawals, func(i, j int) bool {
+		return withdrawals[i].CreatedAt.After(withdrawals[j].CreatedAt)
+	})
+	return withdrawals
+}
+
+func (r *MemoryWithdrawalRepository) DeleteWithdrawal(ctx context.Context, id int) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	d := r.m.data
+	for i, w := range d.withdrawals {
+		if w.ID == id {
+			d.withdrawals = append(d.withdrawals[:i], d.withdrawals[i+1:]...)
+			d.record(changeEntry{model.ChangeEntityWithdrawal, model.ChangeDelete, strconv.Itoa(id), nil})
+			break
+		}
+	}
+	return nil
+}
+
+type MemoryMonthlyIncomeRepository struct {
+	m *memoryStore
+}
+
+func (r *MemoryMonthlyIncomeRepository) SaveMonthlyIncome(ctx context.Context, income model.MonthlyIncome) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	income.ID = r.m.data.id()
+	income.CreatedAt = income.CreatedAt.UTC()
+	r.m.data.incomes = append(r.m.data.incomes, income)
+	return nil
+}
+
+func (r *MemoryMonthlyIncomeRepository) GetAllMonthlyIncomes(ctx context.Context) ([]model.MonthlyIncome, error) {
+	return r.filter(func(model.MonthlyIncome) bool { return true }), nil
+}
+
+func (r *MemoryMonthlyIncomeRepository) GetIncomesByExchange(ctx context.Context, exchange string) ([]model.MonthlyIncome, error) {
+	return r.filter(func(i model.MonthlyIncome) bool { return i.Exchange == exchange }), nil
+}
+
+func (r *MemoryMonthlyIncomeRepository) GetIncomesByDateRange(ctx context.Context, start, end time.Time) ([]model.MonthlyIncome, error) {
+	return r.filter(func(i model.MonthlyIncome) bool {
+		return !i.CreatedAt.Before(start) && !i.CreatedAt.After(end)
+	}), nil
+}
+
+func (r *MemoryMonthlyIncomeRepository) filter(match func(model.MonthlyIncome) bool) []model.MonthlyIncome {
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+
+	var incomes []model.MonthlyIncome
+	for _, i := range r.m.data.incomes {
+		if match(i) {
+			incomes = append(incomes, i)
+		}
+	}
+	sort.SliceStable(incomes, func(a, b int) bool {
+		return incomes[a].CreatedAt.After(incomes[b].CreatedAt)
+	})
+	return incomes
+}
+
+func (r *MemoryMonthlyIncomeRepository) DeleteMonthlyIncome(ctx context.Context, id int) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	d := r.m.data
+	for i, income := range d.incomes {
+		if income.ID == id {
+			d.incomes = append(d.incomes[:i], d.incomes[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+type MemoryAPIKeyRepository struct {
+	m *memoryStore
+}
+
+func (r *MemoryAPIKeyRepository) GetByExchange(ctx context.Context, exchange string) (*model.APIKey, error) {
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+
+	apiKey, ok := r.m.data.apiKeys[exchange]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &apiKey, nil
+}
+
+func (r *MemoryAPIKeyRepository) Upsert(ctx context.Context, apiKey *model.APIKey) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	now := time.Now().UTC()
+	stored, ok := r.m.data.apiKeys[apiKey.Exchange]
+	if !ok {
+		stored = model.APIKey{ID: r.m.data.id(), Exchange: apiKey.Exchange, CreatedAt: now}
+	}
+	stored.APIKey = apiKey.APIKey
+	stored.APISecret = apiKey.APISecret
+	stored.IsActive = true
+	stored.UpdatedAt = now
+	r.m.data.apiKeys[apiKey.Exchange] = stored
+	return nil
+}
+
+func (r *MemoryAPIKeyRepository) GetAll(ctx context.Context) ([]model.APIKey, error) {
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+
+	var apiKeys []model.APIKey
+	for _, k := range r.m.data.apiKeys {
+		apiKeys = append(apiKeys, k)
+	}
+	sort.Slice(apiKeys, func(i, j int) bool { return apiKeys[i].Exchange < apiKeys[j].Exchange })
+	return apiKeys, nil
+}
+
+type MemoryImportProfileRepository struct {
+	m *memoryStore
+}
+
+func (r *MemoryImportProfileRepository) GetByName(ctx context.Context, name string) (*model.ImportProfile, error) {
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+
+	p, ok := r.m.data.importProfiles[name]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &p, nil
+}
+
+func (r *MemoryImportProfileRepository) GetAll(ctx context.Context) ([]model.ImportProfile, error) {
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+
+	var profiles []model.ImportProfile
+	for _, p := range r.m.data.importProfiles {
+		profiles = append(profiles, p)
+	}
+	sort.Slice(profiles, func(i, j int) bool { return profiles[i].Name < profiles[j].Name })
+	return profiles, nil
+}
+
+func (r *MemoryImportProfileRepository) Upsert(ctx context.Context, p *model.ImportProfile) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	now := time.Now().UTC()
+	if existing, ok := r.m.data.importProfiles[p.Name]; ok {
+		p.ID, p.CreatedAt = existing.ID, existing.CreatedAt
+	} else {
+		p.ID, p.CreatedAt = r.m.data.id(), now
+	}
+	p.UpdatedAt = now
+	r.m.data.importProfiles[p.Name] = *p
+	return nil
+}
+
+func (r *MemoryImportProfileRepository) Delete(ctx context.Context, name string) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	delete(r.m.data.importProfiles, name)
+	return nil
+}
+
+type MemorySettingsRepository struct {
+	m *memoryStore
+}
+
+func (r *MemorySettingsRepository) Get(ctx context.Context, key string) (*model.Setting, error) {
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+
+	s, ok := r.m.data.settings[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &s, nil
+}
+
+func (r *MemorySettingsRepository) GetAll(ctx context.Context) ([]model.Setting, error) {
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+
+	var settings []model.Setting
+	for _, s := range r.m.data.settings {
+		settings = append(settings, s)
+	}
+	sort.Slice(settings, func(i, j int) bool { return settings[i].Key < settings[j].Key })
+	return settings, nil
+}
+
+func (r *MemorySettingsRepository) Set(ctx context.Context, key, value string) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	op := model.ChangeInsert
+	if _, ok := r.m.data.settings[key]; ok {
+		op = model.ChangeUpdate
+	}
+	setting := model.Setting{Key: key, Value: value, UpdatedAt: time.Now().UTC()}
+	r.m.data.settings[key] = setting
+	r.m.data.record(changeEntry{model.ChangeEntitySetting, op, key, setting})
+	return nil
+}
+
+func (r *MemorySettingsRepository) Delete(ctx context.Context, key string) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	if _, ok := r.m.data.settings[key]; ok {
+		delete(r.m.data.settings, key)
+		r.m.data.record(changeEntry{model.ChangeEntitySetting, model.ChangeDelete, key, nil})
+	}
+	return nil
+}
+
+type MemoryRawRecordRepository struct {
+	m *memoryStore
+}
+
+func (r *MemoryRawRecordRepository) SaveRawRecords(ctx context.Context, records []model.RawExchangeRecord) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	for _, rec := range records {
+		rec.Payload = append([]byte(nil), rec.Payload...)
+		rec.FetchedAt = rec.FetchedAt.UTC()
+		r.m.data.rawRecords[exchangeKey{rec.Exchange, rec.ExternalID}] = rec
+	}
+	return nil
+}
+
+func (r *MemoryRawRecordRepository) StreamRawRecords(ctx context.Context, exchange string, fn func(model.RawExchangeRecord) error) error {
+	r.m.mu.RLock()
+	var records []model.RawExchangeRecord
+	for _, rec := range r.m.data.rawRecords {
+		if exchange == "" || rec.Exchange == exchange {
+			records = append(records, rec)
+		}
+	}
+	r.m.mu.RUnlock()
+
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].Exchange != records[j].Exchange {
+			return records[i].Exchange < records[j].Exchange
+		}
+		return records[i].ExternalID < records[j].ExternalID
+	})
+	for _, rec := range records {
+		if err := fn(rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type MemoryContractRepository struct {
+	m *memoryStore
+}
+
+func (r *MemoryContractRepository) GetContracts(ctx context.Context, exchange string) ([]model.ContractMeta, error) {
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+
+	var contracts []model.ContractMeta
+	for _, c := range r.m.data.contracts {
+		if c.Exchange == exchange {
+			if c.ContractSizeOverride != nil {
+				size := *c.ContractSizeOverride
+				c.ContractSizeOverride = &size
+			}
+			contracts = append(contracts, c)
+		}
+	}
+	sort.Slice(contracts, func(i, j int) bool { return contracts[i].Symbol < contracts[j].Symbol })
+	return contracts, nil
+}
+
+func (r *MemoryContractRepository) SaveContracts(ctx context.Context, contracts []model.ContractMeta) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	now := time.Now().UTC()
+	for _, c := range contracts {
+		key := exchangeKey{c.Exchange, c.Symbol}
+		existing := r.m.data.contracts[key]
+		c.ContractSizeOverride = existing.ContractSizeOverride
+		c.AppliedContractSize = existing.AppliedContractSize
+		c.UpdatedAt = now
+		r.m.data.contracts[key] = c
+	}
+	return nil
+}
+
+func (r *MemoryContractRepository) SetContractOverride(ctx context.Context, exchange, symbol string, size *float64) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	key := exchangeKey{exchange, symbol}
+	c, ok := r.m.data.contracts[key]
+	if !ok {
+		c = model.ContractMeta{Exchange: exchange, Symbol: symbol, UpdatedAt: time.Now().UTC()}
+	}
+	c.ContractSizeOverride = nil
+	if size != nil {
+		v := *size
+		c.ContractSizeOverride = &v
+	}
+	r.m.data.contracts[key] = c
+	return nil
+}
+
+func (r *MemoryContractRepository) SetAppliedContractSize(ctx context.Context, exchange, symbol string, size float64) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	key := exchangeKey{exchange, symbol}
+	if c, ok := r.m.data.contracts[key]; ok {
+		c.AppliedContractSize = size
+		r.m.data.contracts[key] = c
+	}
+	return nil
+}
+
+func (r *MemoryContractRepository) RescaleVolumes(ctx context.Context, exchange, symbol string, from, to float64, dryRun bool) (int, error) {
+	if from <= 0 || to <= 0 {
+		return 0, fmt.Errorf("contract sizes must be positive")
+	}
+
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	var changes model.PositionChanges
+	for i, p := range r.m.data.positions {
+		if p.Exchange != exchange || p.Symbol != symbol {
+			continue
+		}
+		p.Volume = p.Volume / from * to
+		changes.Changed = append(changes.Changed, p)
+		if !dryRun {
+			r.m.data.positions[i] = p
+		}
+	}
+	if dryRun {
+		return len(changes.Changed), nil
+	}
+
+	r.m.data.record(positionChangeEntries(changes)...)
+	key := exchangeKey{exchange, symbol}
+	if c, ok := r.m.data.contracts[key]; ok {
+		c.AppliedContractSize = to
+		r.m.data.contracts[key] = c
+	}
+	return len(changes.Changed), nil
+}
+
+type MemoryBackupRepository struct {
+	m *memoryStore
+}
+
+// MemoryRestore writes into a copy of the data that replaces the live
+// tables on Commit, so readers never see a partial restore
+type MemoryRestore struct {
+	m       *memoryStore
+	data    *memoryData
+	replace bool
+	done    bool
+}
+
+func (r *MemoryBackupRepository) BeginRestore(ctx context.Context, replace, includeKeys bool) (RestoreWriter, error) {
+	r.m.mu.RLock()
+	data := r.m.data.clone()
+	r.m.mu.RUnlock()
+
+	if replace {
+		data.positions = nil
+		data.withdrawals = nil
+		data.incomes = nil
+		data.settings = make(map[string]model.Setting)
+		data.importProfiles = make(map[string]model.ImportProfile)
+		if includeKeys {
+			data.apiKeys = make(map[string]model.APIKey)
+		}
+	}
+
+	return &MemoryRestore{m: r.m, data: data, replace: replace}, nil
+}
+
+func (s *MemoryRestore) AddPosition(ctx context.Context, p model.Position) error {
+	upsertMemoryPositions(s.data, []model.Position{p})
+	return nil
+}
+
+func (s *MemoryRestore) AddWithdrawal(ctx context.Context, w model.Withdrawal) (bool, error) {
+	w.CreatedAt = w.CreatedAt.UTC()
+	if !s.replace {
+		for _, existing := range s.data.withdrawals {
+			if existing.Exchange == w.Exchange && existing.Amount == w.Amount &&
+				existing.Currency == w.Currency && existing.CreatedAt.Equal(w.CreatedAt) {
+				return false, nil
+			}
+		}
+	}
+	w.ID = s.data.id()
+	s.data.withdrawals = append(s.data.withdrawals, w)
+	return true, nil
+}
+
+func (s *MemoryRestore) AddMonthlyIncome(ctx context.Context, i model.MonthlyIncome) (bool, error) {
+	i.CreatedAt = i.CreatedAt.UTC()
+	if !s.replace {
+		for _, existing := range s.data.incomes {
+			if existing.Exchange == i.Exchange && existing.Amount == i.Amount &&
+				existing.PNL == i.PNL && existing.CreatedAt.Equal(i.CreatedAt) {
+				return false, nil
+			}
+		}
+	}
+	i.ID = s.data.id()
+	s.data.incomes = append(s.data.incomes, i)
+	return true, nil
+}
+
+func (s *MemoryRestore) AddSetting(ctx context.Context, setting model.Setting) error {
+	s.data.settings[setting.Key] = setting
+	return nil
+}
+
+func (s *MemoryRestore) AddImportProfile(ctx context.Context, p model.ImportProfile) error {
+	if existing, ok := s.data.importProfiles[p.Name]; ok {
+		p.ID, p.CreatedAt = existing.ID, existing.CreatedAt
+	} else {
+		p.ID = s.data.id()
+	}
+	s.data.importProfiles[p.Name] = p
+	return nil
+}
+
+func (s *MemoryRestore) AddAPIKey(ctx context.Context, k model.APIKey) error {
+	if existing, ok := s.data.apiKeys[k.Exchange]; ok {
+		k.ID, k.CreatedAt = existing.ID, existing.CreatedAt
+	} else {
+		k.ID = s.data.id()
+	}
+	s.data.apiKeys[k.Exchange] = k
+	return nil
+}
+
+func (s *MemoryRestore) Commit(ctx context.Context) error {
+	if s.done {
+		return fmt.Errorf("restore already finished")
+	}
+	s.done = true
+
+	s.m.mu.Lock()
+	// Keep entries logged since the copy was taken
+	s.data.changes = s.m.data.changes
+	s.data.record(changeEntry{op: model.ChangeReset})
+	s.m.data = s.data
+	s.m.mu.Unlock()
+	return nil
+}
+
+func (s *MemoryRestore) Rollback(ctx context.Context) error {
+	s.done = true
+	return nil
+}
+
+type MemoryChangeLogRepository struct {
+	m *memoryStore
+}
+
+func (r *MemoryChangeLogRepository) GetChanges(ctx context.Context, after int64, limit int) ([]model.Change, error) {
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+
+	// IDs are positions in the log
+	changes := r.m.data.changes
+	start := int(min(max(after, 0), int64(len(changes))))
+	end := min(start+limit, len(changes))
+	return append([]model.Change(nil), changes[start:end]...), nil
+}
+
+func (r *MemoryChangeLogRepository) LatestChangeID(ctx context.Context) (int64, error) {
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+
+	return int64(len(r.m.data.changes)), nil
+}
+
+type MemorySyncRunRepository struct {
+	m *memoryStore
+}
+
+func (r *MemorySyncRunRepository) SaveSyncRun(ctx context.Context, run model.SyncRun) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	run.ID = int64(r.m.data.id())
+	run.StartedAt = run.StartedAt.UTC()
+	run.FinishedAt = run.FinishedAt.UTC()
+	r.m.data.syncRuns = append(r.m.data.syncRuns, run)
+	return nil
+}
+
+func (r *MemorySyncRunRepository) GetSyncRuns(ctx context.Context, exchange string, limit int) ([]model.SyncRun, error) {
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+
+	var runs []model.SyncRun
+	for i := len(r.m.data.syncRuns) - 1; i >= 0 && len(runs) < limit; i-- {
+		if run := r.m.data.syncRuns[i]; exchange == "" || run.Exchange == exchange {
+			runs = append(runs, run)
+		}
+	}
+	return runs, nil
+}
+
+func (r *MemorySyncRunRepository) GetSyncStatus(ctx context.Context) ([]model.SyncAccountStatus, error) {
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+
+	// Runs are appended in ID order, like syncStatusQuery reads them
+	index := make(map[exchangeKey]int)
+	var rows []syncStatusRow
+	runs := make(map[int64]model.SyncRun)
+	for _, run := range r.m.data.syncRuns {
+		runs[run.ID] = run
+		key := exchangeKey{run.Exchange, run.Account}
+		i, ok := index[key]
+		if !ok {
+			i = len(rows)
+			index[key] = i
+			rows = append(rows, syncStatusRow{exchange: run.Exchange, account: run.Account})
+		}
+		row := &rows[i]
+		row.lastID = run.ID
+		if run.ErrorClass == "" {
+			row.okID, row.failures, row.firstFailureID = run.ID, 0, 0
+			continue
+		}
+		if row.failures == 0 {
+			row.firstFailureID = run.ID
+		}
+		row.failures++
+	}
+
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].exchange != rows[j].exchange {
+			return rows[i].exchange < rows[j].exchange
+		}
+		return rows[i].account < rows[j].account
+	})
+	return buildSyncStatus(rows, runs), nil
+}
+
+func (r *MemorySyncRunRepository) DeleteSyncRunsBefore(ctx context.Context, t time.Time) (int64, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	kept := r.m.data.syncRuns[:0]
+	for _, run := range r.m.data.syncRuns {
+		if !run.StartedAt.Before(t) {
+			kept = append(kept, run)
+		}
+	}
+	deleted := int64(len(r.m.data.syncRuns) - len(kept))
+	r.m.data.syncRuns = kept
+	return deleted, nil
+}
@@ -0,0 +1,234 @@
+package repository
+
+import (
+	"github.com/Ravierin/BudgetTracker/backend/internal/instrument"
+	"github.com/Ravierin/BudgetTracker/backend/internal/model"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotFound      = errors.New("not found")
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+const positionColumns = `id, order_id, exchange, account, symbol, base_asset, quote_asset, contract_type, instrument, volume, leverage, closed_pnl, side, date`
+
+// positionSortColumns maps API sort keys to SQL columns
+var positionSortColumns = map[string]string{
+	"":          "date",
+	"date":      "date",
+	"closedPnl": "closed_pnl",
+	"volume":    "volume",
+}
+
+// positionGroupColumns maps API group keys to SQL columns for aggregates
+var positionGroupColumns = map[string]string{
+	"":           "",
+	"exchange":   "exchange",
+	"symbol":     "symbol",
+	"instrument": "instrument",
+}
+
+// queryBuilder accumulates WHERE conditions with numbered placeholders,
+// $N by default or ?N for SQLite
+type queryBuilder struct {
+	where  []string
+	args   []interface{}
+	sqlite bool
+}
+
+// arg registers a value and returns its placeholder
+func (b *queryBuilder) arg(v interface{}) string {
+	if b.sqlite {
+		if t, ok := v.(time.Time); ok {
+			v = sqliteTime(t)
+		}
+		b.args = append(b.args, v)
+		return fmt.Sprintf("?%d", len(b.args))
+	}
+	b.args = append(b.args, v)
+	return fmt.Sprintf("$%d", len(b.args))
+}
+
+func (b *queryBuilder) add(cond string, v interface{}) {
+	b.where = append(b.where, fmt.Sprintf(cond, b.arg(v)))
+}
+
+func (b *queryBuilder) whereClause() string {
+	if len(b.where) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(b.where, " AND ")
+}
+
+// applyPositionFilter adds conditions for every non-empty filter field
+func (b *queryBuilder) applyPositionFilter(f model.PositionFilter) error {
+	if f.Exchange != "" {
+		b.add("exchange = %s", f.Exchange)
+	}
+	if f.Account != "" {
+		b.add("account = %s", f.Account)
+	}
+	if f.Symbol != "" {
+		b.add("symbol = %s", f.Symbol)
+	}
+	if f.Instrument != "" {
+		b.add("instrument = %s", f.Instrument)
+	}
+	if f.Side != "" {
+		b.add("side = %s", f.Side)
+	}
+	if !f.From.IsZero() {
+		b.add("date >= %s", f.From)
+	}
+	if !f.To.IsZero() {
+		b.add("date < %s", f.To)
+	}
+
+	switch f.PnlSign {
+	case "":
+	case "positive":
+		b.where = append(b.where, "closed_pnl > 0")
+	case "negative":
+		b.where = append(b.where, "closed_pnl < 0")
+	default:
+		return fmt.Errorf("invalid pnl sign: %s", f.PnlSign)
+	}
+
+	return nil
+}
+
+// withInstrument fills in the canonical instrument of a position that does
+// not carry one yet
+func withInstrument(p model.Position) model.Position {
+	if p.Instrument != "" {
+		return p
+	}
+	i := instrument.Normalize(p.Exchange, p.Symbol)
+	p.BaseAsset = i.Base
+	p.QuoteAsset = i.Quote
+	p.ContractType = i.ContractType
+	p.Instrument = i.Key()
+	return p
+}
+
+// positionCursor is the keyset position of the last row of a page. Value is
+// the sort value of a volume or PnL sort as the exact decimal text the store
+// returned, so paging a DECIMAL column never goes through float64.
+type positionCursor struct {
+	SortBy string      `json:"s"`
+	Date   int64       `json:"d,omitempty"`
+	Value  json.Number `json:"v,omitempty"`
+	ID     int         `json:"i"`
+}
+
+// encodePositionCursor encodes the cursor after p; value is the text of its
+// sort value, ignored when sorting by date
+func encodePositionCursor(sortBy string, p model.Position, value string) string {
+	c := positionCursor{SortBy: sortBy, ID: p.ID}
+	if positionSortColumns[sortBy] == "date" {
+		c.Date = p.UpdatedAt.UnixMicro()
+	} else {
+		c.Value = json.Number(value)
+	}
+
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// floatSortValue is the cursor value of p for stores that keep volume and
+// PnL as float64, whose shortest formatting parses back exactly
+func floatSortValue(sortBy string, p model.Position) string {
+	switch sortBy {
+	case "closedPnl":
+		return strconv.FormatFloat(p.ClosedPnl, 'g', -1, 64)
+	case "volume":
+		return strconv.FormatFloat(p.Volume, 'g', -1, 64)
+	}
+	return ""
+}
+
+func decodePositionCursor(s string) (positionCursor, error) {
+	var c positionCursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return c, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// buildPositionQuery renders a paginated SELECT for the given query
+func buildPositionQuery(q model.PositionQuery) (string, []interface{}, error) {
+	return buildPositionQueryWith(&queryBuilder{}, q)
+}
+
+func buildPositionQueryWith(b *queryBuilder, q model.PositionQuery) (string, []interface{}, error) {
+	column, ok := positionSortColumns[q.SortBy]
+	if !ok {
+		return "", nil, fmt.Errorf("invalid sort column: %s", q.SortBy)
+	}
+
+	if err := b.applyPositionFilter(q.PositionFilter); err != nil {
+		return "", nil, err
+	}
+
+	direction, op := "DESC", "<"
+	if q.Asc {
+		direction, op = "ASC", ">"
+	}
+
+	if q.Cursor != "" {
+		c, err := decodePositionCursor(q.Cursor)
+		if err != nil {
+			return "", nil, err
+		}
+		if positionSortColumns[c.SortBy] != column {
+			return "", nil, ErrInvalidCursor
+		}
+
+		var value string
+		switch {
+		case column == "date":
+			value = b.arg(time.UnixMicro(c.Date).UTC())
+		case b.sqlite:
+			// REAL columns compare exactly against the float64 they hold
+			f, err := c.Value.Float64()
+			if err != nil {
+				return "", nil, ErrInvalidCursor
+			}
+			value = b.arg(f)
+		default:
+			// Decoding already rejected anything but a JSON number
+			if c.Value == "" {
+				return "", nil, ErrInvalidCursor
+			}
+			value = b.arg(c.Value.String()) + "::numeric"
+		}
+		b.where = append(b.where, fmt.Sprintf("(%s, id) %s (%s, %s)", column, op, value, b.arg(c.ID)))
+	}
+
+	columns := positionColumns
+	if column != "date" && !b.sqlite {
+		// The exact text of the sort value, for the next page's cursor
+		columns += ", " + column + "::text"
+	}
+
+	query := "SELECT " + columns + " FROM position" + b.whereClause() +
+		fmt.Sprintf(" ORDER BY %s %s, id %s", column, direction, direction)
+
+	if q.Limit > 0 {
+		// Fetch one extra row to know whether another page exists
+		query += " LIMIT " + b.arg(q.Limit+1)
+	}
+
+	return query, b.args, nil
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"github.com/Ravierin/BudgetTracker/backend/internal/model"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValueCursorKeepsDecimalText(t *testing.T) {
+	// More digits than a float64 holds
+	const exact = "12345678901.12345678"
+	p := model.Position{ID: 42, ClosedPnl: 12345678901.12345678}
+	cursor := encodePositionCursor("closedPnl", p, exact)
+
+	query, args, err := buildPositionQuery(model.PositionQuery{SortBy: "closedPnl", Cursor: cursor, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(query, "SELECT "+positionColumns+", closed_pnl::text FROM") {
+		t.Errorf("query %q does not select the sort value as text", query)
+	}
+	if !strings.Contains(query, "(closed_pnl, id) < ($1::numeric, $2)") {
+		t.Errorf("query %q does not compare the cursor as numeric", query)
+	}
+	if args[0] != exact || args[1] != 42 {
+		t.Errorf("args %v, want %q and 42", args, exact)
+	}
+
+	query, args, err = buildPositionQueryWith(&queryBuilder{sqlite: true}, model.PositionQuery{SortBy: "closedPnl", Asc: true, Cursor: cursor})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(query, "::") || !strings.Contains(query, "(closed_pnl, id) > (?1, ?2)") {
+		t.Errorf("SQLite query %q", query)
+	}
+	if args[0] != 12345678901.12345678 {
+		t.Errorf("SQLite cursor arg %v, want the float value", args[0])
+	}
+}
+
+func TestDateCursor(t *testing.T) {
+	date := time.Date(2025, 3, 10, 12, 0, 0, 123456000, time.UTC)
+	cursor := encodePositionCursor("date", model.Position{ID: 7, UpdatedAt: date}, "")
+
+	query, args, err := buildPositionQuery(model.PositionQuery{Cursor: cursor})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(query, "::text") || !strings.Contains(query, "(date, id) < ($1, $2)") {
+		t.Errorf("query %q", query)
+	}
+	if got, ok := args[0].(time.Time); !ok || !got.Equal(date) {
+		t.Errorf("cursor date %v, want %v", args[0], date)
+	}
+}
+
+func TestInvalidCursors(t *testing.T) {
+	encode := func(json string) string { return base64.RawURLEncoding.EncodeToString([]byte(json)) }
+	volume := encodePositionCursor("volume", model.Position{ID: 1}, "10.5")
+
+	tests := []struct {
+		name string
+		q    model.PositionQuery
+	}{
+		{"not base64", model.PositionQuery{Cursor: "not a cursor!"}},
+		{"not JSON", model.PositionQuery{Cursor: encode("nope")}},
+		{"value is not a number", model.PositionQuery{SortBy: "volume", Cursor: encode(`{"s":"volume","v":"1; DROP TABLE position","i":1}`)}},
+		{"value missing", model.PositionQuery{SortBy: "volume", Cursor: encode(`{"s":"volume","i":1}`)}},
+		{"other sort column", model.PositionQuery{SortBy: "closedPnl", Cursor: volume}},
+	}
+	for _, tt := range tests {
+		if _, _, err := buildPositionQuery(tt.q); err != ErrInvalidCursor {
+			t.Errorf("%s: err = %v, want ErrInvalidCursor", tt.name, err)
+		}
+	}
+
+	// Cursors issued before values were kept as text still work
+	if _, _, err := buildPositionQuery(model.PositionQuery{SortBy: "volume", Cursor: encode(`{"s":"volume","v":10.5,"i":1}`)}); err != nil {
+		t.Errorf("numeric cursor value: %v", err)
+	}
+}
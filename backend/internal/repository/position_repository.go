@@ -4,6 +4,9 @@ import (
 	"github.com/Ravierin/BudgetTracker/backend/internal/model"
 	"github.com/Ravierin/BudgetTracker/backend/pkg/database"
 	"context"
+	"errors"
+	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/jackc/pgx/v5"
@@ -17,61 +20,81 @@ func NewPositionRepository(db *database.Database) *PositionRepository {
 	return &PositionRepository{db: db}
 }
 
-func (r *PositionRepository) SavePosition(ctx context.Context, position model.Position) error {
-	query := `
-		INSERT INTO position (
-			order_id, exchange, symbol, volume,
-			leverage, closed_pnl, side, date
-		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8
-		)
-		ON CONFLICT (order_id) DO UPDATE SET
-			volume = EXCLUDED.volume,
-			leverage = EXCLUDED.leverage,
-			closed_pnl = EXCLUDED.closed_pnl,
-			side = EXCLUDED.side,
-			date = EXCLUDED.date,
-			updated_at = NOW()
-	`
-
-	_, err := r.db.Pool.Exec(ctx, query,
-		position.OrderID,
-		position.Exchange,
-		position.Symbol,
-		position.Volume,
-		position.Leverage,
-		position.ClosedPnl,
-		position.Side,
-		position.UpdatedAt,
+const upsertPositionQuery = `
+	INSERT INTO position (
+		order_id, exchange, account, symbol, base_asset,
+		quote_asset, contract_type, instrument, volume,
+		leverage, closed_pnl, side, date
+	) VALUES (
+		$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13
 	)
+	ON CONFLICT (order_id) DO UPDATE SET
+		symbol = EXCLUDED.symbol,
+		base_asset = EXCLUDED.base_asset,
+		quote_asset = EXCLUDED.quote_asset,
+		contract_type = EXCLUDED.contract_type,
+		instrument = EXCLUDED.instrument,
+		volume = EXCLUDED.volume,
+		leverage = EXCLUDED.leverage,
+		closed_pnl = EXCLUDED.closed_pnl,
+		side = EXCLUDED.side,
+		date = EXCLUDED.date,
+		updated_at = NOW()
+	WHERE (position.symbol, position.base_asset, position.quote_asset, position.contract_type, position.instrument,
+		position.volume, position.leverage, position.closed_pnl, position.side, position.date)
+		IS DISTINCT FROM (EXCLUDED.symbol, EXCLUDED.base_asset, EXCLUDED.quote_asset, EXCLUDED.contract_type, EXCLUDED.instrument,
+		EXCLUDED.volume, EXCLUDED.leverage, EXCLUDED.closed_pnl, EXCLUDED.side, EXCLUDED.date)
+	RETURNING id, xmax = 0
+`
 
+func (r *PositionRepository) SavePosition(ctx context.Context, position model.Position) error {
+	_, err := r.SavePositionBatch(ctx, []model.Position{position})
 	return err
 }
 
-func (r *PositionRepository) SavePositionBatch(ctx context.Context, positions []model.Position) error {
-	batch := &pgx.Batch{}
+// SavePositionBatch upserts positions and refreshes the daily rollup rows of
+// every day/symbol touched, both before and after the update, in one
+// transaction. A conflicting row is only rewritten when a value differs, and
+// xmax tells the rows it inserted from those it updated.
+func (r *PositionRepository) SavePositionBatch(ctx context.Context, positions []model.Position) (model.PositionChanges, error) {
+	var changes model.PositionChanges
+	if len(positions) == 0 {
+		return changes, nil
+	}
 
-	query := `
-		INSERT INTO position (
-			order_id, exchange, symbol, volume,
-			leverage, closed_pnl, side, date
-		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8
-		)
-		ON CONFLICT (order_id) DO UPDATE SET
-			volume = EXCLUDED.volume,
-			leverage = EXCLUDED.leverage,
-			closed_pnl = EXCLUDED.closed_pnl,
-			side = EXCLUDED.side,
-			date = EXCLUDED.date,
-			updated_at = NOW()
-	`
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return changes, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockRollup(ctx, tx); err != nil {
+		return changes, err
+	}
 
+	orderIDs := make([]string, len(positions))
+	for i, p := range positions {
+		orderIDs[i] = p.OrderID
+	}
+
+	// Keys of the existing rows, in case an update moves a position to another day
+	keys, err := rollupKeysForOrders(ctx, tx, orderIDs)
+	if err != nil {
+		return changes, err
+	}
+
+	batch := &pgx.Batch{}
 	for _, p := range positions {
-		batch.Queue(query,
+		p = withInstrument(p)
+		batch.Queue(upsertPositionQuery,
 			p.OrderID,
 			p.Exchange,
+			accountOrDefault(p.Account),
 			p.Symbol,
+			p.BaseAsset,
+			p.QuoteAsset,
+			p.ContractType,
+			p.Instrument,
 			p.Volume,
 			p.Leverage,
 			p.ClosedPnl,
@@ -80,160 +103,398 @@ func (r *PositionRepository) SavePositionBatch(ctx context.Context, positions []
 		)
 	}
 
-	br := r.db.Pool.SendBatch(ctx, batch)
-	defer br.Close()
+	br := tx.SendBatch(ctx, batch)
+	for _, p := range positions {
+		p = withInstrument(p)
+		p.Account = accountOrDefault(p.Account)
+		var inserted bool
+		err := br.QueryRow().Scan(&p.ID, &inserted)
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			// Stored with the same values
+		case err != nil:
+			br.Close()
+			return changes, err
+		case inserted:
+			changes.Added = append(changes.Added, p)
+		default:
+			changes.Changed = append(changes.Changed, p)
+		}
+	}
+	if err := br.Close(); err != nil {
+		return changes, err
+	}
+
+	newKeys, err := rollupKeysForOrders(ctx, tx, orderIDs)
+	if err != nil {
+		return changes, err
+	}
+
+	if err := refreshRollup(ctx, tx, append(keys, newKeys...)); err != nil {
+		return changes, err
+	}
+
+	if err := recordChanges(ctx, tx, positionChangeEntries(changes)); err != nil {
+		return changes, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return model.PositionChanges{}, err
+	}
+	return changes, nil
+}
+
+func (r *PositionRepository) GetAllPositions(ctx context.Context) ([]model.Position, error) {
+	positions, _, err := r.QueryPositions(ctx, model.PositionQuery{})
+	return positions, err
+}
 
-	for i := 0; i < batch.Len(); i++ {
-		if _, err := br.Exec(); err != nil {
+func (r *PositionRepository) GetPositionsByExchange(ctx context.Context, exchange string) ([]model.Position, error) {
+	positions, _, err := r.QueryPositions(ctx, model.PositionQuery{
+		PositionFilter: model.PositionFilter{Exchange: exchange},
+	})
+	return positions, err
+}
+
+func (r *PositionRepository) GetPositionsByDateRange(ctx context.Context, start, end time.Time) ([]model.Position, error) {
+	positions, _, err := r.QueryPositions(ctx, model.PositionQuery{
+		PositionFilter: model.PositionFilter{From: start, To: end},
+	})
+	return positions, err
+}
+
+// QueryPositions returns one page of positions matching the query and the
+// cursor of the next page, which is empty when there are no more rows
+func (r *PositionRepository) QueryPositions(ctx context.Context, q model.PositionQuery) ([]model.Position, string, error) {
+	var positions []model.Position
+	var values []string
+	err := r.streamPositions(ctx, q, func(p model.Position, value string) error {
+		positions = append(positions, p)
+		values = append(values, value)
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	var nextCursor string
+	if q.Limit > 0 && len(positions) > q.Limit {
+		positions = positions[:q.Limit]
+		nextCursor = encodePositionCursor(q.SortBy, positions[q.Limit-1], values[q.Limit-1])
+	}
+
+	return positions, nextCursor, nil
+}
+
+// StreamPositions calls fn for every row of the query without buffering the
+// result set. With a limit, one extra row is passed to detect the next page.
+func (r *PositionRepository) StreamPositions(ctx context.Context, q model.PositionQuery, fn func(model.Position) error) error {
+	return r.streamPositions(ctx, q, func(p model.Position, _ string) error {
+		return fn(p)
+	})
+}
+
+// streamPositions also passes the decimal text of each row's volume or PnL
+// when the query sorts by it, for cursors
+func (r *PositionRepository) streamPositions(ctx context.Context, q model.PositionQuery, fn func(model.Position, string) error) error {
+	query, args, err := buildPositionQuery(q)
+	if err != nil {
+		return err
+	}
+	valueSort := positionSortColumns[q.SortBy] != "date"
+
+	rows, err := r.db.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p model.Position
+		var value string
+		if valueSort {
+			p, err = scanPosition(rows, &value)
+		} else {
+			p, err = scanPosition(rows)
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(p, value); err != nil {
 			return err
 		}
 	}
 
-	return nil
+	return rows.Err()
 }
 
-func (r *PositionRepository) GetAllPositions(ctx context.Context) ([]model.Position, error) {
+func (r *PositionRepository) GetPositionByID(ctx context.Context, id int) (*model.Position, error) {
+	query := `SELECT ` + positionColumns + ` FROM position WHERE id = $1`
+
+	p, err := scanPosition(r.db.Pool.QueryRow(ctx, query, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+// SumClosedPnl returns the total closed PnL of positions matching the filter
+func (r *PositionRepository) SumClosedPnl(ctx context.Context, filter model.PositionFilter) (float64, error) {
+	b := &queryBuilder{}
+	if err := b.applyPositionFilter(filter); err != nil {
+		return 0, err
+	}
+
+	var total float64
+	query := `SELECT COALESCE(SUM(closed_pnl), 0) FROM position` + b.whereClause()
+	err := r.db.Pool.QueryRow(ctx, query, b.args...).Scan(&total)
+	return total, err
+}
+
+// AggregateMonthly sums PnL and volume per calendar month and exchange
+func (r *PositionRepository) AggregateMonthly(ctx context.Context, filter model.PositionFilter) ([]model.MonthlyIncome, error) {
+	b := &queryBuilder{}
+	if err := b.applyPositionFilter(filter); err != nil {
+		return nil, err
+	}
+
 	query := `
-		SELECT id, order_id, exchange, symbol, volume,
-		       leverage, closed_pnl, side, date
-		FROM position
-		ORDER BY date DESC
+		SELECT date_trunc('month', date) AS month, exchange,
+		       COALESCE(SUM(volume), 0), COALESCE(SUM(closed_pnl), 0)
+		FROM position` + b.whereClause() + `
+		GROUP BY month, exchange
+		ORDER BY month DESC, exchange
 	`
 
-	rows, err := r.db.Pool.Query(ctx, query)
+	rows, err := r.db.Pool.Query(ctx, query, b.args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var positions []model.Position
+	var incomes []model.MonthlyIncome
 	for rows.Next() {
-		var p model.Position
-		err := rows.Scan(
-			&p.ID,
-			&p.OrderID,
-			&p.Exchange,
-			&p.Symbol,
-			&p.Volume,
-			&p.Leverage,
-			&p.ClosedPnl,
-			&p.Side,
-			&p.UpdatedAt,
-		)
-		if err != nil {
+		var i model.MonthlyIncome
+		if err := rows.Scan(&i.CreatedAt, &i.Exchange, &i.Amount, &i.PNL); err != nil {
 			return nil, err
 		}
-		positions = append(positions, p)
+		incomes = append(incomes, i)
 	}
-
-	return positions, rows.Err()
+	return incomes, rows.Err()
 }
 
-func (r *PositionRepository) GetPositionsByExchange(ctx context.Context, exchange string) ([]model.Position, error) {
+// GetPositionStats computes trade counts and totals, optionally grouped by
+// "exchange", "symbol" or "instrument"
+func (r *PositionRepository) GetPositionStats(ctx context.Context, filter model.PositionFilter, groupBy string) ([]model.PositionStats, error) {
+	column, ok := positionGroupColumns[groupBy]
+	if !ok {
+		return nil, fmt.Errorf("invalid group column: %s", groupBy)
+	}
+
+	b := &queryBuilder{}
+	if err := b.applyPositionFilter(filter); err != nil {
+		return nil, err
+	}
+
+	selectKey, groupClause := "''", ""
+	if column != "" {
+		selectKey = column
+		groupClause = " GROUP BY " + column + " ORDER BY " + column
+	}
+
 	query := `
-		SELECT id, order_id, exchange, symbol, volume,
-		       leverage, closed_pnl, side, date
-		FROM position
-		WHERE exchange = $1
-		ORDER BY date DESC
-	`
+		SELECT ` + selectKey + `, COUNT(*),
+		       COUNT(*) FILTER (WHERE closed_pnl > 0),
+		       COUNT(*) FILTER (WHERE closed_pnl < 0),
+		       COALESCE(SUM(closed_pnl), 0), COALESCE(SUM(volume), 0)
+		FROM position` + b.whereClause() + groupClause
 
-	rows, err := r.db.Pool.Query(ctx, query, exchange)
+	rows, err := r.db.Pool.Query(ctx, query, b.args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var positions []model.Position
+	var stats []model.PositionStats
 	for rows.Next() {
-		var p model.Position
-		err := rows.Scan(
-			&p.ID,
-			&p.OrderID,
-			&p.Exchange,
-			&p.Symbol,
-			&p.Volume,
-			&p.Leverage,
-			&p.ClosedPnl,
-			&p.Side,
-			&p.UpdatedAt,
-		)
-		if err != nil {
+		var key string
+		var s model.PositionStats
+		if err := rows.Scan(&key, &s.Trades, &s.Wins, &s.Losses, &s.TotalPnl, &s.TotalVolume); err != nil {
 			return nil, err
 		}
-		positions = append(positions, p)
+		switch column {
+		case "exchange":
+			s.Exchange = key
+		case "symbol":
+			s.Symbol = key
+		case "instrument":
+			s.Instrument = key
+		}
+		stats = append(stats, s)
 	}
+	return stats, rows.Err()
+}
 
-	return positions, rows.Err()
+func (r *PositionRepository) GetPositionByOrderID(ctx context.Context, orderID string) (*model.Position, error) {
+	query := `SELECT ` + positionColumns + ` FROM position WHERE order_id = $1`
+
+	p, err := scanPosition(r.db.Pool.QueryRow(ctx, query, orderID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &p, nil
 }
 
-func (r *PositionRepository) GetPositionsByDateRange(ctx context.Context, start, end time.Time) ([]model.Position, error) {
-	query := `
-		SELECT id, order_id, exchange, symbol, volume,
-		       leverage, closed_pnl, side, date
-		FROM position
-		WHERE date BETWEEN $1 AND $2
-		ORDER BY date DESC
-	`
+// GetPositionsByOrderIDs returns the stored positions among the given order IDs, keyed by order ID
+func (r *PositionRepository) GetPositionsByOrderIDs(ctx context.Context, orderIDs []string) (map[string]model.Position, error) {
+	query := `SELECT ` + positionColumns + ` FROM position WHERE order_id = ANY($1)`
 
-	rows, err := r.db.Pool.Query(ctx, query, start, end)
+	rows, err := r.db.Pool.Query(ctx, query, orderIDs)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var positions []model.Position
+	positions := make(map[string]model.Position)
 	for rows.Next() {
-		var p model.Position
-		err := rows.Scan(
-			&p.ID,
-			&p.OrderID,
-			&p.Exchange,
-			&p.Symbol,
-			&p.Volume,
-			&p.Leverage,
-			&p.ClosedPnl,
-			&p.Side,
-			&p.UpdatedAt,
-		)
+		p, err := scanPosition(rows)
 		if err != nil {
 			return nil, err
 		}
-		positions = append(positions, p)
+		positions[p.OrderID] = p
 	}
-
 	return positions, rows.Err()
 }
 
-func (r *PositionRepository) GetPositionByOrderID(ctx context.Context, orderID string) (*model.Position, error) {
+func (r *PositionRepository) DeletePosition(ctx context.Context, id int) error {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockRollup(ctx, tx); err != nil {
+		return err
+	}
+
+	var key rollupKey
 	query := `
-		SELECT id, order_id, exchange, symbol, volume,
-		       leverage, closed_pnl, side, date
-		FROM position
-		WHERE order_id = $1
+		DELETE FROM position WHERE id = $1
+		RETURNING date::date, exchange, account, symbol
 	`
+	err = tx.QueryRow(ctx, query, id).Scan(&key.Day, &key.Exchange, &key.Account, &key.Symbol)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := refreshRollup(ctx, tx, []rollupKey{key}); err != nil {
+		return err
+	}
+
+	entry := changeEntry{model.ChangeEntityPosition, model.ChangeDelete, strconv.Itoa(id), nil}
+	if err := recordChanges(ctx, tx, []changeEntry{entry}); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// NormalizeInstruments fills in the instrument of positions saved before it
+// was tracked, and of their rollup rows, returning the positions updated
+func (r *PositionRepository) NormalizeInstruments(ctx context.Context) (int64, error) {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockRollup(ctx, tx); err != nil {
+		return 0, err
+	}
+
+	rows, err := tx.Query(ctx, `SELECT DISTINCT exchange, symbol FROM position WHERE instrument = ''`)
+	if err != nil {
+		return 0, err
+	}
+	var symbols []model.Position
+	for rows.Next() {
+		var p model.Position
+		if err := rows.Scan(&p.Exchange, &p.Symbol); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		symbols = append(symbols, withInstrument(p))
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	var updated int64
+	for _, p := range symbols {
+		tag, err := tx.Exec(ctx, `
+			UPDATE position
+			SET base_asset = $3, quote_asset = $4, contract_type = $5, instrument = $6
+			WHERE exchange = $1 AND symbol = $2 AND instrument = ''
+		`, p.Exchange, p.Symbol, p.BaseAsset, p.QuoteAsset, p.ContractType, p.Instrument)
+		if err != nil {
+			return 0, err
+		}
+		updated += tag.RowsAffected()
+
+		query := `UPDATE position_daily_rollup SET instrument = $3 WHERE exchange = $1 AND symbol = $2`
+		if _, err := tx.Exec(ctx, query, p.Exchange, p.Symbol, p.Instrument); err != nil {
+			return 0, err
+		}
+	}
+
+	if updated > 0 {
+		entry := changeEntry{model.ChangeEntityPosition, model.ChangeReset, "", nil}
+		if err := recordChanges(ctx, tx, []changeEntry{entry}); err != nil {
+			return 0, err
+		}
+	}
+
+	return updated, tx.Commit(ctx)
+}
+
+func accountOrDefault(account string) string {
+	if account == "" {
+		return model.DefaultAccount
+	}
+	return account
+}
 
+// scanPosition reads positionColumns followed by any extra columns
+func scanPosition(row pgx.Row, extra ...interface{}) (model.Position, error) {
 	var p model.Position
-	err := r.db.Pool.QueryRow(ctx, query, orderID).Scan(
+	dest := []interface{}{
 		&p.ID,
 		&p.OrderID,
 		&p.Exchange,
+		&p.Account,
 		&p.Symbol,
+		&p.BaseAsset,
+		&p.QuoteAsset,
+		&p.ContractType,
+		&p.Instrument,
 		&p.Volume,
 		&p.Leverage,
 		&p.ClosedPnl,
 		&p.Side,
 		&p.UpdatedAt,
-	)
-	if err != nil {
-		return nil, err
 	}
-
-	return &p, nil
-}
-
-func (r *PositionRepository) DeletePosition(ctx context.Context, id int) error {
-	query := `DELETE FROM position WHERE id = $1`
-	_, err := r.db.Pool.Exec(ctx, query, id)
-	return err
+	err := row.Scan(append(dest, extra...)...)
+	return p, err
 }
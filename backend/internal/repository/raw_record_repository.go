@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"github.com/Ravierin/BudgetTracker/backend/internal/model"
+	"github.com/Ravierin/BudgetTracker/backend/pkg/database"
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type RawRecordRepository struct {
+	db *database.Database
+}
+
+func NewRawRecordRepository(db *database.Database) *RawRecordRepository {
+	return &RawRecordRepository{db: db}
+}
+
+const upsertRawRecordQuery = `
+	INSERT INTO raw_exchange_record (exchange, external_id, kind, payload, fetched_at)
+	VALUES ($1, $2, $3, $4, $5)
+	ON CONFLICT (exchange, external_id) DO UPDATE SET
+		kind = EXCLUDED.kind,
+		payload = EXCLUDED.payload,
+		fetched_at = EXCLUDED.fetched_at
+`
+
+// SaveRawRecords upserts payloads by exchange and external ID; a refetch
+// replaces the stored payload
+func (r *RawRecordRepository) SaveRawRecords(ctx context.Context, records []model.RawExchangeRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	batch := &pgx.Batch{}
+	for _, rec := range records {
+		batch.Queue(upsertRawRecordQuery, rec.Exchange, rec.ExternalID, rec.Kind, rec.Payload, rec.FetchedAt)
+	}
+
+	br := r.db.Pool.SendBatch(ctx, batch)
+	for i := 0; i < batch.Len(); i++ {
+		if _, err := br.Exec(); err != nil {
+			br.Close()
+			return err
+		}
+	}
+	return br.Close()
+}
+
+// StreamRawRecords calls fn for every stored payload, of one exchange unless
+// exchange is empty
+func (r *RawRecordRepository) StreamRawRecords(ctx context.Context, exchange string, fn func(model.RawExchangeRecord) error) error {
+	query := `
+		SELECT exchange, external_id, kind, payload, fetched_at
+		FROM raw_exchange_record
+		WHERE $1 = '' OR exchange = $1
+		ORDER BY exchange, external_id
+	`
+
+	rows, err := r.db.Pool.Query(ctx, query, exchange)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var rec model.RawExchangeRecord
+		if err := rows.Scan(&rec.Exchange, &rec.ExternalID, &rec.Kind, &rec.Payload, &rec.FetchedAt); err != nil {
+			return err
+		}
+		if err := fn(rec); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
@@ -0,0 +1,366 @@
+package repository
+
+import (
+	"github.com/Ravierin/BudgetTracker/backend/internal/model"
+	"github.com/Ravierin/BudgetTracker/backend/migrations"
+	"github.com/Ravierin/BudgetTracker/backend/pkg/database"
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// postgresDSNEnv points the suite at a scratch PostgreSQL database. The
+// position tables are truncated, so never point it at real data.
+const postgresDSNEnv = "BUDGETTRACKER_TEST_PG_DSN"
+
+func TestMemoryStores(t *testing.T) {
+	runRepositorySuite(t, func(t *testing.T) *Stores { return NewMemoryStores() })
+}
+
+func TestSQLiteStores(t *testing.T) {
+	runRepositorySuite(t, newSQLiteTestStores)
+}
+
+func TestPostgresStores(t *testing.T) {
+	dsn := os.Getenv(postgresDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s not set", postgresDSNEnv)
+	}
+	runRepositorySuite(t, func(t *testing.T) *Stores { return newPostgresTestStores(t, dsn) })
+}
+
+func newSQLiteTestStores(t *testing.T) *Stores {
+	t.Helper()
+	db, err := database.NewSQLiteDatabase(filepath.Join(t.TempDir(), "budget.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(db.Close)
+
+	migrator, err := database.NewSQLiteMigrator(db, migrations.SQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return NewSQLiteStores(db)
+}
+
+func newPostgresTestStores(t *testing.T, dsn string) *Stores {
+	t.Helper()
+	db, err := database.NewDatabase(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(db.Close)
+
+	ctx := context.Background()
+	migrator, err := database.NewMigrator(db, migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Pool.Exec(ctx, `TRUNCATE "position", position_daily_rollup, raw_exchange_record RESTART IDENTITY`); err != nil {
+		t.Fatal(err)
+	}
+	return NewPostgresStores(db)
+}
+
+// runRepositorySuite checks behaviour every backend must share; newStores
+// returns empty stores for each subtest
+func runRepositorySuite(t *testing.T, newStores func(t *testing.T) *Stores) {
+	t.Run("KeysetPagination", func(t *testing.T) { testKeysetPagination(t, newStores(t)) })
+	t.Run("Rollup", func(t *testing.T) { testRollup(t, newStores(t)) })
+	t.Run("UpsertChanges", func(t *testing.T) { testUpsertChanges(t, newStores(t)) })
+	t.Run("UpsertSymbol", func(t *testing.T) { testUpsertSymbol(t, newStores(t)) })
+}
+
+var suiteDay = time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+
+func suitePosition(orderID, exchange, symbol string, pnl, volume float64, date time.Time) model.Position {
+	return model.Position{
+		OrderID:   orderID,
+		Exchange:  exchange,
+		Symbol:    symbol,
+		Volume:    volume,
+		Leverage:  10,
+		ClosedPnl: pnl,
+		Side:      "Buy",
+		UpdatedAt: date,
+	}
+}
+
+func testKeysetPagination(t *testing.T, stores *Stores) {
+	ctx := context.Background()
+	// Ties on every sort column make the ID tiebreak matter; fractions no
+	// float64 holds exactly check that cursors keep the stored decimals
+	var positions []model.Position
+	for i := 0; i < 7; i++ {
+		date := suiteDay.Add(time.Duration(i/2) * time.Hour)
+		positions = append(positions, suitePosition(
+			"page-"+string(rune('a'+i)), "bybit", "BTCUSDT", float64(i%3)-0.1, float64(i%2)+12345.12345678, date))
+	}
+	if _, err := stores.Positions.SavePositionBatch(ctx, positions); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, sortBy := range []string{"date", "closedPnl", "volume"} {
+		for _, asc := range []bool{true, false} {
+			q := model.PositionQuery{SortBy: sortBy, Asc: asc}
+			all, _, err := stores.Positions.QueryPositions(ctx, q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(all) != len(positions) {
+				t.Fatalf("%s asc=%v: got %d positions, want %d", sortBy, asc, len(all), len(positions))
+			}
+
+			q.Limit = 3
+			var paged []model.Position
+			for pages := 0; ; pages++ {
+				if pages > len(positions) {
+					t.Fatalf("%s asc=%v: pagination does not terminate", sortBy, asc)
+				}
+				page, next, err := stores.Positions.QueryPositions(ctx, q)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(page) > q.Limit {
+					t.Fatalf("%s asc=%v: page of %d exceeds limit %d", sortBy, asc, len(page), q.Limit)
+				}
+				paged = append(paged, page...)
+				if next == "" {
+					break
+				}
+				q.Cursor = next
+			}
+
+			if len(paged) != len(all) {
+				t.Fatalf("%s asc=%v: paged %d positions, want %d", sortBy, asc, len(paged), len(all))
+			}
+			for i := range all {
+				if paged[i].ID != all[i].ID {
+					t.Fatalf("%s asc=%v: position %d is ID %d, want %d", sortBy, asc, i, paged[i].ID, all[i].ID)
+				}
+			}
+		}
+	}
+
+	if _, _, err := stores.Positions.QueryPositions(ctx, model.PositionQuery{Cursor: "not-a-cursor", Limit: 3}); err == nil {
+		t.Fatal("expected an error for a malformed cursor")
+	}
+}
+
+func testRollup(t *testing.T, stores *Stores) {
+	ctx := context.Background()
+	march := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	april := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	positions := []model.Position{
+		suitePosition("roll-1", "bybit", "BTCUSDT", 10, 100, march.Add(36*time.Hour)),
+		suitePosition("roll-2", "bybit", "ETHUSDT", -4, 50, march.Add(60*time.Hour)),
+		suitePosition("roll-3", "mexc", "BTC_USDT", 7, 20, march.Add(84*time.Hour)),
+		suitePosition("roll-4", "bybit", "BTCUSDT", 3, 30, april.Add(12*time.Hour)),
+	}
+	if _, err := stores.Positions.SavePositionBatch(ctx, positions); err != nil {
+		t.Fatal(err)
+	}
+	// Rewriting a position moves its amounts, and across months
+	update := positions[1]
+	update.ClosedPnl = -6
+	update.UpdatedAt = april.Add(36 * time.Hour)
+	if _, err := stores.Positions.SavePositionBatch(ctx, []model.Position{update}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []model.MonthlyIncome{
+		{Exchange: "bybit", CreatedAt: april, Amount: 80, PNL: -3},
+		{Exchange: "bybit", CreatedAt: march, Amount: 100, PNL: 10},
+		{Exchange: "mexc", CreatedAt: march, Amount: 20, PNL: 7},
+	}
+	checkMonthly := func(name string, got []model.MonthlyIncome) {
+		t.Helper()
+		if len(got) != len(want) {
+			t.Fatalf("%s: got %d months, want %d: %+v", name, len(got), len(want), got)
+		}
+		for i := range want {
+			if got[i].Exchange != want[i].Exchange || !got[i].CreatedAt.Equal(want[i].CreatedAt) ||
+				!approx(got[i].Amount, want[i].Amount) || !approx(got[i].PNL, want[i].PNL) {
+				t.Fatalf("%s: month %d is %+v, want %+v", name, i, got[i], want[i])
+			}
+		}
+	}
+
+	monthly, err := stores.Positions.AggregateMonthly(ctx, model.PositionFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkMonthly("positions", monthly)
+
+	sum, err := stores.Positions.SumClosedPnl(ctx, model.PositionFilter{Exchange: "bybit"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !approx(sum, 7) {
+		t.Fatalf("bybit pnl is %v, want 7", sum)
+	}
+
+	if stores.Rollup == nil {
+		return
+	}
+	filter := model.PositionFilter{}
+	if !stores.Rollup.Supports(filter) {
+		t.Fatal("rollup should support an unfiltered query")
+	}
+	check := func(name string) {
+		t.Helper()
+		monthly, err := stores.Rollup.AggregateMonthly(ctx, filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkMonthly(name, monthly)
+
+		sum, err := stores.Rollup.SumPnl(ctx, model.PositionFilter{Exchange: "bybit"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !approx(sum, 7) {
+			t.Fatalf("%s: bybit pnl is %v, want 7", name, sum)
+		}
+
+		stats, err := stores.Rollup.GetStats(ctx, filter, "exchange")
+		if err != nil {
+			t.Fatal(err)
+		}
+		direct, err := stores.Positions.GetPositionStats(ctx, filter, "exchange")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(stats) != len(direct) {
+			t.Fatalf("%s: got %d stat rows, want %d", name, len(stats), len(direct))
+		}
+		for i := range direct {
+			if stats[i].Exchange != direct[i].Exchange || stats[i].Trades != direct[i].Trades ||
+				stats[i].Wins != direct[i].Wins || stats[i].Losses != direct[i].Losses ||
+				!approx(stats[i].TotalPnl, direct[i].TotalPnl) || !approx(stats[i].TotalVolume, direct[i].TotalVolume) {
+				t.Fatalf("%s: stats %+v, want %+v", name, stats[i], direct[i])
+			}
+		}
+	}
+	check("rollup")
+
+	if _, err := stores.Rollup.Rebuild(ctx); err != nil {
+		t.Fatal(err)
+	}
+	check("rebuilt rollup")
+}
+
+func testUpsertChanges(t *testing.T, stores *Stores) {
+	ctx := context.Background()
+	first := []model.Position{
+		suitePosition("up-1", "bybit", "BTCUSDT", 5, 10, suiteDay),
+		suitePosition("up-2", "bybit", "ETHUSDT", -2, 20, suiteDay.Add(time.Hour)),
+	}
+	changes, err := stores.Positions.SavePositionBatch(ctx, first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes.Added) != 2 || len(changes.Changed) != 0 {
+		t.Fatalf("first save: %d added, %d changed; want 2, 0", len(changes.Added), len(changes.Changed))
+	}
+	for _, p := range changes.Added {
+		if p.ID == 0 {
+			t.Fatalf("added position %s has no ID", p.OrderID)
+		}
+	}
+
+	// The same rows again are neither added nor changed
+	changes, err = stores.Positions.SavePositionBatch(ctx, first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes.Added) != 0 || len(changes.Changed) != 0 {
+		t.Fatalf("unchanged save: %d added, %d changed; want 0, 0", len(changes.Added), len(changes.Changed))
+	}
+
+	updated := first[1]
+	updated.ClosedPnl = 4
+	batch := []model.Position{first[0], updated, suitePosition("up-3", "mexc", "BTC_USDT", 1, 5, suiteDay)}
+	changes, err = stores.Positions.SavePositionBatch(ctx, batch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes.Added) != 1 || changes.Added[0].OrderID != "up-3" {
+		t.Fatalf("mixed save added %+v, want up-3", changes.Added)
+	}
+	if len(changes.Changed) != 1 || changes.Changed[0].OrderID != "up-2" || !approx(changes.Changed[0].ClosedPnl, 4) {
+		t.Fatalf("mixed save changed %+v, want up-2 with pnl 4", changes.Changed)
+	}
+
+	stored, err := stores.Positions.GetPositionByOrderID(ctx, "up-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.ID != changes.Changed[0].ID {
+		t.Fatalf("changed position reports ID %d, stored as %d", changes.Changed[0].ID, stored.ID)
+	}
+	if !approx(stored.ClosedPnl, 4) {
+		t.Fatalf("stored pnl is %v, want 4", stored.ClosedPnl)
+	}
+}
+
+// testUpsertSymbol rewrites a position whose symbol mapping changed, as a
+// reprocess does after a mapper fix
+func testUpsertSymbol(t *testing.T, stores *Stores) {
+	ctx := context.Background()
+	old := suitePosition("sym-1", "bybit", "BTCUSD", 5, 10, suiteDay)
+	if _, err := stores.Positions.SavePositionBatch(ctx, []model.Position{old}); err != nil {
+		t.Fatal(err)
+	}
+
+	remapped := old
+	remapped.Symbol = "BTCUSDT"
+	changes, err := stores.Positions.SavePositionBatch(ctx, []model.Position{remapped})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes.Added) != 0 || len(changes.Changed) != 1 {
+		t.Fatalf("remap: %d added, %d changed; want 0, 1", len(changes.Added), len(changes.Changed))
+	}
+
+	stored, err := stores.Positions.GetPositionByOrderID(ctx, "sym-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Symbol != "BTCUSDT" || stored.Instrument != "BTC/USDT" || stored.BaseAsset != "BTC" ||
+		stored.QuoteAsset != "USDT" || stored.ContractType != "linear_perpetual" {
+		t.Fatalf("stored %+v, want the BTCUSDT mapping", *stored)
+	}
+
+	stats, err := stores.Positions.GetPositionStats(ctx, model.PositionFilter{}, "instrument")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 1 || stats[0].Instrument != "BTC/USDT" {
+		t.Fatalf("instrument stats %+v, want only BTC/USDT", stats)
+	}
+	if stores.Rollup != nil {
+		stats, err := stores.Rollup.GetStats(ctx, model.PositionFilter{}, "instrument")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(stats) != 1 || stats[0].Instrument != "BTC/USDT" {
+			t.Fatalf("rollup instrument stats %+v, want only BTC/USDT", stats)
+		}
+	}
+}
+
+// approx compares amounts that went through DECIMAL or REAL columns
+func approx(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
@@ -0,0 +1,277 @@
+package repository
+
+import (
+	"github.com/Ravierin/BudgetTracker/backend/internal/model"
+	"github.com/Ravierin/BudgetTracker/backend/pkg/database"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// rollupLockID serializes position writes so concurrent syncs cannot
+// recompute the same rollup row from different snapshots
+const rollupLockID = 727001
+
+// rollupKey identifies one row of position_daily_rollup
+type rollupKey struct {
+	Day      time.Time
+	Exchange string
+	Account  string
+	Symbol   string
+}
+
+const refreshRollupDeleteQuery = `
+	DELETE FROM position_daily_rollup r
+	USING unnest($1::date[], $2::text[], $3::text[], $4::text[]) AS k(day, exchange, account, symbol)
+	WHERE r.day = k.day AND r.exchange = k.exchange AND r.account = k.account AND r.symbol = k.symbol
+`
+
+const refreshRollupInsertQuery = `
+	INSERT INTO position_daily_rollup (day, exchange, account, symbol, instrument, trades, wins, losses, pnl, volume, updated_at)
+	SELECT k.day, k.exchange, k.account, k.symbol, MIN(p.instrument),
+	       COUNT(*),
+	       COUNT(*) FILTER (WHERE p.closed_pnl > 0),
+	       COUNT(*) FILTER (WHERE p.closed_pnl < 0),
+	       SUM(p.closed_pnl), SUM(p.volume), NOW()
+	FROM unnest($1::date[], $2::text[], $3::text[], $4::text[]) AS k(day, exchange, account, symbol)
+	JOIN position p
+	  ON p.date >= k.day AND p.date < k.day + 1
+	 AND p.exchange = k.exchange AND p.account = k.account AND p.symbol = k.symbol
+	GROUP BY k.day, k.exchange, k.account, k.symbol
+`
+
+func lockRollup(ctx context.Context, tx pgx.Tx) error {
+	_, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, rollupLockID)
+	return err
+}
+
+// rollupKeysForOrders returns the rollup rows the given positions currently count towards
+func rollupKeysForOrders(ctx context.Context, tx pgx.Tx, orderIDs []string) ([]rollupKey, error) {
+	query := `
+		SELECT DISTINCT date::date, exchange, account, symbol
+		FROM position
+		WHERE order_id = ANY($1)
+	`
+	rows, err := tx.Query(ctx, query, orderIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []rollupKey
+	for rows.Next() {
+		var k rollupKey
+		if err := rows.Scan(&k.Day, &k.Exchange, &k.Account, &k.Symbol); err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+// refreshRollup recomputes the given rollup rows from the position table,
+// dropping rows whose positions are all gone
+func refreshRollup(ctx context.Context, tx pgx.Tx, keys []rollupKey) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	seen := make(map[rollupKey]bool, len(keys))
+	var days []time.Time
+	var exchanges, accounts, symbols []string
+	for _, k := range keys {
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		days = append(days, k.Day)
+		exchanges = append(exchanges, k.Exchange)
+		accounts = append(accounts, k.Account)
+		symbols = append(symbols, k.Symbol)
+	}
+
+	if _, err := tx.Exec(ctx, refreshRollupDeleteQuery, days, exchanges, accounts, symbols); err != nil {
+		return err
+	}
+	_, err := tx.Exec(ctx, refreshRollupInsertQuery, days, exchanges, accounts, symbols)
+	return err
+}
+
+type RollupRepository struct {
+	db *database.Database
+}
+
+func NewRollupRepository(db *database.Database) *RollupRepository {
+	return &RollupRepository{db: db}
+}
+
+// Supports reports whether the filter can be answered from daily rollups:
+// side and PnL sign are not tracked there and date bounds must fall on UTC days
+func (r *RollupRepository) Supports(filter model.PositionFilter) bool {
+	return filter.Side == "" && filter.PnlSign == "" &&
+		isUTCDay(filter.From) && isUTCDay(filter.To)
+}
+
+func isUTCDay(t time.Time) bool {
+	if t.IsZero() {
+		return true
+	}
+	t = t.UTC()
+	return t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 && t.Nanosecond() == 0
+}
+
+func (b *queryBuilder) applyRollupFilter(f model.PositionFilter) {
+	if f.Exchange != "" {
+		b.add("exchange = %s", f.Exchange)
+	}
+	if f.Account != "" {
+		b.add("account = %s", f.Account)
+	}
+	if f.Symbol != "" {
+		b.add("symbol = %s", f.Symbol)
+	}
+	if f.Instrument != "" {
+		b.add("instrument = %s", f.Instrument)
+	}
+	if !f.From.IsZero() {
+		b.add("day >= %s::date", f.From.UTC().Format("2006-01-02"))
+	}
+	if !f.To.IsZero() {
+		b.add("day < %s::date", f.To.UTC().Format("2006-01-02"))
+	}
+}
+
+func (r *RollupRepository) SumPnl(ctx context.Context, filter model.PositionFilter) (float64, error) {
+	b := &queryBuilder{}
+	b.applyRollupFilter(filter)
+
+	var total float64
+	query := `SELECT COALESCE(SUM(pnl), 0) FROM position_daily_rollup` + b.whereClause()
+	err := r.db.Pool.QueryRow(ctx, query, b.args...).Scan(&total)
+	return total, err
+}
+
+// AggregateMonthly sums daily rollups per calendar month and exchange
+func (r *RollupRepository) AggregateMonthly(ctx context.Context, filter model.PositionFilter) ([]model.MonthlyIncome, error) {
+	b := &queryBuilder{}
+	b.applyRollupFilter(filter)
+
+	query := `
+		SELECT date_trunc('month', day::timestamp) AS month, exchange,
+		       COALESCE(SUM(volume), 0), COALESCE(SUM(pnl), 0)
+		FROM position_daily_rollup` + b.whereClause() + `
+		GROUP BY month, exchange
+		ORDER BY month DESC, exchange
+	`
+
+	rows, err := r.db.Pool.Query(ctx, query, b.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var incomes []model.MonthlyIncome
+	for rows.Next() {
+		var i model.MonthlyIncome
+		if err := rows.Scan(&i.CreatedAt, &i.Exchange, &i.Amount, &i.PNL); err != nil {
+			return nil, err
+		}
+		incomes = append(incomes, i)
+	}
+	return incomes, rows.Err()
+}
+
+// GetStats sums trade counts and totals, optionally grouped by exchange,
+// symbol or instrument
+func (r *RollupRepository) GetStats(ctx context.Context, filter model.PositionFilter, groupBy string) ([]model.PositionStats, error) {
+	column, ok := positionGroupColumns[groupBy]
+	if !ok {
+		return nil, fmt.Errorf("invalid group column: %s", groupBy)
+	}
+
+	b := &queryBuilder{}
+	b.applyRollupFilter(filter)
+
+	selectKey, groupClause := "''", ""
+	if column != "" {
+		selectKey = column
+		groupClause = " GROUP BY " + column + " ORDER BY " + column
+	}
+
+	query := `
+		SELECT ` + selectKey + `, COALESCE(SUM(trades), 0),
+		       COALESCE(SUM(wins), 0), COALESCE(SUM(losses), 0),
+		       COALESCE(SUM(pnl), 0), COALESCE(SUM(volume), 0)
+		FROM position_daily_rollup` + b.whereClause() + groupClause
+
+	rows, err := r.db.Pool.Query(ctx, query, b.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []model.PositionStats
+	for rows.Next() {
+		var key string
+		var s model.PositionStats
+		if err := rows.Scan(&key, &s.Trades, &s.Wins, &s.Losses, &s.TotalPnl, &s.TotalVolume); err != nil {
+			return nil, err
+		}
+		switch column {
+		case "exchange":
+			s.Exchange = key
+		case "symbol":
+			s.Symbol = key
+		case "instrument":
+			s.Instrument = key
+		}
+		stats = append(stats, s)
+	}
+	return stats, rows.Err()
+}
+
+// Rebuild recomputes the whole rollup table from positions and returns the
+// number of rollup rows written
+func (r *RollupRepository) Rebuild(ctx context.Context) (int64, error) {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockRollup(ctx, tx); err != nil {
+		return 0, err
+	}
+
+	n, err := rebuildRollup(ctx, tx)
+	if err != nil {
+		return 0, err
+	}
+
+	return n, tx.Commit(ctx)
+}
+
+// rebuildRollup replaces every rollup row; the caller holds the rollup lock
+func rebuildRollup(ctx context.Context, tx pgx.Tx) (int64, error) {
+	if _, err := tx.Exec(ctx, `DELETE FROM position_daily_rollup`); err != nil {
+		return 0, err
+	}
+
+	query := `
+		INSERT INTO position_daily_rollup (day, exchange, account, symbol, instrument, trades, wins, losses, pnl, volume, updated_at)
+		SELECT date::date, exchange, account, symbol, MIN(instrument),
+		       COUNT(*),
+		       COUNT(*) FILTER (WHERE closed_pnl > 0),
+		       COUNT(*) FILTER (WHERE closed_pnl < 0),
+		       SUM(closed_pnl), SUM(volume), NOW()
+		FROM position
+		GROUP BY 1, 2, 3, 4
+	`
+	tag, err := tx.Exec(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"github.com/Ravierin/BudgetTracker/backend/internal/model"
+	"github.com/Ravierin/BudgetTracker/backend/pkg/database"
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type SettingsRepository struct {
+	db *database.Database
+}
+
+func NewSettingsRepository(db *database.Database) *SettingsRepository {
+	return &SettingsRepository{db: db}
+}
+
+func (r *SettingsRepository) Get(ctx context.Context, key string) (*model.Setting, error) {
+	query := `SELECT key, value, updated_at FROM settings WHERE key = $1`
+
+	var s model.Setting
+	err := r.db.Pool.QueryRow(ctx, query, key).Scan(&s.Key, &s.Value, &s.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+func (r *SettingsRepository) GetAll(ctx context.Context) ([]model.Setting, error) {
+	query := `SELECT key, value, updated_at FROM settings ORDER BY key`
+
+	rows, err := r.db.Pool.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var settings []model.Setting
+	for rows.Next() {
+		var s model.Setting
+		if err := rows.Scan(&s.Key, &s.Value, &s.UpdatedAt); err != nil {
+			return nil, err
+		}
+		settings = append(settings, s)
+	}
+	return settings, rows.Err()
+}
+
+func (r *SettingsRepository) Set(ctx context.Context, key, value string) error {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		INSERT INTO settings (key, value, updated_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (key) DO UPDATE SET
+			value = EXCLUDED.value,
+			updated_at = EXCLUDED.updated_at
+		RETURNING updated_at, xmax = 0
+	`
+	s := model.Setting{Key: key, Value: value}
+	var inserted bool
+	if err := tx.QueryRow(ctx, query, key, value).Scan(&s.UpdatedAt, &inserted); err != nil {
+		return err
+	}
+
+	entry := changeEntry{model.ChangeEntitySetting, model.ChangeUpdate, key, s}
+	if inserted {
+		entry.op = model.ChangeInsert
+	}
+	if err := recordChanges(ctx, tx, []changeEntry{entry}); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *SettingsRepository) Delete(ctx context.Context, key string) error {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `DELETE FROM settings WHERE key = $1`
+	tag, err := tx.Exec(ctx, query, key)
+	if err != nil || tag.RowsAffected() == 0 {
+		return err
+	}
+
+	entry := changeEntry{model.ChangeEntitySetting, model.ChangeDelete, key, nil}
+	if err := recordChanges(ctx, tx, []changeEntry{entry}); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"github.com/Ravierin/BudgetTracker/backend/internal/model"
+	"github.com/Ravierin/BudgetTracker/backend/pkg/database"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+const sqliteAPIKeyColumns = `id, exchange, api_key, api_secret, is_active, created_at, updated_at`
+
+type SQLiteAPIKeyRepository struct {
+	db *database.SQLiteDatabase
+}
+
+func NewSQLiteAPIKeyRepository(db *database.SQLiteDatabase) *SQLiteAPIKeyRepository {
+	return &SQLiteAPIKeyRepository{db: db}
+}
+
+func (r *SQLiteAPIKeyRepository) GetByExchange(ctx context.Context, exchange string) (*model.APIKey, error) {
+	query := `SELECT ` + sqliteAPIKeyColumns + ` FROM api_keys WHERE exchange = ?`
+
+	apiKey, err := scanSQLiteAPIKey(r.db.DB.QueryRowContext(ctx, query, exchange))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &apiKey, nil
+}
+
+func (r *SQLiteAPIKeyRepository) Upsert(ctx context.Context, apiKey *model.APIKey) error {
+	query := `
+		INSERT INTO api_keys (exchange, api_key, api_secret, is_active, updated_at)
+		VALUES (?, ?, ?, 1, ?)
+		ON CONFLICT (exchange) DO UPDATE SET
+			api_key = excluded.api_key,
+			api_secret = excluded.api_secret,
+			is_active = 1,
+			updated_at = excluded.updated_at
+	`
+
+	_, err := r.db.DB.ExecContext(ctx, query,
+		apiKey.Exchange,
+		apiKey.APIKey,
+		apiKey.APISecret,
+		sqliteTime(time.Now()),
+	)
+
+	return err
+}
+
+func (r *SQLiteAPIKeyRepository) GetAll(ctx context.Context) ([]model.APIKey, error) {
+	query := `SELECT ` + sqliteAPIKeyColumns + ` FROM api_keys ORDER BY exchange`
+
+	rows, err := r.db.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var apiKeys []model.APIKey
+	for rows.Next() {
+		apiKey, err := scanSQLiteAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		apiKeys = append(apiKeys, apiKey)
+	}
+
+	return apiKeys, rows.Err()
+}
+
+func scanSQLiteAPIKey(row sqlRow) (model.APIKey, error) {
+	var apiKey model.APIKey
+	err := row.Scan(
+		&apiKey.ID,
+		&apiKey.Exchange,
+		&apiKey.APIKey,
+		&apiKey.APISecret,
+		&apiKey.IsActive,
+		sqliteTimestamp{&apiKey.CreatedAt},
+		sqliteTimestamp{&apiKey.UpdatedAt},
+	)
+	return apiKey, err
+}
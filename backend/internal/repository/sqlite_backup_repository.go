@@ -0,0 +1,191 @@
+package repository
+
+import (
+	"github.com/Ravierin/BudgetTracker/backend/internal/model"
+	"github.com/Ravierin/BudgetTracker/backend/pkg/database"
+	"context"
+	"database/sql"
+	"errors"
+)
+
+type SQLiteBackupRepository struct {
+	db *database.SQLiteDatabase
+}
+
+func NewSQLiteBackupRepository(db *database.SQLiteDatabase) *SQLiteBackupRepository {
+	return &SQLiteBackupRepository{db: db}
+}
+
+// SQLiteRestore is Restore for SQLite; positions are written as they arrive
+// since statements in one transaction are already cheap
+type SQLiteRestore struct {
+	tx       *sql.Tx
+	replace  bool
+	position *sql.Stmt
+}
+
+func (r *SQLiteBackupRepository) BeginRestore(ctx context.Context, replace, includeKeys bool) (RestoreWriter, error) {
+	tx, err := r.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if replace {
+		tables := []string{"position", "withdrawal", "monthly_income", "settings", "import_profile"}
+		if includeKeys {
+			tables = append(tables, "api_keys")
+		}
+		for _, table := range tables {
+			if _, err := tx.ExecContext(ctx, "DELETE FROM "+table); err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+		}
+	}
+
+	stmt, err := tx.PrepareContext(ctx, sqliteUpsertPositionQuery)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	return &SQLiteRestore{tx: tx, replace: replace, position: stmt}, nil
+}
+
+func (s *SQLiteRestore) AddPosition(ctx context.Context, p model.Position) error {
+	p = withInstrument(p)
+	_, err := s.position.ExecContext(ctx,
+		p.OrderID,
+		p.Exchange,
+		accountOrDefault(p.Account),
+		p.Symbol,
+		p.BaseAsset,
+		p.QuoteAsset,
+		p.ContractType,
+		p.Instrument,
+		p.Volume,
+		p.Leverage,
+		p.ClosedPnl,
+		p.Side,
+		sqliteTime(p.UpdatedAt),
+	)
+	return err
+}
+
+func (s *SQLiteRestore) AddWithdrawal(ctx context.Context, w model.Withdrawal) (bool, error) {
+	query := `
+		INSERT INTO withdrawal (exchange, amount, currency, date)
+		SELECT ?1, ?2, ?3, ?4
+		WHERE ?5 OR NOT EXISTS (
+			SELECT 1 FROM withdrawal
+			WHERE exchange = ?1 AND amount = ?2 AND currency = ?3 AND date = ?4
+		)
+	`
+	res, err := s.tx.ExecContext(ctx, query, w.Exchange, w.Amount, w.Currency, sqliteTime(w.CreatedAt), s.replace)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (s *SQLiteRestore) AddMonthlyIncome(ctx context.Context, i model.MonthlyIncome) (bool, error) {
+	query := `
+		INSERT INTO monthly_income (exchange, amount, pnl, date)
+		SELECT ?1, ?2, ?3, ?4
+		WHERE ?5 OR NOT EXISTS (
+			SELECT 1 FROM monthly_income
+			WHERE exchange = ?1 AND amount = ?2 AND pnl = ?3 AND date = ?4
+		)
+	`
+	res, err := s.tx.ExecContext(ctx, query, i.Exchange, i.Amount, i.PNL, sqliteTime(i.CreatedAt), s.replace)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (s *SQLiteRestore) AddSetting(ctx context.Context, setting model.Setting) error {
+	query := `
+		INSERT INTO settings (key, value, updated_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT (key) DO UPDATE SET
+			value = excluded.value,
+			updated_at = excluded.updated_at
+	`
+	_, err := s.tx.ExecContext(ctx, query, setting.Key, setting.Value, sqliteTime(setting.UpdatedAt))
+	return err
+}
+
+func (s *SQLiteRestore) AddImportProfile(ctx context.Context, p model.ImportProfile) error {
+	query := `
+		INSERT INTO import_profile (
+			name, exchange, symbol_column, side_column, pnl_column, date_column,
+			date_format, volume_column, leverage_column, order_id_column, delimiter,
+			created_at, updated_at
+		) VALUES (
+			?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?
+		)
+		ON CONFLICT (name) DO UPDATE SET
+			exchange = excluded.exchange,
+			symbol_column = excluded.symbol_column,
+			side_column = excluded.side_column,
+			pnl_column = excluded.pnl_column,
+			date_column = excluded.date_column,
+			date_format = excluded.date_format,
+			volume_column = excluded.volume_column,
+			leverage_column = excluded.leverage_column,
+			order_id_column = excluded.order_id_column,
+			delimiter = excluded.delimiter,
+			updated_at = excluded.updated_at
+	`
+	_, err := s.tx.ExecContext(ctx, query,
+		p.Name,
+		p.Exchange,
+		p.SymbolColumn,
+		p.SideColumn,
+		p.PnlColumn,
+		p.DateColumn,
+		p.DateFormat,
+		p.VolumeColumn,
+		p.LeverageColumn,
+		p.OrderIDColumn,
+		p.Delimiter,
+		sqliteTime(p.CreatedAt),
+		sqliteTime(p.UpdatedAt),
+	)
+	return err
+}
+
+func (s *SQLiteRestore) AddAPIKey(ctx context.Context, k model.APIKey) error {
+	query := `
+		INSERT INTO api_keys (exchange, api_key, api_secret, is_active, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (exchange) DO UPDATE SET
+			api_key = excluded.api_key,
+			api_secret = excluded.api_secret,
+			is_active = excluded.is_active,
+			updated_at = excluded.updated_at
+	`
+	_, err := s.tx.ExecContext(ctx, query,
+		k.Exchange, k.APIKey, k.APISecret, k.IsActive, sqliteTime(k.CreatedAt), sqliteTime(k.UpdatedAt))
+	return err
+}
+
+func (s *SQLiteRestore) Commit(ctx context.Context) error {
+	s.position.Close()
+	if err := recordSQLiteChanges(ctx, s.tx, []changeEntry{{op: model.ChangeReset}}); err != nil {
+		return err
+	}
+	return s.tx.Commit()
+}
+
+func (s *SQLiteRestore) Rollback(ctx context.Context) error {
+	s.position.Close()
+	err := s.tx.Rollback()
+	if errors.Is(err, sql.ErrTxDone) {
+		return nil
+	}
+	return err
+}
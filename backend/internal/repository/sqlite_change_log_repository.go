@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"github.com/Ravierin/BudgetTracker/backend/internal/model"
+	"github.com/Ravierin/BudgetTracker/backend/pkg/database"
+	"context"
+	"database/sql"
+)
+
+type SQLiteChangeLogRepository struct {
+	db *database.SQLiteDatabase
+}
+
+func NewSQLiteChangeLogRepository(db *database.SQLiteDatabase) *SQLiteChangeLogRepository {
+	return &SQLiteChangeLogRepository{db: db}
+}
+
+// recordSQLiteChanges writes entries in tx. Transactions are immediate, so
+// IDs are handed out in commit order without a lock of our own.
+func recordSQLiteChanges(ctx context.Context, tx *sql.Tx, entries []changeEntry) error {
+	for _, e := range entries {
+		data, err := e.encode()
+		if err != nil {
+			return err
+		}
+		var text interface{}
+		if data != nil {
+			text = string(data)
+		}
+		_, err = tx.ExecContext(ctx, `INSERT INTO change_log (entity, op, entity_key, data) VALUES (?, ?, ?, ?)`,
+			e.entity, e.op, e.key, text)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *SQLiteChangeLogRepository) GetChanges(ctx context.Context, after int64, limit int) ([]model.Change, error) {
+	query := `
+		SELECT id, entity, op, entity_key, data, created_at
+		FROM change_log
+		WHERE id > ?
+		ORDER BY id
+		LIMIT ?
+	`
+	rows, err := r.db.DB.QueryContext(ctx, query, after, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []model.Change
+	for rows.Next() {
+		var c model.Change
+		var data sql.NullString
+		if err := rows.Scan(&c.ID, &c.Entity, &c.Op, &c.Key, &data, sqliteTimestamp{&c.CreatedAt}); err != nil {
+			return nil, err
+		}
+		if data.Valid {
+			c.Data = []byte(data.String)
+		}
+		changes = append(changes, c)
+	}
+	return changes, rows.Err()
+}
+
+func (r *SQLiteChangeLogRepository) LatestChangeID(ctx context.Context) (int64, error) {
+	var id int64
+	err := r.db.DB.QueryRowContext(ctx, `SELECT COALESCE(MAX(id), 0) FROM change_log`).Scan(&id)
+	return id, err
+}
@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"github.com/Ravierin/BudgetTracker/backend/internal/model"
+	"github.com/Ravierin/BudgetTracker/backend/pkg/database"
+	"context"
+	"errors"
+)
+
+type SQLiteContractRepository struct {
+	db *database.SQLiteDatabase
+}
+
+func NewSQLiteContractRepository(db *database.SQLiteDatabase) *SQLiteContractRepository {
+	return &SQLiteContractRepository{db: db}
+}
+
+func (r *SQLiteContractRepository) GetContracts(ctx context.Context, exchange string) ([]model.ContractMeta, error) {
+	query := `SELECT ` + contractColumns + ` FROM contract_metadata WHERE exchange = ? ORDER BY symbol`
+
+	rows, err := r.db.DB.QueryContext(ctx, query, exchange)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var contracts []model.ContractMeta
+	for rows.Next() {
+		var c model.ContractMeta
+		err := rows.Scan(&c.Exchange, &c.Symbol, &c.BaseCoin, &c.QuoteCoin, &c.SettleCoin, &c.ContractSize,
+			&c.PriceScale, &c.VolScale, &c.ContractSizeOverride, &c.AppliedContractSize, sqliteTimestamp{&c.UpdatedAt})
+		if err != nil {
+			return nil, err
+		}
+		contracts = append(contracts, c)
+	}
+	return contracts, rows.Err()
+}
+
+// SaveContracts upserts fetched listings in one transaction, keeping
+// overrides and applied sizes
+func (r *SQLiteContractRepository) SaveContracts(ctx context.Context, contracts []model.ContractMeta) error {
+	if len(contracts) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO contract_metadata (
+			exchange, symbol, base_coin, quote_coin, settle_coin,
+			contract_size, price_scale, vol_scale, updated_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, strftime('%Y-%m-%dT%H:%M:%fZ', 'now'))
+		ON CONFLICT (exchange, symbol) DO UPDATE SET
+			base_coin = excluded.base_coin,
+			quote_coin = excluded.quote_coin,
+			settle_coin = excluded.settle_coin,
+			contract_size = excluded.contract_size,
+			price_scale = excluded.price_scale,
+			vol_scale = excluded.vol_scale,
+			updated_at = excluded.updated_at
+	`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, c := range contracts {
+		_, err := stmt.ExecContext(ctx, c.Exchange, c.Symbol, c.BaseCoin, c.QuoteCoin, c.SettleCoin,
+			c.ContractSize, c.PriceScale, c.VolScale)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *SQLiteContractRepository) SetContractOverride(ctx context.Context, exchange, symbol string, size *float64) error {
+	query := `
+		INSERT INTO contract_metadata (exchange, symbol, contract_size_override)
+		VALUES (?, ?, ?)
+		ON CONFLICT (exchange, symbol) DO UPDATE SET
+			contract_size_override = excluded.contract_size_override
+	`
+	_, err := r.db.DB.ExecContext(ctx, query, exchange, symbol, size)
+	return err
+}
+
+func (r *SQLiteContractRepository) SetAppliedContractSize(ctx context.Context, exchange, symbol string, size float64) error {
+	query := `UPDATE contract_metadata SET applied_contract_size = ? WHERE exchange = ? AND symbol = ?`
+	_, err := r.db.DB.ExecContext(ctx, query, size, exchange, symbol)
+	return err
+}
+
+// RescaleVolumes updates the volumes with a single statement and logs the
+// rewritten positions
+func (r *SQLiteContractRepository) RescaleVolumes(ctx context.Context, exchange, symbol string, from, to float64, dryRun bool) (int, error) {
+	if from <= 0 || to <= 0 {
+		return 0, errors.New("contract sizes must be positive")
+	}
+
+	if dryRun {
+		var n int
+		query := `SELECT COUNT(*) FROM position WHERE exchange = ? AND symbol = ?`
+		err := r.db.DB.QueryRowContext(ctx, query, exchange, symbol).Scan(&n)
+		return n, err
+	}
+
+	tx, err := r.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE position SET volume = volume / ?3 * ?4, updated_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now')
+		WHERE exchange = ?1 AND symbol = ?2
+		RETURNING ` + positionColumns
+	rows, err := tx.QueryContext(ctx, query, exchange, symbol, from, to)
+	if err != nil {
+		return 0, err
+	}
+	var changes model.PositionChanges
+	for rows.Next() {
+		p, err := scanSQLitePosition(rows)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		changes.Changed = append(changes.Changed, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	if err := recordSQLiteChanges(ctx, tx, positionChangeEntries(changes)); err != nil {
+		return 0, err
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE contract_metadata SET applied_contract_size = ? WHERE exchange = ? AND symbol = ?`,
+		to, exchange, symbol)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(changes.Changed), nil
+}
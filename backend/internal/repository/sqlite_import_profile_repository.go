@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"github.com/Ravierin/BudgetTracker/backend/internal/model"
+	"github.com/Ravierin/BudgetTracker/backend/pkg/database"
+	"context"
+	"database/sql"
+	"errors"
+)
+
+type SQLiteImportProfileRepository struct {
+	db *database.SQLiteDatabase
+}
+
+func NewSQLiteImportProfileRepository(db *database.SQLiteDatabase) *SQLiteImportProfileRepository {
+	return &SQLiteImportProfileRepository{db: db}
+}
+
+func (r *SQLiteImportProfileRepository) GetByName(ctx context.Context, name string) (*model.ImportProfile, error) {
+	query := `SELECT ` + importProfileColumns + ` FROM import_profile WHERE name = ?`
+
+	p, err := scanSQLiteImportProfile(r.db.DB.QueryRowContext(ctx, query, name))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+func (r *SQLiteImportProfileRepository) GetAll(ctx context.Context) ([]model.ImportProfile, error) {
+	query := `SELECT ` + importProfileColumns + ` FROM import_profile ORDER BY name`
+
+	rows, err := r.db.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var profiles []model.ImportProfile
+	for rows.Next() {
+		p, err := scanSQLiteImportProfile(rows)
+		if err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, p)
+	}
+	return profiles, rows.Err()
+}
+
+func (r *SQLiteImportProfileRepository) Upsert(ctx context.Context, p *model.ImportProfile) error {
+	query := `
+		INSERT INTO import_profile (
+			name, exchange, symbol_column, side_column, pnl_column, date_column,
+			date_format, volume_column, leverage_column, order_id_column, delimiter
+		) VALUES (
+			?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?
+		)
+		ON CONFLICT (name) DO UPDATE SET
+			exchange = excluded.exchange,
+			symbol_column = excluded.symbol_column,
+			side_column = excluded.side_column,
+			pnl_column = excluded.pnl_column,
+			date_column = excluded.date_column,
+			date_format = excluded.date_format,
+			volume_column = excluded.volume_column,
+			leverage_column = excluded.leverage_column,
+			order_id_column = excluded.order_id_column,
+			delimiter = excluded.delimiter,
+			updated_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now')
+		RETURNING id, created_at, updated_at
+	`
+
+	return r.db.DB.QueryRowContext(ctx, query,
+		p.Name,
+		p.Exchange,
+		p.SymbolColumn,
+		p.SideColumn,
+		p.PnlColumn,
+		p.DateColumn,
+		p.DateFormat,
+		p.VolumeColumn,
+		p.LeverageColumn,
+		p.OrderIDColumn,
+		p.Delimiter,
+	).Scan(&p.ID, sqliteTimestamp{&p.CreatedAt}, sqliteTimestamp{&p.UpdatedAt})
+}
+
+func (r *SQLiteImportProfileRepository) Delete(ctx context.Context, name string) error {
+	_, err := r.db.DB.ExecContext(ctx, `DELETE FROM import_profile WHERE name = ?`, name)
+	return err
+}
+
+func scanSQLiteImportProfile(row sqlRow) (model.ImportProfile, error) {
+	var p model.ImportProfile
+	err := row.Scan(
+		&p.ID,
+		&p.Name,
+		&p.Exchange,
+		&p.SymbolColumn,
+		&p.SideColumn,
+		&p.PnlColumn,
+		&p.DateColumn,
+		&p.DateFormat,
+		&p.VolumeColumn,
+		&p.LeverageColumn,
+		&p.OrderIDColumn,
+		&p.Delimiter,
+		sqliteTimestamp{&p.CreatedAt},
+		sqliteTimestamp{&p.UpdatedAt},
+	)
+	return p, err
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"github.com/Ravierin/BudgetTracker/backend/internal/model"
+	"github.com/Ravierin/BudgetTracker/backend/pkg/database"
+	"context"
+	"time"
+)
+
+type SQLiteMonthlyIncomeRepository struct {
+	db *database.SQLiteDatabase
+}
+
+func NewSQLiteMonthlyIncomeRepository(db *database.SQLiteDatabase) *SQLiteMonthlyIncomeRepository {
+	return &SQLiteMonthlyIncomeRepository{db: db}
+}
+
+func (r *SQLiteMonthlyIncomeRepository) SaveMonthlyIncome(ctx context.Context, income model.MonthlyIncome) error {
+	query := `
+		INSERT INTO monthly_income (exchange, amount, pnl, date)
+		VALUES (?, ?, ?, ?)
+	`
+	_, err := r.db.DB.ExecContext(ctx, query,
+		income.Exchange,
+		income.Amount,
+		income.PNL,
+		sqliteTime(income.CreatedAt),
+	)
+	return err
+}
+
+func (r *SQLiteMonthlyIncomeRepository) GetAllMonthlyIncomes(ctx context.Context) ([]model.MonthlyIncome, error) {
+	query := `
+		SELECT id, exchange, amount, pnl, date
+		FROM monthly_income
+		ORDER BY date DESC
+	`
+	return r.query(ctx, query)
+}
+
+func (r *SQLiteMonthlyIncomeRepository) GetIncomesByExchange(ctx context.Context, exchange string) ([]model.MonthlyIncome, error) {
+	query := `
+		SELECT id, exchange, amount, pnl, date
+		FROM monthly_income
+		WHERE exchange = ?
+		ORDER BY date DESC
+	`
+	return r.query(ctx, query, exchange)
+}
+
+func (r *SQLiteMonthlyIncomeRepository) GetIncomesByDateRange(ctx context.Context, start, end time.Time) ([]model.MonthlyIncome, error) {
+	query := `
+		SELECT id, exchange, amount, pnl, date
+		FROM monthly_income
+		WHERE date BETWEEN ? AND ?
+		ORDER BY date DESC
+	`
+	return r.query(ctx, query, sqliteTime(start), sqliteTime(end))
+}
+
+func (r *SQLiteMonthlyIncomeRepository) query(ctx context.Context, query string, args ...interface{}) ([]model.MonthlyIncome, error) {
+	rows, err := r.db.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var incomes []model.MonthlyIncome
+	for rows.Next() {
+		var i model.MonthlyIncome
+		if err := rows.Scan(&i.ID, &i.Exchange, &i.Amount, &i.PNL, sqliteTimestamp{&i.CreatedAt}); err != nil {
+			return nil, err
+		}
+		incomes = append(incomes, i)
+	}
+	return incomes, rows.Err()
+}
+
+func (r *SQLiteMonthlyIncomeRepository) DeleteMonthlyIncome(ctx context.Context, id int) error {
+	_, err := r.db.DB.ExecContext(ctx, `DELETE FROM monthly_income WHERE id = ?`, id)
+	return err
+}
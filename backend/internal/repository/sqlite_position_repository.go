@@ -0,0 +1,501 @@
+package repository
+
+import (
+	"github.com/Ravierin/BudgetTracker/backend/internal/model"
+	"github.com/Ravierin/BudgetTracker/backend/pkg/database"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sqliteTimeLayout is fixed-width UTC so stored times compare lexically,
+// and matches strftime('%Y-%m-%dT%H:%M:%fZ')
+const sqliteTimeLayout = "2006-01-02T15:04:05.000Z"
+
+// sqliteInChunk keeps IN lists well below SQLite's bound parameter limit
+const sqliteInChunk = 500
+
+func sqliteTime(t time.Time) string {
+	return t.UTC().Format(sqliteTimeLayout)
+}
+
+// sqliteTimestamp scans a stored time back into a time.Time
+type sqliteTimestamp struct {
+	t *time.Time
+}
+
+func (s sqliteTimestamp) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*s.t = time.Time{}
+		return nil
+	case time.Time:
+		*s.t = v.UTC()
+		return nil
+	case []byte:
+		return s.parse(string(v))
+	case string:
+		return s.parse(v)
+	default:
+		return fmt.Errorf("cannot scan %T into time", src)
+	}
+}
+
+func (s sqliteTimestamp) parse(v string) error {
+	t, err := time.Parse(sqliteTimeLayout, v)
+	if err != nil {
+		if t, err = time.Parse(time.RFC3339Nano, v); err != nil {
+			return err
+		}
+	}
+	*s.t = t.UTC()
+	return nil
+}
+
+const sqliteUpsertPositionQuery = `
+	INSERT INTO position (
+		order_id, exchange, account, symbol, base_asset,
+		quote_asset, contract_type, instrument, volume,
+		leverage, closed_pnl, side, date
+	) VALUES (
+		?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?
+	)
+	ON CONFLICT (order_id) DO UPDATE SET
+		symbol = excluded.symbol,
+		base_asset = excluded.base_asset,
+		quote_asset = excluded.quote_asset,
+		contract_type = excluded.contract_type,
+		instrument = excluded.instrument,
+		volume = excluded.volume,
+		leverage = excluded.leverage,
+		closed_pnl = excluded.closed_pnl,
+		side = excluded.side,
+		date = excluded.date,
+		updated_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now')
+	WHERE position.symbol IS NOT excluded.symbol
+		OR position.base_asset IS NOT excluded.base_asset
+		OR position.quote_asset IS NOT excluded.quote_asset
+		OR position.contract_type IS NOT excluded.contract_type
+		OR position.instrument IS NOT excluded.instrument
+		OR position.volume IS NOT excluded.volume
+		OR position.leverage IS NOT excluded.leverage
+		OR position.closed_pnl IS NOT excluded.closed_pnl
+		OR position.side IS NOT excluded.side
+		OR position.date IS NOT excluded.date
+	RETURNING id
+`
+
+type SQLitePositionRepository struct {
+	db *database.SQLiteDatabase
+}
+
+func NewSQLitePositionRepository(db *database.SQLiteDatabase) *SQLitePositionRepository {
+	return &SQLitePositionRepository{db: db}
+}
+
+func (r *SQLitePositionRepository) SavePosition(ctx context.Context, position model.Position) error {
+	_, err := r.SavePositionBatch(ctx, []model.Position{position})
+	return err
+}
+
+// SavePositionBatch upserts all positions in one transaction
+func (r *SQLitePositionRepository) SavePositionBatch(ctx context.Context, positions []model.Position) (model.PositionChanges, error) {
+	if len(positions) == 0 {
+		return model.PositionChanges{}, nil
+	}
+
+	tx, err := r.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return model.PositionChanges{}, err
+	}
+	defer tx.Rollback()
+
+	changes, err := upsertSQLitePositions(ctx, tx, positions)
+	if err != nil {
+		return model.PositionChanges{}, err
+	}
+
+	if err := recordSQLiteChanges(ctx, tx, positionChangeEntries(changes)); err != nil {
+		return model.PositionChanges{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return model.PositionChanges{}, err
+	}
+	return changes, nil
+}
+
+// upsertSQLitePositions writes positions and reports the changes. SQLite has
+// no xmax, so an order ID looked up before the upsert tells an update from an
+// insert; the upsert returns no row when the stored values are the same.
+func upsertSQLitePositions(ctx context.Context, tx *sql.Tx, positions []model.Position) (model.PositionChanges, error) {
+	var changes model.PositionChanges
+
+	exists, err := tx.PrepareContext(ctx, `SELECT EXISTS (SELECT 1 FROM position WHERE order_id = ?)`)
+	if err != nil {
+		return changes, err
+	}
+	defer exists.Close()
+
+	stmt, err := tx.PrepareContext(ctx, sqliteUpsertPositionQuery)
+	if err != nil {
+		return changes, err
+	}
+	defer stmt.Close()
+
+	for _, p := range positions {
+		p = withInstrument(p)
+		p.Account = accountOrDefault(p.Account)
+
+		var stored bool
+		if err := exists.QueryRowContext(ctx, p.OrderID).Scan(&stored); err != nil {
+			return changes, err
+		}
+
+		err := stmt.QueryRowContext(ctx,
+			p.OrderID,
+			p.Exchange,
+			p.Account,
+			p.Symbol,
+			p.BaseAsset,
+			p.QuoteAsset,
+			p.ContractType,
+			p.Instrument,
+			p.Volume,
+			p.Leverage,
+			p.ClosedPnl,
+			p.Side,
+			sqliteTime(p.UpdatedAt),
+		).Scan(&p.ID)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			// Stored with the same values
+		case err != nil:
+			return changes, err
+		case stored:
+			changes.Changed = append(changes.Changed, p)
+		default:
+			changes.Added = append(changes.Added, p)
+		}
+	}
+	return changes, nil
+}
+
+func (r *SQLitePositionRepository) GetAllPositions(ctx context.Context) ([]model.Position, error) {
+	positions, _, err := r.QueryPositions(ctx, model.PositionQuery{})
+	return positions, err
+}
+
+func (r *SQLitePositionRepository) GetPositionsByExchange(ctx context.Context, exchange string) ([]model.Position, error) {
+	positions, _, err := r.QueryPositions(ctx, model.PositionQuery{
+		PositionFilter: model.PositionFilter{Exchange: exchange},
+	})
+	return positions, err
+}
+
+func (r *SQLitePositionRepository) GetPositionsByDateRange(ctx context.Context, start, end time.Time) ([]model.Position, error) {
+	positions, _, err := r.QueryPositions(ctx, model.PositionQuery{
+		PositionFilter: model.PositionFilter{From: start, To: end},
+	})
+	return positions, err
+}
+
+func (r *SQLitePositionRepository) QueryPositions(ctx context.Context, q model.PositionQuery) ([]model.Position, string, error) {
+	var positions []model.Position
+	err := r.StreamPositions(ctx, q, func(p model.Position) error {
+		positions = append(positions, p)
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	var nextCursor string
+	if q.Limit > 0 && len(positions) > q.Limit {
+		positions = positions[:q.Limit]
+		last := positions[len(positions)-1]
+		nextCursor = encodePositionCursor(q.SortBy, last, floatSortValue(q.SortBy, last))
+	}
+
+	return positions, nextCursor, nil
+}
+
+func (r *SQLitePositionRepository) StreamPositions(ctx context.Context, q model.PositionQuery, fn func(model.Position) error) error {
+	query, args, err := buildPositionQueryWith(&queryBuilder{sqlite: true}, q)
+	if err != nil {
+		return err
+	}
+
+	rows, err := r.db.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		p, err := scanSQLitePosition(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(p); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+func (r *SQLitePositionRepository) GetPositionByID(ctx context.Context, id int) (*model.Position, error) {
+	query := `SELECT ` + positionColumns + ` FROM position WHERE id = ?`
+	return r.getPosition(ctx, query, id)
+}
+
+func (r *SQLitePositionRepository) GetPositionByOrderID(ctx context.Context, orderID string) (*model.Position, error) {
+	query := `SELECT ` + positionColumns + ` FROM position WHERE order_id = ?`
+	return r.getPosition(ctx, query, orderID)
+}
+
+func (r *SQLitePositionRepository) getPosition(ctx context.Context, query string, arg interface{}) (*model.Position, error) {
+	p, err := scanSQLitePosition(r.db.DB.QueryRowContext(ctx, query, arg))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+func (r *SQLitePositionRepository) GetPositionsByOrderIDs(ctx context.Context, orderIDs []string) (map[string]model.Position, error) {
+	positions := make(map[string]model.Position)
+
+	for start := 0; start < len(orderIDs); start += sqliteInChunk {
+		chunk := orderIDs[start:min(start+sqliteInChunk, len(orderIDs))]
+
+		args := make([]interface{}, len(chunk))
+		for i, id := range chunk {
+			args[i] = id
+		}
+		query := `SELECT ` + positionColumns + ` FROM position WHERE order_id IN (` +
+			strings.TrimSuffix(strings.Repeat("?, ", len(chunk)), ", ") + `)`
+
+		rows, err := r.db.DB.QueryContext(ctx, query, args...)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			p, err := scanSQLitePosition(rows)
+			if err != nil {
+				rows.Close()
+				return nil, err
+			}
+			positions[p.OrderID] = p
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	return positions, nil
+}
+
+func (r *SQLitePositionRepository) SumClosedPnl(ctx context.Context, filter model.PositionFilter) (float64, error) {
+	b := &queryBuilder{sqlite: true}
+	if err := b.applyPositionFilter(filter); err != nil {
+		return 0, err
+	}
+
+	var total float64
+	query := `SELECT COALESCE(SUM(closed_pnl), 0) FROM position` + b.whereClause()
+	err := r.db.DB.QueryRowContext(ctx, query, b.args...).Scan(&total)
+	return total, err
+}
+
+func (r *SQLitePositionRepository) AggregateMonthly(ctx context.Context, filter model.PositionFilter) ([]model.MonthlyIncome, error) {
+	b := &queryBuilder{sqlite: true}
+	if err := b.applyPositionFilter(filter); err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT strftime('%Y-%m-01T00:00:00.000Z', date) AS month, exchange,
+		       COALESCE(SUM(volume), 0), COALESCE(SUM(closed_pnl), 0)
+		FROM position` + b.whereClause() + `
+		GROUP BY month, exchange
+		ORDER BY month DESC, exchange
+	`
+
+	rows, err := r.db.DB.QueryContext(ctx, query, b.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var incomes []model.MonthlyIncome
+	for rows.Next() {
+		var i model.MonthlyIncome
+		if err := rows.Scan(sqliteTimestamp{&i.CreatedAt}, &i.Exchange, &i.Amount, &i.PNL); err != nil {
+			return nil, err
+		}
+		incomes = append(incomes, i)
+	}
+	return incomes, rows.Err()
+}
+
+func (r *SQLitePositionRepository) GetPositionStats(ctx context.Context, filter model.PositionFilter, groupBy string) ([]model.PositionStats, error) {
+	column, ok := positionGroupColumns[groupBy]
+	if !ok {
+		return nil, fmt.Errorf("invalid group column: %s", groupBy)
+	}
+
+	b := &queryBuilder{sqlite: true}
+	if err := b.applyPositionFilter(filter); err != nil {
+		return nil, err
+	}
+
+	selectKey, groupClause := "''", ""
+	if column != "" {
+		selectKey = column
+		groupClause = " GROUP BY " + column + " ORDER BY " + column
+	}
+
+	query := `
+		SELECT ` + selectKey + `, COUNT(*),
+		       COUNT(*) FILTER (WHERE closed_pnl > 0),
+		       COUNT(*) FILTER (WHERE closed_pnl < 0),
+		       COALESCE(SUM(closed_pnl), 0), COALESCE(SUM(volume), 0)
+		FROM position` + b.whereClause() + groupClause
+
+	rows, err := r.db.DB.QueryContext(ctx, query, b.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []model.PositionStats
+	for rows.Next() {
+		var key string
+		var s model.PositionStats
+		if err := rows.Scan(&key, &s.Trades, &s.Wins, &s.Losses, &s.TotalPnl, &s.TotalVolume); err != nil {
+			return nil, err
+		}
+		switch column {
+		case "exchange":
+			s.Exchange = key
+		case "symbol":
+			s.Symbol = key
+		case "instrument":
+			s.Instrument = key
+		}
+		stats = append(stats, s)
+	}
+	return stats, rows.Err()
+}
+
+func (r *SQLitePositionRepository) DeletePosition(ctx context.Context, id int) error {
+	tx, err := r.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM position WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return err
+	}
+
+	entry := changeEntry{model.ChangeEntityPosition, model.ChangeDelete, strconv.Itoa(id), nil}
+	if err := recordSQLiteChanges(ctx, tx, []changeEntry{entry}); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// NormalizeInstruments fills in the instrument of positions saved before it
+// was tracked, returning the positions updated
+func (r *SQLitePositionRepository) NormalizeInstruments(ctx context.Context) (int64, error) {
+	tx, err := r.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `SELECT DISTINCT exchange, symbol FROM position WHERE instrument = ''`)
+	if err != nil {
+		return 0, err
+	}
+	var symbols []model.Position
+	for rows.Next() {
+		var p model.Position
+		if err := rows.Scan(&p.Exchange, &p.Symbol); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		symbols = append(symbols, withInstrument(p))
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	var updated int64
+	for _, p := range symbols {
+		res, err := tx.ExecContext(ctx, `
+			UPDATE position
+			SET base_asset = ?3, quote_asset = ?4, contract_type = ?5, instrument = ?6
+			WHERE exchange = ?1 AND symbol = ?2 AND instrument = ''
+		`, p.Exchange, p.Symbol, p.BaseAsset, p.QuoteAsset, p.ContractType, p.Instrument)
+		if err != nil {
+			return 0, err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		updated += n
+	}
+
+	if updated > 0 {
+		entry := changeEntry{model.ChangeEntityPosition, model.ChangeReset, "", nil}
+		if err := recordSQLiteChanges(ctx, tx, []changeEntry{entry}); err != nil {
+			return 0, err
+		}
+	}
+
+	return updated, tx.Commit()
+}
+
+type sqlRow interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanSQLitePosition(row sqlRow) (model.Position, error) {
+	var p model.Position
+	err := row.Scan(
+		&p.ID,
+		&p.OrderID,
+		&p.Exchange,
+		&p.Account,
+		&p.Symbol,
+		&p.BaseAsset,
+		&p.QuoteAsset,
+		&p.ContractType,
+		&p.Instrument,
+		&p.Volume,
+		&p.Leverage,
+		&p.ClosedPnl,
+		&p.Side,
+		sqliteTimestamp{&p.UpdatedAt},
+	)
+	return p, err
+}
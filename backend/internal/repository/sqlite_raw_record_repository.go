@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"github.com/Ravierin/BudgetTracker/backend/internal/model"
+	"github.com/Ravierin/BudgetTracker/backend/pkg/database"
+	"context"
+)
+
+type SQLiteRawRecordRepository struct {
+	db *database.SQLiteDatabase
+}
+
+func NewSQLiteRawRecordRepository(db *database.SQLiteDatabase) *SQLiteRawRecordRepository {
+	return &SQLiteRawRecordRepository{db: db}
+}
+
+// SaveRawRecords upserts payloads in one transaction
+func (r *SQLiteRawRecordRepository) SaveRawRecords(ctx context.Context, records []model.RawExchangeRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO raw_exchange_record (exchange, external_id, kind, payload, fetched_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (exchange, external_id) DO UPDATE SET
+			kind = excluded.kind,
+			payload = excluded.payload,
+			fetched_at = excluded.fetched_at
+	`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, rec := range records {
+		_, err := stmt.ExecContext(ctx, rec.Exchange, rec.ExternalID, rec.Kind, string(rec.Payload), sqliteTime(rec.FetchedAt))
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *SQLiteRawRecordRepository) StreamRawRecords(ctx context.Context, exchange string, fn func(model.RawExchangeRecord) error) error {
+	query := `
+		SELECT exchange, external_id, kind, payload, fetched_at
+		FROM raw_exchange_record
+		WHERE ?1 = '' OR exchange = ?1
+		ORDER BY exchange, external_id
+	`
+
+	rows, err := r.db.DB.QueryContext(ctx, query, exchange)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var rec model.RawExchangeRecord
+		var payload string
+		if err := rows.Scan(&rec.Exchange, &rec.ExternalID, &rec.Kind, &payload, sqliteTimestamp{&rec.FetchedAt}); err != nil {
+			return err
+		}
+		rec.Payload = []byte(payload)
+		if err := fn(rec); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"github.com/Ravierin/BudgetTracker/backend/internal/model"
+	"github.com/Ravierin/BudgetTracker/backend/pkg/database"
+	"context"
+	"database/sql"
+	"errors"
+)
+
+type SQLiteSettingsRepository struct {
+	db *database.SQLiteDatabase
+}
+
+func NewSQLiteSettingsRepository(db *database.SQLiteDatabase) *SQLiteSettingsRepository {
+	return &SQLiteSettingsRepository{db: db}
+}
+
+func (r *SQLiteSettingsRepository) Get(ctx context.Context, key string) (*model.Setting, error) {
+	query := `SELECT key, value, updated_at FROM settings WHERE key = ?`
+
+	var s model.Setting
+	err := r.db.DB.QueryRowContext(ctx, query, key).Scan(&s.Key, &s.Value, sqliteTimestamp{&s.UpdatedAt})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+func (r *SQLiteSettingsRepository) GetAll(ctx context.Context) ([]model.Setting, error) {
+	query := `SELECT key, value, updated_at FROM settings ORDER BY key`
+
+	rows, err := r.db.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var settings []model.Setting
+	for rows.Next() {
+		var s model.Setting
+		if err := rows.Scan(&s.Key, &s.Value, sqliteTimestamp{&s.UpdatedAt}); err != nil {
+			return nil, err
+		}
+		settings = append(settings, s)
+	}
+	return settings, rows.Err()
+}
+
+func (r *SQLiteSettingsRepository) Set(ctx context.Context, key, value string) error {
+	tx, err := r.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	entry := changeEntry{model.ChangeEntitySetting, model.ChangeInsert, key, nil}
+	var stored bool
+	if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM settings WHERE key = ?)`, key).Scan(&stored); err != nil {
+		return err
+	}
+	if stored {
+		entry.op = model.ChangeUpdate
+	}
+
+	query := `
+		INSERT INTO settings (key, value, updated_at)
+		VALUES (?, ?, strftime('%Y-%m-%dT%H:%M:%fZ', 'now'))
+		ON CONFLICT (key) DO UPDATE SET
+			value = excluded.value,
+			updated_at = excluded.updated_at
+		RETURNING updated_at
+	`
+	s := model.Setting{Key: key, Value: value}
+	if err := tx.QueryRowContext(ctx, query, key, value).Scan(sqliteTimestamp{&s.UpdatedAt}); err != nil {
+		return err
+	}
+
+	entry.data = s
+	if err := recordSQLiteChanges(ctx, tx, []changeEntry{entry}); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *SQLiteSettingsRepository) Delete(ctx context.Context, key string) error {
+	tx, err := r.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM settings WHERE key = ?`, key)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return err
+	}
+
+	entry := changeEntry{model.ChangeEntitySetting, model.ChangeDelete, key, nil}
+	if err := recordSQLiteChanges(ctx, tx, []changeEntry{entry}); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"github.com/Ravierin/BudgetTracker/backend/internal/model"
+	"github.com/Ravierin/BudgetTracker/backend/pkg/database"
+	"context"
+	"strings"
+	"time"
+)
+
+type SQLiteSyncRunRepository struct {
+	db *database.SQLiteDatabase
+}
+
+func NewSQLiteSyncRunRepository(db *database.SQLiteDatabase) *SQLiteSyncRunRepository {
+	return &SQLiteSyncRunRepository{db: db}
+}
+
+func (r *SQLiteSyncRunRepository) SaveSyncRun(ctx context.Context, run model.SyncRun) error {
+	query := `
+		INSERT INTO sync_run (
+			exchange, account, triggered_by, started_at, finished_at, pages,
+			fetched, inserted, updated, error_class, error_message
+		) VALUES (
+			?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?
+		)
+	`
+	_, err := r.db.DB.ExecContext(ctx, query,
+		run.Exchange,
+		run.Account,
+		run.Trigger,
+		sqliteTime(run.StartedAt),
+		sqliteTime(run.FinishedAt),
+		run.Pages,
+		run.Fetched,
+		run.Inserted,
+		run.Updated,
+		run.ErrorClass,
+		run.ErrorMessage,
+	)
+	return err
+}
+
+func (r *SQLiteSyncRunRepository) GetSyncRuns(ctx context.Context, exchange string, limit int) ([]model.SyncRun, error) {
+	query := `
+		SELECT ` + syncRunColumns + `
+		FROM sync_run
+		WHERE ?1 = '' OR exchange = ?1
+		ORDER BY id DESC
+		LIMIT ?2
+	`
+	rows, err := r.db.DB.QueryContext(ctx, query, exchange, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var runs []model.SyncRun
+	for rows.Next() {
+		run, err := scanSQLiteSyncRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
+
+func (r *SQLiteSyncRunRepository) GetSyncStatus(ctx context.Context) ([]model.SyncAccountStatus, error) {
+	rows, err := r.db.DB.QueryContext(ctx, syncStatusQuery)
+	if err != nil {
+		return nil, err
+	}
+	var statusRows []syncStatusRow
+	var ids []interface{}
+	for rows.Next() {
+		var s syncStatusRow
+		if err := rows.Scan(&s.exchange, &s.account, &s.lastID, &s.okID, &s.failures, &s.firstFailureID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		statusRows = append(statusRows, s)
+		for _, id := range s.runIDs() {
+			ids = append(ids, id)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(statusRows) == 0 {
+		return []model.SyncAccountStatus{}, nil
+	}
+
+	// Three IDs per account stay far below sqliteInChunk
+	query := `SELECT ` + syncRunColumns + ` FROM sync_run WHERE id IN (` +
+		strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ") + `)`
+	rows, err = r.db.DB.QueryContext(ctx, query, ids...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	runs := make(map[int64]model.SyncRun)
+	for rows.Next() {
+		run, err := scanSQLiteSyncRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		runs[run.ID] = run
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return buildSyncStatus(statusRows, runs), nil
+}
+
+func (r *SQLiteSyncRunRepository) DeleteSyncRunsBefore(ctx context.Context, t time.Time) (int64, error) {
+	res, err := r.db.DB.ExecContext(ctx, `DELETE FROM sync_run WHERE started_at < ?`, sqliteTime(t))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func scanSQLiteSyncRun(row sqlRow) (model.SyncRun, error) {
+	var run model.SyncRun
+	err := row.Scan(
+		&run.ID,
+		&run.Exchange,
+		&run.Account,
+		&run.Trigger,
+		sqliteTimestamp{&run.StartedAt},
+		sqliteTimestamp{&run.FinishedAt},
+		&run.Pages,
+		&run.Fetched,
+		&run.Inserted,
+		&run.Updated,
+		&run.ErrorClass,
+		&run.ErrorMessage,
+	)
+	return run, err
+}
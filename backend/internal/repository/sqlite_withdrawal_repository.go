@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"github.com/Ravierin/BudgetTracker/backend/internal/model"
+	"github.com/Ravierin/BudgetTracker/backend/pkg/database"
+	"context"
+	"database/sql"
+	"strconv"
+	"time"
+)
+
+type SQLiteWithdrawalRepository struct {
+	db *database.SQLiteDatabase
+}
+
+func NewSQLiteWithdrawalRepository(db *database.SQLiteDatabase) *SQLiteWithdrawalRepository {
+	return &SQLiteWithdrawalRepository{db: db}
+}
+
+func (r *SQLiteWithdrawalRepository) SaveWithdrawal(ctx context.Context, withdrawal model.Withdrawal) error {
+	tx, err := r.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO withdrawal (exchange, amount, currency, date)
+		VALUES (?, ?, ?, ?)
+		RETURNING id
+	`
+	err = tx.QueryRowContext(ctx, query,
+		withdrawal.Exchange,
+		withdrawal.Amount,
+		withdrawal.Currency,
+		sqliteTime(withdrawal.CreatedAt),
+	).Scan(&withdrawal.ID)
+	if err != nil {
+		return err
+	}
+
+	entry := changeEntry{model.ChangeEntityWithdrawal, model.ChangeInsert, strconv.Itoa(withdrawal.ID), withdrawal}
+	if err := recordSQLiteChanges(ctx, tx, []changeEntry{entry}); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *SQLiteWithdrawalRepository) GetAllWithdrawals(ctx context.Context) ([]model.Withdrawal, error) {
+	query := `
+		SELECT id, exchange, amount, currency, date
+		FROM withdrawal
+		ORDER BY date DESC
+	`
+	return r.query(ctx, query)
+}
+
+func (r *SQLiteWithdrawalRepository) GetWithdrawalsByExchange(ctx context.Context, exchange string) ([]model.Withdrawal, error) {
+	query := `
+		SELECT id, exchange, amount, currency, date
+		FROM withdrawal
+		WHERE exchange = ?
+		ORDER BY date DESC
+	`
+	return r.query(ctx, query, exchange)
+}
+
+func (r *SQLiteWithdrawalRepository) GetWithdrawalsByDateRange(ctx context.Context, start, end time.Time) ([]model.Withdrawal, error) {
+	query := `
+		SELECT id, exchange, amount, currency, date
+		FROM withdrawal
+		WHERE date BETWEEN ? AND ?
+		ORDER BY date DESC
+	`
+	return r.query(ctx, query, sqliteTime(start), sqliteTime(end))
+}
+
+func (r *SQLiteWithdrawalRepository) query(ctx context.Context, query string, args ...interface{}) ([]model.Withdrawal, error) {
+	rows, err := r.db.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var withdrawals []model.Withdrawal
+	for rows.Next() {
+		w, err := scanSQLiteWithdrawal(rows)
+		if err != nil {
+			return nil, err
+		}
+		withdrawals = append(withdrawals, w)
+	}
+	return withdrawals, rows.Err()
+}
+
+func (r *SQLiteWithdrawalRepository) StreamWithdrawals(ctx context.Context, filter model.WithdrawalFilter, fn func(model.Withdrawal) error) error {
+	b := queryBuilder{sqlite: true}
+	b.applyWithdrawalFilter(filter)
+	query := `SELECT id, exchange, amount, currency, date FROM withdrawal` + b.whereClause() + ` ORDER BY date DESC`
+	rows, err := r.db.DB.QueryContext(ctx, query, b.args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		w, err := scanSQLiteWithdrawal(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(w); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (r *SQLiteWithdrawalRepository) DeleteWithdrawal(ctx context.Context, id int) error {
+	tx, err := r.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM withdrawal WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return err
+	}
+
+	entry := changeEntry{model.ChangeEntityWithdrawal, model.ChangeDelete, strconv.Itoa(id), nil}
+	if err := recordSQLiteChanges(ctx, tx, []changeEntry{entry}); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func scanSQLiteWithdrawal(rows *sql.Rows) (model.Withdrawal, error) {
+	var w model.Withdrawal
+	err := rows.Scan(&w.ID, &w.Exchange, &w.Amount, &w.Currency, sqliteTimestamp{&w.CreatedAt})
+	return w, err
+}
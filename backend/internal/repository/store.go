@@ -0,0 +1,178 @@
+package repository
+
+import (
+	"github.com/Ravierin/BudgetTracker/backend/internal/model"
+	"github.com/Ravierin/BudgetTracker/backend/pkg/database"
+	"context"
+	"time"
+)
+
+// PositionStore persists closed positions, de-duplicated by order ID.
+// SavePositionBatch reports the positions it inserted and those whose
+// stored values it changed.
+type PositionStore interface {
+	SavePosition(ctx context.Context, position model.Position) error
+	SavePositionBatch(ctx context.Context, positions []model.Position) (model.PositionChanges, error)
+	GetAllPositions(ctx context.Context) ([]model.Position, error)
+	GetPositionsByExchange(ctx context.Context, exchange string) ([]model.Position, error)
+	GetPositionsByDateRange(ctx context.Context, start, end time.Time) ([]model.Position, error)
+	QueryPositions(ctx context.Context, q model.PositionQuery) ([]model.Position, string, error)
+	StreamPositions(ctx context.Context, q model.PositionQuery, fn func(model.Position) error) error
+	GetPositionByID(ctx context.Context, id int) (*model.Position, error)
+	GetPositionByOrderID(ctx context.Context, orderID string) (*model.Position, error)
+	GetPositionsByOrderIDs(ctx context.Context, orderIDs []string) (map[string]model.Position, error)
+	SumClosedPnl(ctx context.Context, filter model.PositionFilter) (float64, error)
+	AggregateMonthly(ctx context.Context, filter model.PositionFilter) ([]model.MonthlyIncome, error)
+	GetPositionStats(ctx context.Context, filter model.PositionFilter, groupBy string) ([]model.PositionStats, error)
+	DeletePosition(ctx context.Context, id int) error
+	NormalizeInstruments(ctx context.Context) (int64, error)
+}
+
+// RollupStore answers aggregates from pre-computed daily rows
+type RollupStore interface {
+	Supports(filter model.PositionFilter) bool
+	SumPnl(ctx context.Context, filter model.PositionFilter) (float64, error)
+	AggregateMonthly(ctx context.Context, filter model.PositionFilter) ([]model.MonthlyIncome, error)
+	GetStats(ctx context.Context, filter model.PositionFilter, groupBy string) ([]model.PositionStats, error)
+	Rebuild(ctx context.Context) (int64, error)
+}
+
+type WithdrawalStore interface {
+	SaveWithdrawal(ctx context.Context, withdrawal model.Withdrawal) error
+	GetAllWithdrawals(ctx context.Context) ([]model.Withdrawal, error)
+	GetWithdrawalsByExchange(ctx context.Context, exchange string) ([]model.Withdrawal, error)
+	GetWithdrawalsByDateRange(ctx context.Context, start, end time.Time) ([]model.Withdrawal, error)
+	StreamWithdrawals(ctx context.Context, filter model.WithdrawalFilter, fn func(model.Withdrawal) error) error
+	DeleteWithdrawal(ctx context.Context, id int) error
+}
+
+type MonthlyIncomeStore interface {
+	SaveMonthlyIncome(ctx context.Context, income model.MonthlyIncome) error
+	GetAllMonthlyIncomes(ctx context.Context) ([]model.MonthlyIncome, error)
+	GetIncomesByExchange(ctx context.Context, exchange string) ([]model.MonthlyIncome, error)
+	GetIncomesByDateRange(ctx context.Context, start, end time.Time) ([]model.MonthlyIncome, error)
+	DeleteMonthlyIncome(ctx context.Context, id int) error
+}
+
+type APIKeyStore interface {
+	GetByExchange(ctx context.Context, exchange string) (*model.APIKey, error)
+	Upsert(ctx context.Context, apiKey *model.APIKey) error
+	GetAll(ctx context.Context) ([]model.APIKey, error)
+}
+
+type ImportProfileStore interface {
+	GetByName(ctx context.Context, name string) (*model.ImportProfile, error)
+	GetAll(ctx context.Context) ([]model.ImportProfile, error)
+	Upsert(ctx context.Context, p *model.ImportProfile) error
+	Delete(ctx context.Context, name string) error
+}
+
+type SettingsStore interface {
+	Get(ctx context.Context, key string) (*model.Setting, error)
+	GetAll(ctx context.Context) ([]model.Setting, error)
+	Set(ctx context.Context, key, value string) error
+	Delete(ctx context.Context, key string) error
+}
+
+// RawRecordStore keeps the exchange payloads positions were mapped from,
+// one per exchange and external ID
+type RawRecordStore interface {
+	SaveRawRecords(ctx context.Context, records []model.RawExchangeRecord) error
+	StreamRawRecords(ctx context.Context, exchange string, fn func(model.RawExchangeRecord) error) error
+}
+
+// ContractStore keeps exchange contract metadata. Saving fetched listings
+// never touches overrides or applied sizes. RescaleVolumes multiplies the
+// volume of every position of a symbol, synced or imported, by to/from and
+// applies the size to the symbol in one transaction; with dryRun it only
+// counts the positions.
+type ContractStore interface {
+	GetContracts(ctx context.Context, exchange string) ([]model.ContractMeta, error)
+	SaveContracts(ctx context.Context, contracts []model.ContractMeta) error
+	SetContractOverride(ctx context.Context, exchange, symbol string, size *float64) error
+	SetAppliedContractSize(ctx context.Context, exchange, symbol string, size float64) error
+	RescaleVolumes(ctx context.Context, exchange, symbol string, from, to float64, dryRun bool) (int, error)
+}
+
+// ChangeStore reads the change log that the position, withdrawal and
+// settings repositories write with every committed change, oldest first
+type ChangeStore interface {
+	GetChanges(ctx context.Context, after int64, limit int) ([]model.Change, error)
+	LatestChangeID(ctx context.Context) (int64, error)
+}
+
+// SyncRunStore keeps one row per sync attempt of an exchange account
+type SyncRunStore interface {
+	SaveSyncRun(ctx context.Context, run model.SyncRun) error
+	GetSyncRuns(ctx context.Context, exchange string, limit int) ([]model.SyncRun, error)
+	GetSyncStatus(ctx context.Context) ([]model.SyncAccountStatus, error)
+	DeleteSyncRunsBefore(ctx context.Context, t time.Time) (int64, error)
+}
+
+// BackupStore opens the transaction a backup is restored in
+type BackupStore interface {
+	BeginRestore(ctx context.Context, replace, includeKeys bool) (RestoreWriter, error)
+}
+
+// RestoreWriter writes backup records; nothing is visible until Commit
+type RestoreWriter interface {
+	AddPosition(ctx context.Context, p model.Position) error
+	AddWithdrawal(ctx context.Context, w model.Withdrawal) (bool, error)
+	AddMonthlyIncome(ctx context.Context, i model.MonthlyIncome) (bool, error)
+	AddSetting(ctx context.Context, setting model.Setting) error
+	AddImportProfile(ctx context.Context, p model.ImportProfile) error
+	AddAPIKey(ctx context.Context, k model.APIKey) error
+	Commit(ctx context.Context) error
+	Rollback(ctx context.Context) error
+}
+
+// Stores bundles one implementation of every repository
+type Stores struct {
+	Positions      PositionStore
+	Rollup         RollupStore // nil when the backend aggregates positions directly
+	Withdrawals    WithdrawalStore
+	MonthlyIncomes MonthlyIncomeStore
+	APIKeys        APIKeyStore
+	ImportProfiles ImportProfileStore
+	Settings       SettingsStore
+	RawRecords     RawRecordStore
+	Contracts      ContractStore
+	Backup         BackupStore
+	Changes        ChangeStore
+	SyncRuns       SyncRunStore
+}
+
+func NewPostgresStores(db *database.Database) *Stores {
+	return &Stores{
+		Positions:      NewPositionRepository(db),
+		Rollup:         NewRollupRepository(db),
+		Withdrawals:    NewWithdrawalRepository(db),
+		MonthlyIncomes: NewMonthlyIncomeRepository(db),
+		APIKeys:        NewAPIKeyRepository(db),
+		ImportProfiles: NewImportProfileRepository(db),
+		Settings:       NewSettingsRepository(db),
+		RawRecords:     NewRawRecordRepository(db),
+		Contracts:      NewContractRepository(db),
+		Backup:         NewBackupRepository(db),
+		Changes:        NewChangeLogRepository(db),
+		SyncRuns:       NewSyncRunRepository(db),
+	}
+}
+
+// NewSQLiteStores has no rollup: aggregates are computed from the position
+// table, which is fast enough at single-user sizes
+func NewSQLiteStores(db *database.SQLiteDatabase) *Stores {
+	return &Stores{
+		Positions:      NewSQLitePositionRepository(db),
+		Withdrawals:    NewSQLiteWithdrawalRepository(db),
+		MonthlyIncomes: NewSQLiteMonthlyIncomeRepository(db),
+		APIKeys:        NewSQLiteAPIKeyRepository(db),
+		ImportProfiles: NewSQLiteImportProfileRepository(db),
+		Settings:       NewSQLiteSettingsRepository(db),
+		RawRecords:     NewSQLiteRawRecordRepository(db),
+		Contracts:      NewSQLiteContractRepository(db),
+		Backup:         NewSQLiteBackupRepository(db),
+		Changes:        NewSQLiteChangeLogRepository(db),
+		SyncRuns:       NewSQLiteSyncRunRepository(db),
+	}
+}
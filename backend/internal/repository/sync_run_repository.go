@@ -0,0 +1,190 @@
+package repository
+
+import (
+	"github.com/Ravierin/BudgetTracker/backend/internal/model"
+	"github.com/Ravierin/BudgetTracker/backend/pkg/database"
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const syncRunColumns = `id, exchange, account, triggered_by, started_at, finished_at, pages, fetched, inserted, updated, error_class, error_message`
+
+// syncStatusQuery finds, per account, the last run, the last successful one
+// and the runs since it, which all failed. Plain SQL shared by both backends.
+const syncStatusQuery = `
+	SELECT a.exchange, a.account, a.last_id, a.ok_id, COUNT(f.id), COALESCE(MIN(f.id), 0)
+	FROM (
+		SELECT exchange, account, MAX(id) AS last_id,
+		       COALESCE(MAX(CASE WHEN error_class = '' THEN id END), 0) AS ok_id
+		FROM sync_run
+		GROUP BY exchange, account
+	) a
+	LEFT JOIN sync_run f ON f.exchange = a.exchange AND f.account = a.account AND f.id > a.ok_id
+	GROUP BY a.exchange, a.account, a.last_id, a.ok_id
+	ORDER BY a.exchange, a.account
+`
+
+// syncStatusRow is a row of syncStatusQuery; the IDs are 0 when there is no
+// such run
+type syncStatusRow struct {
+	exchange, account string
+	lastID, okID      int64
+	failures          int
+	firstFailureID    int64
+}
+
+func (s syncStatusRow) runIDs() []int64 {
+	return []int64{s.lastID, s.okID, s.firstFailureID}
+}
+
+// buildSyncStatus resolves the run IDs of status rows against runs
+func buildSyncStatus(rows []syncStatusRow, runs map[int64]model.SyncRun) []model.SyncAccountStatus {
+	statuses := make([]model.SyncAccountStatus, 0, len(rows))
+	for _, row := range rows {
+		status := model.SyncAccountStatus{
+			Exchange:      row.exchange,
+			Account:       row.account,
+			LastRun:       runs[row.lastID],
+			FailureStreak: row.failures,
+		}
+		if run, ok := runs[row.okID]; ok {
+			status.LastSuccess = &run
+		}
+		if run, ok := runs[row.firstFailureID]; ok {
+			status.FailingSince = &run.StartedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+type SyncRunRepository struct {
+	db *database.Database
+}
+
+func NewSyncRunRepository(db *database.Database) *SyncRunRepository {
+	return &SyncRunRepository{db: db}
+}
+
+func (r *SyncRunRepository) SaveSyncRun(ctx context.Context, run model.SyncRun) error {
+	query := `
+		INSERT INTO sync_run (
+			exchange, account, triggered_by, started_at, finished_at, pages,
+			fetched, inserted, updated, error_class, error_message
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
+		)
+	`
+	_, err := r.db.Pool.Exec(ctx, query,
+		run.Exchange,
+		run.Account,
+		run.Trigger,
+		run.StartedAt,
+		run.FinishedAt,
+		run.Pages,
+		run.Fetched,
+		run.Inserted,
+		run.Updated,
+		run.ErrorClass,
+		run.ErrorMessage,
+	)
+	return err
+}
+
+// GetSyncRuns returns the latest runs first, optionally of one exchange
+func (r *SyncRunRepository) GetSyncRuns(ctx context.Context, exchange string, limit int) ([]model.SyncRun, error) {
+	query := `
+		SELECT ` + syncRunColumns + `
+		FROM sync_run
+		WHERE $1::text = '' OR exchange = $1
+		ORDER BY id DESC
+		LIMIT $2
+	`
+	rows, err := r.db.Pool.Query(ctx, query, exchange, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var runs []model.SyncRun
+	for rows.Next() {
+		run, err := scanSyncRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
+
+func (r *SyncRunRepository) GetSyncStatus(ctx context.Context) ([]model.SyncAccountStatus, error) {
+	rows, err := r.db.Pool.Query(ctx, syncStatusQuery)
+	if err != nil {
+		return nil, err
+	}
+	var statusRows []syncStatusRow
+	var ids []int64
+	for rows.Next() {
+		var s syncStatusRow
+		if err := rows.Scan(&s.exchange, &s.account, &s.lastID, &s.okID, &s.failures, &s.firstFailureID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		statusRows = append(statusRows, s)
+		ids = append(ids, s.runIDs()...)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = r.db.Pool.Query(ctx, `SELECT `+syncRunColumns+` FROM sync_run WHERE id = ANY($1)`, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	runs := make(map[int64]model.SyncRun)
+	for rows.Next() {
+		run, err := scanSyncRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		runs[run.ID] = run
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return buildSyncStatus(statusRows, runs), nil
+}
+
+// DeleteSyncRunsBefore prunes runs started before t
+func (r *SyncRunRepository) DeleteSyncRunsBefore(ctx context.Context, t time.Time) (int64, error) {
+	tag, err := r.db.Pool.Exec(ctx, `DELETE FROM sync_run WHERE started_at < $1`, t)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+func scanSyncRun(row pgx.Row) (model.SyncRun, error) {
+	var run model.SyncRun
+	err := row.Scan(
+		&run.ID,
+		&run.Exchange,
+		&run.Account,
+		&run.Trigger,
+		&run.StartedAt,
+		&run.FinishedAt,
+		&run.Pages,
+		&run.Fetched,
+		&run.Inserted,
+		&run.Updated,
+		&run.ErrorClass,
+		&run.ErrorMessage,
+	)
+	return run, err
+}
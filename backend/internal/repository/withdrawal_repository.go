@@ -4,6 +4,7 @@ import (
 	"github.com/Ravierin/BudgetTracker/backend/internal/model"
 	"github.com/Ravierin/BudgetTracker/backend/pkg/database"
 	"context"
+	"strconv"
 	"time"
 )
 
@@ -16,17 +17,33 @@ func NewWithdrawalRepository(db *database.Database) *WithdrawalRepository {
 }
 
 func (r *WithdrawalRepository) SaveWithdrawal(ctx context.Context, withdrawal model.Withdrawal) error {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
 	query := `
 		INSERT INTO withdrawal (exchange, amount, currency, date)
 		VALUES ($1, $2, $3, $4)
+		RETURNING id
 	`
-	_, err := r.db.Pool.Exec(ctx, query,
+	err = tx.QueryRow(ctx, query,
 		withdrawal.Exchange,
 		withdrawal.Amount,
 		withdrawal.Currency,
 		withdrawal.CreatedAt,
-	)
-	return err
+	).Scan(&withdrawal.ID)
+	if err != nil {
+		return err
+	}
+
+	entry := changeEntry{model.ChangeEntityWithdrawal, model.ChangeInsert, strconv.Itoa(withdrawal.ID), withdrawal}
+	if err := recordChanges(ctx, tx, []changeEntry{entry}); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
 }
 
 func (r *WithdrawalRepository) GetAllWithdrawals(ctx context.Context) ([]model.Withdrawal, error) {
@@ -103,8 +120,60 @@ func (r *WithdrawalRepository) GetWithdrawalsByDateRange(ctx context.Context, st
 	return withdrawals, rows.Err()
 }
 
+// StreamWithdrawals calls fn for every withdrawal matching the filter,
+// newest first, without buffering the result set
+func (r *WithdrawalRepository) StreamWithdrawals(ctx context.Context, filter model.WithdrawalFilter, fn func(model.Withdrawal) error) error {
+	var b queryBuilder
+	b.applyWithdrawalFilter(filter)
+	query := `SELECT id, exchange, amount, currency, date FROM withdrawal` + b.whereClause() + ` ORDER BY date DESC`
+	rows, err := r.db.Pool.Query(ctx, query, b.args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var w model.Withdrawal
+		if err := rows.Scan(&w.ID, &w.Exchange, &w.Amount, &w.Currency, &w.CreatedAt); err != nil {
+			return err
+		}
+		if err := fn(w); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// applyWithdrawalFilter adds conditions for every non-empty filter field
+func (b *queryBuilder) applyWithdrawalFilter(f model.WithdrawalFilter) {
+	if f.Exchange != "" {
+		b.add("exchange = %s", f.Exchange)
+	}
+	if !f.From.IsZero() {
+		b.add("date >= %s", f.From)
+	}
+	if !f.To.IsZero() {
+		b.add("date < %s", f.To)
+	}
+}
+
 func (r *WithdrawalRepository) DeleteWithdrawal(ctx context.Context, id int) error {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
 	query := `DELETE FROM withdrawal WHERE id = $1`
-	_, err := r.db.Pool.Exec(ctx, query, id)
-	return err
+	tag, err := tx.Exec(ctx, query, id)
+	if err != nil || tag.RowsAffected() == 0 {
+		return err
+	}
+
+	entry := changeEntry{model.ChangeEntityWithdrawal, model.ChangeDelete, strconv.Itoa(id), nil}
+	if err := recordChanges(ctx, tx, []changeEntry{entry}); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
 }
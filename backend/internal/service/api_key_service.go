@@ -7,10 +7,10 @@ import (
 )
 
 type APIKeyService struct {
-	repo *repository.APIKeyRepository
+	repo repository.APIKeyStore
 }
 
-func NewAPIKeyService(repo *repository.APIKeyRepository) *APIKeyService {
+func NewAPIKeyService(repo repository.APIKeyStore) *APIKeyService {
 	return &APIKeyService{repo: repo}
 }
 
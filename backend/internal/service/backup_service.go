@@ -0,0 +1,261 @@
+package service
+
+import (
+	"github.com/Ravierin/BudgetTracker/backend/internal/backup"
+	"github.com/Ravierin/BudgetTracker/backend/internal/model"
+	"github.com/Ravierin/BudgetTracker/backend/internal/repository"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// BackupOptions selects the archive format and whether API keys are
+// included. Keys are only ever written encrypted with Passphrase.
+type BackupOptions struct {
+	Format      string // "json" or "ndjson"
+	IncludeKeys bool
+	Passphrase  string
+}
+
+// RestoreOptions selects how a backup is applied. "merge" upserts into the
+// existing data, "replace" empties the tables first.
+type RestoreOptions struct {
+	Mode       string
+	Passphrase string
+}
+
+type BackupService struct {
+	positionRepo   repository.PositionStore
+	withdrawalRepo repository.WithdrawalStore
+	incomeRepo     repository.MonthlyIncomeStore
+	settingsRepo   repository.SettingsStore
+	profileRepo    repository.ImportProfileStore
+	apiKeyRepo     repository.APIKeyStore
+	backupRepo     repository.BackupStore
+}
+
+func NewBackupService(
+	positionRepo repository.PositionStore,
+	withdrawalRepo repository.WithdrawalStore,
+	incomeRepo repository.MonthlyIncomeStore,
+	settingsRepo repository.SettingsStore,
+	profileRepo repository.ImportProfileStore,
+	apiKeyRepo repository.APIKeyStore,
+	backupRepo repository.BackupStore,
+) *BackupService {
+	return &BackupService{
+		positionRepo:   positionRepo,
+		withdrawalRepo: withdrawalRepo,
+		incomeRepo:     incomeRepo,
+		settingsRepo:   settingsRepo,
+		profileRepo:    profileRepo,
+		apiKeyRepo:     apiKeyRepo,
+		backupRepo:     backupRepo,
+	}
+}
+
+// Backup streams every table into a versioned archive
+func (s *BackupService) Backup(ctx context.Context, w io.Writer, opts BackupOptions) error {
+	header := backup.Header{
+		Format:    backup.FormatName,
+		Version:   backup.Version,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	var keyCipher *backup.KeyCipher
+	if opts.IncludeKeys {
+		var err error
+		if keyCipher, err = backup.NewKeyCipher(opts.Passphrase, nil); err != nil {
+			return err
+		}
+		header.KeySalt = keyCipher.Salt()
+	}
+
+	bw, err := backup.NewWriter(opts.Format, w, header)
+	if err != nil {
+		return err
+	}
+
+	err = s.positionRepo.StreamPositions(ctx, model.PositionQuery{}, func(p model.Position) error {
+		return bw.WriteRecord(backup.KindPosition, p)
+	})
+	if err != nil {
+		return fmt.Errorf("positions: %w", err)
+	}
+
+	err = s.withdrawalRepo.StreamWithdrawals(ctx, model.WithdrawalFilter{}, func(wd model.Withdrawal) error {
+		return bw.WriteRecord(backup.KindWithdrawal, wd)
+	})
+	if err != nil {
+		return fmt.Errorf("withdrawals: %w", err)
+	}
+
+	incomes, err := s.incomeRepo.GetAllMonthlyIncomes(ctx)
+	if err != nil {
+		return fmt.Errorf("monthly income: %w", err)
+	}
+	for _, i := range incomes {
+		if err := bw.WriteRecord(backup.KindMonthlyIncome, i); err != nil {
+			return err
+		}
+	}
+
+	settings, err := s.settingsRepo.GetAll(ctx)
+	if err != nil {
+		return fmt.Errorf("settings: %w", err)
+	}
+	for _, setting := range settings {
+		if err := bw.WriteRecord(backup.KindSetting, setting); err != nil {
+			return err
+		}
+	}
+
+	profiles, err := s.profileRepo.GetAll(ctx)
+	if err != nil {
+		return fmt.Errorf("import profiles: %w", err)
+	}
+	for _, p := range profiles {
+		if err := bw.WriteRecord(backup.KindImportProfile, p); err != nil {
+			return err
+		}
+	}
+
+	if keyCipher != nil {
+		keys, err := s.apiKeyRepo.GetAll(ctx)
+		if err != nil {
+			return fmt.Errorf("api keys: %w", err)
+		}
+		for _, k := range keys {
+			if k.APIKey, err = keyCipher.Encrypt(k.APIKey); err != nil {
+				return err
+			}
+			if k.APISecret, err = keyCipher.Encrypt(k.APISecret); err != nil {
+				return err
+			}
+			if err := bw.WriteRecord(backup.KindAPIKey, k); err != nil {
+				return err
+			}
+		}
+	}
+
+	return bw.Close()
+}
+
+// Restore validates the archive header and applies every record in one
+// transaction; any error leaves the database untouched
+func (s *BackupService) Restore(ctx context.Context, r io.Reader, opts RestoreOptions) (*model.RestoreResult, error) {
+	if opts.Mode == "" {
+		opts.Mode = "merge"
+	}
+	if opts.Mode != "merge" && opts.Mode != "replace" {
+		return nil, fmt.Errorf("invalid restore mode: %s", opts.Mode)
+	}
+
+	br, err := backup.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer br.Close()
+
+	// Encrypted keys are skipped when no passphrase is given, so a backup
+	// can still be restored without them
+	var keyCipher *backup.KeyCipher
+	if br.Header.KeySalt != "" && opts.Passphrase != "" {
+		if keyCipher, err = backup.NewKeyCipherForHeader(opts.Passphrase, br.Header); err != nil {
+			return nil, err
+		}
+	}
+
+	restore, err := s.backupRepo.BeginRestore(ctx, opts.Mode == "replace", keyCipher != nil)
+	if err != nil {
+		return nil, err
+	}
+	defer restore.Rollback(ctx)
+
+	result := &model.RestoreResult{Mode: opts.Mode}
+	err = br.Records(func(kind string, data json.RawMessage) error {
+		switch kind {
+		case backup.KindPosition:
+			var p model.Position
+			if err := json.Unmarshal(data, &p); err != nil {
+				return invalidRecord("position", err)
+			}
+			if p.OrderID == "" {
+				return fmt.Errorf("%w: position %d has no order ID", backup.ErrInvalidArchive, p.ID)
+			}
+			result.Positions++
+			return restore.AddPosition(ctx, p)
+
+		case backup.KindWithdrawal:
+			var wd model.Withdrawal
+			if err := json.Unmarshal(data, &wd); err != nil {
+				return invalidRecord("withdrawal", err)
+			}
+			inserted, err := restore.AddWithdrawal(ctx, wd)
+			if inserted {
+				result.Withdrawals++
+			}
+			return err
+
+		case backup.KindMonthlyIncome:
+			var i model.MonthlyIncome
+			if err := json.Unmarshal(data, &i); err != nil {
+				return invalidRecord("monthly income", err)
+			}
+			inserted, err := restore.AddMonthlyIncome(ctx, i)
+			if inserted {
+				result.MonthlyIncomes++
+			}
+			return err
+
+		case backup.KindSetting:
+			var setting model.Setting
+			if err := json.Unmarshal(data, &setting); err != nil {
+				return invalidRecord("setting", err)
+			}
+			result.Settings++
+			return restore.AddSetting(ctx, setting)
+
+		case backup.KindImportProfile:
+			var p model.ImportProfile
+			if err := json.Unmarshal(data, &p); err != nil {
+				return invalidRecord("import profile", err)
+			}
+			result.ImportProfiles++
+			return restore.AddImportProfile(ctx, p)
+
+		case backup.KindAPIKey:
+			if keyCipher == nil {
+				return nil
+			}
+			var k model.APIKey
+			if err := json.Unmarshal(data, &k); err != nil {
+				return invalidRecord("api key", err)
+			}
+			var err error
+			if k.APIKey, err = keyCipher.Decrypt(k.APIKey); err != nil {
+				return err
+			}
+			if k.APISecret, err = keyCipher.Decrypt(k.APISecret); err != nil {
+				return err
+			}
+			result.APIKeys++
+			return restore.AddAPIKey(ctx, k)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := restore.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func invalidRecord(kind string, err error) error {
+	return fmt.Errorf("%w: invalid %s: %v", backup.ErrInvalidArchive, kind, err)
+}
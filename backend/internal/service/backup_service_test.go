@@ -0,0 +1,191 @@
+package service
+
+import (
+	"github.com/Ravierin/BudgetTracker/backend/internal/backup"
+	"github.com/Ravierin/BudgetTracker/backend/internal/model"
+	"github.com/Ravierin/BudgetTracker/backend/internal/repository"
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+const testPassphrase = "correct horse"
+
+func newTestBackupService(stores *repository.Stores) *BackupService {
+	return NewBackupService(stores.Positions, stores.Withdrawals, stores.MonthlyIncomes,
+		stores.Settings, stores.ImportProfiles, stores.APIKeys, stores.Backup)
+}
+
+// newBackupSource fills memory stores with one or two rows of every kind.
+// The memory store already holds an empty key row per exchange.
+func newBackupSource(t *testing.T) *repository.Stores {
+	t.Helper()
+	ctx := context.Background()
+	stores := repository.NewMemoryStores()
+	date := time.Date(2025, 5, 2, 8, 30, 0, 0, time.UTC)
+
+	positions := []model.Position{
+		{OrderID: "a", Exchange: "bybit", Symbol: "BTCUSDT", Side: "Buy", Volume: 100, Leverage: 10, ClosedPnl: 1.5, UpdatedAt: date},
+		{OrderID: "b", Exchange: "mexc", Symbol: "ETHUSDT", Side: "Sell", Volume: 50, Leverage: 5, ClosedPnl: -2, UpdatedAt: date.Add(time.Hour)},
+	}
+	if _, err := stores.Positions.SavePositionBatch(ctx, positions); err != nil {
+		t.Fatal(err)
+	}
+	if err := stores.Withdrawals.SaveWithdrawal(ctx, model.Withdrawal{Exchange: "bybit", Amount: 100, Currency: "USDT", CreatedAt: date}); err != nil {
+		t.Fatal(err)
+	}
+	if err := stores.MonthlyIncomes.SaveMonthlyIncome(ctx, model.MonthlyIncome{Exchange: "mexc", Amount: 200, PNL: 12, CreatedAt: date}); err != nil {
+		t.Fatal(err)
+	}
+	if err := stores.Settings.Set(ctx, "theme", "dark"); err != nil {
+		t.Fatal(err)
+	}
+	profile := testImportProfile
+	if err := stores.ImportProfiles.Upsert(ctx, &profile); err != nil {
+		t.Fatal(err)
+	}
+	if err := stores.APIKeys.Upsert(ctx, &model.APIKey{Exchange: "bybit", APIKey: "key", APISecret: "secret", IsActive: true}); err != nil {
+		t.Fatal(err)
+	}
+	return stores
+}
+
+func writeBackup(t *testing.T, stores *repository.Stores, opts BackupOptions) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := newTestBackupService(stores).Backup(context.Background(), &buf, opts); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func orderIDs(t *testing.T, stores *repository.Stores) []string {
+	t.Helper()
+	positions, err := stores.Positions.GetAllPositions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := make([]string, 0, len(positions))
+	for _, p := range positions {
+		ids = append(ids, p.OrderID)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func TestBackupRestoreRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	source := newBackupSource(t)
+
+	for _, format := range []string{"json", "ndjson"} {
+		archive := writeBackup(t, source, BackupOptions{Format: format, IncludeKeys: true, Passphrase: testPassphrase})
+		if bytes.Contains(archive, []byte("secret")) {
+			t.Errorf("%s archive holds the API secret in clear text", format)
+		}
+
+		target := repository.NewMemoryStores()
+		if err := target.Positions.SavePosition(ctx, model.Position{OrderID: "stale", Exchange: "bybit", Symbol: "XRPUSDT"}); err != nil {
+			t.Fatal(err)
+		}
+
+		result, err := newTestBackupService(target).Restore(ctx, bytes.NewReader(archive), RestoreOptions{Mode: "replace", Passphrase: testPassphrase})
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		want := model.RestoreResult{Mode: "replace", Positions: 2, Withdrawals: 1, MonthlyIncomes: 1, Settings: 1, ImportProfiles: 1, APIKeys: 2}
+		if *result != want {
+			t.Errorf("%s result %+v, want %+v", format, *result, want)
+		}
+
+		if got := orderIDs(t, target); !reflect.DeepEqual(got, []string{"a", "b"}) {
+			t.Errorf("%s positions %v, want a and b without the stale one", format, got)
+		}
+		restored, err := target.Positions.GetPositionByOrderID(ctx, "b")
+		if err != nil || restored.ClosedPnl != -2 || restored.Volume != 50 || restored.Leverage != 5 {
+			t.Errorf("%s position b = %+v, %v", format, restored, err)
+		}
+
+		withdrawals, _ := target.Withdrawals.GetAllWithdrawals(ctx)
+		if len(withdrawals) != 1 || withdrawals[0].Amount != 100 || withdrawals[0].Currency != "USDT" {
+			t.Errorf("%s withdrawals %+v", format, withdrawals)
+		}
+		incomes, _ := target.MonthlyIncomes.GetAllMonthlyIncomes(ctx)
+		if len(incomes) != 1 || incomes[0].PNL != 12 {
+			t.Errorf("%s monthly incomes %+v", format, incomes)
+		}
+		if setting, err := target.Settings.Get(ctx, "theme"); err != nil || setting == nil || setting.Value != "dark" {
+			t.Errorf("%s setting %+v, %v", format, setting, err)
+		}
+		if profile, err := target.ImportProfiles.GetByName(ctx, "venue"); err != nil || profile == nil || profile.PnlColumn != "pnl" {
+			t.Errorf("%s import profile %+v, %v", format, profile, err)
+		}
+		key, err := target.APIKeys.GetByExchange(ctx, "bybit")
+		if err != nil || key == nil || key.APIKey != "key" || key.APISecret != "secret" || !key.IsActive {
+			t.Errorf("%s API key %+v, %v, want it decrypted", format, key, err)
+		}
+	}
+}
+
+func TestRestoreWithWrongPassphraseChangesNothing(t *testing.T) {
+	ctx := context.Background()
+	archive := writeBackup(t, newBackupSource(t), BackupOptions{IncludeKeys: true, Passphrase: testPassphrase})
+
+	target := repository.NewMemoryStores()
+	if err := target.Positions.SavePosition(ctx, model.Position{OrderID: "kept", Exchange: "bybit", Symbol: "XRPUSDT"}); err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := target.APIKeys.GetAll(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = newTestBackupService(target).Restore(ctx, bytes.NewReader(archive), RestoreOptions{Mode: "replace", Passphrase: "battery staple"})
+	if !errors.Is(err, backup.ErrWrongPassphrase) {
+		t.Fatalf("got %v, want ErrWrongPassphrase", err)
+	}
+	if got := orderIDs(t, target); !reflect.DeepEqual(got, []string{"kept"}) {
+		t.Errorf("positions %v after a failed restore, want them untouched", got)
+	}
+	if after, _ := target.APIKeys.GetAll(ctx); !reflect.DeepEqual(after, keys) {
+		t.Errorf("API keys %+v after a failed restore, want %+v", after, keys)
+	}
+}
+
+func TestRestoreWithoutPassphraseSkipsKeys(t *testing.T) {
+	ctx := context.Background()
+	archive := writeBackup(t, newBackupSource(t), BackupOptions{IncludeKeys: true, Passphrase: testPassphrase})
+
+	target := repository.NewMemoryStores()
+	if err := target.APIKeys.Upsert(ctx, &model.APIKey{Exchange: "bybit", APIKey: "own", APISecret: "own"}); err != nil {
+		t.Fatal(err)
+	}
+	s := newTestBackupService(target)
+
+	result, err := s.Restore(ctx, bytes.NewReader(archive), RestoreOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Mode != "merge" || result.Positions != 2 || result.Withdrawals != 1 || result.APIKeys != 0 {
+		t.Errorf("result %+v, want a merge without keys", *result)
+	}
+	if key, _ := target.APIKeys.GetByExchange(ctx, "bybit"); key == nil || key.APIKey != "own" {
+		t.Errorf("API key %+v, want the existing one kept", key)
+	}
+
+	// Merging the same archive again adds no duplicate withdrawals or income
+	result, err = s.Restore(ctx, bytes.NewReader(archive), RestoreOptions{Mode: "merge"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Withdrawals != 0 || result.MonthlyIncomes != 0 {
+		t.Errorf("second merge %+v, want no new withdrawals or income", *result)
+	}
+	if withdrawals, _ := target.Withdrawals.GetAllWithdrawals(ctx); len(withdrawals) != 1 {
+		t.Errorf("%d withdrawals after merging twice, want 1", len(withdrawals))
+	}
+}
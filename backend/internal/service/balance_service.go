@@ -1,7 +1,6 @@
 package service
 
 import (
-	"github.com/Ravierin/BudgetTracker/backend/internal/api"
 	"github.com/Ravierin/BudgetTracker/backend/internal/model"
 	"context"
 )
@@ -48,14 +47,8 @@ func (s *BalanceService) GetTotalBalance(ctx context.Context) (float64, []model.
 }
 
 func (s *BalanceService) getExchangeBalance(ctx context.Context, exchange, apiKey, apiSecret string) (float64, error) {
-	var client api.ExchangeClient
-
-	switch exchange {
-	case "bybit":
-		client = api.NewBybitClient(apiKey, apiSecret)
-	case "mexc":
-		client = api.NewMEXClient(apiKey, apiSecret)
-	default:
+	client := newExchangeClient(ctx, exchange, apiKey, apiSecret)
+	if client == nil {
 		return 0, nil
 	}
 
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"github.com/Ravierin/BudgetTracker/backend/internal/model"
+	"github.com/Ravierin/BudgetTracker/backend/internal/repository"
+	"context"
+	"strconv"
+)
+
+// defaultChangeLimit is the page size of the change feed when none is given
+const defaultChangeLimit = 500
+
+type ChangeService struct {
+	repo repository.ChangeStore
+}
+
+func NewChangeService(repo repository.ChangeStore) *ChangeService {
+	return &ChangeService{repo: repo}
+}
+
+// GetChanges returns up to limit changes committed after the since cursor.
+// Without a cursor it returns no changes, only the current cursor: clients
+// take it before loading their copy, and replaying a change already in the
+// copy is harmless.
+func (s *ChangeService) GetChanges(ctx context.Context, since string, limit int) (model.ChangeFeed, error) {
+	feed := model.ChangeFeed{Changes: []model.Change{}}
+	if limit <= 0 {
+		limit = defaultChangeLimit
+	}
+
+	if since == "" {
+		latest, err := s.repo.LatestChangeID(ctx)
+		if err != nil {
+			return feed, err
+		}
+		feed.Cursor = strconv.FormatInt(latest, 10)
+		return feed, nil
+	}
+
+	after, err := strconv.ParseInt(since, 10, 64)
+	if err != nil || after < 0 {
+		return feed, repository.ErrInvalidCursor
+	}
+
+	// One extra row tells whether another page follows
+	changes, err := s.repo.GetChanges(ctx, after, limit+1)
+	if err != nil {
+		return feed, err
+	}
+	if len(changes) > limit {
+		changes = changes[:limit]
+		feed.HasMore = true
+	}
+	if len(changes) > 0 {
+		feed.Changes = changes
+		after = changes[len(changes)-1].ID
+	}
+	feed.Cursor = strconv.FormatInt(after, 10)
+	return feed, nil
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"github.com/Ravierin/BudgetTracker/backend/internal/api"
+	"github.com/Ravierin/BudgetTracker/backend/internal/model"
+	"github.com/Ravierin/BudgetTracker/backend/internal/repository"
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+)
+
+// ContractService keeps MEXC contract metadata and the position volumes
+// derived from it consistent. Stored volumes and the mappers always use
+// each symbol's applied contract size; BackfillVolumes, run on request
+// only, is the one step that moves it to the effective (listed or
+// overridden) size, rescaling the stored volumes in the same transaction.
+type ContractService struct {
+	store repository.ContractStore
+	mu    sync.Mutex
+}
+
+func NewContractService(store repository.ContractStore) *ContractService {
+	return &ContractService{store: store}
+}
+
+func (s *ContractService) GetContracts(ctx context.Context) ([]model.ContractMeta, error) {
+	return s.store.GetContracts(ctx, "mexc")
+}
+
+// Load hands the applied contract sizes to the MEXC mappers
+func (s *ContractService) Load(ctx context.Context) error {
+	contracts, err := s.store.GetContracts(ctx, "mexc")
+	if err != nil {
+		return err
+	}
+	publishContractSizes(contracts)
+	return nil
+}
+
+// Refresh fetches and stores the MEXC contract listing. Stored volumes are
+// left alone; the result previews the rescale BackfillVolumes would apply.
+func (s *ContractService) Refresh(ctx context.Context) (*model.VolumeBackfillResult, error) {
+	contracts, err := api.NewMEXClient(ctx, "", "").GetContracts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.SaveContracts(ctx, contracts); err != nil {
+		return nil, err
+	}
+	log.Printf("[contracts] Refreshed %d MEXC contracts", len(contracts))
+
+	return s.BackfillVolumes(ctx, true)
+}
+
+// SetOverride sets a manual contract size for a symbol, or clears it when
+// size is nil, and previews the rescale like Refresh
+func (s *ContractService) SetOverride(ctx context.Context, symbol string, size *float64) (*model.VolumeBackfillResult, error) {
+	if size != nil && (*size <= 0 || math.IsInf(*size, 0) || math.IsNaN(*size)) {
+		return nil, fmt.Errorf("contract size must be positive")
+	}
+	if err := s.store.SetContractOverride(ctx, "mexc", symbol, size); err != nil {
+		return nil, err
+	}
+	return s.BackfillVolumes(ctx, true)
+}
+
+// BackfillVolumes rescales the stored volumes of every MEXC symbol whose
+// effective contract size differs from the applied one, unless dryRun is
+// set. Imported positions are rescaled along with synced ones: MEXC
+// statements give contracts too, and the importer converts them with the
+// same applied size.
+func (s *ContractService) BackfillVolumes(ctx context.Context, dryRun bool) (*model.VolumeBackfillResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	contracts, err := s.store.GetContracts(ctx, "mexc")
+	if err != nil {
+		return nil, err
+	}
+
+	result := &model.VolumeBackfillResult{DryRun: dryRun, Symbols: []model.VolumeRescale{}}
+	for i, c := range contracts {
+		to := effectiveContractSize(c)
+		from := c.AppliedContractSize
+		if from == 0 {
+			from = api.LegacyContractSize(c.Symbol)
+		}
+		if to <= 0 || c.AppliedContractSize == to {
+			continue
+		}
+
+		var n int
+		switch {
+		case from != to:
+			n, err = s.store.RescaleVolumes(ctx, "mexc", c.Symbol, from, to, dryRun)
+		case !dryRun:
+			// Only the legacy guess was applied, and it was right
+			err = s.store.SetAppliedContractSize(ctx, "mexc", c.Symbol, to)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if n > 0 {
+			result.Symbols = append(result.Symbols, model.VolumeRescale{Symbol: c.Symbol, From: from, To: to, Positions: n})
+			result.Positions += n
+		}
+		if !dryRun {
+			contracts[i].AppliedContractSize = to
+			result.Applied = true
+		}
+	}
+
+	if result.Applied {
+		publishContractSizes(contracts)
+	}
+	if result.Positions > 0 && !dryRun {
+		log.Printf("[contracts] Rescaled %d MEXC position volumes across %d symbols", result.Positions, len(result.Symbols))
+	}
+	return result, nil
+}
+
+// Stale reports whether the stored contract listing is older than maxAge,
+// so a refresh is due at startup
+func (s *ContractService) Stale(ctx context.Context, maxAge time.Duration) (bool, error) {
+	contracts, err := s.store.GetContracts(ctx, "mexc")
+	if err != nil {
+		return false, err
+	}
+	var last time.Time
+	for _, c := range contracts {
+		if c.ContractSize > 0 && c.UpdatedAt.After(last) {
+			last = c.UpdatedAt
+		}
+	}
+	return time.Since(last) > maxAge, nil
+}
+
+func effectiveContractSize(c model.ContractMeta) float64 {
+	if c.ContractSizeOverride != nil {
+		return *c.ContractSizeOverride
+	}
+	return c.ContractSize
+}
+
+func publishContractSizes(contracts []model.ContractMeta) {
+	sizes := make(map[string]float64, len(contracts))
+	for _, c := range contracts {
+		if c.AppliedContractSize > 0 {
+			sizes[c.Symbol] = c.AppliedContractSize
+		}
+	}
+	api.SetMEXCContractSizes(sizes)
+}
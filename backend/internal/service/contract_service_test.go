@@ -0,0 +1,166 @@
+package service
+
+import (
+	"github.com/Ravierin/BudgetTracker/backend/internal/api"
+	"github.com/Ravierin/BudgetTracker/backend/internal/fakeexchange"
+	"github.com/Ravierin/BudgetTracker/backend/internal/model"
+	"github.com/Ravierin/BudgetTracker/backend/internal/repository"
+	"context"
+	"math"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestContractService serves the default scenario's contract listing
+// and stores two BTC_USDT positions at the legacy size of 10: one synced,
+// with its raw payload, and one imported from a MEXC statement
+func newTestContractService(t *testing.T) (*ContractService, *repository.Stores) {
+	t.Helper()
+	scenario, err := fakeexchange.LoadScenario("default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(fakeexchange.NewServer(scenario))
+	t.Cleanup(srv.Close)
+
+	baseURL := api.MEXCBaseURL
+	api.MEXCBaseURL = srv.URL
+	t.Cleanup(func() {
+		api.MEXCBaseURL = baseURL
+		api.SetMEXCContractSizes(nil)
+	})
+
+	ctx := context.Background()
+	stores := repository.NewMemoryStores()
+	date := time.Date(2025, 5, 2, 8, 0, 0, 0, time.UTC)
+	positions := []model.Position{
+		{OrderID: "101", Exchange: "mexc", Symbol: "BTC_USDT", Volume: 1000, Leverage: 10, ClosedPnl: 2, Side: "Buy", UpdatedAt: date},
+	}
+	if _, err := stores.Positions.SavePositionBatch(ctx, positions); err != nil {
+		t.Fatal(err)
+	}
+	err = stores.RawRecords.SaveRawRecords(ctx, []model.RawExchangeRecord{{
+		Exchange:   "mexc",
+		ExternalID: "101",
+		Kind:       api.RawMEXCHistoryPosition,
+		Payload:    []byte(`{"positionId":101,"symbol":"BTC_USDT"}`),
+		FetchedAt:  date,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 10 contracts opened at 10, converted with the legacy size
+	statement := `Position ID,Futures Trading Pair,Direction,Leverage,Closed Volume,Avg. Open Price,Closed PnL,Close Time
+imported,BTCUSDT,long,10,10,10,2,2025-05-02 08:00:00
+`
+	imports := NewImportService(stores.Positions, stores.ImportProfiles)
+	if _, err := imports.ImportPositions(ctx, "mexc", strings.NewReader(statement), false); err != nil {
+		t.Fatal(err)
+	}
+	if v := storedVolume(t, stores, "imported"); v != 1000 {
+		t.Fatalf("imported volume %v, want 1000", v)
+	}
+
+	return NewContractService(stores.Contracts), stores
+}
+
+func storedVolume(t *testing.T, stores *repository.Stores, orderID string) float64 {
+	t.Helper()
+	p, err := stores.Positions.GetPositionByOrderID(context.Background(), orderID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p.Volume
+}
+
+func TestRefreshOnlyPreviewsRescale(t *testing.T) {
+	ctx := context.Background()
+	s, stores := newTestContractService(t)
+
+	preview, err := s.Refresh(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !preview.DryRun || preview.Applied || preview.Positions != 2 {
+		t.Fatalf("refresh: %+v, want a dry run over 2 positions", preview)
+	}
+	if len(preview.Symbols) != 1 || preview.Symbols[0] != (model.VolumeRescale{Symbol: "BTC_USDT", From: 10, To: 0.0001, Positions: 2}) {
+		t.Fatalf("refresh symbols: %+v, want BTC_USDT from 10 to 0.0001", preview.Symbols)
+	}
+	if v := storedVolume(t, stores, "101"); v != 1000 {
+		t.Fatalf("refresh rewrote a volume to %v", v)
+	}
+	if size := api.GetContractSize("BTC_USDT"); size != 10 {
+		t.Fatalf("refresh applied contract size %v", size)
+	}
+}
+
+func TestBackfillVolumesRescalesSyncedAndImportedPositions(t *testing.T) {
+	ctx := context.Background()
+	s, stores := newTestContractService(t)
+	if _, err := s.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := s.BackfillVolumes(ctx, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.DryRun || !result.Applied || result.Positions != 2 {
+		t.Fatalf("backfill: %+v, want 2 positions applied", result)
+	}
+
+	if v := storedVolume(t, stores, "101"); math.Abs(v-0.01) > 1e-12 {
+		t.Errorf("synced volume %v, want 0.01", v)
+	}
+	if v := storedVolume(t, stores, "imported"); math.Abs(v-0.01) > 1e-12 {
+		t.Errorf("imported volume %v, want 0.01", v)
+	}
+	if size := api.GetContractSize("BTC_USDT"); size != 0.0001 {
+		t.Errorf("mapper contract size %v, want 0.0001", size)
+	}
+
+	again, err := s.BackfillVolumes(ctx, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Positions != 0 || len(again.Symbols) != 0 {
+		t.Errorf("second backfill: %+v, want nothing left to rescale", again)
+	}
+}
+
+func TestSetOverride(t *testing.T) {
+	ctx := context.Background()
+	s, stores := newTestContractService(t)
+	if _, err := s.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.BackfillVolumes(ctx, false); err != nil {
+		t.Fatal(err)
+	}
+
+	size := 0.001
+	preview, err := s.SetOverride(ctx, "BTC_USDT", &size)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if preview.Applied || preview.Positions != 2 || preview.Symbols[0].From != 0.0001 || preview.Symbols[0].To != 0.001 {
+		t.Fatalf("override: %+v, want a preview from 0.0001 to 0.001", preview)
+	}
+
+	if _, err := s.BackfillVolumes(ctx, false); err != nil {
+		t.Fatal(err)
+	}
+	if v := storedVolume(t, stores, "101"); math.Abs(v-0.1) > 1e-12 {
+		t.Errorf("overridden volume %v, want 0.1", v)
+	}
+
+	for _, bad := range []float64{0, -1, math.NaN(), math.Inf(1)} {
+		if _, err := s.SetOverride(ctx, "BTC_USDT", &bad); err == nil {
+			t.Errorf("override %v: expected an error", bad)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"github.com/Ravierin/BudgetTracker/backend/internal/importer"
+	"github.com/Ravierin/BudgetTracker/backend/internal/model"
+	"github.com/Ravierin/BudgetTracker/backend/internal/repository"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+type ImportService struct {
+	repo        repository.PositionStore
+	profileRepo repository.ImportProfileStore
+}
+
+func NewImportService(repo repository.PositionStore, profileRepo repository.ImportProfileStore) *ImportService {
+	return &ImportService{repo: repo, profileRepo: profileRepo}
+}
+
+// ImportPositions parses an exchange CSV statement and upserts its positions,
+// de-duplicated by order ID against already synced rows. With dryRun nothing
+// is written and the result is a preview.
+func (s *ImportService) ImportPositions(ctx context.Context, format string, r io.Reader, dryRun bool) (*model.ImportResult, error) {
+	rows, rowErrors, err := importer.Parse(format, r)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.apply(ctx, format, rows, rowErrors, dryRun, false)
+}
+
+// ImportWithProfile imports a CSV from a manual venue using a saved column
+// mapping. Unlike statement imports it is all-or-nothing: if any line fails
+// validation, the errors are reported and no row is written.
+func (s *ImportService) ImportWithProfile(ctx context.Context, profileName string, r io.Reader, dryRun bool) (*model.ImportResult, error) {
+	profile, err := s.profileRepo.GetByName(ctx, profileName)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, rowErrors, err := importer.ParseMapped(r, *profile)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.apply(ctx, profile.Name, rows, rowErrors, dryRun, true)
+}
+
+func (s *ImportService) GetProfiles(ctx context.Context) ([]model.ImportProfile, error) {
+	return s.profileRepo.GetAll(ctx)
+}
+
+func (s *ImportService) SaveProfile(ctx context.Context, profile *model.ImportProfile) error {
+	if err := importer.ValidateProfile(*profile); err != nil {
+		return err
+	}
+	return s.profileRepo.Upsert(ctx, profile)
+}
+
+func (s *ImportService) DeleteProfile(ctx context.Context, name string) error {
+	return s.profileRepo.Delete(ctx, name)
+}
+
+// apply classifies parsed rows as new, updated, unchanged or conflicting and
+// saves new and updated rows in a single batch unless dryRun is set, or
+// strict is set and some line had errors
+func (s *ImportService) apply(ctx context.Context, format string, rows []importer.Row, rowErrors []model.ImportRowError, dryRun, strict bool) (*model.ImportResult, error) {
+	result := &model.ImportResult{
+		Format:    format,
+		DryRun:    dryRun,
+		New:       []model.Position{},
+		Updated:   []model.ImportChange{},
+		Conflicts: []model.ImportChange{},
+		Errors:    rowErrors,
+	}
+	if result.Errors == nil {
+		result.Errors = []model.ImportRowError{}
+	}
+
+	// Drop repeated order IDs within the file, keeping the first occurrence
+	firstLine := make(map[string]int, len(rows))
+	unique := rows[:0:0]
+	for _, row := range rows {
+		if line, ok := firstLine[row.Position.OrderID]; ok {
+			result.Errors = append(result.Errors, model.ImportRowError{
+				Line:    row.Line,
+				Message: fmt.Sprintf("duplicate order ID %s (first seen on line %d)", row.Position.OrderID, line),
+			})
+			continue
+		}
+		firstLine[row.Position.OrderID] = row.Line
+		unique = append(unique, row)
+	}
+
+	orderIDs := make([]string, len(unique))
+	for i, row := range unique {
+		orderIDs[i] = row.Position.OrderID
+	}
+
+	existing, err := s.repo.GetPositionsByOrderIDs(ctx, orderIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var toSave []model.Position
+	for _, row := range unique {
+		stored, ok := existing[row.Position.OrderID]
+		if !ok {
+			result.New = append(result.New, row.Position)
+			toSave = append(toSave, row.Position)
+			continue
+		}
+
+		imported := mergeImported(stored, row)
+		switch {
+		case stored.Exchange != imported.Exchange || stored.Symbol != imported.Symbol:
+			result.Conflicts = append(result.Conflicts, model.ImportChange{Line: row.Line, Imported: imported, Existing: stored})
+		case samePosition(stored, imported):
+			result.Unchanged++
+		default:
+			result.Updated = append(result.Updated, model.ImportChange{Line: row.Line, Imported: imported, Existing: stored})
+			toSave = append(toSave, imported)
+		}
+	}
+
+	if dryRun || len(toSave) == 0 || (strict && len(result.Errors) > 0) {
+		return result, nil
+	}
+
+	if _, err := s.repo.SavePositionBatch(ctx, toSave); err != nil {
+		return nil, err
+	}
+	result.Applied = true
+
+	return result, nil
+}
+
+// mergeImported keeps the stored side, volume and leverage where the file
+// has no value for them, so re-importing a statement without those columns
+// leaves synced rows alone
+func mergeImported(stored model.Position, row importer.Row) model.Position {
+	p := row.Position
+	if row.Has&importer.FieldSide == 0 {
+		p.Side = stored.Side
+	}
+	if row.Has&importer.FieldVolume == 0 {
+		p.Volume = stored.Volume
+	}
+	if row.Has&importer.FieldLeverage == 0 {
+		p.Leverage = stored.Leverage
+	}
+	return p
+}
+
+// samePosition compares the fields an import may change, tolerating the
+// precision lost by statements (rounded numbers, whole-second times)
+func samePosition(stored, imported model.Position) bool {
+	return math.Abs(stored.ClosedPnl-imported.ClosedPnl) < 1e-6 &&
+		math.Abs(stored.Volume-imported.Volume) <= 1e-6*math.Max(1, math.Abs(stored.Volume)) &&
+		stored.Leverage == imported.Leverage &&
+		stored.Side == imported.Side &&
+		stored.UpdatedAt.Sub(imported.UpdatedAt).Abs() < time.Second
+}
@@ -0,0 +1,183 @@
+package service
+
+import (
+	"github.com/Ravierin/BudgetTracker/backend/internal/model"
+	"github.com/Ravierin/BudgetTracker/backend/internal/repository"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testImportProfile = model.ImportProfile{
+	Name:          "venue",
+	Exchange:      "venue",
+	SymbolColumn:  "symbol",
+	SideColumn:    "side",
+	PnlColumn:     "pnl",
+	DateColumn:    "time",
+	DateFormat:    "unix",
+	VolumeColumn:  "volume",
+	OrderIDColumn: "id",
+}
+
+func newTestImportService(t *testing.T) (*ImportService, *repository.Stores) {
+	t.Helper()
+	ctx := context.Background()
+	stores := repository.NewMemoryStores()
+	s := NewImportService(stores.Positions, stores.ImportProfiles)
+
+	profile := testImportProfile
+	if err := s.SaveProfile(ctx, &profile); err != nil {
+		t.Fatal(err)
+	}
+
+	date := time.Unix(1746000000, 0).UTC()
+	stored := []model.Position{
+		{OrderID: "updated", Exchange: "venue", Symbol: "BTCUSDT", Volume: 10, Leverage: 1, ClosedPnl: 1, Side: "Buy", UpdatedAt: date},
+		{OrderID: "unchanged", Exchange: "venue", Symbol: "BTCUSDT", Volume: 10, Leverage: 1, ClosedPnl: 3, Side: "Sell", UpdatedAt: date},
+		{OrderID: "conflict", Exchange: "venue", Symbol: "ETHUSDT", Volume: 10, Leverage: 1, ClosedPnl: 3, Side: "Sell", UpdatedAt: date},
+	}
+	if _, err := stores.Positions.SavePositionBatch(ctx, stored); err != nil {
+		t.Fatal(err)
+	}
+	return s, stores
+}
+
+const testImportCSV = `id,symbol,side,pnl,volume,time
+new,BTCUSDT,buy,5,10,1746000000
+updated,BTCUSDT,buy,2,10,1746000000
+unchanged,BTCUSDT,sell,3,10,1746000000
+conflict,BTCUSDT,sell,3,10,1746000000
+`
+
+func TestImportWithProfile(t *testing.T) {
+	ctx := context.Background()
+	s, stores := newTestImportService(t)
+
+	preview, err := s.ImportWithProfile(ctx, "venue", strings.NewReader(testImportCSV), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if preview.Applied || len(preview.New) != 1 || len(preview.Updated) != 1 || preview.Unchanged != 1 || len(preview.Conflicts) != 1 {
+		t.Fatalf("dry run: %+v, want 1 new, 1 updated, 1 unchanged, 1 conflict, not applied", preview)
+	}
+	if p, _ := stores.Positions.GetPositionByOrderID(ctx, "new"); p != nil {
+		t.Fatal("dry run wrote a position")
+	}
+
+	result, err := s.ImportWithProfile(ctx, "venue", strings.NewReader(testImportCSV), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Applied || len(result.New) != 1 || len(result.Updated) != 1 || len(result.Errors) != 0 {
+		t.Fatalf("import: %+v, want 1 new and 1 updated, applied", result)
+	}
+
+	tests := []struct {
+		orderID string
+		symbol  string
+		pnl     float64
+	}{
+		{"new", "BTCUSDT", 5},
+		{"updated", "BTCUSDT", 2},
+		// Conflicting rows are reported, never written
+		{"conflict", "ETHUSDT", 3},
+	}
+	for _, tt := range tests {
+		p, err := stores.Positions.GetPositionByOrderID(ctx, tt.orderID)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.orderID, err)
+		}
+		if p.Symbol != tt.symbol || p.ClosedPnl != tt.pnl {
+			t.Errorf("%s: stored %s with pnl %v, want %s with %v", tt.orderID, p.Symbol, p.ClosedPnl, tt.symbol, tt.pnl)
+		}
+	}
+}
+
+func TestImportWithProfileIsAllOrNothing(t *testing.T) {
+	ctx := context.Background()
+	s, stores := newTestImportService(t)
+
+	csv := testImportCSV + "broken,BTCUSDT,buy,not-a-number,10,1746000000\n"
+	result, err := s.ImportWithProfile(ctx, "venue", strings.NewReader(csv), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Applied || len(result.Errors) != 1 || result.Errors[0].Line != 6 {
+		t.Fatalf("import: %+v, want one error on line 6 and nothing applied", result)
+	}
+	if p, _ := stores.Positions.GetPositionByOrderID(ctx, "new"); p != nil {
+		t.Fatal("a failed import wrote a position")
+	}
+}
+
+func TestImportRejectsDuplicateOrderIDs(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newTestImportService(t)
+
+	csv := "id,symbol,side,pnl,volume,time\n" +
+		"dup,BTCUSDT,buy,1,10,1746000000\n" +
+		"dup,BTCUSDT,buy,2,10,1746000000\n"
+	result, err := s.ImportWithProfile(ctx, "venue", strings.NewReader(csv), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.New) != 1 || result.New[0].ClosedPnl != 1 || len(result.Errors) != 1 || result.Errors[0].Line != 3 {
+		t.Fatalf("import: %+v, want the first row kept and the repeat reported on line 3", result)
+	}
+}
+
+func TestSaveProfileValidates(t *testing.T) {
+	s, _ := newTestImportService(t)
+
+	profile := testImportProfile
+	profile.PnlColumn = ""
+	if err := s.SaveProfile(context.Background(), &profile); err == nil {
+		t.Fatal("expected an error for a profile without a PnL column")
+	}
+}
+
+func TestReimportKeepsSyncedFieldsTheFileLacks(t *testing.T) {
+	ctx := context.Background()
+	stores := repository.NewMemoryStores()
+	s := NewImportService(stores.Positions, stores.ImportProfiles)
+
+	synced := model.Position{
+		OrderID: "synced-1", Exchange: "bybit", Symbol: "BTCUSDT", Volume: 500, Leverage: 20, ClosedPnl: 5, Side: "Sell",
+		UpdatedAt: time.Date(2025, 5, 2, 8, 0, 0, 0, time.UTC),
+	}
+	if _, err := stores.Positions.SavePositionBatch(ctx, []model.Position{synced}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A statement without side, volume or leverage columns
+	statement := func(pnl string) *strings.Reader {
+		return strings.NewReader("Contracts,Closed P&L,Trade Time(UTC+0),Order ID\n" +
+			"BTCUSDT," + pnl + ",2025-05-02 08:00:00,synced-1\n")
+	}
+
+	same, err := s.ImportPositions(ctx, "bybit", statement("5"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if same.Unchanged != 1 || len(same.Updated) != 0 {
+		t.Fatalf("re-import: %+v, want the synced row unchanged", same)
+	}
+
+	changed, err := s.ImportPositions(ctx, "bybit", statement("6"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed.Applied || len(changed.Updated) != 1 {
+		t.Fatalf("import with a new PnL: %+v, want 1 updated row", changed)
+	}
+
+	p, err := stores.Positions.GetPositionByOrderID(ctx, "synced-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.ClosedPnl != 6 || p.Volume != 500 || p.Leverage != 20 || p.Side != "Sell" {
+		t.Fatalf("stored %+v, want PnL 6 with the synced volume, leverage and side", *p)
+	}
+}
@@ -8,10 +8,10 @@ import (
 )
 
 type MonthlyIncomeService struct {
-	repo *repository.MonthlyIncomeRepository
+	repo repository.MonthlyIncomeStore
 }
 
-func NewMonthlyIncomeService(repo *repository.MonthlyIncomeRepository) *MonthlyIncomeService {
+func NewMonthlyIncomeService(repo repository.MonthlyIncomeStore) *MonthlyIncomeService {
 	return &MonthlyIncomeService{repo: repo}
 }
 
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"github.com/Ravierin/BudgetTracker/backend/internal/api"
+	"github.com/Ravierin/BudgetTracker/backend/internal/model"
+	"context"
+	"sort"
+	"sync"
+)
+
+// OpenPositionService keeps the latest open positions of every exchange.
+// Syncs update the snapshots; reads fetch exchanges that have none yet.
+type OpenPositionService struct {
+	apiKeyService *APIKeyService
+	mu            sync.RWMutex
+	snapshots     map[string][]model.OpenPosition
+}
+
+func NewOpenPositionService(apiKeyService *APIKeyService) *OpenPositionService {
+	return &OpenPositionService{
+		apiKeyService: apiKeyService,
+		snapshots:     make(map[string][]model.OpenPosition),
+	}
+}
+
+// Update replaces the snapshot of an exchange
+func (s *OpenPositionService) Update(exchange string, positions []model.OpenPosition) {
+	if positions == nil {
+		positions = []model.OpenPosition{}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snapshots[exchange] = positions
+}
+
+// Apply merges streamed changes into the snapshot of an exchange: each
+// replaces the position of its symbol and side, and a zero size removes it
+// (every side of the symbol when no side is given). It returns the
+// resulting snapshot.
+func (s *OpenPositionService) Apply(exchange string, changes []model.OpenPosition) []model.OpenPosition {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	positions := s.snapshots[exchange]
+	for _, c := range changes {
+		kept := make([]model.OpenPosition, 0, len(positions)+1)
+		for _, p := range positions {
+			if p.Symbol != c.Symbol || (p.Side != c.Side && c.Side != "") {
+				kept = append(kept, p)
+			}
+		}
+		if c.Size > 0 {
+			kept = append(kept, c)
+		}
+		positions = kept
+	}
+	if positions == nil {
+		positions = []model.OpenPosition{}
+	}
+
+	s.snapshots[exchange] = positions
+	return positions
+}
+
+// GetOpenPositions returns the open positions of every exchange with active
+// keys, fetching those without a snapshot, or all of them when refresh is
+// set. Exchanges that fail to answer are reported in Errors.
+func (s *OpenPositionService) GetOpenPositions(ctx context.Context, refresh bool) (*model.OpenPositions, error) {
+	apiKeys, err := s.apiKeyService.GetAllAPIKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &model.OpenPositions{Positions: []model.OpenPosition{}}
+	for _, key := range apiKeys {
+		if !key.IsActive || key.APIKey == "" || key.APISecret == "" {
+			continue
+		}
+
+		s.mu.RLock()
+		positions, ok := s.snapshots[key.Exchange]
+		s.mu.RUnlock()
+
+		if refresh || !ok {
+			client := newExchangeClient(ctx, key.Exchange, key.APIKey, key.APISecret)
+			if client == nil {
+				continue
+			}
+			fetched, err := client.GetOpenPositions(ctx)
+			if err != nil {
+				if result.Errors == nil {
+					result.Errors = make(map[string]string)
+				}
+				result.Errors[key.Exchange] = err.Error()
+				continue
+			}
+			s.Update(key.Exchange, fetched)
+			positions = fetched
+		}
+
+		for _, p := range positions {
+			result.Positions = append(result.Positions, p)
+			result.UnrealizedPnl += p.UnrealizedPnl
+		}
+	}
+
+	sort.Slice(result.Positions, func(i, j int) bool {
+		a, b := result.Positions[i], result.Positions[j]
+		if a.Exchange != b.Exchange {
+			return a.Exchange < b.Exchange
+		}
+		if a.Symbol != b.Symbol {
+			return a.Symbol < b.Symbol
+		}
+		return a.Side < b.Side
+	})
+	return result, nil
+}
+
+// newExchangeClient returns the client of a supported exchange, or nil
+func newExchangeClient(ctx context.Context, exchange, apiKey, apiSecret string) api.ExchangeClient {
+	switch exchange {
+	case "bybit":
+		return api.NewBybitClient(ctx, apiKey, apiSecret)
+	case "mexc":
+		return api.NewMEXClient(ctx, apiKey, apiSecret)
+	default:
+		return nil
+	}
+}
@@ -8,18 +8,19 @@ import (
 )
 
 type PositionService struct {
-	repo *repository.PositionRepository
+	repo   repository.PositionStore
+	rollup repository.RollupStore
 }
 
-func NewPositionService(repo *repository.PositionRepository) *PositionService {
-	return &PositionService{repo: repo}
+func NewPositionService(repo repository.PositionStore, rollup repository.RollupStore) *PositionService {
+	return &PositionService{repo: repo, rollup: rollup}
 }
 
 func (s *PositionService) SavePosition(ctx context.Context, position model.Position) error {
 	return s.repo.SavePosition(ctx, position)
 }
 
-func (s *PositionService) SavePositionsBatch(ctx context.Context, positions []model.Position) error {
+func (s *PositionService) SavePositionsBatch(ctx context.Context, positions []model.Position) (model.PositionChanges, error) {
 	return s.repo.SavePositionBatch(ctx, positions)
 }
 
@@ -39,85 +40,63 @@ func (s *PositionService) DeletePosition(ctx context.Context, id int) error {
 	return s.repo.DeletePosition(ctx, id)
 }
 
-func (s *PositionService) CalculateTotalPnl(ctx context.Context, exchange string) (float64, error) {
-	positions, err := s.repo.GetAllPositions(ctx)
-	if err != nil {
-		return 0, err
-	}
+func (s *PositionService) QueryPositions(ctx context.Context, q model.PositionQuery) ([]model.Position, string, error) {
+	return s.repo.QueryPositions(ctx, q)
+}
+
+func (s *PositionService) StreamPositions(ctx context.Context, q model.PositionQuery, fn func(model.Position) error) error {
+	return s.repo.StreamPositions(ctx, q, fn)
+}
+
+func (s *PositionService) GetPosition(ctx context.Context, id int) (*model.Position, error) {
+	return s.repo.GetPositionByID(ctx, id)
+}
 
-	var totalPnl float64
-	for _, p := range positions {
-		if exchange == "" || p.Exchange == exchange {
-			totalPnl += p.ClosedPnl
-		}
+// GetPositionStats reads from the daily rollup unless the filter needs
+// per-position detail (side, PnL sign or intraday bounds)
+func (s *PositionService) GetPositionStats(ctx context.Context, filter model.PositionFilter, groupBy string) ([]model.PositionStats, error) {
+	if s.rollup != nil && s.rollup.Supports(filter) {
+		return s.rollup.GetStats(ctx, filter, groupBy)
 	}
+	return s.repo.GetPositionStats(ctx, filter, groupBy)
+}
 
-	return totalPnl, nil
+func (s *PositionService) CalculateTotalPnl(ctx context.Context, exchange string) (float64, error) {
+	return s.sumPnl(ctx, model.PositionFilter{Exchange: exchange})
 }
 
 func (s *PositionService) CalculateMonthlyPnl(ctx context.Context, year int, month time.Month, exchange string) (float64, error) {
 	start := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
 	end := start.AddDate(0, 1, 0)
 
-	positions, err := s.repo.GetPositionsByDateRange(ctx, start, end)
-	if err != nil {
-		return 0, err
-	}
+	return s.sumPnl(ctx, model.PositionFilter{
+		Exchange: exchange,
+		From:     start,
+		To:       end,
+	})
+}
 
-	var totalPnl float64
-	for _, p := range positions {
-		if exchange == "" || p.Exchange == exchange {
-			totalPnl += p.ClosedPnl
-		}
+func (s *PositionService) sumPnl(ctx context.Context, filter model.PositionFilter) (float64, error) {
+	if s.rollup != nil && s.rollup.Supports(filter) {
+		return s.rollup.SumPnl(ctx, filter)
 	}
-
-	return totalPnl, nil
+	return s.repo.SumClosedPnl(ctx, filter)
 }
 
 // AggregateMonthlyPnl aggregates PnL by month from positions
-// Returns monthly income data grouped by year-month
-func (s *PositionService) AggregateMonthlyPnl(ctx context.Context, exchange string) ([]model.MonthlyIncome, error) {
-	positions, err := s.repo.GetAllPositions(ctx)
-	if err != nil {
-		return nil, err
-	}
-
-	// Group by year-month and exchange
-	monthlyMap := make(map[string]*model.MonthlyIncome)
-	
-	for _, p := range positions {
-		if exchange != "" && p.Exchange != exchange {
-			continue
-		}
-
-		// Get year-month key (e.g., "2026-01-mexc")
-		var key string
-		if exchange == "" {
-			key = p.UpdatedAt.Format("2006-01") + "-" + p.Exchange
-		} else {
-			key = p.UpdatedAt.Format("2006-01")
-		}
-		
-		monthStart := time.Date(p.UpdatedAt.Year(), p.UpdatedAt.Month(), 1, 0, 0, 0, 0, p.UpdatedAt.Location())
-		
-		if existing, ok := monthlyMap[key]; ok {
-			existing.PNL += p.ClosedPnl
-			existing.Amount += p.Volume
-		} else {
-			monthlyMap[key] = &model.MonthlyIncome{
-				Exchange:  p.Exchange,
-				PNL:       p.ClosedPnl,
-				Amount:    p.Volume,
-				CreatedAt: monthStart,
-			}
-		}
+// Returns monthly income data grouped by year-month and exchange
+func (s *PositionService) AggregateMonthlyPnl(ctx context.Context, filter model.PositionFilter) ([]model.MonthlyIncome, error) {
+	if s.rollup != nil && s.rollup.Supports(filter) {
+		return s.rollup.AggregateMonthly(ctx, filter)
 	}
+	return s.repo.AggregateMonthly(ctx, filter)
+}
 
-	// Convert map to slice
-	result := make([]model.MonthlyIncome, 0, len(monthlyMap))
-	for _, income := range monthlyMap {
-		result = append(result, *income)
+// RebuildRollup recomputes the daily rollup from all stored positions; it
+// is a no-op for backends without one
+func (s *PositionService) RebuildRollup(ctx context.Context) (int64, error) {
+	if s.rollup == nil {
+		return 0, nil
 	}
-
-	return result, nil
+	return s.rollup.Rebuild(ctx)
 }
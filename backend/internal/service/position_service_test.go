@@ -0,0 +1,116 @@
+package service
+
+import (
+	"github.com/Ravierin/BudgetTracker/backend/internal/model"
+	"github.com/Ravierin/BudgetTracker/backend/internal/repository"
+	"context"
+	"math"
+	"testing"
+	"time"
+)
+
+func newTestPositionService(t *testing.T) (*PositionService, *repository.Stores) {
+	t.Helper()
+	stores := repository.NewMemoryStores()
+	positions := []model.Position{
+		{OrderID: "b-1", Exchange: "bybit", Symbol: "BTCUSDT", Volume: 100, Leverage: 10, ClosedPnl: 12, Side: "Buy",
+			UpdatedAt: time.Date(2025, 4, 3, 10, 0, 0, 0, time.UTC)},
+		{OrderID: "b-2", Exchange: "bybit", Symbol: "ETHUSDT", Volume: 40, Leverage: 5, ClosedPnl: -5, Side: "Sell",
+			UpdatedAt: time.Date(2025, 4, 20, 10, 0, 0, 0, time.UTC)},
+		{OrderID: "m-1", Exchange: "mexc", Symbol: "BTC_USDT", Volume: 60, Leverage: 20, ClosedPnl: 4, Side: "Sell",
+			UpdatedAt: time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	if _, err := stores.Positions.SavePositionBatch(context.Background(), positions); err != nil {
+		t.Fatal(err)
+	}
+	return NewPositionService(stores.Positions, stores.Rollup), stores
+}
+
+func TestPositionServicePnl(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newTestPositionService(t)
+
+	tests := []struct {
+		name     string
+		month    time.Month
+		exchange string
+		want     float64
+	}{
+		{"april", time.April, "", 7},
+		{"april bybit", time.April, "bybit", 7},
+		// The month end is exclusive, so May 1st 00:00 belongs to May
+		{"may", time.May, "", 4},
+		{"may bybit", time.May, "bybit", 0},
+	}
+	for _, tt := range tests {
+		got, err := s.CalculateMonthlyPnl(ctx, 2025, tt.month, tt.exchange)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s: pnl %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	total, err := s.CalculateTotalPnl(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(total-11) > 1e-9 {
+		t.Errorf("total pnl %v, want 11", total)
+	}
+}
+
+func TestPositionServiceStatsByInstrument(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newTestPositionService(t)
+
+	// BTCUSDT on Bybit and BTC_USDT on MEXC are one instrument
+	stats, err := s.GetPositionStats(ctx, model.PositionFilter{}, "instrument")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []model.PositionStats{
+		{Instrument: "BTC/USDT", Trades: 2, Wins: 2, TotalPnl: 16, TotalVolume: 160},
+		{Instrument: "ETH/USDT", Trades: 1, Losses: 1, TotalPnl: -5, TotalVolume: 40},
+	}
+	if len(stats) != len(want) {
+		t.Fatalf("got %d stat rows, want %d: %+v", len(stats), len(want), stats)
+	}
+	for i := range want {
+		if stats[i] != want[i] {
+			t.Errorf("stats[%d] = %+v, want %+v", i, stats[i], want[i])
+		}
+	}
+
+	if _, err := s.GetPositionStats(ctx, model.PositionFilter{}, "side"); err == nil {
+		t.Error("expected an error for an unknown group")
+	}
+}
+
+func TestPositionServiceAggregateMonthly(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newTestPositionService(t)
+
+	incomes, err := s.AggregateMonthlyPnl(ctx, model.PositionFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []model.MonthlyIncome{
+		{Exchange: "mexc", Amount: 60, PNL: 4, CreatedAt: time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)},
+		{Exchange: "bybit", Amount: 140, PNL: 7, CreatedAt: time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	if len(incomes) != len(want) {
+		t.Fatalf("got %d months, want %d: %+v", len(incomes), len(want), incomes)
+	}
+	for i := range want {
+		if incomes[i] != want[i] {
+			t.Errorf("incomes[%d] = %+v, want %+v", i, incomes[i], want[i])
+		}
+	}
+
+	// Without a rollup there is nothing to rebuild
+	if n, err := s.RebuildRollup(ctx); err != nil || n != 0 {
+		t.Errorf("RebuildRollup = %d, %v; want 0, nil", n, err)
+	}
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"github.com/Ravierin/BudgetTracker/backend/internal/api"
+	"github.com/Ravierin/BudgetTracker/backend/internal/instrument"
+	"github.com/Ravierin/BudgetTracker/backend/internal/model"
+	"github.com/Ravierin/BudgetTracker/backend/internal/repository"
+	"context"
+	"math"
+	"time"
+)
+
+// reprocessBatch bounds the order ID lookups and writes of a reprocess
+const reprocessBatch = 500
+
+type RawRecordService struct {
+	raw       repository.RawRecordStore
+	positions repository.PositionStore
+}
+
+func NewRawRecordService(raw repository.RawRecordStore, positions repository.PositionStore) *RawRecordService {
+	return &RawRecordService{raw: raw, positions: positions}
+}
+
+// SaveRecords stores the payloads of a sync
+func (s *RawRecordService) SaveRecords(ctx context.Context, records []model.RawExchangeRecord) error {
+	return s.raw.SaveRawRecords(ctx, records)
+}
+
+// Reprocess maps every stored payload (of one exchange unless exchange is
+// empty) with the current mappers and saves the positions whose mapping
+// changed, unless dryRun is set
+func (s *RawRecordService) Reprocess(ctx context.Context, exchange string, dryRun bool) (*model.ReprocessResult, error) {
+	result := &model.ReprocessResult{
+		Exchange: exchange,
+		DryRun:   dryRun,
+		Changed:  []model.ReprocessChange{},
+		Errors:   []model.ReprocessError{},
+	}
+
+	// Map everything first: SQLite cannot read positions while the
+	// payload query is still open
+	var mapped []model.Position
+	err := s.raw.StreamRawRecords(ctx, exchange, func(rec model.RawExchangeRecord) error {
+		result.Records++
+		p, err := api.MapRawRecord(rec)
+		if err != nil {
+			result.Errors = append(result.Errors, model.ReprocessError{
+				Exchange:   rec.Exchange,
+				ExternalID: rec.ExternalID,
+				Message:    err.Error(),
+			})
+			return nil
+		}
+		mapped = append(mapped, p)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for start := 0; start < len(mapped); start += reprocessBatch {
+		batch := mapped[start:min(start+reprocessBatch, len(mapped))]
+
+		orderIDs := make([]string, len(batch))
+		for i, p := range batch {
+			orderIDs[i] = p.OrderID
+		}
+		existing, err := s.positions.GetPositionsByOrderIDs(ctx, orderIDs)
+		if err != nil {
+			return nil, err
+		}
+
+		var toSave []model.Position
+		for _, p := range batch {
+			stored, ok := existing[p.OrderID]
+			switch {
+			case !ok:
+				result.Missing++
+			case sameMapping(stored, p):
+				result.Unchanged++
+			default:
+				result.Changed = append(result.Changed, model.ReprocessChange{Before: stored, After: p})
+				toSave = append(toSave, p)
+			}
+		}
+
+		if dryRun || len(toSave) == 0 {
+			continue
+		}
+		if _, err := s.positions.SavePositionBatch(ctx, toSave); err != nil {
+			return nil, err
+		}
+		result.Applied = true
+	}
+
+	return result, nil
+}
+
+// sameMapping compares the fields a sync writes, the instrument derived
+// from the symbol included, allowing for the eight decimals PostgreSQL
+// keeps and its microsecond timestamps
+func sameMapping(stored, mapped model.Position) bool {
+	i := instrument.Normalize(mapped.Exchange, mapped.Symbol)
+	return stored.Symbol == mapped.Symbol &&
+		stored.Instrument == i.Key() &&
+		stored.BaseAsset == i.Base &&
+		stored.QuoteAsset == i.Quote &&
+		stored.ContractType == i.ContractType &&
+		math.Abs(stored.ClosedPnl-mapped.ClosedPnl) < 1e-8 &&
+		math.Abs(stored.Volume-mapped.Volume) < 1e-8 &&
+		stored.Leverage == mapped.Leverage &&
+		stored.Side == mapped.Side &&
+		stored.UpdatedAt.Sub(mapped.UpdatedAt).Abs() < time.Microsecond
+}
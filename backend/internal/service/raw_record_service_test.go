@@ -0,0 +1,96 @@
+package service
+
+import (
+	"github.com/Ravierin/BudgetTracker/backend/internal/api"
+	"github.com/Ravierin/BudgetTracker/backend/internal/model"
+	"github.com/Ravierin/BudgetTracker/backend/internal/repository"
+	"context"
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestReprocessRemapsSymbol(t *testing.T) {
+	ctx := context.Background()
+	stores := repository.NewMemoryStores()
+	s := NewRawRecordService(stores.RawRecords, stores.Positions)
+
+	date := time.Date(2025, 5, 2, 8, 0, 0, 0, time.UTC)
+	// Stored by an older mapper that read the wrong symbol
+	stored := []model.Position{
+		{OrderID: "remapped", Exchange: "bybit", Symbol: "BTCUSD", Volume: 100, Leverage: 10, ClosedPnl: 5, Side: "Buy", UpdatedAt: date},
+		{OrderID: "same", Exchange: "bybit", Symbol: "ETHUSDT", Volume: 50, Leverage: 5, ClosedPnl: -1, Side: "Sell", UpdatedAt: date},
+	}
+	if _, err := stores.Positions.SavePositionBatch(ctx, stored); err != nil {
+		t.Fatal(err)
+	}
+
+	ms := date.UnixMilli()
+	records := []model.RawExchangeRecord{
+		bybitClosedPnlRecord(t, "remapped", "BTCUSDT", "100", "10", "5", "Buy", ms),
+		bybitClosedPnlRecord(t, "same", "ETHUSDT", "50", "5", "-1", "Sell", ms),
+	}
+	if err := s.SaveRecords(ctx, records); err != nil {
+		t.Fatal(err)
+	}
+
+	preview, err := s.Reprocess(ctx, "bybit", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if preview.Records != 2 || preview.Unchanged != 1 || len(preview.Changed) != 1 || preview.Applied {
+		t.Fatalf("dry run: %+v, want 1 changed and 1 unchanged, not applied", preview)
+	}
+	if p, _ := stores.Positions.GetPositionByOrderID(ctx, "remapped"); p.Symbol != "BTCUSD" {
+		t.Fatalf("dry run wrote symbol %s", p.Symbol)
+	}
+
+	result, err := s.Reprocess(ctx, "bybit", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Applied || len(result.Changed) != 1 || result.Changed[0].Before.Symbol != "BTCUSD" {
+		t.Fatalf("reprocess: %+v, want the BTCUSD position changed", result)
+	}
+
+	p, err := stores.Positions.GetPositionByOrderID(ctx, "remapped")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Symbol != "BTCUSDT" || p.Instrument != "BTC/USDT" || p.ContractType != "linear_perpetual" {
+		t.Fatalf("reprocessed position %+v, want the BTCUSDT mapping", *p)
+	}
+
+	again, err := s.Reprocess(ctx, "bybit", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Unchanged != 2 || len(again.Changed) != 0 {
+		t.Fatalf("second reprocess: %+v, want everything unchanged", again)
+	}
+}
+
+// bybitClosedPnlRecord is a stored closed-pnl payload, in Bybit's string fields
+func bybitClosedPnlRecord(t *testing.T, orderID, symbol, volume, leverage, pnl, side string, updatedMs int64) model.RawExchangeRecord {
+	t.Helper()
+	payload, err := json.Marshal(map[string]string{
+		"orderId":       orderID,
+		"symbol":        symbol,
+		"cumEntryValue": volume,
+		"leverage":      leverage,
+		"closedPnl":     pnl,
+		"side":          side,
+		"updatedTime":   strconv.FormatInt(updatedMs, 10),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return model.RawExchangeRecord{
+		Exchange:   "bybit",
+		ExternalID: orderID,
+		Kind:       api.RawBybitClosedPnl,
+		Payload:    payload,
+		FetchedAt:  time.UnixMilli(updatedMs).UTC(),
+	}
+}
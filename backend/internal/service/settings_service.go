@@ -0,0 +1,27 @@
+package service
+
+import (
+	"github.com/Ravierin/BudgetTracker/backend/internal/model"
+	"github.com/Ravierin/BudgetTracker/backend/internal/repository"
+	"context"
+)
+
+type SettingsService struct {
+	repo repository.SettingsStore
+}
+
+func NewSettingsService(repo repository.SettingsStore) *SettingsService {
+	return &SettingsService{repo: repo}
+}
+
+func (s *SettingsService) GetSettings(ctx context.Context) ([]model.Setting, error) {
+	return s.repo.GetAll(ctx)
+}
+
+func (s *SettingsService) SaveSetting(ctx context.Context, key, value string) error {
+	return s.repo.Set(ctx, key, value)
+}
+
+func (s *SettingsService) DeleteSetting(ctx context.Context, key string) error {
+	return s.repo.Delete(ctx, key)
+}
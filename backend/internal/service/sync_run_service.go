@@ -0,0 +1,48 @@
+package service
+
+import (
+	"github.com/Ravierin/BudgetTracker/backend/internal/model"
+	"github.com/Ravierin/BudgetTracker/backend/internal/repository"
+	"context"
+	"log"
+	"time"
+)
+
+// syncRunRetention is how long sync runs are kept
+const syncRunRetention = 30 * 24 * time.Hour
+
+type SyncRunService struct {
+	repo repository.SyncRunStore
+}
+
+func NewSyncRunService(repo repository.SyncRunStore) *SyncRunService {
+	return &SyncRunService{repo: repo}
+}
+
+// Record saves a finished run. Failures are only logged: losing a history
+// row must not fail the sync.
+func (s *SyncRunService) Record(ctx context.Context, run model.SyncRun) {
+	if err := s.repo.SaveSyncRun(ctx, run); err != nil {
+		log.Printf("[%s] Failed to record sync run: %v", run.Exchange, err)
+	}
+}
+
+// Prune deletes the runs past the retention period
+func (s *SyncRunService) Prune(ctx context.Context) error {
+	n, err := s.repo.DeleteSyncRunsBefore(ctx, time.Now().Add(-syncRunRetention))
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		log.Printf("Pruned %d sync runs", n)
+	}
+	return nil
+}
+
+func (s *SyncRunService) GetRuns(ctx context.Context, exchange string, limit int) ([]model.SyncRun, error) {
+	return s.repo.GetSyncRuns(ctx, exchange, limit)
+}
+
+func (s *SyncRunService) GetStatus(ctx context.Context) ([]model.SyncAccountStatus, error) {
+	return s.repo.GetSyncStatus(ctx)
+}
@@ -8,10 +8,10 @@ import (
 )
 
 type WithdrawalService struct {
-	repo *repository.WithdrawalRepository
+	repo repository.WithdrawalStore
 }
 
-func NewWithdrawalService(repo *repository.WithdrawalRepository) *WithdrawalService {
+func NewWithdrawalService(repo repository.WithdrawalStore) *WithdrawalService {
 	return &WithdrawalService{repo: repo}
 }
 
@@ -31,6 +31,20 @@ func (s *WithdrawalService) GetWithdrawalsByDateRange(ctx context.Context, start
 	return s.repo.GetWithdrawalsByDateRange(ctx, start, end)
 }
 
+// GetWithdrawals returns the withdrawals matching the filter, newest first
+func (s *WithdrawalService) GetWithdrawals(ctx context.Context, filter model.WithdrawalFilter) ([]model.Withdrawal, error) {
+	var withdrawals []model.Withdrawal
+	err := s.repo.StreamWithdrawals(ctx, filter, func(w model.Withdrawal) error {
+		withdrawals = append(withdrawals, w)
+		return nil
+	})
+	return withdrawals, err
+}
+
+func (s *WithdrawalService) StreamWithdrawals(ctx context.Context, filter model.WithdrawalFilter, fn func(model.Withdrawal) error) error {
+	return s.repo.StreamWithdrawals(ctx, filter, fn)
+}
+
 func (s *WithdrawalService) DeleteWithdrawal(ctx context.Context, id int) error {
 	return s.repo.DeleteWithdrawal(ctx, id)
 }
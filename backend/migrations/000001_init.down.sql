@@ -1,3 +1,4 @@
-DROP TABLE Position;
-DROP TABLE Withdrawals;
-DROP TABLE MonthlyIncome;
\ No newline at end of file
+DROP TABLE IF EXISTS "position";
+DROP TABLE IF EXISTS withdrawal;
+DROP TABLE IF EXISTS monthlyincome;
+DROP TABLE IF EXISTS monthly_income;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_position_symbol_date;
+DROP INDEX IF EXISTS idx_position_exchange_date;
+DROP INDEX IF EXISTS idx_position_date_id;
@@ -0,0 +1,3 @@
+CREATE INDEX IF NOT EXISTS idx_position_date_id ON "position" (date DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_position_exchange_date ON "position" (exchange, date DESC);
+CREATE INDEX IF NOT EXISTS idx_position_symbol_date ON "position" (symbol, date DESC);
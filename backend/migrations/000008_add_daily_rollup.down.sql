@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS position_daily_rollup;
+ALTER TABLE "position" DROP COLUMN IF EXISTS account;
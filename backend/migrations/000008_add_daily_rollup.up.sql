@@ -0,0 +1,25 @@
+ALTER TABLE "position" ADD COLUMN IF NOT EXISTS account TEXT NOT NULL DEFAULT 'default';
+
+CREATE TABLE IF NOT EXISTS position_daily_rollup (
+    day DATE NOT NULL,
+    exchange TEXT NOT NULL,
+    account TEXT NOT NULL,
+    symbol TEXT NOT NULL,
+    trades INT NOT NULL,
+    wins INT NOT NULL,
+    losses INT NOT NULL,
+    pnl DECIMAL(20, 8) NOT NULL,
+    volume DECIMAL(20, 8) NOT NULL,
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (day, exchange, account, symbol)
+);
+
+INSERT INTO position_daily_rollup (day, exchange, account, symbol, trades, wins, losses, pnl, volume)
+SELECT date::date, exchange, account, symbol,
+       COUNT(*),
+       COUNT(*) FILTER (WHERE closed_pnl > 0),
+       COUNT(*) FILTER (WHERE closed_pnl < 0),
+       SUM(closed_pnl), SUM(volume)
+FROM "position"
+GROUP BY 1, 2, 3, 4
+ON CONFLICT DO NOTHING;
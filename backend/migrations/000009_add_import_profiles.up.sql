@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS import_profile (
+    id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    exchange TEXT NOT NULL,
+    symbol_column TEXT NOT NULL,
+    side_column TEXT NOT NULL,
+    pnl_column TEXT NOT NULL,
+    date_column TEXT NOT NULL,
+    date_format TEXT NOT NULL DEFAULT '',
+    volume_column TEXT NOT NULL DEFAULT '',
+    leverage_column TEXT NOT NULL DEFAULT '',
+    order_id_column TEXT NOT NULL DEFAULT '',
+    delimiter TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
@@ -0,0 +1,3 @@
+-- The reconciled schema is a superset of both previous layouts and stored
+-- values are not narrowed back, so there is nothing to revert.
+SELECT 1;
@@ -0,0 +1,52 @@
+-- Databases created by the old Docker entrypoint (VARCHAR, TIMESTAMP,
+-- DECIMAL(20, 8), monthly_income) and by these migrations (TEXT,
+-- TIMESTAMPTZ, DECIMAL(10, 2), MonthlyIncome) drifted apart. Bring both to
+-- the same schema.
+
+-- 000001 created the table unquoted, i.e. as "monthlyincome"
+DO $$
+BEGIN
+    IF to_regclass('monthlyincome') IS NOT NULL AND to_regclass('monthly_income') IS NULL THEN
+        ALTER TABLE monthlyincome RENAME TO monthly_income;
+    END IF;
+END $$;
+
+ALTER TABLE "position" ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ DEFAULT NOW();
+ALTER TABLE withdrawal ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ DEFAULT NOW();
+ALTER TABLE monthly_income ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ DEFAULT NOW();
+
+-- Amounts need more than two decimals for crypto quantities and PnL
+ALTER TABLE "position"
+    ALTER COLUMN volume TYPE DECIMAL(20, 8),
+    ALTER COLUMN closed_pnl TYPE DECIMAL(20, 8);
+ALTER TABLE withdrawal ALTER COLUMN amount TYPE DECIMAL(20, 8);
+ALTER TABLE monthly_income
+    ALTER COLUMN amount TYPE DECIMAL(20, 8),
+    ALTER COLUMN pnl TYPE DECIMAL(20, 8);
+
+-- VARCHAR -> TEXT, and TIMESTAMP -> TIMESTAMPTZ reading stored values as UTC
+DO $$
+DECLARE
+    col RECORD;
+BEGIN
+    FOR col IN
+        SELECT table_name, column_name, data_type
+        FROM information_schema.columns
+        WHERE table_schema = current_schema()
+          AND table_name IN ('position', 'withdrawal', 'monthly_income', 'api_keys',
+                             'position_daily_rollup', 'import_profile', 'settings')
+          AND data_type IN ('character varying', 'timestamp without time zone')
+    LOOP
+        IF col.data_type = 'character varying' THEN
+            EXECUTE format('ALTER TABLE %I ALTER COLUMN %I TYPE TEXT', col.table_name, col.column_name);
+        ELSIF col.column_name IN ('created_at', 'updated_at') THEN
+            EXECUTE format('ALTER TABLE %I ALTER COLUMN %I DROP DEFAULT', col.table_name, col.column_name);
+            EXECUTE format('ALTER TABLE %I ALTER COLUMN %I TYPE TIMESTAMPTZ USING %I AT TIME ZONE ''UTC''',
+                           col.table_name, col.column_name, col.column_name);
+            EXECUTE format('ALTER TABLE %I ALTER COLUMN %I SET DEFAULT NOW()', col.table_name, col.column_name);
+        ELSE
+            EXECUTE format('ALTER TABLE %I ALTER COLUMN %I TYPE TIMESTAMPTZ USING %I AT TIME ZONE ''UTC''',
+                           col.table_name, col.column_name, col.column_name);
+        END IF;
+    END LOOP;
+END $$;
@@ -0,0 +1,11 @@
+-- Exchange payloads as fetched, so positions can be re-mapped after a
+-- mapper fix without refetching (which is impossible past the exchanges'
+-- retention windows). kind names the endpoint and so the mapper.
+CREATE TABLE IF NOT EXISTS raw_exchange_record (
+    exchange TEXT NOT NULL,
+    external_id TEXT NOT NULL,
+    kind TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    fetched_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (exchange, external_id)
+);
@@ -0,0 +1,18 @@
+-- Futures contract metadata fetched from the exchanges (MEXC
+-- /api/v1/contract/detail), with optional manual contract size overrides.
+-- applied_contract_size is the size stored position volumes use; 0 means
+-- the fixed guesses used before this table existed.
+CREATE TABLE IF NOT EXISTS contract_metadata (
+    exchange TEXT NOT NULL,
+    symbol TEXT NOT NULL,
+    base_coin TEXT NOT NULL DEFAULT '',
+    quote_coin TEXT NOT NULL DEFAULT '',
+    settle_coin TEXT NOT NULL DEFAULT '',
+    contract_size DECIMAL(30, 12) NOT NULL DEFAULT 0,
+    price_scale INTEGER NOT NULL DEFAULT 0,
+    vol_scale INTEGER NOT NULL DEFAULT 0,
+    contract_size_override DECIMAL(30, 12),
+    applied_contract_size DECIMAL(30, 12) NOT NULL DEFAULT 0,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (exchange, symbol)
+);
@@ -0,0 +1,9 @@
+ALTER TABLE position_daily_rollup DROP COLUMN IF EXISTS instrument;
+
+DROP INDEX IF EXISTS idx_position_instrument_date;
+
+ALTER TABLE "position"
+    DROP COLUMN IF EXISTS instrument,
+    DROP COLUMN IF EXISTS contract_type,
+    DROP COLUMN IF EXISTS quote_asset,
+    DROP COLUMN IF EXISTS base_asset;
@@ -0,0 +1,14 @@
+-- Canonical instrument of each position (see internal/instrument), so
+-- BTCUSDT on Bybit and BTC_USDT on MEXC filter and group as BTC/USDT.
+-- symbol keeps the exchange's own name. Existing rows are filled in by the
+-- application at startup.
+ALTER TABLE "position"
+    ADD COLUMN IF NOT EXISTS base_asset TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS quote_asset TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS contract_type TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS instrument TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_position_instrument_date ON "position" (instrument, date DESC);
+
+-- Rollup rows stay keyed by exchange symbol; instrument follows from it
+ALTER TABLE position_daily_rollup ADD COLUMN IF NOT EXISTS instrument TEXT NOT NULL DEFAULT '';
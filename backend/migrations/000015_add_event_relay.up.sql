@@ -0,0 +1,14 @@
+-- Websocket events published by one instance, for the hubs of the others.
+-- Each insert is announced with NOTIFY budgettracker_events; listeners read
+-- every row after the last one they saw, so they catch up after a dropped
+-- connection. Rows are pruned after an hour.
+CREATE TABLE IF NOT EXISTS event_relay (
+    id BIGSERIAL PRIMARY KEY,
+    origin TEXT NOT NULL,
+    topic TEXT NOT NULL DEFAULT '',
+    type TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_event_relay_created_at ON event_relay (created_at);
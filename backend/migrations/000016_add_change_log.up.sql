@@ -0,0 +1,13 @@
+-- Committed inserts, updates and deletes of positions, withdrawals and
+-- settings, served by GET /api/v1/changes. The repositories write entries in
+-- the transaction of the change and hold an advisory lock from the first
+-- entry to commit, so IDs become visible in commit order and a cursor never
+-- skips an entry.
+CREATE TABLE IF NOT EXISTS change_log (
+    id BIGSERIAL PRIMARY KEY,
+    entity TEXT NOT NULL,
+    op TEXT NOT NULL,
+    entity_key TEXT NOT NULL DEFAULT '',
+    data JSONB,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,20 @@
+-- One row per sync attempt of an exchange account, for GET /api/v1/sync/status
+-- and /sync/runs. error_class is empty for runs that succeeded. Rows older
+-- than 30 days are pruned.
+CREATE TABLE IF NOT EXISTS sync_run (
+    id BIGSERIAL PRIMARY KEY,
+    exchange TEXT NOT NULL,
+    account TEXT NOT NULL,
+    triggered_by TEXT NOT NULL DEFAULT '',
+    started_at TIMESTAMPTZ NOT NULL,
+    finished_at TIMESTAMPTZ NOT NULL,
+    pages INTEGER NOT NULL DEFAULT 0,
+    fetched INTEGER NOT NULL DEFAULT 0,
+    inserted INTEGER NOT NULL DEFAULT 0,
+    updated INTEGER NOT NULL DEFAULT 0,
+    error_class TEXT NOT NULL DEFAULT '',
+    error_message TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_sync_run_account ON sync_run (exchange, account, id);
+CREATE INDEX IF NOT EXISTS idx_sync_run_started_at ON sync_run (started_at);
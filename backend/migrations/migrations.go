@@ -0,0 +1,20 @@
+// Package migrations embeds the SQL schema migrations so the binary can
+// apply them without external tooling.
+package migrations
+
+import (
+	"embed"
+	"io/fs"
+)
+
+// FS holds every NNNNNN_name.up.sql / .down.sql file for PostgreSQL
+//
+//go:embed *.sql
+var FS embed.FS
+
+//go:embed sqlite/*.sql
+var sqliteFS embed.FS
+
+// SQLite holds the migrations of the SQLite backend, which start from the
+// current schema instead of replaying the PostgreSQL history
+var SQLite, _ = fs.Sub(sqliteFS, "sqlite")
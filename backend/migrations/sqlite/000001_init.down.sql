@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS settings;
+DROP TABLE IF EXISTS import_profile;
+DROP TABLE IF EXISTS api_keys;
+DROP TABLE IF EXISTS monthly_income;
+DROP TABLE IF EXISTS withdrawal;
+DROP TABLE IF EXISTS position;
@@ -0,0 +1,81 @@
+-- SQLite schema for single-user installs, equivalent to the PostgreSQL
+-- migrations up to 000011. Times are UTC text in a fixed-width
+-- YYYY-MM-DDTHH:MM:SS.sssZ form so they compare and sort lexically.
+
+CREATE TABLE IF NOT EXISTS position (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    order_id TEXT NOT NULL UNIQUE,
+    exchange TEXT NOT NULL,
+    account TEXT NOT NULL DEFAULT 'default',
+    symbol TEXT NOT NULL,
+    volume REAL NOT NULL,
+    leverage INTEGER NOT NULL,
+    closed_pnl REAL NOT NULL,
+    side TEXT NOT NULL,
+    date TEXT NOT NULL,
+    created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now')),
+    updated_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_position_date_id ON position (date DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_position_exchange_date ON position (exchange, date DESC);
+CREATE INDEX IF NOT EXISTS idx_position_symbol_date ON position (symbol, date DESC);
+
+CREATE TABLE IF NOT EXISTS withdrawal (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    exchange TEXT NOT NULL,
+    amount REAL NOT NULL,
+    currency TEXT NOT NULL,
+    date TEXT NOT NULL,
+    created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now')),
+    updated_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now'))
+);
+
+CREATE TABLE IF NOT EXISTS monthly_income (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    exchange TEXT NOT NULL,
+    amount REAL NOT NULL,
+    pnl REAL NOT NULL,
+    date TEXT NOT NULL,
+    created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now')),
+    updated_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now'))
+);
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    exchange TEXT NOT NULL UNIQUE,
+    api_key TEXT NOT NULL,
+    api_secret TEXT NOT NULL,
+    is_active INTEGER NOT NULL DEFAULT 1,
+    created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now')),
+    updated_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now'))
+);
+
+INSERT INTO api_keys (exchange, api_key, api_secret, is_active)
+VALUES
+    ('mexc', '', '', 0),
+    ('bybit', '', '', 0)
+ON CONFLICT (exchange) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS import_profile (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE,
+    exchange TEXT NOT NULL,
+    symbol_column TEXT NOT NULL,
+    side_column TEXT NOT NULL,
+    pnl_column TEXT NOT NULL,
+    date_column TEXT NOT NULL,
+    date_format TEXT NOT NULL DEFAULT '',
+    volume_column TEXT NOT NULL DEFAULT '',
+    leverage_column TEXT NOT NULL DEFAULT '',
+    order_id_column TEXT NOT NULL DEFAULT '',
+    delimiter TEXT NOT NULL DEFAULT '',
+    created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now')),
+    updated_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now'))
+);
+
+CREATE TABLE IF NOT EXISTS settings (
+    key TEXT PRIMARY KEY,
+    value TEXT NOT NULL,
+    updated_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now'))
+);
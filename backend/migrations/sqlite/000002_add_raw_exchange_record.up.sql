@@ -0,0 +1,9 @@
+-- Exchange payloads as fetched, see the PostgreSQL migration 000012
+CREATE TABLE IF NOT EXISTS raw_exchange_record (
+    exchange TEXT NOT NULL,
+    external_id TEXT NOT NULL,
+    kind TEXT NOT NULL,
+    payload TEXT NOT NULL,
+    fetched_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now')),
+    PRIMARY KEY (exchange, external_id)
+);
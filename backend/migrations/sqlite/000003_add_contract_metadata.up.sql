@@ -0,0 +1,15 @@
+-- Futures contract metadata, see the PostgreSQL migration 000013
+CREATE TABLE IF NOT EXISTS contract_metadata (
+    exchange TEXT NOT NULL,
+    symbol TEXT NOT NULL,
+    base_coin TEXT NOT NULL DEFAULT '',
+    quote_coin TEXT NOT NULL DEFAULT '',
+    settle_coin TEXT NOT NULL DEFAULT '',
+    contract_size REAL NOT NULL DEFAULT 0,
+    price_scale INTEGER NOT NULL DEFAULT 0,
+    vol_scale INTEGER NOT NULL DEFAULT 0,
+    contract_size_override REAL,
+    applied_contract_size REAL NOT NULL DEFAULT 0,
+    updated_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now')),
+    PRIMARY KEY (exchange, symbol)
+);
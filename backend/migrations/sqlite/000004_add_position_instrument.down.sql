@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_position_instrument_date;
+
+ALTER TABLE position DROP COLUMN instrument;
+ALTER TABLE position DROP COLUMN contract_type;
+ALTER TABLE position DROP COLUMN quote_asset;
+ALTER TABLE position DROP COLUMN base_asset;
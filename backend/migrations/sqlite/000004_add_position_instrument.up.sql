@@ -0,0 +1,7 @@
+-- Canonical instrument of each position, see the PostgreSQL migration 000014
+ALTER TABLE position ADD COLUMN base_asset TEXT NOT NULL DEFAULT '';
+ALTER TABLE position ADD COLUMN quote_asset TEXT NOT NULL DEFAULT '';
+ALTER TABLE position ADD COLUMN contract_type TEXT NOT NULL DEFAULT '';
+ALTER TABLE position ADD COLUMN instrument TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_position_instrument_date ON position (instrument, date DESC);
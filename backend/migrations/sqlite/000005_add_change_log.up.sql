@@ -0,0 +1,10 @@
+-- Change feed entries, see the PostgreSQL migration 000016. SQLite has a
+-- single writer, so AUTOINCREMENT IDs are already in commit order.
+CREATE TABLE IF NOT EXISTS change_log (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    entity TEXT NOT NULL,
+    op TEXT NOT NULL,
+    entity_key TEXT NOT NULL DEFAULT '',
+    data TEXT,
+    created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now'))
+);
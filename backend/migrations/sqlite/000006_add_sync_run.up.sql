@@ -0,0 +1,18 @@
+-- Sync attempts, see the PostgreSQL migration 000017
+CREATE TABLE IF NOT EXISTS sync_run (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    exchange TEXT NOT NULL,
+    account TEXT NOT NULL,
+    triggered_by TEXT NOT NULL DEFAULT '',
+    started_at TEXT NOT NULL,
+    finished_at TEXT NOT NULL,
+    pages INTEGER NOT NULL DEFAULT 0,
+    fetched INTEGER NOT NULL DEFAULT 0,
+    inserted INTEGER NOT NULL DEFAULT 0,
+    updated INTEGER NOT NULL DEFAULT 0,
+    error_class TEXT NOT NULL DEFAULT '',
+    error_message TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_sync_run_account ON sync_run (exchange, account, id);
+CREATE INDEX IF NOT EXISTS idx_sync_run_started_at ON sync_run (started_at);
@@ -0,0 +1,40 @@
+// Package cluster coordinates backend instances sharing one database:
+// leadership decides which instance syncs each exchange account, and the
+// relay carries websocket events between their hubs.
+package cluster
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+)
+
+// Leader decides which instance runs singleton work
+type Leader interface {
+	// IsLeader reports whether this instance holds the named leadership,
+	// taking it when it is free
+	IsLeader(ctx context.Context, name string) bool
+}
+
+// Single is the Leader of a deployment with one instance, such as every
+// SQLite one: it leads everything
+type Single struct{}
+
+func (Single) IsLeader(ctx context.Context, name string) bool {
+	return true
+}
+
+// SyncLeadership names the leadership over syncing an exchange account
+func SyncLeadership(exchange string) string {
+	return "sync:" + exchange
+}
+
+// NewInstanceID returns an ID telling this process apart from the other
+// instances, e.g. "backend-1/3fa2c1d0"
+func NewInstanceID() string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	host, _ := os.Hostname()
+	return host + "/" + hex.EncodeToString(b)
+}
@@ -0,0 +1,287 @@
+package cluster
+
+import (
+	"github.com/Ravierin/BudgetTracker/backend/pkg/database"
+	"github.com/Ravierin/BudgetTracker/backend/pkg/websocket"
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// leaderLockClass is the first key of leadership advisory locks; the second
+// is hashtext(name). The other lock IDs live next to their users (727001
+// rollup, 727002 migrations, 727004 change log, 727005 event relay).
+const leaderLockClass = 727003
+
+// relayLockID is held from an event's insert until it commits, so relay
+// IDs commit in order and a listener past one never skips an earlier one
+const relayLockID = 727005
+
+// PostgresLeader holds leaderships as session advisory locks, each on its
+// own connection outside the pool. The connection is kept while another
+// instance leads, so retries don't dial again. Postgres releases a lock when
+// its session ends, so a crashed instance hands over within a sync interval.
+type PostgresLeader struct {
+	db    *database.Database
+	mu    sync.Mutex
+	conns map[string]*pgx.Conn
+	held  map[string]bool
+}
+
+func NewPostgresLeader(db *database.Database) *PostgresLeader {
+	return &PostgresLeader{
+		db:    db,
+		conns: make(map[string]*pgx.Conn),
+		held:  make(map[string]bool),
+	}
+}
+
+func (l *PostgresLeader) IsLeader(ctx context.Context, name string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	conn := l.conns[name]
+	if conn != nil && l.held[name] {
+		if err := conn.Ping(ctx); err == nil {
+			return true
+		}
+		// The session is gone and the lock with it
+		l.drop(name)
+		conn = nil
+		log.Printf("Lost %s leadership", name)
+	}
+
+	if conn == nil {
+		var err error
+		conn, err = pgx.ConnectConfig(ctx, l.db.Pool.Config().ConnConfig.Copy())
+		if err != nil {
+			log.Printf("Failed to connect for %s leadership: %v", name, err)
+			return false
+		}
+		l.conns[name] = conn
+	}
+
+	var locked bool
+	err := conn.QueryRow(ctx, `SELECT pg_try_advisory_lock($1, hashtext($2))`, leaderLockClass, name).Scan(&locked)
+	if err != nil {
+		// Dial again on the next call
+		l.drop(name)
+		return false
+	}
+	if !locked {
+		return false
+	}
+
+	l.held[name] = true
+	log.Printf("Took %s leadership", name)
+	return true
+}
+
+// drop closes the connection of a leadership, releasing its lock if held.
+// Called with mu held.
+func (l *PostgresLeader) drop(name string) {
+	if conn, ok := l.conns[name]; ok {
+		conn.Close(context.Background())
+	}
+	delete(l.conns, name)
+	delete(l.held, name)
+}
+
+// Close gives up every leadership
+func (l *PostgresLeader) Close() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for name := range l.conns {
+		l.drop(name)
+	}
+}
+
+const (
+	relayChannel = "budgettracker_events"
+	// relayRetention is how long relayed events are kept for listeners that
+	// reconnect
+	relayRetention = time.Hour
+)
+
+const relayInsertQuery = `
+	WITH e AS (
+		INSERT INTO event_relay (origin, topic, type, payload)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	)
+	SELECT pg_notify('` + relayChannel + `', id::text) FROM e
+`
+
+type relayedEvent struct {
+	topic     string
+	eventType string
+	payload   []byte
+}
+
+// PostgresRelay carries hub events between instances: published events are
+// written to event_relay and announced with NOTIFY, and the events of other
+// instances are delivered to the local hub.
+type PostgresRelay struct {
+	db       *database.Database
+	hub      *websocket.Hub
+	instance string
+	out      chan relayedEvent
+}
+
+func NewPostgresRelay(db *database.Database, hub *websocket.Hub, instance string) *PostgresRelay {
+	return &PostgresRelay{
+		db:       db,
+		hub:      hub,
+		instance: instance,
+		out:      make(chan relayedEvent, 256),
+	}
+}
+
+// Forward queues an event for the other instances; it never blocks the
+// publisher and drops events while the database is unreachable
+func (r *PostgresRelay) Forward(topic, eventType string, payload interface{}) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("Failed to encode %s event for relay: %v", eventType, err)
+		return
+	}
+	select {
+	case r.out <- relayedEvent{topic: topic, eventType: eventType, payload: data}:
+	default:
+		log.Printf("Event relay queue full, dropping %s event", eventType)
+	}
+}
+
+// Start writes forwarded events and listens for those of other instances
+// until ctx ends
+func (r *PostgresRelay) Start(ctx context.Context) {
+	go r.write(ctx)
+
+	var lastID int64
+	if err := r.db.Pool.QueryRow(ctx, `SELECT COALESCE(MAX(id), 0) FROM event_relay`).Scan(&lastID); err != nil {
+		log.Printf("Failed to read event relay position: %v", err)
+	}
+
+	for ctx.Err() == nil {
+		err := r.listen(ctx, &lastID)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("Event relay listener failed: %v; reconnecting", err)
+		select {
+		case <-time.After(5 * time.Second):
+		case <-ctx.Done():
+		}
+	}
+}
+
+func (r *PostgresRelay) write(ctx context.Context) {
+	for {
+		select {
+		case e := <-r.out:
+			if err := r.insert(ctx, e); err != nil {
+				log.Printf("Failed to relay %s event: %v", e.eventType, err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// insert writes an event under relayLockID; its NOTIFY goes out on commit
+func (r *PostgresRelay) insert(ctx context.Context, e relayedEvent) error {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, relayLockID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, relayInsertQuery, r.instance, e.topic, e.eventType, e.payload); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// listen delivers relayed events on a dedicated connection, catching up on
+// the ones written since lastID first
+func (r *PostgresRelay) listen(ctx context.Context, lastID *int64) error {
+	conn, err := pgx.ConnectConfig(ctx, r.db.Pool.Config().ConnConfig.Copy())
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, `LISTEN `+relayChannel); err != nil {
+		return err
+	}
+	if err := r.deliver(ctx, conn, lastID); err != nil {
+		return err
+	}
+
+	pruned := time.Now()
+	for {
+		// Wake up now and then to prune even when nothing is relayed
+		waitCtx, cancel := context.WithTimeout(ctx, time.Minute)
+		_, err := conn.WaitForNotification(waitCtx)
+		cancel()
+		if err != nil && ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil && waitCtx.Err() == nil {
+			return err
+		}
+
+		if err := r.deliver(ctx, conn, lastID); err != nil {
+			return err
+		}
+		if time.Since(pruned) > 10*time.Minute {
+			pruned = time.Now()
+			if _, err := conn.Exec(ctx, `DELETE FROM event_relay WHERE created_at < $1`, time.Now().Add(-relayRetention)); err != nil {
+				log.Printf("Failed to prune event relay: %v", err)
+			}
+		}
+	}
+}
+
+// deliver passes on the events after lastID. Inserts are serialized by
+// relayLockID, so every ID below a visible one has committed already.
+func (r *PostgresRelay) deliver(ctx context.Context, conn *pgx.Conn, lastID *int64) error {
+	rows, err := conn.Query(ctx, `
+		SELECT id, origin, topic, type, payload
+		FROM event_relay
+		WHERE id > $1
+		ORDER BY id
+	`, *lastID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var origin, topic, eventType string
+		var payload []byte
+		if err := rows.Scan(&id, &origin, &topic, &eventType, &payload); err != nil {
+			return err
+		}
+		*lastID = id
+		r.receive(origin, topic, eventType, payload)
+	}
+	return rows.Err()
+}
+
+// receive passes a relayed event to the local hub, unless this instance
+// wrote it and its clients already have it
+func (r *PostgresRelay) receive(origin, topic, eventType string, payload []byte) {
+	if origin != r.instance {
+		r.hub.Deliver(topic, eventType, payload)
+	}
+}
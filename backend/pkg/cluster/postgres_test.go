@@ -0,0 +1,121 @@
+package cluster
+
+import (
+	"github.com/Ravierin/BudgetTracker/backend/pkg/websocket"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	gorilla "github.com/gorilla/websocket"
+)
+
+func newTestRelay(instance string) (*PostgresRelay, *websocket.Hub) {
+	hub := websocket.NewHub()
+	go hub.Run()
+	relay := NewPostgresRelay(nil, hub, instance)
+	hub.SetRelay(relay)
+	return relay, hub
+}
+
+func nextForwarded(t *testing.T, r *PostgresRelay) relayedEvent {
+	t.Helper()
+	select {
+	case e := <-r.out:
+		return e
+	default:
+		t.Fatal("no event queued for the relay")
+		return relayedEvent{}
+	}
+}
+
+func TestForwardEncodesPayload(t *testing.T) {
+	r, _ := newTestRelay("a/1")
+
+	r.Forward("positions:bybit", websocket.EventPositionsAdded, map[string]int{"count": 2})
+	e := nextForwarded(t, r)
+	if e.topic != "positions:bybit" || e.eventType != websocket.EventPositionsAdded || string(e.payload) != `{"count":2}` {
+		t.Errorf("queued %s %s %s", e.topic, e.eventType, e.payload)
+	}
+
+	r.Forward("positions", "broken", make(chan int))
+	if len(r.out) != 0 {
+		t.Error("queued an event whose payload cannot be encoded")
+	}
+
+	// A full queue drops events instead of blocking the publisher
+	for i := 0; i < cap(r.out)+1; i++ {
+		r.Forward("positions", websocket.EventPositionsAdded, i)
+	}
+	if len(r.out) != cap(r.out) {
+		t.Errorf("%d events queued, want %d", len(r.out), cap(r.out))
+	}
+}
+
+// clientEvent is an event as a client decodes it
+type clientEvent struct {
+	Type    string          `json:"type"`
+	Topic   string          `json:"topic"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// dialHub connects a client to hub and waits until the hub has registered it
+func dialHub(t *testing.T, hub *websocket.Hub) *gorilla.Conn {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(hub.HandleWebSocket))
+	t.Cleanup(srv.Close)
+	conn, _, err := gorilla.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	if err := conn.WriteJSON(websocket.Request{Action: "subscribe", Topics: []string{"positions"}}); err != nil {
+		t.Fatal(err)
+	}
+	if e := readClientEvent(t, conn); e.Type != websocket.EventSubscriptions {
+		t.Fatalf("got %s, want the subscriptions reply", e.Type)
+	}
+	return conn
+}
+
+func readClientEvent(t *testing.T, conn *gorilla.Conn) clientEvent {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var e clientEvent
+	if err := conn.ReadJSON(&e); err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func TestRelayCarriesEventsToOtherInstances(t *testing.T) {
+	a, hubA := newTestRelay("a/1")
+	b, hubB := newTestRelay("b/2")
+	conn := dialHub(t, hubB)
+
+	hubA.Publish("positions:bybit", websocket.EventPositionsAdded, map[string]int{"count": 2})
+	e := nextForwarded(t, a)
+
+	// Every instance reads the event back from event_relay, its writer too
+	a.receive(a.instance, e.topic, e.eventType, e.payload)
+	b.receive(a.instance, e.topic, e.eventType, e.payload)
+
+	if got := hubA.Metrics().Published; got != 1 {
+		t.Errorf("writer published %d events, want its own event only once", got)
+	}
+	if got := hubB.Metrics().Published; got != 1 {
+		t.Errorf("other instance published %d events, want 1", got)
+	}
+	if len(b.out) != 0 {
+		t.Error("a delivered event was forwarded again")
+	}
+
+	// Clients of the other instance get the event as published
+	got := readClientEvent(t, conn)
+	if got.Type != websocket.EventPositionsAdded || got.Topic != "positions:bybit" || string(got.Payload) != `{"count":2}` {
+		t.Errorf("client got %s on %q with %s", got.Type, got.Topic, got.Payload)
+	}
+}
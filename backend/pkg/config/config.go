@@ -1,18 +1,61 @@
 package config
 
 import (
+	"github.com/Ravierin/BudgetTracker/backend/pkg/scheduler"
+	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
+	// Driver selects the storage backend: "postgres" (default) or "sqlite"
+	Driver string
+	// Path is the SQLite database file
+	Path string
+
 	Host     string
 	Port     string
 	User     string
 	Password string
 	Name     string
 	SSLMode  string
+	// AutoMigrate applies pending migrations at startup; when disabled the
+	// server refuses to start unless the schema is already up to date
+	AutoMigrate bool
+
+	// Exchange API base URLs; empty keeps the live endpoints
+	BybitBaseURL string
+	MEXCBaseURL  string
+	// RecordDir captures raw exchange traffic there; ReplayDir answers
+	// exchange requests from such a capture instead of the network
+	RecordDir string
+	ReplayDir string
+
+	// Streams lists the exchanges ingested from their private websocket
+	// streams; their REST syncs only fill gaps, every StreamPollInterval
+	Streams            []string
+	StreamPollInterval time.Duration
+	// Private stream URLs; empty keeps the live endpoints
+	BybitStreamURL string
+	MEXCStreamURL  string
+
+	// SyncSchedule and SyncJitter apply to every exchange's REST sync;
+	// SyncSchedules and SyncJitters hold the per-exchange overrides from
+	// SYNC_SCHEDULE_<EXCHANGE> and SYNC_JITTER_<EXCHANGE>
+	SyncSchedule  scheduler.Schedule
+	SyncSchedules map[string]scheduler.Schedule
+	SyncJitter    time.Duration
+	SyncJitters   map[string]time.Duration
+	SyncTimeout   time.Duration
+	// The sync of every exchange once the server is up
+	InitialSyncDelay   time.Duration
+	InitialSyncTimeout time.Duration
+	// Maintenance jobs
+	ContractRefreshSchedule scheduler.Schedule
+	SyncRunPruneSchedule    scheduler.Schedule
 }
 
 func LoadConfig() (*Config, error) {
@@ -21,16 +64,135 @@ func LoadConfig() (*Config, error) {
 		// Silently ignore - env vars should be set by Docker
 	}
 
+	driver := os.Getenv("DB_DRIVER")
+	if driver == "" {
+		driver = "postgres"
+	}
+	path := os.Getenv("DB_PATH")
+	if path == "" {
+		path = "budgettracker.db"
+	}
+
+	var streams []string
+	for _, exchange := range strings.Split(os.Getenv("EXCHANGE_STREAMS"), ",") {
+		if exchange = strings.ToLower(strings.TrimSpace(exchange)); exchange != "" {
+			streams = append(streams, exchange)
+		}
+	}
+	pollInterval, err := durationEnv("EXCHANGE_STREAM_POLL_INTERVAL", 5*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	syncSchedule, err := scheduleEnv("SYNC_SCHEDULE", "@every 30s")
+	if err != nil {
+		return nil, err
+	}
+	syncJitter, err := durationEnv("SYNC_JITTER", 0)
+	if err != nil {
+		return nil, err
+	}
+	syncSchedules := make(map[string]scheduler.Schedule)
+	syncJitters := make(map[string]time.Duration)
+	for _, kv := range os.Environ() {
+		name, value, _ := strings.Cut(kv, "=")
+		if value == "" {
+			continue
+		}
+		if exchange, ok := strings.CutPrefix(name, "SYNC_SCHEDULE_"); ok {
+			if syncSchedules[strings.ToLower(exchange)], err = scheduleEnv(name, ""); err != nil {
+				return nil, err
+			}
+		}
+		if exchange, ok := strings.CutPrefix(name, "SYNC_JITTER_"); ok {
+			if syncJitters[strings.ToLower(exchange)], err = durationEnv(name, 0); err != nil {
+				return nil, err
+			}
+		}
+	}
+	syncTimeout, err := durationEnv("SYNC_TIMEOUT", 2*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	initialSyncDelay, err := durationEnv("INITIAL_SYNC_DELAY", 2*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	initialSyncTimeout, err := durationEnv("INITIAL_SYNC_TIMEOUT", 5*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	contractSchedule, err := scheduleEnv("CONTRACT_REFRESH_SCHEDULE", "@every 24h")
+	if err != nil {
+		return nil, err
+	}
+	pruneSchedule, err := scheduleEnv("SYNC_RUN_PRUNE_SCHEDULE", "@hourly")
+	if err != nil {
+		return nil, err
+	}
+
 	return &Config{
+		Driver: driver,
+		Path:   path,
+
 		Host:     os.Getenv("DB_HOST"),
 		Port:     os.Getenv("DB_PORT"),
 		User:     os.Getenv("DB_USER"),
 		Password: os.Getenv("DB_PASSWORD"),
 		Name:     os.Getenv("DB_NAME"),
 		SSLMode:  os.Getenv("DB_SSL_MODE"),
+
+		AutoMigrate: os.Getenv("DB_AUTO_MIGRATE") != "false",
+
+		BybitBaseURL: os.Getenv("BYBIT_BASE_URL"),
+		MEXCBaseURL:  os.Getenv("MEXC_BASE_URL"),
+		RecordDir:    os.Getenv("EXCHANGE_RECORD_DIR"),
+		ReplayDir:    os.Getenv("EXCHANGE_REPLAY_DIR"),
+
+		Streams:            streams,
+		StreamPollInterval: pollInterval,
+		BybitStreamURL:     os.Getenv("BYBIT_STREAM_URL"),
+		MEXCStreamURL:      os.Getenv("MEXC_STREAM_URL"),
+
+		SyncSchedule:  syncSchedule,
+		SyncSchedules: syncSchedules,
+		SyncJitter:    syncJitter,
+		SyncJitters:   syncJitters,
+		SyncTimeout:   syncTimeout,
+
+		InitialSyncDelay:   initialSyncDelay,
+		InitialSyncTimeout: initialSyncTimeout,
+
+		ContractRefreshSchedule: contractSchedule,
+		SyncRunPruneSchedule:    pruneSchedule,
 	}, nil
 }
 
+// durationEnv reads a positive duration, or zero where the default is zero
+func durationEnv(name string, def time.Duration) (time.Duration, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 || (d == 0 && def != 0) {
+		return 0, fmt.Errorf("invalid %s: %s", name, v)
+	}
+	return d, nil
+}
+
+func scheduleEnv(name, def string) (scheduler.Schedule, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		v = def
+	}
+	schedule, err := scheduler.Parse(v)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", name, err)
+	}
+	return schedule, nil
+}
+
 func (c *Config) GetDSN() string {
 	return "postgres://" + c.User + ":" + c.Password + "@" + c.Host + ":" + c.Port + "/" + c.Name + "?sslmode=" + c.SSLMode
 }
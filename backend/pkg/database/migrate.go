@@ -0,0 +1,353 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// migrationLockID keeps replicas from migrating the same database concurrently
+const migrationLockID = 727002
+
+// legacyBaselineVersion is recorded for databases created by the old Docker
+// entrypoint, which had no version table. Later migrations are idempotent,
+// so they are replayed on top of it.
+const legacyBaselineVersion = 6
+
+// ErrDirtySchema means a migration was interrupted; the schema has to be
+// inspected and the version fixed with "migrate force" before starting
+var ErrDirtySchema = errors.New("database schema is dirty")
+
+var migrationFileRe = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Migrator applies migrations and tracks the schema version in the
+// schema_migrations table, using the same layout as golang-migrate so
+// databases migrated with that tool are picked up as is
+type Migrator struct {
+	open       func(ctx context.Context) (migrationSession, error)
+	migrations []Migration
+}
+
+// migrationSession is an exclusive connection to the database being
+// migrated, with the version table in place
+type migrationSession interface {
+	readVersion(ctx context.Context) (int64, bool, error)
+	setVersion(ctx context.Context, version int64, dirty bool) error
+	// apply runs sql and records version to, resetting to from on failure
+	apply(ctx context.Context, from, to int64, sql string) error
+	close()
+}
+
+func NewMigrator(db *Database, fsys fs.FS) (*Migrator, error) {
+	migrations, err := loadMigrations(fsys)
+	if err != nil {
+		return nil, err
+	}
+	open := func(ctx context.Context) (migrationSession, error) {
+		return openPgSession(ctx, db)
+	}
+	return &Migrator{open: open, migrations: migrations}, nil
+}
+
+func loadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		match := migrationFileRe.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: %w", entry.Name(), err)
+		}
+		data, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(data)
+		} else {
+			m.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// Latest returns the version of the newest embedded migration
+func (m *Migrator) Latest() int64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Version returns the current schema version, 0 for an empty database
+func (m *Migrator) Version(ctx context.Context) (int64, bool, error) {
+	var version int64
+	var dirty bool
+	err := m.withSession(ctx, func(s migrationSession) error {
+		var err error
+		version, dirty, err = s.readVersion(ctx)
+		return err
+	})
+	return version, dirty, err
+}
+
+// Check refuses a dirty schema or one that does not match the binary
+func (m *Migrator) Check(ctx context.Context) error {
+	version, dirty, err := m.Version(ctx)
+	if err != nil {
+		return err
+	}
+	return m.checkVersion(version, dirty)
+}
+
+func (m *Migrator) checkVersion(version int64, dirty bool) error {
+	if dirty {
+		return fmt.Errorf("%w at version %d", ErrDirtySchema, version)
+	}
+	if version > m.Latest() {
+		return fmt.Errorf("database schema version %d is newer than this binary (%d)", version, m.Latest())
+	}
+	if version < m.Latest() {
+		return fmt.Errorf("database schema version %d is behind %d; run \"migrate up\"", version, m.Latest())
+	}
+	return nil
+}
+
+// Up applies all pending migrations and returns how many ran
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	applied := 0
+	err := m.withSession(ctx, func(s migrationSession) error {
+		version, dirty, err := s.readVersion(ctx)
+		if err != nil {
+			return err
+		}
+		if dirty || version > m.Latest() {
+			return m.checkVersion(version, dirty)
+		}
+
+		for _, migration := range m.migrations {
+			if migration.Version <= version {
+				continue
+			}
+			if err := s.apply(ctx, version, migration.Version, migration.Up); err != nil {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			log.Printf("Applied migration %d_%s", migration.Version, migration.Name)
+			version = migration.Version
+			applied++
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down reverts the given number of migrations
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	return m.withSession(ctx, func(s migrationSession) error {
+		version, dirty, err := s.readVersion(ctx)
+		if err != nil {
+			return err
+		}
+		if dirty {
+			return fmt.Errorf("%w at version %d", ErrDirtySchema, version)
+		}
+
+		for ; steps > 0 && version > 0; steps-- {
+			i := sort.Search(len(m.migrations), func(i int) bool {
+				return m.migrations[i].Version >= version
+			})
+			if i == len(m.migrations) || m.migrations[i].Version != version {
+				return fmt.Errorf("no migration found for version %d", version)
+			}
+			migration := m.migrations[i]
+			if migration.Down == "" {
+				return fmt.Errorf("migration %d_%s cannot be reverted", migration.Version, migration.Name)
+			}
+
+			var previous int64
+			if i > 0 {
+				previous = m.migrations[i-1].Version
+			}
+			if err := s.apply(ctx, version, previous, migration.Down); err != nil {
+				return fmt.Errorf("revert %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			log.Printf("Reverted migration %d_%s", migration.Version, migration.Name)
+			version = previous
+		}
+		return nil
+	})
+}
+
+// Force records a version without running anything, to clear a dirty flag
+// after the schema has been repaired by hand
+func (m *Migrator) Force(ctx context.Context, version int64) error {
+	return m.withSession(ctx, func(s migrationSession) error {
+		return s.setVersion(ctx, version, false)
+	})
+}
+
+func (m *Migrator) withSession(ctx context.Context, fn func(s migrationSession) error) error {
+	s, err := m.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer s.close()
+	return fn(s)
+}
+
+// pgSession holds a pooled connection and the migration advisory lock, so
+// replicas starting together do not migrate concurrently
+type pgSession struct {
+	pooled *pgxpool.Conn
+	conn   *pgx.Conn
+}
+
+func openPgSession(ctx context.Context, db *Database) (*pgSession, error) {
+	pooled, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s := &pgSession{pooled: pooled, conn: pooled.Conn()}
+
+	if _, err := s.conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+		pooled.Release()
+		return nil, err
+	}
+	if err := s.ensureVersionTable(ctx); err != nil {
+		s.close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *pgSession) close() {
+	s.conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockID)
+	s.pooled.Release()
+}
+
+func (s *pgSession) ensureVersionTable(ctx context.Context) error {
+	var exists, legacy bool
+	err := s.conn.QueryRow(ctx, `
+		SELECT to_regclass('schema_migrations') IS NOT NULL,
+		       to_regclass('position') IS NOT NULL
+	`).Scan(&exists, &legacy)
+	if err != nil || exists {
+		return err
+	}
+
+	_, err = s.conn.Exec(ctx, versionTableQuery)
+	if err != nil || !legacy {
+		return err
+	}
+
+	log.Printf("Found a schema without version table, recording baseline version %d", legacyBaselineVersion)
+	return s.setVersion(ctx, legacyBaselineVersion, false)
+}
+
+func (s *pgSession) readVersion(ctx context.Context) (int64, bool, error) {
+	var version int64
+	var dirty bool
+	err := s.conn.QueryRow(ctx, readVersionQuery).Scan(&version, &dirty)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, false, nil
+	}
+	return version, dirty, err
+}
+
+func (s *pgSession) setVersion(ctx context.Context, version int64, dirty bool) error {
+	return setPgVersion(ctx, s.conn, version, dirty)
+}
+
+// apply marks the target version dirty, then runs the SQL and records the
+// clean version in one transaction. If the SQL fails, the transaction is
+// rolled back and the previous version restored; the dirty flag only
+// survives when the process dies mid-migration.
+func (s *pgSession) apply(ctx context.Context, from, to int64, sql string) error {
+	if err := s.setVersion(ctx, to, true); err != nil {
+		return err
+	}
+
+	tx, err := s.conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, sql); err != nil {
+		tx.Rollback(ctx)
+		if resetErr := s.setVersion(ctx, from, false); resetErr != nil {
+			log.Printf("Failed to reset schema version after failed migration: %v", resetErr)
+		}
+		return err
+	}
+	if err := setPgVersion(ctx, tx, to, false); err != nil {
+		tx.Rollback(ctx)
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+const versionTableQuery = `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT NOT NULL PRIMARY KEY,
+		dirty BOOLEAN NOT NULL
+	)
+`
+
+const readVersionQuery = `SELECT version, dirty FROM schema_migrations LIMIT 1`
+
+type pgExecer interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+func setPgVersion(ctx context.Context, db pgExecer, version int64, dirty bool) error {
+	if _, err := db.Exec(ctx, `DELETE FROM schema_migrations`); err != nil {
+		return err
+	}
+	if version <= 0 {
+		return nil
+	}
+	_, err := db.Exec(ctx, `INSERT INTO schema_migrations (version, dirty) VALUES ($1, $2)`, version, dirty)
+	return err
+}
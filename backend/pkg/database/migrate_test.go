@@ -0,0 +1,304 @@
+package database
+
+import (
+	"github.com/Ravierin/BudgetTracker/backend/migrations"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const postgresDSNEnv = "BUDGETTRACKER_TEST_PG_DSN"
+
+var testMigrations = fstest.MapFS{
+	"000001_create_a.up.sql":   {Data: []byte(`CREATE TABLE a (id INTEGER PRIMARY KEY);`)},
+	"000001_create_a.down.sql": {Data: []byte(`DROP TABLE a;`)},
+	"000002_create_b.up.sql":   {Data: []byte(`CREATE TABLE b (id INTEGER PRIMARY KEY);`)},
+	"000002_create_b.down.sql": {Data: []byte(`DROP TABLE b;`)},
+	"000003_create_c.up.sql":   {Data: []byte(`CREATE TABLE c (id INTEGER PRIMARY KEY);`)},
+	"README.md":                {Data: []byte("not a migration")},
+}
+
+func newSQLiteTestMigrator(t *testing.T, fsys fstest.MapFS) (*Migrator, *SQLiteDatabase) {
+	t.Helper()
+	db, err := NewSQLiteDatabase(filepath.Join(t.TempDir(), "budget.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(db.Close)
+
+	m, err := NewSQLiteMigrator(db, fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m, db
+}
+
+func expectVersion(t *testing.T, m *Migrator, want int64, wantDirty bool) {
+	t.Helper()
+	version, dirty, err := m.Version(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != want || dirty != wantDirty {
+		t.Fatalf("version %d (dirty %v), want %d (dirty %v)", version, dirty, want, wantDirty)
+	}
+}
+
+func tableExists(t *testing.T, db *SQLiteDatabase, name string) bool {
+	t.Helper()
+	var n int
+	err := db.DB.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`, name).Scan(&n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n > 0
+}
+
+func TestMigratorUpAndDown(t *testing.T) {
+	ctx := context.Background()
+	m, db := newSQLiteTestMigrator(t, testMigrations)
+
+	expectVersion(t, m, 0, false)
+	if err := m.Check(ctx); err == nil || !strings.Contains(err.Error(), "behind") {
+		t.Errorf("Check on an empty database: %v, want a behind error", err)
+	}
+
+	applied, err := m.Up(ctx)
+	if err != nil || applied != 3 {
+		t.Fatalf("Up applied %d, %v, want 3", applied, err)
+	}
+	expectVersion(t, m, 3, false)
+	if err := m.Check(ctx); err != nil {
+		t.Errorf("Check after Up: %v", err)
+	}
+	if applied, err := m.Up(ctx); err != nil || applied != 0 {
+		t.Errorf("second Up applied %d, %v, want nothing", applied, err)
+	}
+
+	// Migration 3 has no down file
+	if err := m.Down(ctx, 1); err == nil || !strings.Contains(err.Error(), "cannot be reverted") {
+		t.Errorf("Down over 3: %v, want an irreversible error", err)
+	}
+	if err := m.Force(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Down(ctx, 5); err != nil {
+		t.Fatal(err)
+	}
+	expectVersion(t, m, 0, false)
+	for _, table := range []string{"a", "b"} {
+		if tableExists(t, db, table) {
+			t.Errorf("table %s survived Down", table)
+		}
+	}
+}
+
+func TestMigratorRefusesDirtySchema(t *testing.T) {
+	ctx := context.Background()
+	m, db := newSQLiteTestMigrator(t, testMigrations)
+	if err := m.Force(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	// As left behind by a process that died during migration 1
+	if _, err := db.DB.Exec(`UPDATE schema_migrations SET dirty = ?`, true); err != nil {
+		t.Fatal(err)
+	}
+	expectVersion(t, m, 1, true)
+
+	if _, err := m.Up(ctx); !errors.Is(err, ErrDirtySchema) {
+		t.Errorf("Up: %v, want ErrDirtySchema", err)
+	}
+	if err := m.Down(ctx, 1); !errors.Is(err, ErrDirtySchema) {
+		t.Errorf("Down: %v, want ErrDirtySchema", err)
+	}
+	if err := m.Check(ctx); !errors.Is(err, ErrDirtySchema) {
+		t.Errorf("Check: %v, want ErrDirtySchema", err)
+	}
+	if tableExists(t, db, "b") {
+		t.Error("Up ran on a dirty schema")
+	}
+
+	// Force clears the flag once the schema has been repaired
+	if err := m.Force(ctx, 0); err != nil {
+		t.Fatal(err)
+	}
+	if applied, err := m.Up(ctx); err != nil || applied != 3 {
+		t.Errorf("Up after Force applied %d, %v, want 3", applied, err)
+	}
+}
+
+func TestFailedMigrationKeepsPreviousVersion(t *testing.T) {
+	ctx := context.Background()
+	fsys := fstest.MapFS{
+		"000001_create_a.up.sql": testMigrations["000001_create_a.up.sql"],
+		"000002_broken.up.sql":   {Data: []byte(`CREATE TABLE b (id INTEGER); INSERT INTO missing VALUES (1);`)},
+	}
+	m, db := newSQLiteTestMigrator(t, fsys)
+
+	applied, err := m.Up(ctx)
+	if err == nil || !strings.Contains(err.Error(), "migration 2_broken") {
+		t.Fatalf("Up: %v, want migration 2 to fail", err)
+	}
+	if applied != 1 {
+		t.Errorf("applied %d, want 1", applied)
+	}
+	expectVersion(t, m, 1, false)
+	if tableExists(t, db, "b") {
+		t.Error("the failed migration was not rolled back")
+	}
+}
+
+func TestMigratorRefusesNewerSchema(t *testing.T) {
+	ctx := context.Background()
+	m, db := newSQLiteTestMigrator(t, testMigrations)
+	if err := m.Force(ctx, 4); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.Up(ctx); err == nil || !strings.Contains(err.Error(), "newer than this binary") {
+		t.Errorf("Up: %v, want a newer schema error", err)
+	}
+	if err := m.Check(ctx); err == nil || !strings.Contains(err.Error(), "newer than this binary") {
+		t.Errorf("Check: %v, want a newer schema error", err)
+	}
+	if tableExists(t, db, "a") {
+		t.Error("Up ran on a newer schema")
+	}
+}
+
+func TestLoadMigrationsRejectsBrokenSets(t *testing.T) {
+	tests := map[string]fstest.MapFS{
+		"no up file": {
+			"000001_create_a.down.sql": testMigrations["000001_create_a.down.sql"],
+		},
+		"two names": {
+			"000001_create_a.up.sql":   testMigrations["000001_create_a.up.sql"],
+			"000001_create_x.down.sql": testMigrations["000001_create_a.down.sql"],
+		},
+	}
+	for name, fsys := range tests {
+		if _, err := loadMigrations(fsys); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestSQLiteMigrations(t *testing.T) {
+	ctx := context.Background()
+	db, err := NewSQLiteDatabase(filepath.Join(t.TempDir(), "budget.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	m, err := NewSQLiteMigrator(db, migrations.SQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Check(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Down(ctx, int(m.Latest())); err != nil {
+		t.Fatal(err)
+	}
+	expectVersion(t, m, 0, false)
+	if applied, err := m.Up(ctx); err != nil || int64(applied) != m.Latest() {
+		t.Errorf("Up after Down applied %d, %v, want %d", applied, err, m.Latest())
+	}
+}
+
+// newPostgresTestDatabase connects to an empty schema of its own, dropped
+// when the test ends
+func newPostgresTestDatabase(t *testing.T) *Database {
+	t.Helper()
+	dsn := os.Getenv(postgresDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s not set", postgresDSNEnv)
+	}
+	ctx := context.Background()
+
+	admin, err := NewDatabase(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(admin.Close)
+	schema := fmt.Sprintf("migrate_test_%d", time.Now().UnixNano())
+	if _, err := admin.Pool.Exec(ctx, `CREATE SCHEMA `+schema); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		admin.Pool.Exec(context.Background(), `DROP SCHEMA `+schema+` CASCADE`)
+	})
+
+	config, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.ConnConfig.RuntimeParams["search_path"] = schema
+	pool, err := pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+	return &Database{Pool: pool}
+}
+
+func TestPostgresLegacyBaseline(t *testing.T) {
+	ctx := context.Background()
+	db := newPostgresTestDatabase(t)
+
+	// A schema created by the old entrypoint: tables, no version table
+	all, err := loadMigrations(migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, migration := range all {
+		if migration.Version > legacyBaselineVersion {
+			break
+		}
+		if _, err := db.Pool.Exec(ctx, migration.Up); err != nil {
+			t.Fatalf("migration %d: %v", migration.Version, err)
+		}
+	}
+
+	m, err := NewMigrator(db, migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectVersion(t, m, legacyBaselineVersion, false)
+
+	applied, err := m.Up(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := len(all) - legacyBaselineVersion; applied != want {
+		t.Errorf("Up applied %d, want %d", applied, want)
+	}
+	if err := m.Check(ctx); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPostgresEmptyDatabaseStartsAtZero(t *testing.T) {
+	ctx := context.Background()
+	m, err := NewMigrator(newPostgresTestDatabase(t), migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectVersion(t, m, 0, false)
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	expectVersion(t, m, m.Latest(), false)
+}
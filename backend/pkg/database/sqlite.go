@@ -0,0 +1,133 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"net/url"
+
+	_ "modernc.org/sqlite"
+)
+
+// SQLiteDatabase is a single-file database for local single-user installs
+type SQLiteDatabase struct {
+	DB *sql.DB
+}
+
+// NewSQLiteDatabase opens (or creates) the database file. WAL lets reads
+// run alongside a write, and immediate transactions make concurrent writers
+// wait on the busy timeout instead of failing on lock upgrades.
+func NewSQLiteDatabase(path string) (*SQLiteDatabase, error) {
+	params := url.Values{}
+	params.Add("_pragma", "busy_timeout(10000)")
+	params.Add("_pragma", "journal_mode(WAL)")
+	params.Add("_pragma", "foreign_keys(ON)")
+	params.Set("_txlock", "immediate")
+
+	db, err := sql.Open("sqlite", "file:"+path+"?"+params.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite database: %w", err)
+	}
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open sqlite database: %w", err)
+	}
+
+	return &SQLiteDatabase{DB: db}, nil
+}
+
+func (d *SQLiteDatabase) Close() {
+	if d.DB != nil {
+		d.DB.Close()
+	}
+}
+
+// NewSQLiteMigrator applies the SQLite flavour of the schema migrations
+func NewSQLiteMigrator(db *SQLiteDatabase, fsys fs.FS) (*Migrator, error) {
+	migrations, err := loadMigrations(fsys)
+	if err != nil {
+		return nil, err
+	}
+	open := func(ctx context.Context) (migrationSession, error) {
+		return openSQLiteSession(ctx, db)
+	}
+	return &Migrator{open: open, migrations: migrations}, nil
+}
+
+// sqliteSession needs no lock beyond SQLite's own: only one process uses
+// the file and every migration runs in an immediate transaction
+type sqliteSession struct {
+	conn *sql.Conn
+}
+
+func openSQLiteSession(ctx context.Context, db *SQLiteDatabase) (*sqliteSession, error) {
+	conn, err := db.DB.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.ExecContext(ctx, versionTableQuery); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &sqliteSession{conn: conn}, nil
+}
+
+func (s *sqliteSession) close() {
+	s.conn.Close()
+}
+
+func (s *sqliteSession) readVersion(ctx context.Context) (int64, bool, error) {
+	var version int64
+	var dirty bool
+	err := s.conn.QueryRowContext(ctx, readVersionQuery).Scan(&version, &dirty)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	return version, dirty, err
+}
+
+func (s *sqliteSession) setVersion(ctx context.Context, version int64, dirty bool) error {
+	return setSQLiteVersion(ctx, s.conn, version, dirty)
+}
+
+func (s *sqliteSession) apply(ctx context.Context, from, to int64, query string) error {
+	if err := s.setVersion(ctx, to, true); err != nil {
+		return err
+	}
+
+	tx, err := s.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, query); err != nil {
+		tx.Rollback()
+		if resetErr := s.setVersion(ctx, from, false); resetErr != nil {
+			log.Printf("Failed to reset schema version after failed migration: %v", resetErr)
+		}
+		return err
+	}
+	if err := setSQLiteVersion(ctx, tx, to, false); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+type sqlExecer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+func setSQLiteVersion(ctx context.Context, db sqlExecer, version int64, dirty bool) error {
+	if _, err := db.ExecContext(ctx, `DELETE FROM schema_migrations`); err != nil {
+		return err
+	}
+	if version <= 0 {
+		return nil
+	}
+	_, err := db.ExecContext(ctx, `INSERT INTO schema_migrations (version, dirty) VALUES (?, ?)`, version, dirty)
+	return err
+}
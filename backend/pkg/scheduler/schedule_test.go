@@ -0,0 +1,111 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func date(year int, month time.Month, day, hour, min int) time.Time {
+	return time.Date(year, month, day, hour, min, 0, 0, time.UTC)
+}
+
+func TestCronNext(t *testing.T) {
+	tests := []struct {
+		spec     string
+		from     time.Time
+		want     time.Time
+		describe string
+	}{
+		{"* * * * *", date(2025, 5, 6, 10, 0).Add(30 * time.Second), date(2025, 5, 6, 10, 1), "next minute, never the current one"},
+		{"*/15 * * * *", date(2025, 5, 6, 10, 7), date(2025, 5, 6, 10, 15), "step over the whole range"},
+		{"5/20 * * * *", date(2025, 5, 6, 10, 26), date(2025, 5, 6, 10, 45), "step from a start value"},
+		{"0 9-17/4 * * *", date(2025, 5, 6, 10, 0), date(2025, 5, 6, 13, 0), "stepped range"},
+		{"0 9-11 * * *", date(2025, 5, 6, 11, 30), date(2025, 5, 7, 9, 0), "range wraps to the next day"},
+		{"30 8 * * 1,3,5", date(2025, 5, 6, 9, 0), date(2025, 5, 7, 8, 30), "list of weekdays"},
+		{"0 0 * * 7", date(2025, 5, 6, 9, 0), date(2025, 5, 11, 0, 0), "7 is Sunday"},
+		{"0 0 * * 0", date(2025, 5, 6, 9, 0), date(2025, 5, 11, 0, 0), "0 is Sunday"},
+		{"0 0 13 * 5", date(2025, 5, 1, 9, 0), date(2025, 5, 2, 0, 0), "day of week matches first"},
+		{"0 0 13 * 5", date(2025, 5, 10, 9, 0), date(2025, 5, 13, 0, 0), "day of month matches first"},
+		{"0 0 13 * *", date(2025, 5, 1, 9, 0), date(2025, 5, 13, 0, 0), "unrestricted day of week"},
+		{"0 0 * * 5", date(2025, 5, 3, 9, 0), date(2025, 5, 9, 0, 0), "unrestricted day of month"},
+		{"0 0 1 * *", date(2025, 5, 31, 12, 0), date(2025, 6, 1, 0, 0), "month boundary"},
+		{"0 0 31 * *", date(2025, 4, 1, 0, 0), date(2025, 5, 31, 0, 0), "skips months without the day"},
+		{"59 23 31 12 *", date(2025, 12, 31, 23, 59), date(2026, 12, 31, 23, 59), "year boundary"},
+		{"@yearly", date(2025, 12, 31, 23, 59), date(2026, 1, 1, 0, 0), "descriptor across the year"},
+		{"@hourly", date(2025, 5, 6, 23, 0), date(2025, 5, 7, 0, 0), "descriptor across the day"},
+		{"@weekly", date(2025, 5, 6, 9, 0), date(2025, 5, 11, 0, 0), "weekly on Sunday"},
+		{"0 0 29 2 *", date(2025, 3, 1, 0, 0), date(2028, 2, 29, 0, 0), "leap day"},
+		{"0 0 30 2 *", date(2025, 1, 1, 0, 0), time.Time{}, "impossible date"},
+	}
+
+	for _, tt := range tests {
+		s, err := Parse(tt.spec)
+		if err != nil {
+			t.Errorf("%s: Parse(%q): %v", tt.describe, tt.spec, err)
+			continue
+		}
+		if got := s.Next(tt.from); !got.Equal(tt.want) {
+			t.Errorf("%s: %q after %v = %v, want %v", tt.describe, tt.spec, tt.from, got, tt.want)
+		}
+		if s.String() != tt.spec {
+			t.Errorf("String() = %q, want %q", s.String(), tt.spec)
+		}
+	}
+}
+
+func TestParseRejectsInvalidSpecs(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"1-x * * * *",
+		"@every",
+		"@every x",
+		"@every -1m",
+		"@every 0s",
+		"@fortnightly",
+	} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("Parse(%q): expected an error", spec)
+		}
+	}
+}
+
+func TestEvery(t *testing.T) {
+	s, err := Parse("@every 90s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	from := date(2025, 12, 31, 23, 59).Add(10 * time.Second)
+	if got, want := s.Next(from), date(2026, 1, 1, 0, 0).Add(40*time.Second); !got.Equal(want) {
+		t.Errorf("Next = %v, want %v", got, want)
+	}
+	if s.String() != "@every 1m30s" {
+		t.Errorf("String() = %q", s.String())
+	}
+}
+
+func TestOnce(t *testing.T) {
+	at := date(2025, 5, 6, 10, 0)
+	s := once(at)
+	if got := s.Next(at.Add(-time.Hour)); !got.Equal(at) {
+		t.Errorf("Next before = %v, want %v", got, at)
+	}
+	for _, after := range []time.Time{at, at.Add(time.Second)} {
+		if got := s.Next(after); !got.IsZero() {
+			t.Errorf("Next(%v) = %v, want no further run", after, got)
+		}
+	}
+
+	if next := Once(time.Hour).Next(time.Now()); time.Until(next) <= 0 || time.Until(next) > time.Hour {
+		t.Errorf("Once(1h) runs at %v", next)
+	}
+}
@@ -0,0 +1,344 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+var (
+	ErrUnknownJob = errors.New("unknown job")
+	ErrJobPaused  = errors.New("job is paused")
+)
+
+// Job is a unit of recurring work
+type Job struct {
+	Name     string
+	Schedule Schedule
+	// Jitter delays each scheduled run by a random amount up to it, so
+	// jobs on the same schedule don't fire together
+	Jitter time.Duration
+	// Timeout bounds each run; zero leaves it unbounded
+	Timeout time.Duration
+	Run     func(ctx context.Context) error
+}
+
+// JobStatus is a snapshot of a job for the admin API
+type JobStatus struct {
+	Name      string     `json:"name"`
+	Schedule  string     `json:"schedule"`
+	Paused    bool       `json:"paused"`
+	Running   bool       `json:"running"`
+	NextRun   *time.Time `json:"nextRun,omitempty"`
+	LastStart *time.Time `json:"lastStart,omitempty"`
+	LastEnd   *time.Time `json:"lastEnd,omitempty"`
+	LastError string     `json:"lastError,omitempty"`
+	Runs      int        `json:"runs"`
+	Skipped   int        `json:"skipped"`
+}
+
+type job struct {
+	Job
+	trigger   chan struct{}
+	paused    bool
+	running   bool
+	pending   bool
+	next      time.Time
+	lastStart time.Time
+	lastEnd   time.Time
+	lastError string
+	runs      int
+	skipped   int
+}
+
+// Scheduler runs jobs on their schedules. A job never overlaps itself: a
+// scheduled run that comes due while the previous one is still going is
+// skipped, and a triggered one waits for it to finish.
+type Scheduler struct {
+	mu      sync.Mutex
+	jobs    map[string]*job
+	order   []string
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	started bool
+	clock   clock
+}
+
+// clock is the scheduler's source of time; tests swap in a manual one
+type clock interface {
+	Now() time.Time
+	NewTimer(d time.Duration) timer
+}
+
+type timer interface {
+	C() <-chan time.Time
+	Reset(d time.Duration) bool
+	Stop() bool
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) NewTimer(d time.Duration) timer { return realTimer{time.NewTimer(d)} }
+
+type realTimer struct{ *time.Timer }
+
+func (t realTimer) C() <-chan time.Time { return t.Timer.C }
+
+// New returns a scheduler whose jobs run under ctx; canceling it has the
+// same effect as Stop without the wait
+func New(ctx context.Context) *Scheduler {
+	ctx, cancel := context.WithCancel(ctx)
+	return &Scheduler{
+		jobs:   make(map[string]*job),
+		ctx:    ctx,
+		cancel: cancel,
+		clock:  realClock{},
+	}
+}
+
+// Add registers a job; added after Start, it is scheduled right away
+func (s *Scheduler) Add(j Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.jobs[j.Name]; ok {
+		return fmt.Errorf("job %s already registered", j.Name)
+	}
+	entry := &job{Job: j, trigger: make(chan struct{}, 1)}
+	s.jobs[j.Name] = entry
+	s.order = append(s.order, j.Name)
+	if s.started {
+		s.wg.Add(1)
+		go s.loop(entry)
+	}
+	return nil
+}
+
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.started {
+		return
+	}
+	s.started = true
+	for _, name := range s.order {
+		s.wg.Add(1)
+		go s.loop(s.jobs[name])
+	}
+}
+
+// Stop cancels the context of running jobs and waits for them to return,
+// or for ctx to end
+func (s *Scheduler) Stop(ctx context.Context) error {
+	s.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Pause stops a job from running until Resume; a run in progress finishes
+func (s *Scheduler) Pause(name string) error {
+	return s.update(name, func(j *job) { j.paused = true })
+}
+
+func (s *Scheduler) Resume(name string) error {
+	return s.update(name, func(j *job) { j.paused = false })
+}
+
+// Trigger runs a job now, or once the current run finishes. Further calls
+// before that run starts are absorbed.
+func (s *Scheduler) Trigger(name string) error {
+	s.mu.Lock()
+	j, ok := s.jobs[name]
+	paused := ok && j.paused
+	s.mu.Unlock()
+
+	switch {
+	case !ok:
+		return ErrUnknownJob
+	case paused:
+		return ErrJobPaused
+	}
+	select {
+	case j.trigger <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Jobs returns the status of every job, in registration order
+func (s *Scheduler) Jobs() []JobStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statuses := make([]JobStatus, 0, len(s.order))
+	for _, name := range s.order {
+		statuses = append(statuses, s.jobs[name].status())
+	}
+	return statuses
+}
+
+// Job returns the status of a single job
+func (s *Scheduler) Job(name string) (JobStatus, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	j, ok := s.jobs[name]
+	if !ok {
+		return JobStatus{}, ErrUnknownJob
+	}
+	return j.status(), nil
+}
+
+func (s *Scheduler) update(name string, fn func(j *job)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	j, ok := s.jobs[name]
+	if !ok {
+		return ErrUnknownJob
+	}
+	fn(j)
+	return nil
+}
+
+func (j *job) status() JobStatus {
+	status := JobStatus{
+		Name:      j.Name,
+		Schedule:  j.Schedule.String(),
+		Paused:    j.paused,
+		Running:   j.running,
+		LastError: j.lastError,
+		Runs:      j.runs,
+		Skipped:   j.skipped,
+	}
+	if !j.next.IsZero() {
+		next := j.next
+		status.NextRun = &next
+	}
+	if !j.lastStart.IsZero() {
+		lastStart := j.lastStart
+		status.LastStart = &lastStart
+	}
+	if !j.lastEnd.IsZero() {
+		lastEnd := j.lastEnd
+		status.LastEnd = &lastEnd
+	}
+	return status
+}
+
+// loop waits for the job's scheduled times and triggers and starts its runs
+func (s *Scheduler) loop(j *job) {
+	defer s.wg.Done()
+
+	timer := s.clock.NewTimer(0)
+	if !timer.Stop() {
+		<-timer.C()
+	}
+	defer timer.Stop()
+
+	s.plan(j, timer, s.clock.Now())
+	for {
+		select {
+		case now := <-timer.C():
+			s.fire(j, false)
+			s.plan(j, timer, now)
+		case <-j.trigger:
+			s.fire(j, true)
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+// plan sets the timer to the job's next run after t, plus jitter; with no
+// next run, only triggers can start the job
+func (s *Scheduler) plan(j *job, timer timer, t time.Time) {
+	next := j.Schedule.Next(t)
+	if !next.IsZero() && j.Jitter > 0 {
+		next = next.Add(rand.N(j.Jitter))
+	}
+
+	s.mu.Lock()
+	j.next = next
+	s.mu.Unlock()
+
+	if !next.IsZero() {
+		timer.Reset(next.Sub(s.clock.Now()))
+	}
+}
+
+// fire starts a run unless the job is paused or already running
+func (s *Scheduler) fire(j *job, triggered bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case j.paused:
+		return
+	case j.running && triggered:
+		j.pending = true
+		return
+	case j.running:
+		j.skipped++
+		log.Printf("[jobs] %s is still running, skipping this run", j.Name)
+		return
+	}
+	j.running = true
+	s.wg.Add(1)
+	go s.run(j)
+}
+
+func (s *Scheduler) run(j *job) {
+	defer s.wg.Done()
+
+	for {
+		ctx, cancel := s.ctx, context.CancelFunc(func() {})
+		if j.Timeout > 0 {
+			ctx, cancel = context.WithTimeout(ctx, j.Timeout)
+		}
+		start := s.clock.Now()
+		s.mu.Lock()
+		j.lastStart = start
+		s.mu.Unlock()
+
+		err := j.Run(ctx)
+		cancel()
+		// A run cut short by Stop isn't a failure worth logging
+		if err != nil && !(errors.Is(err, context.Canceled) && s.ctx.Err() != nil) {
+			log.Printf("[jobs] %s failed after %v: %v", j.Name, s.clock.Now().Sub(start).Round(time.Millisecond), err)
+		}
+
+		s.mu.Lock()
+		j.lastEnd = s.clock.Now()
+		j.runs++
+		j.lastError = ""
+		if err != nil {
+			j.lastError = err.Error()
+		}
+		again := j.pending && !j.paused && s.ctx.Err() == nil
+		j.pending = false
+		j.running = again
+		s.mu.Unlock()
+		if !again {
+			return
+		}
+	}
+}
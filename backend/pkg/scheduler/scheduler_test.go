@@ -0,0 +1,330 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+// manualClock only moves when a test advances it
+type manualClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*manualTimer
+	// armed receives the deadline of every timer set in the future
+	armed chan time.Time
+}
+
+type manualTimer struct {
+	clock    *manualClock
+	c        chan time.Time
+	deadline time.Time
+	active   bool
+}
+
+func newManualClock(now time.Time) *manualClock {
+	return &manualClock{now: now, armed: make(chan time.Time, 64)}
+}
+
+func (c *manualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *manualClock) NewTimer(d time.Duration) timer {
+	t := &manualTimer{clock: c, c: make(chan time.Time, 1)}
+	c.mu.Lock()
+	c.timers = append(c.timers, t)
+	c.mu.Unlock()
+	t.Reset(d)
+	return t
+}
+
+// Advance moves the clock on and fires the timers that came due
+func (c *manualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	c.fireLocked()
+}
+
+func (c *manualClock) fireLocked() {
+	for _, t := range c.timers {
+		if t.active && !t.deadline.After(c.now) {
+			t.active = false
+			select {
+			case t.c <- c.now:
+			default:
+			}
+		}
+	}
+}
+
+// expectTimer waits for the scheduler to set a timer for want
+func (c *manualClock) expectTimer(t *testing.T, want time.Time) {
+	t.Helper()
+	select {
+	case got := <-c.armed:
+		if !got.Equal(want) {
+			t.Fatalf("timer set for %v, want %v", got, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no timer set, want one for %v", want)
+	}
+}
+
+func (t *manualTimer) C() <-chan time.Time { return t.c }
+
+func (t *manualTimer) Reset(d time.Duration) bool {
+	c := t.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	wasActive := t.active
+	t.deadline = c.now.Add(d)
+	t.active = true
+	if d > 0 {
+		c.armed <- t.deadline
+	}
+	c.fireLocked()
+	return wasActive
+}
+
+func (t *manualTimer) Stop() bool {
+	c := t.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	wasActive := t.active
+	t.active = false
+	return wasActive
+}
+
+// blockingJob signals each run's start and holds it until released
+type blockingJob struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (b *blockingJob) run(ctx context.Context) error {
+	b.started <- struct{}{}
+	select {
+	case <-b.release:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (b *blockingJob) expectStart(t *testing.T) {
+	t.Helper()
+	select {
+	case <-b.started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("job did not start")
+	}
+}
+
+func (b *blockingJob) expectNoStart(t *testing.T) {
+	t.Helper()
+	select {
+	case <-b.started:
+		t.Fatal("job started")
+	default:
+	}
+}
+
+var start = date(2025, 5, 6, 10, 0)
+
+// newTestScheduler starts a scheduler on a manual clock with one blocking
+// job named "job"
+func newTestScheduler(t *testing.T, schedule Schedule) (*Scheduler, *manualClock, *blockingJob) {
+	t.Helper()
+	clock := newManualClock(start)
+	s := New(context.Background())
+	s.clock = clock
+	b := &blockingJob{started: make(chan struct{}, 8), release: make(chan struct{})}
+	if err := s.Add(Job{Name: "job", Schedule: schedule, Run: b.run}); err != nil {
+		t.Fatal(err)
+	}
+	s.Start()
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := s.Stop(ctx); err != nil {
+			t.Errorf("Stop: %v", err)
+		}
+	})
+	return s, clock, b
+}
+
+// waitFor yields until cond holds; runs finish on their own goroutines
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		runtime.Gosched()
+	}
+}
+
+func status(t *testing.T, s *Scheduler) JobStatus {
+	t.Helper()
+	st, err := s.Job("job")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return st
+}
+
+func idleAfter(t *testing.T, s *Scheduler, runs int) func() bool {
+	return func() bool {
+		st := status(t, s)
+		return st.Runs == runs && !st.Running
+	}
+}
+
+func TestSchedulerRunsOnSchedule(t *testing.T) {
+	s, clock, b := newTestScheduler(t, Every(time.Minute))
+	clock.expectTimer(t, start.Add(time.Minute))
+
+	clock.Advance(59 * time.Second)
+	b.expectNoStart(t)
+
+	clock.Advance(time.Second)
+	b.expectStart(t)
+	clock.expectTimer(t, start.Add(2*time.Minute))
+	b.release <- struct{}{}
+	waitFor(t, "the run to finish", idleAfter(t, s, 1))
+
+	st := status(t, s)
+	if st.LastStart == nil || !st.LastStart.Equal(start.Add(time.Minute)) || st.NextRun == nil || !st.NextRun.Equal(start.Add(2*time.Minute)) {
+		t.Errorf("status %+v, want last start at 10:01 and next run at 10:02", st)
+	}
+	if st.Skipped != 0 || st.LastError != "" {
+		t.Errorf("status %+v, want a clean run", st)
+	}
+}
+
+func TestSchedulerSkipsOverlappingRuns(t *testing.T) {
+	s, clock, b := newTestScheduler(t, Every(time.Minute))
+	clock.expectTimer(t, start.Add(time.Minute))
+
+	clock.Advance(time.Minute)
+	b.expectStart(t)
+	clock.expectTimer(t, start.Add(2*time.Minute))
+
+	// Still running when the next run comes due
+	clock.Advance(time.Minute)
+	clock.expectTimer(t, start.Add(3*time.Minute))
+	b.expectNoStart(t)
+	if st := status(t, s); st.Skipped != 1 || st.Runs != 0 || !st.Running {
+		t.Errorf("status %+v, want one skipped run while running", st)
+	}
+
+	b.release <- struct{}{}
+	waitFor(t, "the run to finish", idleAfter(t, s, 1))
+
+	clock.Advance(time.Minute)
+	b.expectStart(t)
+	b.release <- struct{}{}
+	waitFor(t, "the second run to finish", idleAfter(t, s, 2))
+	if st := status(t, s); st.Skipped != 1 {
+		t.Errorf("status %+v, want still one skipped run", st)
+	}
+}
+
+func TestTriggerWhileRunning(t *testing.T) {
+	s, clock, b := newTestScheduler(t, Every(time.Hour))
+	clock.expectTimer(t, start.Add(time.Hour))
+	j := s.jobs["job"]
+	pending := func() bool {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return j.pending && len(j.trigger) == 0
+	}
+
+	if err := s.Trigger("job"); err != nil {
+		t.Fatal(err)
+	}
+	b.expectStart(t)
+
+	// Triggers during the run queue a single follow-up run
+	for i := 0; i < 3; i++ {
+		if err := s.Trigger("job"); err != nil {
+			t.Fatal(err)
+		}
+		waitFor(t, "the trigger to be queued", pending)
+	}
+
+	b.release <- struct{}{}
+	b.expectStart(t)
+	b.release <- struct{}{}
+	waitFor(t, "both runs to finish", idleAfter(t, s, 2))
+	b.expectNoStart(t)
+
+	if st := status(t, s); st.Skipped != 0 || !st.NextRun.Equal(start.Add(time.Hour)) {
+		t.Errorf("status %+v, want triggers to leave the schedule alone", st)
+	}
+}
+
+func TestPauseAndResume(t *testing.T) {
+	s, clock, b := newTestScheduler(t, Every(time.Minute))
+	clock.expectTimer(t, start.Add(time.Minute))
+
+	if err := s.Pause("job"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Trigger("job"); !errors.Is(err, ErrJobPaused) {
+		t.Errorf("Trigger while paused: %v, want ErrJobPaused", err)
+	}
+	clock.Advance(time.Minute)
+	clock.expectTimer(t, start.Add(2*time.Minute))
+	b.expectNoStart(t)
+	if st := status(t, s); !st.Paused || st.Runs != 0 || st.Skipped != 0 {
+		t.Errorf("status %+v, want paused without runs", st)
+	}
+
+	if err := s.Resume("job"); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(time.Minute)
+	b.expectStart(t)
+	b.release <- struct{}{}
+	waitFor(t, "the run to finish", idleAfter(t, s, 1))
+	if st := status(t, s); st.Paused {
+		t.Errorf("status %+v, want resumed", st)
+	}
+
+	for _, err := range []error{s.Pause("nope"), s.Resume("nope"), s.Trigger("nope")} {
+		if !errors.Is(err, ErrUnknownJob) {
+			t.Errorf("unknown job: %v, want ErrUnknownJob", err)
+		}
+	}
+}
+
+func TestOnceRunsASingleTime(t *testing.T) {
+	s, clock, b := newTestScheduler(t, once(start.Add(30*time.Second)))
+	clock.expectTimer(t, start.Add(30*time.Second))
+
+	clock.Advance(30 * time.Second)
+	b.expectStart(t)
+	b.release <- struct{}{}
+	waitFor(t, "the run to finish", idleAfter(t, s, 1))
+	waitFor(t, "no next run", func() bool { return status(t, s).NextRun == nil })
+
+	clock.Advance(time.Hour)
+	b.expectNoStart(t)
+
+	// It can still be triggered by hand
+	if err := s.Trigger("job"); err != nil {
+		t.Fatal(err)
+	}
+	b.expectStart(t)
+	b.release <- struct{}{}
+	waitFor(t, "the triggered run to finish", idleAfter(t, s, 2))
+}
@@ -6,9 +6,13 @@ import (
 	"github.com/Ravierin/BudgetTracker/backend/internal/model"
 	"github.com/Ravierin/BudgetTracker/backend/internal/repository"
 	"github.com/Ravierin/BudgetTracker/backend/internal/service"
+	"github.com/Ravierin/BudgetTracker/backend/pkg/cluster"
+	"github.com/Ravierin/BudgetTracker/backend/pkg/scheduler"
 	"github.com/Ravierin/BudgetTracker/backend/pkg/websocket"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"strings"
@@ -17,66 +21,95 @@ import (
 	"github.com/gorilla/mux"
 )
 
+// syncWriteTimeout bounds the writes that finish a sync
+const syncWriteTimeout = 30 * time.Second
+
 type Server struct {
+	// ctx is the root context: requests and the exchange clients the server
+	// creates end with it
+	ctx               context.Context
 	router            *mux.Router
 	positionService   *service.PositionService
 	withdrawalService *service.WithdrawalService
 	incomeService     *service.MonthlyIncomeService
 	apiKeyService     *service.APIKeyService
 	balanceService    *service.BalanceService
-	positionRepo      *repository.PositionRepository
+	importService     *service.ImportService
+	backupService     *service.BackupService
+	settingsService   *service.SettingsService
+	rawRecordService  *service.RawRecordService
+	contractService   *service.ContractService
+	openPositions     *service.OpenPositionService
+	changeService     *service.ChangeService
+	syncRuns          *service.SyncRunService
+	positionRepo      repository.PositionStore
 	bybitClient       *api.BybitClient
 	mexcClient        *api.MEXClient
+	leader            cluster.Leader
+	jobs              *scheduler.Scheduler
 	wsHub             *websocket.Hub
 }
 
 func NewServer(
+	ctx context.Context,
 	positionService *service.PositionService,
 	withdrawalService *service.WithdrawalService,
 	incomeService *service.MonthlyIncomeService,
 	apiKeyService *service.APIKeyService,
 	balanceService *service.BalanceService,
-	positionRepo *repository.PositionRepository,
+	importService *service.ImportService,
+	backupService *service.BackupService,
+	settingsService *service.SettingsService,
+	rawRecordService *service.RawRecordService,
+	contractService *service.ContractService,
+	openPositions *service.OpenPositionService,
+	changeService *service.ChangeService,
+	syncRuns *service.SyncRunService,
+	positionRepo repository.PositionStore,
 	bybitClient *api.BybitClient,
 	mexcClient *api.MEXClient,
+	leader cluster.Leader,
+	jobs *scheduler.Scheduler,
 ) *Server {
 	hub := websocket.NewHub()
 	go hub.Run()
 
 	s := &Server{
+		ctx:               ctx,
 		router:            mux.NewRouter(),
 		positionService:   positionService,
 		withdrawalService: withdrawalService,
 		incomeService:     incomeService,
 		apiKeyService:     apiKeyService,
 		balanceService:    balanceService,
+		importService:     importService,
+		backupService:     backupService,
+		settingsService:   settingsService,
+		rawRecordService:  rawRecordService,
+		contractService:   contractService,
+		openPositions:     openPositions,
+		changeService:     changeService,
+		syncRuns:          syncRuns,
 		positionRepo:      positionRepo,
 		bybitClient:       bybitClient,
 		mexcClient:        mexcClient,
+		leader:            leader,
+		jobs:              jobs,
 		wsHub:             hub,
 	}
 
 	s.setupRoutes()
-	
-	// Initial sync of positions from all exchanges
-	go s.initialSync()
-	
+
 	return s
 }
 
-// initialSync performs initial synchronization from all exchanges
-func (s *Server) initialSync() {
-	// Wait a bit for server to start
-	time.Sleep(2 * time.Second)
-
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
-	defer cancel()
-
+// InitialSync performs initial synchronization from all exchanges; it is
+// scheduled to run once, shortly after startup
+func (s *Server) InitialSync(ctx context.Context) error {
 	// Get all active API keys
 	apiKeys, err := s.apiKeyService.GetAllAPIKeys(ctx)
 	if err != nil {
-		log.Printf("Failed to get API keys for initial sync: %v", err)
-		return
+		return fmt.Errorf("failed to get API keys for initial sync: %w", err)
 	}
 
 	log.Printf("Found %d API keys configured", len(apiKeys))
@@ -87,6 +120,10 @@ func (s *Server) initialSync() {
 			log.Printf("[%s] Skipping inactive key", key.Exchange)
 			continue
 		}
+		if !s.leader.IsLeader(ctx, cluster.SyncLeadership(key.Exchange)) {
+			log.Printf("[%s] Another instance syncs this exchange", key.Exchange)
+			continue
+		}
 
 		log.Printf("[%s] Starting initial sync...", key.Exchange)
 		synced := s.syncExchange(ctx, key.Exchange, key.APIKey, key.APISecret)
@@ -95,46 +132,118 @@ func (s *Server) initialSync() {
 	}
 
 	log.Printf("Initial sync completed. Total positions synced: %d", totalSynced)
+	return nil
 }
 
 // syncExchange syncs positions for a single exchange
 func (s *Server) syncExchange(ctx context.Context, exchangeName, apiKey, apiSecret string) int {
-	var positions []model.Position
-	var err error
-
+	var client api.ExchangeClient
 	switch exchangeName {
 	case "bybit":
-		client := api.NewBybitClient(apiKey, apiSecret)
-		positions, err = client.GetPositionsWithContext(ctx)
+		client = api.NewBybitClient(s.ctx, apiKey, apiSecret)
 	case "mexc":
-		client := api.NewMEXClient(apiKey, apiSecret)
-		positions, err = client.GetPositionsWithContext(ctx)
+		client = api.NewMEXClient(s.ctx, apiKey, apiSecret)
 	default:
 		return 0
 	}
 
+	run := syncPositions(ctx, client, exchangeName, model.SyncTriggerInitial, s.positionService, s.rawRecordService, s.openPositions, s.syncRuns, s.wsHub)
+	if run.ErrorClass != "" {
+		return 0
+	}
+	return run.Fetched
+}
+
+// syncPositions fetches and saves the closed positions of an exchange,
+// publishing sync status and position events, and records the attempt as a
+// sync run, which it returns
+func syncPositions(
+	ctx context.Context,
+	client api.ExchangeClient,
+	exchangeName, trigger string,
+	positionService *service.PositionService,
+	rawRecordService *service.RawRecordService,
+	openPositions *service.OpenPositionService,
+	syncRuns *service.SyncRunService,
+	hub *websocket.Hub,
+) model.SyncRun {
+	run := model.SyncRun{
+		Exchange:  exchangeName,
+		Account:   model.DefaultAccount,
+		Trigger:   trigger,
+		StartedAt: time.Now(),
+	}
+	fail := func(class string, err error) model.SyncRun {
+		publishSyncStatus(hub, exchangeName, websocket.SyncFailed, 0, err)
+		run.ErrorClass, run.ErrorMessage = class, err.Error()
+		run.FinishedAt = time.Now()
+		writeCtx, cancel := writeContext(ctx)
+		defer cancel()
+		syncRuns.Record(writeCtx, run)
+		return run
+	}
+
+	publishSyncStatus(hub, exchangeName, websocket.SyncStarted, 0, nil)
+	syncOpenPositions(ctx, client, exchangeName, openPositions, hub)
+
+	positions, records, err := client.FetchPositions(api.WithPageCounter(ctx, &run.Pages))
 	if err != nil {
-		// Skip temporary errors silently
-		if !containsTemporaryError(err.Error()) {
-			log.Printf("[%s] Sync error: %v", exchangeName, err)
+		// Rate limits, timeouts and shutdowns clear up by themselves; don't
+		// log them
+		if errors.Is(err, context.Canceled) || containsTemporaryError(err.Error()) {
+			return fail(model.SyncErrorTemporary, err)
 		}
-		return 0
+		log.Printf("[%s] Sync error: %v", exchangeName, err)
+		return fail(model.SyncErrorExchange, err)
+	}
+	run.Fetched = len(positions)
+
+	// What was fetched gets saved even if the sync is canceled meanwhile
+	writeCtx, cancel := writeContext(ctx)
+	defer cancel()
+
+	// Payloads are kept for reprocessing; losing them doesn't lose positions
+	if err := rawRecordService.SaveRecords(writeCtx, records); err != nil {
+		log.Printf("[%s] Failed to save raw payloads: %v", exchangeName, err)
 	}
 
 	if len(positions) > 0 {
-		if err := s.positionService.SavePositionsBatch(ctx, positions); err != nil {
+		changes, err := positionService.SavePositionsBatch(writeCtx, positions)
+		if err != nil {
 			log.Printf("[%s] Failed to save positions: %v", exchangeName, err)
-			return 0
+			return fail(model.SyncErrorStorage, err)
 		}
-		log.Printf("[%s] Synced %d positions", exchangeName, len(positions))
+		run.Inserted, run.Updated = len(changes.Added), len(changes.Changed)
+		log.Printf("[%s] Synced %d positions (%d new, %d changed)", exchangeName, len(positions), run.Inserted, run.Updated)
+		publishPositionChanges(hub, exchangeName, changes, "")
+	} else {
+		log.Printf("[%s] No positions found", exchangeName)
 	}
 
-	return len(positions)
+	publishSyncStatus(hub, exchangeName, websocket.SyncCompleted, len(positions), nil)
+	run.FinishedAt = time.Now()
+	syncRuns.Record(writeCtx, run)
+	return run
+}
+
+// writeContext bounds the writes that finish a sync without inheriting the
+// sync's cancellation, so a shutdown never cuts a batch upsert short
+func writeContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(ctx), syncWriteTimeout)
+}
+
+func publishSyncStatus(hub *websocket.Hub, exchangeName, status string, count int, err error) {
+	payload := websocket.SyncStatus{Exchange: exchangeName, Status: status, Count: count}
+	if err != nil {
+		payload.Error = err.Error()
+	}
+	hub.Publish(websocket.ExchangeTopic(websocket.TopicSyncStatus, exchangeName), websocket.EventSyncStatus, payload)
 }
 
 func (s *Server) setupRoutes() {
 	s.router.Use(loggingMiddleware)
 	s.router.Use(corsMiddleware)
+	s.router.Use(s.shutdownMiddleware)
 
 	// Handle CORS preflight for all routes
 	s.router.Methods("OPTIONS").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -145,9 +254,11 @@ func (s *Server) setupRoutes() {
 
 	positionHandler := handler.NewPositionHandler(s.positionService, s.wsHub)
 	api.HandleFunc("/positions", positionHandler.GetAllPositions).Methods("GET")
-	api.HandleFunc("/positions/{id}", positionHandler.GetPosition).Methods("GET")
+	api.HandleFunc("/positions/stats", positionHandler.GetPositionStats).Methods("GET")
+	api.HandleFunc("/positions/open", handler.NewOpenPositionHandler(s.openPositions).GetOpenPositions).Methods("GET")
+	api.HandleFunc("/positions/{id:[0-9]+}", positionHandler.GetPosition).Methods("GET")
 	api.HandleFunc("/positions", positionHandler.CreatePosition).Methods("POST")
-	api.HandleFunc("/positions/{id}", positionHandler.DeletePosition).Methods("DELETE")
+	api.HandleFunc("/positions/{id:[0-9]+}", positionHandler.DeletePosition).Methods("DELETE")
 	api.HandleFunc("/positions/sync", positionHandler.SyncPositions).Methods("POST")
 
 	withdrawalHandler := handler.NewWithdrawalHandler(s.withdrawalService, s.wsHub)
@@ -159,6 +270,43 @@ func (s *Server) setupRoutes() {
 	api.HandleFunc("/monthly-income", incomeHandler.GetAllMonthlyIncomes).Methods("GET")
 	api.HandleFunc("/monthly-income/{id}", incomeHandler.GetMonthlyIncome).Methods("GET")
 
+	importHandler := handler.NewImportHandler(s.importService, s.wsHub)
+	api.HandleFunc("/import/profiles", importHandler.GetProfiles).Methods("GET")
+	api.HandleFunc("/import/profiles", importHandler.SaveProfile).Methods("POST")
+	api.HandleFunc("/import/profiles/{name}", importHandler.DeleteProfile).Methods("DELETE")
+	api.HandleFunc("/import/custom/{name}", importHandler.ImportWithProfile).Methods("POST")
+	api.HandleFunc("/import/{format}", importHandler.ImportPositions).Methods("POST")
+
+	exportHandler := handler.NewExportHandler(s.positionService, s.withdrawalService)
+	api.HandleFunc("/export/{kind}", exportHandler.Export).Methods("GET")
+
+	settingsHandler := handler.NewSettingsHandler(s.settingsService)
+	api.HandleFunc("/settings", settingsHandler.GetSettings).Methods("GET")
+	api.HandleFunc("/settings/{key}", settingsHandler.SaveSetting).Methods("PUT")
+	api.HandleFunc("/settings/{key}", settingsHandler.DeleteSetting).Methods("DELETE")
+
+	api.HandleFunc("/changes", handler.NewChangeHandler(s.changeService).GetChanges).Methods("GET")
+
+	syncHandler := handler.NewSyncHandler(s.syncRuns)
+	api.HandleFunc("/sync/status", syncHandler.GetSyncStatus).Methods("GET")
+	api.HandleFunc("/sync/runs", syncHandler.GetSyncRuns).Methods("GET")
+
+	contractHandler := handler.NewContractHandler(s.contractService)
+	api.HandleFunc("/contracts", contractHandler.GetContracts).Methods("GET")
+	api.HandleFunc("/contracts/refresh", contractHandler.RefreshContracts).Methods("POST")
+	api.HandleFunc("/contracts/backfill-volumes", contractHandler.BackfillVolumes).Methods("POST")
+	api.HandleFunc("/contracts/{symbol}/override", contractHandler.SetOverride).Methods("PUT")
+
+	backupHandler := handler.NewBackupHandler(s.backupService, s.wsHub)
+	api.HandleFunc("/admin/backup", backupHandler.Backup).Methods("GET")
+	api.HandleFunc("/admin/restore", backupHandler.Restore).Methods("POST")
+
+	jobHandler := handler.NewJobHandler(s.jobs)
+	api.HandleFunc("/admin/jobs", jobHandler.GetJobs).Methods("GET")
+	api.HandleFunc("/admin/jobs/{name}/pause", jobHandler.PauseJob).Methods("POST")
+	api.HandleFunc("/admin/jobs/{name}/resume", jobHandler.ResumeJob).Methods("POST")
+	api.HandleFunc("/admin/jobs/{name}/run", jobHandler.RunJob).Methods("POST")
+
 	apiKeyHandler := handler.NewAPIKeyHandler(s.apiKeyService)
 	api.HandleFunc("/api-keys", apiKeyHandler.GetAPIKeys).Methods("GET")
 	api.HandleFunc("/api-keys", apiKeyHandler.SaveAPIKeys).Methods("POST")
@@ -171,6 +319,7 @@ func (s *Server) setupRoutes() {
 	api.HandleFunc("/test/clear", testHandler.DeleteTestPositions).Methods("POST")
 
 	api.HandleFunc("/ws", s.wsHub.HandleWebSocket)
+	api.HandleFunc("/ws/metrics", s.wsHub.HandleMetrics).Methods("GET")
 
 	s.router.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -200,11 +349,25 @@ func loggingMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// shutdownMiddleware ends a request's context with the root context, so the
+// work a handler starts, such as a sync, stops on shutdown
+func (s *Server) shutdownMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+		stop := context.AfterFunc(s.ctx, cancel)
+		defer stop()
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
 func corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Backup-Passphrase")
+		w.Header().Set("Access-Control-Expose-Headers", "X-Next-Cursor, Content-Disposition")
 		w.Header().Set("Access-Control-Max-Age", "86400")
 
 		if r.Method == "OPTIONS" {
@@ -217,106 +380,151 @@ func corsMiddleware(next http.Handler) http.Handler {
 }
 
 type SyncService struct {
-	positionService *service.PositionService
-	apiKeyService   *service.APIKeyService
-	wsHub           *websocket.Hub
-	interval        time.Duration
-	stopChan        chan struct{}
-	exchangeName    string
+	// ctx is the root context; the exchange clients of syncs end with it
+	ctx              context.Context
+	positionService  *service.PositionService
+	rawRecordService *service.RawRecordService
+	openPositions    *service.OpenPositionService
+	apiKeyService    *service.APIKeyService
+	syncRuns         *service.SyncRunService
+	wsHub            *websocket.Hub
+	leader           cluster.Leader
+	jobs             *scheduler.Scheduler
+	exchangeName     string
 }
 
 func NewSyncService(
+	ctx context.Context,
 	positionService *service.PositionService,
+	rawRecordService *service.RawRecordService,
+	openPositions *service.OpenPositionService,
 	apiKeyService *service.APIKeyService,
+	syncRuns *service.SyncRunService,
 	wsHub *websocket.Hub,
-	interval time.Duration,
+	leader cluster.Leader,
+	jobs *scheduler.Scheduler,
 	exchangeName string,
 ) *SyncService {
 	return &SyncService{
-		positionService: positionService,
-		apiKeyService:   apiKeyService,
-		wsHub:           wsHub,
-		interval:        interval,
-		stopChan:        make(chan struct{}),
-		exchangeName:    exchangeName,
+		ctx:              ctx,
+		positionService:  positionService,
+		rawRecordService: rawRecordService,
+		openPositions:    openPositions,
+		apiKeyService:    apiKeyService,
+		syncRuns:         syncRuns,
+		wsHub:            wsHub,
+		leader:           leader,
+		jobs:             jobs,
+		exchangeName:     exchangeName,
 	}
 }
 
-func (s *SyncService) Start() {
-	ticker := time.NewTicker(s.interval)
-	defer ticker.Stop()
+// SyncJobName is the scheduler job that syncs an exchange
+func SyncJobName(exchangeName string) string {
+	return "sync-" + exchangeName
+}
 
-	for {
-		select {
-		case <-ticker.C:
-			s.sync()
-		case <-s.stopChan:
-			return
-		}
-	}
+// Register schedules the exchange's syncs; each run is bounded by timeout
+func (s *SyncService) Register(schedule scheduler.Schedule, jitter, timeout time.Duration) error {
+	return s.jobs.Add(scheduler.Job{
+		Name:     SyncJobName(s.exchangeName),
+		Schedule: schedule,
+		Jitter:   jitter,
+		Timeout:  timeout,
+		Run:      s.sync,
+	})
 }
 
-func (s *SyncService) Stop() {
-	close(s.stopChan)
+// Trigger runs a sync now, or right after the one in progress; a pending
+// trigger absorbs further calls
+func (s *SyncService) Trigger() {
+	if err := s.jobs.Trigger(SyncJobName(s.exchangeName)); err != nil && !errors.Is(err, scheduler.ErrJobPaused) {
+		log.Printf("[%s] Failed to trigger sync: %v", s.exchangeName, err)
+	}
 }
 
-func (s *SyncService) sync() {
+// sync runs one scheduled sync. Failed syncs are recorded as sync runs
+// rather than returned, so only lookup errors fail the job.
+func (s *SyncService) sync(ctx context.Context) error {
+	// With several instances on one database, only the leader syncs
+	if !s.leader.IsLeader(ctx, cluster.SyncLeadership(s.exchangeName)) {
+		return nil
+	}
 	log.Printf("[%s] Starting sync...", s.exchangeName)
-	
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
-	defer cancel()
 
 	apiKey, err := s.apiKeyService.GetAPIKey(ctx, s.exchangeName)
 	if err != nil {
-		log.Printf("[%s] Failed to get API key: %v", s.exchangeName, err)
-		return // Skip silently
+		return fmt.Errorf("failed to get API key: %w", err)
 	}
 
 	if apiKey.APIKey == "" || apiKey.APISecret == "" {
 		log.Printf("[%s] API keys not configured", s.exchangeName)
-		return // Keys not configured, skip silently
+		return nil // Keys not configured, skip silently
 	}
 
-	var positions []model.Position
+	var client api.ExchangeClient
 	switch s.exchangeName {
 	case "bybit":
-		client := api.NewBybitClient(apiKey.APIKey, apiKey.APISecret)
-		positions, err = client.GetPositionsWithContext(ctx)
+		client = api.NewBybitClient(s.ctx, apiKey.APIKey, apiKey.APISecret)
 	case "mexc":
-		client := api.NewMEXClient(apiKey.APIKey, apiKey.APISecret)
-		positions, err = client.GetPositionsWithContext(ctx)
+		client = api.NewMEXClient(s.ctx, apiKey.APIKey, apiKey.APISecret)
 	default:
-		return
+		return nil
+	}
+
+	syncPositions(ctx, client, s.exchangeName, model.SyncTriggerInterval, s.positionService, s.rawRecordService, s.openPositions, s.syncRuns, s.wsHub)
+	return nil
+}
+
+// publishPositionChanges sends the positions an upsert added and changed,
+// each as its own event; unchanged rows are not sent at all
+func publishPositionChanges(hub *websocket.Hub, exchangeName string, changes model.PositionChanges, source string) {
+	topic := websocket.ExchangeTopic(websocket.TopicPositions, exchangeName)
+	if len(changes.Added) > 0 {
+		hub.Publish(topic, websocket.EventPositionsAdded, websocket.PositionsChanged{
+			Exchange:  exchangeName,
+			Positions: changes.Added,
+			Count:     len(changes.Added),
+			Source:    source,
+		})
 	}
+	if len(changes.Changed) > 0 {
+		hub.Publish(topic, websocket.EventPositionsChanged, websocket.PositionsChanged{
+			Exchange:  exchangeName,
+			Positions: changes.Changed,
+			Count:     len(changes.Changed),
+			Source:    source,
+		})
+	}
+}
 
+// syncOpenPositions refreshes the open position snapshot of an exchange and
+// pushes it to websocket clients
+func syncOpenPositions(ctx context.Context, client api.ExchangeClient, exchangeName string, openPositions *service.OpenPositionService, hub *websocket.Hub) {
+	positions, err := client.GetOpenPositions(ctx)
 	if err != nil {
-		// Log only significant errors, skip rate limits/temporary issues
-		errMsg := err.Error()
-		if containsTemporaryError(errMsg) {
-			return // Don't log temporary errors
+		if !containsTemporaryError(err.Error()) {
+			log.Printf("[%s] Open positions error: %v", exchangeName, err)
 		}
-		log.Printf("[%s] Sync error: %v", s.exchangeName, err)
 		return
 	}
+	openPositions.Update(exchangeName, positions)
+	broadcastOpenPositions(hub, exchangeName, positions)
+}
 
-	if len(positions) > 0 {
-		if err := s.positionService.SavePositionsBatch(ctx, positions); err != nil {
-			log.Printf("[%s] Failed to save positions: %v", s.exchangeName, err)
-			return
-		}
-		log.Printf("[%s] Synced %d positions", s.exchangeName, len(positions))
-	} else {
-		log.Printf("[%s] No positions found", s.exchangeName)
+func broadcastOpenPositions(hub *websocket.Hub, exchangeName string, positions []model.OpenPosition) {
+	var unrealizedPnl float64
+	for _, p := range positions {
+		unrealizedPnl += p.UnrealizedPnl
 	}
-
-	// Broadcast update
-	message := map[string]interface{}{
-		"type":      "positions_update",
-		"positions": positions,
-		"count":     len(positions),
-		"exchange":  s.exchangeName,
+	if positions == nil {
+		positions = []model.OpenPosition{}
 	}
-	s.wsHub.Broadcast(message)
+	hub.Publish(websocket.ExchangeTopic(websocket.TopicOpenPositions, exchangeName), websocket.EventOpenPositionsUpdate, websocket.OpenPositionsUpdate{
+		Exchange:      exchangeName,
+		Positions:     positions,
+		UnrealizedPnl: unrealizedPnl,
+	})
 }
 
 // containsTemporaryError checks if error is temporary (rate limit, timeout, etc.)
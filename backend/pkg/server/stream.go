@@ -0,0 +1,161 @@
+package server
+
+import (
+	"github.com/Ravierin/BudgetTracker/backend/internal/api"
+	"github.com/Ravierin/BudgetTracker/backend/internal/service"
+	"github.com/Ravierin/BudgetTracker/backend/pkg/cluster"
+	"github.com/Ravierin/BudgetTracker/backend/pkg/websocket"
+	"context"
+	"errors"
+	"log"
+	"time"
+)
+
+const (
+	streamMinBackoff = time.Second
+	streamMaxBackoff = time.Minute
+)
+
+// errNotLeader ends a session attempt on an instance that doesn't sync the
+// exchange
+var errNotLeader = errors.New("another instance streams this exchange")
+
+// StreamService ingests the private websocket stream of one exchange. It
+// reconnects with exponential backoff and, once every session is
+// subscribed, triggers a REST sync to fill whatever was missed meanwhile.
+type StreamService struct {
+	positionService *service.PositionService
+	openPositions   *service.OpenPositionService
+	apiKeyService   *service.APIKeyService
+	syncService     *SyncService
+	wsHub           *websocket.Hub
+	leader          cluster.Leader
+	exchangeName    string
+}
+
+func NewStreamService(
+	positionService *service.PositionService,
+	openPositions *service.OpenPositionService,
+	apiKeyService *service.APIKeyService,
+	syncService *SyncService,
+	wsHub *websocket.Hub,
+	leader cluster.Leader,
+	exchangeName string,
+) *StreamService {
+	return &StreamService{
+		positionService: positionService,
+		openPositions:   openPositions,
+		apiKeyService:   apiKeyService,
+		syncService:     syncService,
+		wsHub:           wsHub,
+		leader:          leader,
+		exchangeName:    exchangeName,
+	}
+}
+
+// Start streams until ctx ends; a save in progress then still completes
+func (s *StreamService) Start(ctx context.Context) {
+	backoff := streamMinBackoff
+	for {
+		started := time.Now()
+		err := s.session(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		// Followers check back as often as the leader's lock can lapse
+		if errors.Is(err, errNotLeader) {
+			select {
+			case <-time.After(streamMaxBackoff):
+			case <-ctx.Done():
+				return
+			}
+			continue
+		}
+
+		// A session that held for a while was healthy; start over
+		if time.Since(started) > streamMaxBackoff {
+			backoff = streamMinBackoff
+		}
+		log.Printf("[%s] Stream disconnected: %v; reconnecting in %v", s.exchangeName, err, backoff)
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return
+		}
+		backoff = min(backoff*2, streamMaxBackoff)
+	}
+}
+
+func (s *StreamService) session(ctx context.Context) error {
+	leadership := cluster.SyncLeadership(s.exchangeName)
+	if !s.leader.IsLeader(ctx, leadership) {
+		return errNotLeader
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	// Drop the session if the leadership lapses, so two instances never
+	// stream the same account
+	go func() {
+		ticker := time.NewTicker(streamMaxBackoff)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if !s.leader.IsLeader(ctx, leadership) {
+					cancel()
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	apiKey, err := s.apiKeyService.GetAPIKey(ctx, s.exchangeName)
+	if err != nil {
+		return err
+	}
+	if apiKey.APIKey == "" || apiKey.APISecret == "" {
+		return errors.New("API keys not configured")
+	}
+
+	var client api.ExchangeClient
+	switch s.exchangeName {
+	case "bybit":
+		client = api.NewBybitClient(ctx, apiKey.APIKey, apiKey.APISecret)
+	case "mexc":
+		client = api.NewMEXClient(ctx, apiKey.APIKey, apiKey.APISecret)
+	default:
+		return errors.New("streaming not supported")
+	}
+
+	return client.StreamSession(ctx, func(ev api.StreamEvent) {
+		s.handle(ctx, ev)
+	})
+}
+
+func (s *StreamService) handle(ctx context.Context, ev api.StreamEvent) {
+	if ev.Connected {
+		log.Printf("[%s] Stream connected", s.exchangeName)
+		s.syncService.Trigger()
+	}
+
+	if len(ev.Closed) > 0 {
+		saveCtx, cancel := writeContext(ctx)
+		changes, err := s.positionService.SavePositionsBatch(saveCtx, ev.Closed)
+		cancel()
+		if err != nil {
+			log.Printf("[%s] Failed to save streamed positions: %v", s.exchangeName, err)
+		} else {
+			log.Printf("[%s] Streamed %d closed positions", s.exchangeName, len(ev.Closed))
+			publishPositionChanges(s.wsHub, s.exchangeName, changes, "stream")
+		}
+	}
+
+	if len(ev.Open) > 0 {
+		broadcastOpenPositions(s.wsHub, s.exchangeName, s.openPositions.Apply(s.exchangeName, ev.Open))
+	}
+}
@@ -0,0 +1,246 @@
+package server
+
+import (
+	"github.com/Ravierin/BudgetTracker/backend/internal/api"
+	"github.com/Ravierin/BudgetTracker/backend/internal/fakeexchange"
+	"github.com/Ravierin/BudgetTracker/backend/internal/model"
+	"github.com/Ravierin/BudgetTracker/backend/internal/repository"
+	"github.com/Ravierin/BudgetTracker/backend/internal/service"
+	"github.com/Ravierin/BudgetTracker/backend/pkg/cluster"
+	"github.com/Ravierin/BudgetTracker/backend/pkg/websocket"
+	"context"
+	"math"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// recordingRelay keeps the events a hub publishes, in order
+type recordingRelay struct {
+	mu     sync.Mutex
+	events []relayedEvent
+}
+
+type relayedEvent struct {
+	topic     string
+	eventType string
+	payload   interface{}
+}
+
+func (r *recordingRelay) Forward(topic, eventType string, payload interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, relayedEvent{topic: topic, eventType: eventType, payload: payload})
+}
+
+// take returns the events relayed so far and forgets them
+func (r *recordingRelay) take() []relayedEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	events := r.events
+	r.events = nil
+	return events
+}
+
+type syncFixture struct {
+	sync     *SyncService
+	stores   *repository.Stores
+	syncRuns *service.SyncRunService
+	relay    *recordingRelay
+}
+
+// newSyncFixture points both exchange clients at a fake exchange serving
+// scenario and syncs exchangeName into memory stores with apiSecret
+func newSyncFixture(t *testing.T, scenario *fakeexchange.Scenario, exchangeName, apiSecret string) *syncFixture {
+	t.Helper()
+	srv := httptest.NewServer(fakeexchange.NewServer(scenario))
+	t.Cleanup(srv.Close)
+
+	prevBybit, prevMEXC := api.BybitBaseURL, api.MEXCBaseURL
+	api.BybitBaseURL, api.MEXCBaseURL = srv.URL, srv.URL
+	t.Cleanup(func() { api.BybitBaseURL, api.MEXCBaseURL = prevBybit, prevMEXC })
+
+	stores := repository.NewMemoryStores()
+	apiKeys := service.NewAPIKeyService(stores.APIKeys)
+	key := &model.APIKey{Exchange: exchangeName, APIKey: scenario.APIKey, APISecret: apiSecret, IsActive: true}
+	if err := apiKeys.SaveAPIKey(context.Background(), key); err != nil {
+		t.Fatal(err)
+	}
+
+	relay := &recordingRelay{}
+	hub := websocket.NewHub()
+	hub.SetRelay(relay)
+
+	syncRuns := service.NewSyncRunService(stores.SyncRuns)
+	s := NewSyncService(
+		context.Background(),
+		service.NewPositionService(stores.Positions, stores.Rollup),
+		service.NewRawRecordService(stores.RawRecords, stores.Positions),
+		service.NewOpenPositionService(apiKeys),
+		apiKeys,
+		syncRuns,
+		hub,
+		cluster.Single{},
+		nil,
+		exchangeName,
+	)
+	return &syncFixture{sync: s, stores: stores, syncRuns: syncRuns, relay: relay}
+}
+
+// lastRun is the sync run recorded most recently for the exchange
+func (f *syncFixture) lastRun(t *testing.T, exchangeName string) model.SyncRun {
+	t.Helper()
+	runs, err := f.syncRuns.GetRuns(context.Background(), exchangeName, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 1 {
+		t.Fatalf("got %d sync runs, want 1", len(runs))
+	}
+	return runs[0]
+}
+
+func TestSyncAgainstFakeExchange(t *testing.T) {
+	scenario, err := fakeexchange.LoadScenario("default")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Closed PnL by order ID, as each exchange reports it
+	bybitPnl := make(map[string]float64)
+	for _, p := range scenario.Bybit.ClosedPnl {
+		pnl, err := strconv.ParseFloat(p.ClosedPnl, 64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		bybitPnl[p.OrderID] = pnl
+	}
+	mexcPnl := make(map[string]float64)
+	for _, p := range scenario.MEXC.HistoryPositions {
+		mexcPnl[strconv.FormatInt(p.PositionID, 10)] = p.CloseProfitLoss
+	}
+
+	// Flat Bybit positions are listed with size 0 and never reported open
+	bybitOpen := 0
+	for _, p := range scenario.Bybit.OpenPositions {
+		if size, _ := strconv.ParseFloat(p.Size, 64); size != 0 {
+			bybitOpen++
+		}
+	}
+
+	tests := []struct {
+		exchange string
+		pnl      map[string]float64
+		open     int
+	}{
+		{"bybit", bybitPnl, bybitOpen},
+		{"mexc", mexcPnl, len(scenario.MEXC.OpenPositions)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.exchange, func(t *testing.T) {
+			ctx := context.Background()
+			f := newSyncFixture(t, scenario, tt.exchange, scenario.APISecret)
+
+			if err := f.sync.sync(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			for orderID, pnl := range tt.pnl {
+				p, err := f.stores.Positions.GetPositionByOrderID(ctx, orderID)
+				if err != nil {
+					t.Fatalf("position %s: %v", orderID, err)
+				}
+				if p.Exchange != tt.exchange || math.Abs(p.ClosedPnl-pnl) > 1e-9 {
+					t.Errorf("position %s stored on %s with pnl %v, want %s with %v", orderID, p.Exchange, p.ClosedPnl, tt.exchange, pnl)
+				}
+			}
+			raw := 0
+			err = f.stores.RawRecords.StreamRawRecords(ctx, tt.exchange, func(model.RawExchangeRecord) error {
+				raw++
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if raw != len(tt.pnl) {
+				t.Errorf("stored %d raw records, want %d", raw, len(tt.pnl))
+			}
+
+			events := f.relay.take()
+			wantTypes := []string{websocket.EventSyncStatus, websocket.EventOpenPositionsUpdate, websocket.EventPositionsAdded, websocket.EventSyncStatus}
+			if len(events) != len(wantTypes) {
+				t.Fatalf("got %d events %+v, want %v", len(events), events, wantTypes)
+			}
+			for i, want := range wantTypes {
+				if events[i].eventType != want {
+					t.Errorf("event %d is %s, want %s", i, events[i].eventType, want)
+				}
+			}
+			if status := events[0].payload.(websocket.SyncStatus); status.Status != websocket.SyncStarted {
+				t.Errorf("first status %+v, want started", status)
+			}
+			if open := events[1].payload.(websocket.OpenPositionsUpdate); len(open.Positions) != tt.open {
+				t.Errorf("broadcast %d open positions, want %d", len(open.Positions), tt.open)
+			}
+			added := events[2].payload.(websocket.PositionsChanged)
+			if events[2].topic != websocket.ExchangeTopic(websocket.TopicPositions, tt.exchange) || added.Count != len(tt.pnl) || len(added.Positions) != len(tt.pnl) {
+				t.Errorf("positions_added on %s with %d positions, want %d", events[2].topic, added.Count, len(tt.pnl))
+			}
+			if status := events[3].payload.(websocket.SyncStatus); status.Status != websocket.SyncCompleted || status.Count != len(tt.pnl) {
+				t.Errorf("last status %+v, want completed with %d", status, len(tt.pnl))
+			}
+
+			run := f.lastRun(t, tt.exchange)
+			if run.Fetched != len(tt.pnl) || run.Inserted != len(tt.pnl) || run.Updated != 0 || run.ErrorClass != "" {
+				t.Errorf("sync run %+v, want %d fetched and inserted", run, len(tt.pnl))
+			}
+
+			// Syncing again finds nothing new, so no position events go out
+			if err := f.sync.sync(ctx); err != nil {
+				t.Fatal(err)
+			}
+			for _, e := range f.relay.take() {
+				if e.eventType == websocket.EventPositionsAdded || e.eventType == websocket.EventPositionsChanged {
+					t.Errorf("second sync published %s", e.eventType)
+				}
+			}
+			if run := f.lastRun(t, tt.exchange); run.Inserted != 0 || run.Updated != 0 {
+				t.Errorf("second sync run %+v, want nothing inserted or updated", run)
+			}
+		})
+	}
+}
+
+func TestSyncRecordsExchangeFailure(t *testing.T) {
+	scenario, err := fakeexchange.LoadScenario("default")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, exchangeName := range []string{"bybit", "mexc"} {
+		t.Run(exchangeName, func(t *testing.T) {
+			ctx := context.Background()
+			f := newSyncFixture(t, scenario, exchangeName, "wrong-secret")
+
+			if err := f.sync.sync(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			events := f.relay.take()
+			last := events[len(events)-1]
+			if status, ok := last.payload.(websocket.SyncStatus); !ok || status.Status != websocket.SyncFailed || status.Error == "" {
+				t.Errorf("last event %+v, want a failed sync status", last)
+			}
+			for _, e := range events {
+				if e.eventType == websocket.EventPositionsAdded {
+					t.Error("a failed sync published positions")
+				}
+			}
+			if run := f.lastRun(t, exchangeName); run.ErrorClass == "" || run.Inserted != 0 {
+				t.Errorf("sync run %+v, want a classified failure", run)
+			}
+		})
+	}
+}
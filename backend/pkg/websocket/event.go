@@ -0,0 +1,148 @@
+package websocket
+
+import (
+	"github.com/Ravierin/BudgetTracker/backend/internal/model"
+	"strings"
+	"time"
+)
+
+// ProtocolVersion is bumped whenever an event's payload changes incompatibly
+const ProtocolVersion = 2
+
+// Event is the envelope of every message sent to clients. Seq numbers the
+// published events in order, without gaps; replies to a client's own
+// requests carry the sequence number of the last published event.
+type Event struct {
+	Type      string      `json:"type"`
+	Version   int         `json:"version"`
+	Seq       uint64      `json:"seq"`
+	Timestamp time.Time   `json:"timestamp"`
+	Topic     string      `json:"topic,omitempty"`
+	Payload   interface{} `json:"payload"`
+}
+
+// Event types
+const (
+	EventPositionsAdded      = "positions_added"
+	EventPositionsChanged    = "positions_changed"
+	EventPositionCreated     = "position_created"
+	EventPositionDeleted     = "position_deleted"
+	EventPositionsImported   = "positions_imported"
+	EventOpenPositionsUpdate = "open_positions_update"
+	EventWithdrawalCreated   = "withdrawal_created"
+	EventWithdrawalDeleted   = "withdrawal_deleted"
+	EventBackupRestored      = "backup_restored"
+	EventSyncStatus          = "sync_status"
+	EventSubscriptions       = "subscriptions"
+	EventResyncRequired      = "resync_required"
+	EventError               = "error"
+)
+
+// Topics. Per-exchange topics are "<topic>:<exchange>", see ExchangeTopic.
+const (
+	TopicPositions     = "positions"
+	TopicOpenPositions = "open-positions"
+	TopicWithdrawals   = "withdrawals"
+	TopicBackup        = "backup"
+	TopicSyncStatus    = "sync-status"
+)
+
+// ExchangeTopic scopes a topic to an exchange; without an exchange the
+// event concerns the whole topic
+func ExchangeTopic(topic, exchange string) string {
+	if exchange == "" {
+		return topic
+	}
+	return topic + ":" + exchange
+}
+
+// topicMatches reports whether a subscription receives events of topic:
+// "positions" receives every "positions:<exchange>", and "positions:bybit"
+// receives events published on plain "positions"
+func topicMatches(subscription, topic string) bool {
+	if subscription == topic {
+		return true
+	}
+	if strings.HasPrefix(topic, subscription+":") {
+		return true
+	}
+	family, _, scoped := strings.Cut(subscription, ":")
+	return scoped && family == topic
+}
+
+// PositionsChanged is the payload of positions_added and positions_changed:
+// the positions a sync stored for the first time, or whose values it
+// changed. A sync that writes nothing new publishes neither.
+type PositionsChanged struct {
+	Exchange  string           `json:"exchange"`
+	Positions []model.Position `json:"positions"`
+	Count     int              `json:"count"`
+	// Source is "stream" for closes from a private stream, empty for syncs
+	Source string `json:"source,omitempty"`
+}
+
+// PositionDeleted is the payload of position_deleted
+type PositionDeleted struct {
+	PositionID int `json:"positionId"`
+}
+
+// PositionsImported is the payload of positions_imported
+type PositionsImported struct {
+	Exchange string `json:"exchange"`
+	Count    int    `json:"count"`
+}
+
+// OpenPositionsUpdate is the payload of open_positions_update, the whole
+// open position snapshot of an exchange
+type OpenPositionsUpdate struct {
+	Exchange      string               `json:"exchange"`
+	Positions     []model.OpenPosition `json:"positions"`
+	UnrealizedPnl float64              `json:"unrealizedPnl"`
+}
+
+// WithdrawalDeleted is the payload of withdrawal_deleted
+type WithdrawalDeleted struct {
+	WithdrawalID int `json:"withdrawalId"`
+}
+
+// Sync states
+const (
+	SyncStarted   = "started"
+	SyncCompleted = "completed"
+	SyncFailed    = "failed"
+)
+
+// SyncStatus is the payload of sync_status
+type SyncStatus struct {
+	Exchange string `json:"exchange"`
+	Status   string `json:"status"`
+	Count    int    `json:"count,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Subscriptions is the payload of subscriptions, the reply to a subscribe
+// or unsubscribe request. No topics means every event is delivered.
+type Subscriptions struct {
+	Topics []string `json:"topics"`
+}
+
+// ResyncRequired is the payload of resync_required, sent instead of a
+// replay when events after Since are no longer kept (Oldest is the first
+// one that is) or Since is ahead of the hub, which restarted. The client
+// should reload over REST and continue from the event's seq.
+type ResyncRequired struct {
+	Since  uint64 `json:"since"`
+	Oldest uint64 `json:"oldest"`
+}
+
+// ErrorPayload is the payload of error, the reply to an invalid request
+type ErrorPayload struct {
+	Message string `json:"message"`
+}
+
+// Request is a message from a client:
+// {"action": "subscribe"|"unsubscribe", "topics": [...]}
+type Request struct {
+	Action string   `json:"action"`
+	Topics []string `json:"topics"`
+}
@@ -1,109 +1,380 @@
 package websocket
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"log"
+	"net"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/gorilla/websocket"
 )
 
+// historySize is how many recent events the hub keeps for clients that
+// reconnect with ?since=<seq>
+const historySize = 512
+
+// sendBuffer holds a full replay plus the live events queued behind it.
+// A client that lets it fill up is disconnected as too slow.
+const sendBuffer = historySize + 256
+
+const (
+	// writeWait bounds every write, so a stalled peer can't block its writer
+	writeWait = 10 * time.Second
+	// pongWait is how long a client may stay silent, pongs included; pings
+	// go out often enough that a live client always answers in time
+	pongWait       = 60 * time.Second
+	pingPeriod     = pongWait * 9 / 10
+	maxMessageSize = 4096
+)
+
+// Relay forwards the events published on this instance to the hubs of
+// the others, which Deliver them
+type Relay interface {
+	Forward(topic, eventType string, payload interface{})
+}
+
 type Hub struct {
 	clients    map[*Client]bool
-	broadcast  chan interface{}
-	register   chan *Client
+	relay      Relay
+	register   chan registration
 	unregister chan *Client
-	mu         sync.RWMutex
+	// seq and history are guarded by mu, so an event is numbered, kept and
+	// delivered in one step and replays can't miss or repeat one
+	seq     uint64
+	history []published
+	mu      sync.RWMutex
+	// closing is set by Shutdown; writers counts the clients' writePumps
+	closing bool
+	writers sync.WaitGroup
+
+	published   atomic.Uint64
+	dropped     atomic.Uint64
+	slowClients atomic.Uint64
+	deadPeers   atomic.Uint64
+}
+
+// Metrics are the hub's counters since start
+type Metrics struct {
+	Clients   int    `json:"clients"`
+	Published uint64 `json:"published"`
+	// DroppedMessages were not delivered to a client whose buffer was full
+	DroppedMessages uint64 `json:"droppedMessages"`
+	// SlowClients were disconnected for not keeping up
+	SlowClients uint64 `json:"slowClients"`
+	// DeadPeers were disconnected for not answering pings
+	DeadPeers uint64 `json:"deadPeers"`
 }
 
 type Client struct {
 	hub  *Hub
 	conn *websocket.Conn
 	send chan []byte
+	// closeFrame is the close message sent once send is closed; set before
+	// closing it
+	closeFrame []byte
+
+	// topics the client subscribed to; none means every topic
+	topicsMu sync.RWMutex
+	topics   map[string]bool
+}
+
+// published is an encoded event kept for replay
+type published struct {
+	seq   uint64
+	topic string
+	data  []byte
+}
+
+type registration struct {
+	client *Client
+	// since is the last sequence number the client saw, nil for none
+	since *uint64
 }
 
+// NewHub starts numbering events from its start time (in units of 1/1024
+// ms, still exact as a JavaScript number), so a since from a previous run or
+// another instance falls outside the kept range and gets resync_required
 func NewHub() *Hub {
 	return &Hub{
 		clients:    make(map[*Client]bool),
-		broadcast:  make(chan interface{}, 256),
-		register:   make(chan *Client),
+		register:   make(chan registration),
 		unregister: make(chan *Client),
+		seq:        uint64(time.Now().UnixMilli()) << 10,
 	}
 }
 
 func (h *Hub) Run() {
 	for {
 		select {
-		case client := <-h.register:
+		case reg := <-h.register:
 			h.mu.Lock()
-			h.clients[client] = true
+			if h.closing {
+				reg.client.closeFrame = goingAway
+				close(reg.client.send)
+				h.mu.Unlock()
+				continue
+			}
+			h.clients[reg.client] = true
+			if reg.since != nil {
+				h.replay(reg.client, *reg.since)
+			}
 			h.mu.Unlock()
 
 		case client := <-h.unregister:
 			h.mu.Lock()
-			if _, ok := h.clients[client]; ok {
-				delete(h.clients, client)
-				close(client.send)
-			}
+			h.remove(client)
 			h.mu.Unlock()
+		}
+	}
+}
 
-		case message := <-h.broadcast:
-			data, err := json.Marshal(message)
-			if err != nil {
-				continue
-			}
+// remove is the only place a client leaves the hub: closing send makes its
+// writer send a close frame and hang up. Called with mu held.
+func (h *Hub) remove(client *Client) {
+	if _, ok := h.clients[client]; ok {
+		delete(h.clients, client)
+		close(client.send)
+	}
+}
 
-			h.mu.RLock()
-			for client := range h.clients {
-				select {
-				case client.send <- data:
-				default:
-					close(client.send)
-					delete(h.clients, client)
-				}
-			}
-			h.mu.RUnlock()
+// goingAway tells clients the server is shutting down, so they reconnect
+// (with ?since=) once it is back
+var goingAway = websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down")
+
+// Shutdown disconnects every client with a going-away close frame and
+// waits until the frames are written or ctx ends. Clients connecting
+// afterwards are refused; ones already upgrading are closed the same way.
+func (h *Hub) Shutdown(ctx context.Context) error {
+	h.mu.Lock()
+	h.closing = true
+	for client := range h.clients {
+		client.closeFrame = goingAway
+		h.remove(client)
+	}
+	h.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		h.writers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// replay queues the kept events after since for a client, or tells it to
+// resync when some of them are no longer kept. Called with mu held.
+func (h *Hub) replay(client *Client, since uint64) {
+	oldest := h.seq + 1
+	if len(h.history) > 0 {
+		oldest = h.history[0].seq
+	}
+	if since > h.seq || since+1 < oldest {
+		h.sendTo(client, h.event(EventResyncRequired, "", ResyncRequired{Since: since, Oldest: oldest}))
+		return
+	}
+
+	for _, p := range h.history {
+		if p.seq > since && client.subscribed(p.topic) {
+			client.send <- p.data
 		}
 	}
 }
 
-func (h *Hub) Broadcast(message interface{}) {
+// SetRelay makes Publish forward events to other instances. Call it before
+// publishing starts.
+func (h *Hub) SetRelay(relay Relay) {
+	h.relay = relay
+}
+
+// Publish numbers an event, keeps it for replay and sends it to the clients
+// subscribed to its topic, here and on the other instances
+func (h *Hub) Publish(topic, eventType string, payload interface{}) {
+	h.publish(topic, eventType, payload)
+	if h.relay != nil {
+		h.relay.Forward(topic, eventType, payload)
+	}
+}
+
+// Deliver publishes an event relayed from another instance to this
+// instance's clients. Sequence numbers are per instance.
+func (h *Hub) Deliver(topic, eventType string, payload json.RawMessage) {
+	h.publish(topic, eventType, payload)
+}
+
+func (h *Hub) publish(topic, eventType string, payload interface{}) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	event := h.event(eventType, topic, payload)
+	event.Seq = h.seq + 1
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Failed to encode %s event: %v", eventType, err)
+		return
+	}
+	h.seq = event.Seq
+	h.published.Add(1)
+
+	if len(h.history) == historySize {
+		copy(h.history, h.history[1:])
+		h.history = h.history[:historySize-1]
+	}
+	h.history = append(h.history, published{seq: event.Seq, topic: topic, data: data})
+
+	for client := range h.clients {
+		if !client.subscribed(topic) {
+			continue
+		}
+		select {
+		case client.send <- data:
+		default:
+			h.dropped.Add(1)
+			h.slowClients.Add(1)
+			log.Printf("WebSocket client %s too slow, disconnecting", client.conn.RemoteAddr())
+			h.remove(client)
+		}
+	}
+}
+
+// event wraps a payload; Seq is the last published sequence number until
+// Publish numbers it. Called with mu held.
+func (h *Hub) event(eventType, topic string, payload interface{}) Event {
+	return Event{
+		Type:      eventType,
+		Version:   ProtocolVersion,
+		Seq:       h.seq,
+		Timestamp: time.Now().UTC(),
+		Topic:     topic,
+		Payload:   payload,
+	}
+}
+
+// sendTo queues an event for one client. Called with mu held.
+func (h *Hub) sendTo(client *Client, event Event) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
 	select {
-	case h.broadcast <- message:
+	case client.send <- data:
 	default:
+		h.dropped.Add(1)
 	}
 }
 
+// Metrics returns the hub's counters
+func (h *Hub) Metrics() Metrics {
+	h.mu.RLock()
+	clients := len(h.clients)
+	h.mu.RUnlock()
+
+	return Metrics{
+		Clients:         clients,
+		Published:       h.published.Load(),
+		DroppedMessages: h.dropped.Load(),
+		SlowClients:     h.slowClients.Load(),
+		DeadPeers:       h.deadPeers.Load(),
+	}
+}
+
+func (h *Hub) HandleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.Metrics())
+}
+
+// reply answers a client's own request
+func (h *Hub) reply(client *Client, eventType string, payload interface{}) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.clients[client]; ok {
+		h.sendTo(client, h.event(eventType, "", payload))
+	}
+}
+
+// HandleWebSocket accepts a client. ?topics=a,b subscribes it up front and
+// ?since=<seq> replays the events published after seq on those topics.
 func (h *Hub) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	var since *uint64
+	if v := q.Get("since"); v != "" {
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid since", http.StatusBadRequest)
+			return
+		}
+		since = &n
+	}
+
+	// The writer is counted before the client reaches Run, so Shutdown
+	// waits for it; once Shutdown has begun, new clients are turned away
+	h.mu.Lock()
+	if h.closing {
+		h.mu.Unlock()
+		http.Error(w, "Server shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	h.writers.Add(1)
+	h.mu.Unlock()
+
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
+		h.writers.Done()
 		log.Printf("WebSocket upgrade error: %v", err)
 		return
 	}
 
 	client := &Client{
-		hub:  h,
-		conn: conn,
-		send: make(chan []byte, 256),
+		hub:    h,
+		conn:   conn,
+		send:   make(chan []byte, sendBuffer),
+		topics: make(map[string]bool),
+	}
+	for _, topic := range strings.Split(q.Get("topics"), ",") {
+		if topic = strings.TrimSpace(topic); topic != "" {
+			client.topics[topic] = true
+		}
 	}
 
-	h.register <- client
+	h.register <- registration{client: client, since: since}
 
 	go client.writePump()
 	go client.readPump()
 }
 
+// writePump is the connection's only writer. A failed write closes the
+// connection, which ends readPump and unregisters the client.
 func (c *Client) writePump() {
+	ticker := time.NewTicker(pingPeriod)
 	defer func() {
+		ticker.Stop()
 		c.conn.Close()
+		c.hub.writers.Done()
 	}()
 
 	for {
 		select {
 		case message, ok := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
 			if !ok {
-				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				closeFrame := c.closeFrame
+				if closeFrame == nil {
+					closeFrame = []byte{}
+				}
+				c.conn.WriteMessage(websocket.CloseMessage, closeFrame)
 				return
 			}
 
@@ -116,24 +387,91 @@ func (c *Client) writePump() {
 			if err := w.Close(); err != nil {
 				return
 			}
+
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
 		}
 	}
 }
 
+// readPump handles client requests until the connection fails or the
+// client stays silent, pongs included, for pongWait
 func (c *Client) readPump() {
 	defer func() {
 		c.hub.unregister <- c
 		c.conn.Close()
 	}()
 
+	c.conn.SetReadLimit(maxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
 	for {
-		_, _, err := c.conn.ReadMessage()
+		_, data, err := c.conn.ReadMessage()
 		if err != nil {
-			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				c.hub.deadPeers.Add(1)
+				log.Printf("WebSocket client %s stopped answering pings, disconnecting", c.conn.RemoteAddr())
 			}
 			break
 		}
+		c.conn.SetReadDeadline(time.Now().Add(pongWait))
+		c.handleRequest(data)
+	}
+}
+
+func (c *Client) handleRequest(data []byte) {
+	var req Request
+	if err := json.Unmarshal(data, &req); err != nil {
+		c.hub.reply(c, EventError, ErrorPayload{Message: "invalid request: " + err.Error()})
+		return
+	}
+
+	c.topicsMu.Lock()
+	switch req.Action {
+	case "subscribe":
+		for _, topic := range req.Topics {
+			c.topics[topic] = true
+		}
+	case "unsubscribe":
+		for _, topic := range req.Topics {
+			delete(c.topics, topic)
+		}
+	default:
+		c.topicsMu.Unlock()
+		c.hub.reply(c, EventError, ErrorPayload{Message: "unknown action: " + req.Action})
+		return
+	}
+	topics := make([]string, 0, len(c.topics))
+	for topic := range c.topics {
+		topics = append(topics, topic)
+	}
+	c.topicsMu.Unlock()
+
+	sort.Strings(topics)
+	c.hub.reply(c, EventSubscriptions, Subscriptions{Topics: topics})
+}
+
+// subscribed reports whether the client receives events of topic
+func (c *Client) subscribed(topic string) bool {
+	c.topicsMu.RLock()
+	defer c.topicsMu.RUnlock()
+
+	if len(c.topics) == 0 {
+		return true
+	}
+	for subscription := range c.topics {
+		if topicMatches(subscription, topic) {
+			return true
+		}
 	}
+	return false
 }
 
 var upgrader = websocket.Upgrader{
@@ -0,0 +1,236 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestTopicMatches(t *testing.T) {
+	tests := []struct {
+		subscription, topic string
+		want                bool
+	}{
+		{"positions", "positions", true},
+		{"positions", "positions:bybit", true},
+		{"positions:bybit", "positions", true},
+		{"positions:bybit", "positions:bybit", true},
+		{"positions:bybit", "positions:mexc", false},
+		{"positions", "open-positions", false},
+		{"positions", "open-positions:bybit", false},
+		{"open-positions", "positions", false},
+		{"pos", "positions:bybit", false},
+		{"positions:bybit", "withdrawals", false},
+	}
+	for _, tt := range tests {
+		if got := topicMatches(tt.subscription, tt.topic); got != tt.want {
+			t.Errorf("topicMatches(%q, %q) = %v, want %v", tt.subscription, tt.topic, got, tt.want)
+		}
+	}
+}
+
+// received is an event as a client decodes it
+type received struct {
+	Type    string          `json:"type"`
+	Seq     uint64          `json:"seq"`
+	Topic   string          `json:"topic"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+func newTestHub(t *testing.T) (*Hub, string) {
+	t.Helper()
+	hub := NewHub()
+	go hub.Run()
+	srv := httptest.NewServer(http.HandlerFunc(hub.HandleWebSocket))
+	t.Cleanup(srv.Close)
+	return hub, "ws" + strings.TrimPrefix(srv.URL, "http")
+}
+
+func dial(t *testing.T, url string) *websocket.Conn {
+	t.Helper()
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func readEvent(t *testing.T, conn *websocket.Conn) received {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var e received
+	if err := conn.ReadJSON(&e); err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+// expectNothingQueued asks for the client's subscriptions and expects the
+// reply to be the next message, i.e. nothing else was sent before it. The
+// reply also proves the hub has registered the client.
+func expectNothingQueued(t *testing.T, conn *websocket.Conn) received {
+	t.Helper()
+	if err := conn.WriteJSON(Request{Action: "subscribe"}); err != nil {
+		t.Fatal(err)
+	}
+	e := readEvent(t, conn)
+	if e.Type != EventSubscriptions {
+		t.Fatalf("got %s on %q, want nothing before the subscriptions reply", e.Type, e.Topic)
+	}
+	return e
+}
+
+func lastSeq(h *Hub) uint64 {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.seq
+}
+
+func TestPublishFiltersByTopicAndNumbersEvents(t *testing.T) {
+	hub, url := newTestHub(t)
+	all := dial(t, url)
+	bybit := dial(t, url+"?topics=positions:bybit")
+	withdrawals := dial(t, url+"?topics=withdrawals,backup")
+	for _, conn := range []*websocket.Conn{all, bybit, withdrawals} {
+		expectNothingQueued(t, conn)
+	}
+
+	before := lastSeq(hub)
+	topics := []string{"positions:bybit", "positions:mexc", TopicWithdrawals, TopicPositions}
+	for _, topic := range topics {
+		hub.Publish(topic, EventPositionsAdded, PositionsChanged{})
+	}
+
+	tests := []struct {
+		name string
+		conn *websocket.Conn
+		want []int
+	}{
+		{"no topics", all, []int{0, 1, 2, 3}},
+		{"exchange topic", bybit, []int{0, 3}},
+		{"other topic", withdrawals, []int{2}},
+	}
+	for _, tt := range tests {
+		for _, i := range tt.want {
+			e := readEvent(t, tt.conn)
+			if e.Topic != topics[i] || e.Seq != before+uint64(i)+1 {
+				t.Errorf("%s: got %q with seq %d, want %q with seq %d", tt.name, e.Topic, e.Seq, topics[i], before+uint64(i)+1)
+			}
+		}
+		// Replies carry the seq of the last published event
+		if reply := expectNothingQueued(t, tt.conn); reply.Seq != before+uint64(len(topics)) {
+			t.Errorf("%s: reply seq %d, want %d", tt.name, reply.Seq, before+uint64(len(topics)))
+		}
+	}
+}
+
+func TestSinceReplaysKeptEvents(t *testing.T) {
+	hub, url := newTestHub(t)
+	first := lastSeq(hub) + 1
+	topics := []string{"positions:bybit", TopicWithdrawals, "positions:mexc", TopicWithdrawals, TopicPositions}
+	for _, topic := range topics {
+		hub.Publish(topic, EventPositionsAdded, PositionsChanged{})
+	}
+
+	// Everything after the second event, on the positions topics only
+	conn := dial(t, url+"?topics=positions&since="+strconv.FormatUint(first+1, 10))
+	for _, i := range []int{2, 4} {
+		if e := readEvent(t, conn); e.Topic != topics[i] || e.Seq != first+uint64(i) {
+			t.Errorf("replayed %q with seq %d, want %q with seq %d", e.Topic, e.Seq, topics[i], first+uint64(i))
+		}
+	}
+	expectNothingQueued(t, conn)
+
+	hub.Publish("positions:bybit", EventPositionsAdded, PositionsChanged{})
+	if e := readEvent(t, conn); e.Seq != first+uint64(len(topics)) {
+		t.Errorf("live event seq %d, want %d after the replay", e.Seq, first+uint64(len(topics)))
+	}
+
+	// A client that is up to date gets nothing replayed
+	upToDate := dial(t, url+"?since="+strconv.FormatUint(lastSeq(hub), 10))
+	expectNothingQueued(t, upToDate)
+}
+
+func TestSinceOutsideHistoryRequiresResync(t *testing.T) {
+	hub, url := newTestHub(t)
+	first := lastSeq(hub) + 1
+	const extra = 10
+	for i := 0; i < historySize+extra; i++ {
+		hub.Publish(TopicPositions, EventPositionsAdded, PositionsChanged{})
+	}
+	oldest := first + extra
+	newest := lastSeq(hub)
+
+	tests := []struct {
+		name  string
+		since uint64
+	}{
+		{"older than the history", oldest - 2},
+		{"ahead of the hub", newest + 1},
+	}
+	for _, tt := range tests {
+		conn := dial(t, url+"?since="+strconv.FormatUint(tt.since, 10))
+		e := readEvent(t, conn)
+		var payload ResyncRequired
+		if err := json.Unmarshal(e.Payload, &payload); err != nil {
+			t.Fatal(err)
+		}
+		if e.Type != EventResyncRequired || e.Seq != newest || payload != (ResyncRequired{Since: tt.since, Oldest: oldest}) {
+			t.Errorf("%s: got %s with seq %d and %+v, want resync_required at %d from %d", tt.name, e.Type, e.Seq, payload, newest, oldest)
+		}
+		expectNothingQueued(t, conn)
+	}
+
+	// The event just before the oldest kept one leaves no gap to replay
+	conn := dial(t, url+"?since="+strconv.FormatUint(oldest-1, 10))
+	for seq := oldest; seq <= newest; seq++ {
+		if e := readEvent(t, conn); e.Type != EventPositionsAdded || e.Seq != seq {
+			t.Fatalf("replayed %s with seq %d, want seq %d", e.Type, e.Seq, seq)
+		}
+	}
+	expectNothingQueued(t, conn)
+}
+
+func TestShutdownClosesAndRefusesClients(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+	srv := httptest.NewServer(http.HandlerFunc(hub.HandleWebSocket))
+	defer srv.Close()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http")
+
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := hub.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, _, err = conn.ReadMessage()
+	var closeErr *websocket.CloseError
+	if !errors.As(err, &closeErr) || closeErr.Code != websocket.CloseGoingAway {
+		t.Fatalf("read after shutdown: %v, want a going-away close", err)
+	}
+
+	_, resp, err := websocket.DefaultDialer.Dial(url, nil)
+	if err == nil {
+		t.Fatal("connected after shutdown")
+	}
+	if resp == nil || resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("dial after shutdown: %v, want 503", err)
+	}
+}
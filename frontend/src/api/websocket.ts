@@ -1,12 +1,38 @@
-export type WSMessage = {
+import type { OpenPosition, Position, Withdrawal } from '../types';
+
+// Envelope of every server message; `seq` numbers published events in order
+export type WSMessage<P = any> = {
   type: string;
-  data?: any;
-  positions?: any[];
+  version: number;
+  seq: number;
+  timestamp: string;
+  topic?: string;
+  payload: P;
+};
+
+// Payload of positions_added and positions_changed; only rows a sync
+// actually wrote are sent
+export type PositionsChangedPayload = {
+  exchange: string;
+  positions: Position[];
+  count: number;
+  source?: 'stream';
+};
+export type PositionDeletedPayload = { positionId: number };
+export type PositionsImportedPayload = { exchange: string; count: number };
+export type OpenPositionsUpdatePayload = {
+  exchange: string;
+  positions: OpenPosition[];
+  unrealizedPnl: number;
+};
+export type WithdrawalCreatedPayload = Withdrawal;
+export type WithdrawalDeletedPayload = { withdrawalId: number };
+export type ResyncRequiredPayload = { since: number; oldest: number };
+export type SyncStatusPayload = {
+  exchange: string;
+  status: 'started' | 'completed' | 'failed';
   count?: number;
-  exchange?: string;
-  positionId?: number;
-  withdrawalId?: number;
-  incomeId?: number;
+  error?: string;
 };
 
 export type WSListener = (message: WSMessage) => void;
@@ -14,6 +40,10 @@ export type WSListener = (message: WSMessage) => void;
 class WebSocketService {
   private ws: WebSocket | null = null;
   private listeners: WSListener[] = [];
+  // Topics such as "positions:bybit" or "sync-status"; empty receives everything
+  private topics = new Set<string>();
+  // Last event seen; reconnects ask the server to replay what came after it
+  private lastSeq: number | null = null;
   private reconnectTimeout = 3000;
   private isManualClose = false;
 
@@ -21,7 +51,15 @@ class WebSocketService {
     this.isManualClose = false;
     
     try {
-      this.ws = new WebSocket(url);
+      const params = new URLSearchParams();
+      if (this.lastSeq !== null) {
+        params.set('since', String(this.lastSeq));
+      }
+      if (this.topics.size > 0) {
+        params.set('topics', [...this.topics].join(','));
+      }
+      const query = params.toString();
+      this.ws = new WebSocket(query ? `${url}?${query}` : url);
 
       this.ws.onopen = () => {
         console.log('WebSocket connected');
@@ -31,6 +69,8 @@ class WebSocketService {
       this.ws.onmessage = (event) => {
         try {
           const message: WSMessage = JSON.parse(event.data);
+          // resync_required: missed events are gone, listeners reload instead
+          this.lastSeq = message.seq;
           this.listeners.forEach(listener => listener(message));
         } catch (e) {
           console.error('Failed to parse WS message:', e);
@@ -61,6 +101,22 @@ class WebSocketService {
     }
   }
 
+  subscribe(...topics: string[]) {
+    topics.forEach(t => this.topics.add(t));
+    this.send({ action: 'subscribe', topics });
+  }
+
+  unsubscribe(...topics: string[]) {
+    topics.forEach(t => this.topics.delete(t));
+    this.send({ action: 'unsubscribe', topics });
+  }
+
+  private send(request: { action: 'subscribe' | 'unsubscribe'; topics: string[] }) {
+    if (this.ws?.readyState === WebSocket.OPEN) {
+      this.ws.send(JSON.stringify(request));
+    }
+  }
+
   addListener(listener: WSListener) {
     this.listeners.push(listener);
     return () => {
@@ -41,7 +41,7 @@ export function Dashboard() {
     calculateStats();
 
     const unsubscribe = wsService.addListener((message: WSMessage) => {
-      if (['positions_update', 'position_created', 'position_deleted'].includes(message.type)) {
+      if (['positions_added', 'positions_changed', 'position_created', 'position_deleted', 'positions_imported', 'resync_required'].includes(message.type)) {
         calculateStats();
       }
     });
@@ -25,7 +25,7 @@ export function MonthlyIncome() {
     loadIncomes();
 
     const unsubscribe = wsService.addListener((message: WSMessage) => {
-      if (['monthly_income_created', 'monthly_income_deleted'].includes(message.type)) {
+      if (['monthly_income_created', 'monthly_income_deleted', 'positions_added', 'positions_changed', 'position_created', 'position_deleted', 'positions_imported', 'resync_required'].includes(message.type)) {
         loadIncomes();
       }
     });
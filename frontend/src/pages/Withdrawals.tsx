@@ -43,7 +43,7 @@ export function Withdrawals() {
     loadWithdrawals();
 
     const unsubscribe = wsService.addListener((message: WSMessage) => {
-      if (['withdrawal_created', 'withdrawal_deleted'].includes(message.type)) {
+      if (['withdrawal_created', 'withdrawal_deleted', 'resync_required'].includes(message.type)) {
         loadWithdrawals();
       }
     });
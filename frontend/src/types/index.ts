@@ -3,6 +3,10 @@ export interface Position {
   orderId: string;
   exchange: string;
   symbol: string;
+  baseAsset?: string;
+  quoteAsset?: string;
+  contractType?: string;
+  instrument?: string;
   volume: number;
   leverage: number;
   closedPnl: number;
@@ -10,6 +14,21 @@ export interface Position {
   date: string;
 }
 
+export interface OpenPosition {
+  exchange: string;
+  symbol: string;
+  instrument: string;
+  side: string;
+  size: number;
+  entryPrice: number;
+  markPrice: number;
+  positionValue: number;
+  unrealizedPnl: number;
+  leverage: number;
+  liquidationPrice: number;
+  updatedAt: string;
+}
+
 export interface Withdrawal {
   id: number;
   exchange: string;